@@ -0,0 +1,115 @@
+// Package activity samples how long the user has been idle, to tell how
+// many minutes of a work phase had any keyboard or mouse input. It never
+// sees the input itself, only the time since the last of it.
+package activity
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+var ErrUnsupported = errors.New("cannot tell how long the user has been idle on this system")
+
+// DefaultInterval is how often a Sampler asks; a minute with input is an
+// active one.
+const DefaultInterval = time.Minute
+
+// Prober reports how long it has been since the last keyboard or mouse
+// input.
+type Prober interface {
+	Idle() (time.Duration, error)
+}
+
+// Fake is a Prober that answers from Idles in turn, repeating the last.
+type Fake struct {
+	mu    sync.Mutex
+	Idles []time.Duration
+	next  int
+}
+
+func (f *Fake) Idle() (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.Idles) == 0 {
+		return 0, nil
+	}
+	d := f.Idles[min(f.next, len(f.Idles)-1)]
+	f.next++
+	return d, nil
+}
+
+// Sampler asks a Prober every interval while started, from a background
+// goroutine that only runs between Start and Stop, and counts the samples
+// that found input since the one before. Start and Stop are for one
+// goroutine; Take can be called from any.
+type Sampler struct {
+	prober   Prober
+	clock    engine.Clock
+	interval time.Duration
+
+	mu      sync.Mutex
+	sampled int
+	active  int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewSampler(p Prober, clock engine.Clock, interval time.Duration) *Sampler {
+	return &Sampler{prober: p, clock: clock, interval: interval}
+}
+
+// Start begins sampling, one interval from now. It does nothing if the
+// sampler is already running.
+func (s *Sampler) Start() {
+	if s.stop != nil {
+		return
+	}
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	go s.run(s.clock.NewTicker(s.interval), s.stop, s.done)
+}
+
+// Stop ends sampling and waits for the goroutine to exit. Counts so far are
+// kept.
+func (s *Sampler) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop, s.done = nil, nil
+}
+
+// Take returns the counts since the last Take and starts them over.
+func (s *Sampler) Take() (sampled, active int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sampled, active = s.sampled, s.active
+	s.sampled, s.active = 0, 0
+	return sampled, active
+}
+
+func (s *Sampler) run(ticker engine.Ticker, stop, done chan struct{}) {
+	defer close(done)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C():
+		}
+		idle, err := s.prober.Idle()
+		if err != nil {
+			continue
+		}
+		s.mu.Lock()
+		s.sampled++
+		if idle < s.interval {
+			s.active++
+		}
+		s.mu.Unlock()
+	}
+}
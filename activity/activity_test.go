@@ -0,0 +1,74 @@
+package activity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// step moves clock on a minute and waits for s to have taken want samples.
+func step(t *testing.T, clock *engine.MockClock, s *Sampler, want int) {
+	t.Helper()
+	clock.Advance(time.Minute)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		sampled := s.sampled
+		s.mu.Unlock()
+		if sampled == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d samples, want %d", sampled, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	clock := engine.NewMockClock(time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC))
+	// Input 10s and 59s before the first and third samples; none for the
+	// second's whole minute, nor since.
+	fake := &Fake{Idles: []time.Duration{10 * time.Second, 2 * time.Minute, 59 * time.Second, time.Minute}}
+	s := NewSampler(fake, clock, time.Minute)
+
+	s.Start()
+	s.Start()
+	for i := 1; i <= 3; i++ {
+		step(t, clock, s, i)
+	}
+	s.Stop()
+	if sampled, active := s.Take(); sampled != 3 || active != 2 {
+		t.Errorf("took %d samples, %d active, want 3, 2", sampled, active)
+	}
+
+	// Stopped, as through a break or pause, it asks nothing.
+	clock.Advance(10 * time.Minute)
+	s.Stop()
+	if sampled, active := s.Take(); sampled != 0 || active != 0 || fake.next != 3 {
+		t.Errorf("stopped: took %d samples, %d active, and asked %d times, want none and 3", sampled, active, fake.next)
+	}
+
+	// Started again it samples a minute on, and idle for exactly the
+	// interval counts as no input.
+	s.Start()
+	clock.Advance(59 * time.Second)
+	step(t, clock, s, 1)
+	s.Stop()
+	if sampled, active := s.Take(); sampled != 1 || active != 0 {
+		t.Errorf("restarted: took %d samples, %d active, want 1, 0", sampled, active)
+	}
+}
+
+func TestFakeRepeatsLast(t *testing.T) {
+	f := &Fake{Idles: []time.Duration{time.Second, time.Minute}}
+	for i, want := range []time.Duration{time.Second, time.Minute, time.Minute} {
+		if got, err := f.Idle(); got != want || err != nil {
+			t.Errorf("call %d: %s, %v, want %s", i, got, err, want)
+		}
+	}
+	if got, err := (&Fake{}).Idle(); got != 0 || err != nil {
+		t.Errorf("no idles: %s, %v, want 0", got, err)
+	}
+}
@@ -0,0 +1,77 @@
+package activity
+
+import "time"
+
+// AwayPolicy is what to do about a phase no one has touched since it
+// began, such as the work after a break the user walked away from: pause
+// it PauseAfter into the phase, then stop the session StopAfter after
+// that. A zero PauseAfter stops StopAfter into the phase without pausing
+// first; a zero StopAfter only pauses.
+type AwayPolicy struct {
+	PauseAfter time.Duration
+	StopAfter  time.Duration
+}
+
+// Off reports whether the policy does nothing.
+func (p AwayPolicy) Off() bool {
+	return p.PauseAfter <= 0 && p.StopAfter <= 0
+}
+
+// AwayStage is where an AwayWatch has got to.
+type AwayStage int
+
+const (
+	// StagePresent has seen input since the phase began, or not for long
+	// enough to act on.
+	StagePresent AwayStage = iota
+	// StagePaused calls for pausing the phase.
+	StagePaused
+	// StageStopped calls for stopping the session. It is final.
+	StageStopped
+)
+
+// AwayWatch runs an AwayPolicy over one phase at a time. It only decides;
+// pausing and stopping are up to the caller.
+type AwayWatch struct {
+	policy AwayPolicy
+	stage  AwayStage
+	// pausedIdle is the idle time when the watch paused, which input since
+	// takes idle back under.
+	pausedIdle time.Duration
+}
+
+// Reset starts the watch over for a new phase under p.
+func (w *AwayWatch) Reset(p AwayPolicy) {
+	*w = AwayWatch{policy: p}
+}
+
+// Stage is where the watch has got to.
+func (w *AwayWatch) Stage() AwayStage { return w.stage }
+
+// Observe moves the watch on given how far into the phase it is, not
+// counting pauses, and how long since the last input. Input seen while
+// paused goes back to StagePresent.
+func (w *AwayWatch) Observe(into, idle time.Duration) AwayStage {
+	p := w.policy
+	switch w.stage {
+	case StagePresent:
+		// Any input since the phase began means someone is there.
+		if idle < into {
+			break
+		}
+		switch {
+		case p.PauseAfter > 0 && into >= p.PauseAfter:
+			w.stage, w.pausedIdle = StagePaused, idle
+		case p.PauseAfter <= 0 && p.StopAfter > 0 && into >= p.StopAfter:
+			w.stage = StageStopped
+		}
+	case StagePaused:
+		switch {
+		case idle < w.pausedIdle:
+			w.stage = StagePresent
+		case p.StopAfter > 0 && idle-w.pausedIdle >= p.StopAfter:
+			w.stage = StageStopped
+		}
+	}
+	return w.stage
+}
@@ -0,0 +1,95 @@
+package activity
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAwayWatch drives a watch with polls of how far into the phase it is
+// and how long since the last input, checking the stage after each.
+func TestAwayWatch(t *testing.T) {
+	type poll struct {
+		into, idle time.Duration
+		want       AwayStage
+	}
+	m := time.Minute
+	tests := []struct {
+		name   string
+		policy AwayPolicy
+		polls  []poll
+	}{
+		{"gone, paused then stopped", AwayPolicy{PauseAfter: 5 * m, StopAfter: 10 * m}, []poll{
+			{1 * m, 20 * m, StagePresent},
+			{5 * m, 24 * m, StagePaused},
+			// Paused, the phase stands still while idle goes on.
+			{5 * m, 30 * m, StagePaused},
+			{5 * m, 34*m - time.Second, StagePaused},
+			{5 * m, 34 * m, StageStopped},
+			// Stopped is final, whatever comes after.
+			{5 * m, 0, StageStopped},
+		}},
+		{"back while paused", AwayPolicy{PauseAfter: 5 * m, StopAfter: 10 * m}, []poll{
+			{5 * m, 24 * m, StagePaused},
+			{5 * m, 10 * time.Second, StagePresent},
+			// Present, the watch waits on idle to cover the phase again.
+			{7 * m, 2 * m, StagePresent},
+			{8 * m, 8 * m, StagePaused},
+		}},
+		{"input since the phase began", AwayPolicy{PauseAfter: 5 * m, StopAfter: 10 * m}, []poll{
+			{5 * m, 4 * m, StagePresent},
+			{20 * m, 19 * m, StagePresent},
+			{30 * m, 30 * m, StagePaused},
+		}},
+		{"pause only", AwayPolicy{PauseAfter: 5 * m}, []poll{
+			{5 * m, 5 * m, StagePaused},
+			{5 * m, 10 * time.Hour, StagePaused},
+		}},
+		{"stop only", AwayPolicy{StopAfter: 10 * m}, []poll{
+			{5 * m, 5 * m, StagePresent},
+			{10*m - time.Second, 10 * m, StagePresent},
+			{10 * m, 10 * m, StageStopped},
+		}},
+		{"off", AwayPolicy{}, []poll{
+			{time.Hour, time.Hour, StagePresent},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w AwayWatch
+			w.Reset(tt.policy)
+			for i, p := range tt.polls {
+				if got := w.Observe(p.into, p.idle); got != p.want || w.Stage() != got {
+					t.Fatalf("poll %d, %s in and %s idle: stage %d, want %d", i+1, p.into, p.idle, got, p.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAwayWatchReset(t *testing.T) {
+	var w AwayWatch
+	w.Reset(AwayPolicy{StopAfter: time.Minute})
+	if w.Observe(time.Minute, time.Minute) != StageStopped {
+		t.Fatal("not stopped")
+	}
+	w.Reset(AwayPolicy{PauseAfter: time.Minute})
+	if w.Stage() != StagePresent || w.Observe(time.Minute, time.Minute) != StagePaused {
+		t.Error("reset did not start the watch over under the new policy")
+	}
+}
+
+func TestAwayPolicyOff(t *testing.T) {
+	for _, tt := range []struct {
+		p    AwayPolicy
+		want bool
+	}{
+		{AwayPolicy{}, true},
+		{AwayPolicy{PauseAfter: -time.Minute}, true},
+		{AwayPolicy{PauseAfter: time.Minute}, false},
+		{AwayPolicy{StopAfter: time.Minute}, false},
+	} {
+		if got := tt.p.Off(); got != tt.want {
+			t.Errorf("%+v: off %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
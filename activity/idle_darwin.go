@@ -0,0 +1,36 @@
+package activity
+
+import (
+	"errors"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+type ioreg struct{}
+
+// New reads HIDIdleTime from the IOHIDSystem registry entry through ioreg,
+// which needs no permissions.
+func New() (Prober, error) {
+	if _, err := exec.LookPath("ioreg"); err != nil {
+		return nil, ErrUnsupported
+	}
+	return ioreg{}, nil
+}
+
+// hidIdle matches e.g. `"HIDIdleTime" = 1234567890`, in nanoseconds.
+var hidIdle = regexp.MustCompile(`"HIDIdleTime" = (\d+)`)
+
+func (ioreg) Idle() (time.Duration, error) {
+	out, err := exec.Command("ioreg", "-c", "IOHIDSystem", "-d", "4").Output()
+	if err != nil {
+		return 0, err
+	}
+	m := hidIdle.FindSubmatch(out)
+	if m == nil {
+		return 0, errors.New("ioreg reported no HIDIdleTime")
+	}
+	ns, err := strconv.ParseInt(string(m[1]), 10, 64)
+	return time.Duration(ns), err
+}
@@ -0,0 +1,59 @@
+package activity
+
+import (
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// New picks xprintidle under X11 and Mutter's idle monitor under GNOME on
+// Wayland. Other Wayland compositors do not tell clients how long the
+// user has been idle.
+func New() (Prober, error) {
+	switch {
+	case os.Getenv("DISPLAY") != "" && os.Getenv("WAYLAND_DISPLAY") == "":
+		if _, err := exec.LookPath("xprintidle"); err == nil {
+			return xprintidle{}, nil
+		}
+	case strings.Contains(os.Getenv("XDG_CURRENT_DESKTOP"), "GNOME"):
+		if _, err := exec.LookPath("gdbus"); err == nil {
+			return mutter{}, nil
+		}
+	}
+	return nil, ErrUnsupported
+}
+
+type xprintidle struct{}
+
+func (xprintidle) Idle() (time.Duration, error) {
+	out, err := exec.Command("xprintidle").Output()
+	if err != nil {
+		return 0, err
+	}
+	ms, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	return time.Duration(ms) * time.Millisecond, err
+}
+
+type mutter struct{}
+
+// mutterIdle matches GetIdletime's reply, e.g. "(uint64 12345,)".
+var mutterIdle = regexp.MustCompile(`uint64 (\d+)`)
+
+func (mutter) Idle() (time.Duration, error) {
+	out, err := exec.Command("gdbus", "call", "--session",
+		"--dest", "org.gnome.Mutter.IdleMonitor",
+		"--object-path", "/org/gnome/Mutter/IdleMonitor/Core",
+		"--method", "org.gnome.Mutter.IdleMonitor.GetIdletime").Output()
+	if err != nil {
+		return 0, err
+	}
+	m := mutterIdle.FindSubmatch(out)
+	if m == nil {
+		return 0, ErrUnsupported
+	}
+	ms, err := strconv.ParseInt(string(m[1]), 10, 64)
+	return time.Duration(ms) * time.Millisecond, err
+}
@@ -0,0 +1,7 @@
+//go:build !(linux || darwin)
+
+package activity
+
+func New() (Prober, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,107 @@
+// Package calendar reads iCalendar feeds to find meetings that collide with
+// a planned pomodoro session.
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const fetchTimeout = 10 * time.Second
+
+type Occurrence struct {
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
+
+// Load parses a calendar from a local path (with ~ expansion) or an
+// http(s)/webcal URL.
+func Load(ctx context.Context, src string) ([]Event, error) {
+	if strings.HasPrefix(src, "webcal://") {
+		src = "https://" + strings.TrimPrefix(src, "webcal://")
+	}
+
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		return fetch(ctx, src)
+	}
+
+	if rest, ok := strings.CutPrefix(src, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		src = filepath.Join(home, rest)
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+func fetch(ctx context.Context, url string) ([]Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+
+	return Parse(resp.Body)
+}
+
+// Between returns timed (non all-day) occurrences overlapping [from, to),
+// ordered by start time.
+func Between(events []Event, from, to time.Time) []Occurrence {
+	var out []Occurrence
+
+	for _, e := range events {
+		if e.AllDay {
+			continue
+		}
+
+		length := e.End.Sub(e.Start)
+		add := func(start time.Time) {
+			end := start.Add(length)
+			if start.Before(to) && (end.After(from) || (length == 0 && !start.Before(from))) {
+				out = append(out, Occurrence{Summary: e.Summary, Start: start, End: end})
+			}
+		}
+
+		if e.rule == nil {
+			add(e.Start)
+			continue
+		}
+
+		e.rule.occurrences(e.Start, to, func(start time.Time) bool {
+			add(start)
+			return true
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
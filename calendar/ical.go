@@ -0,0 +1,204 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Event struct {
+	Summary string
+	Start   time.Time
+	End     time.Time
+	AllDay  bool
+
+	rule *recurrence
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads VEVENT components from an iCalendar stream. Properties other
+// than SUMMARY, DTSTART, DTEND, DURATION, and RRULE are ignored.
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var cur *Event
+	var duration time.Duration
+
+	for n, line := range lines {
+		prop, ok := parseProperty(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case prop.name == "BEGIN" && prop.value == "VEVENT":
+			cur = &Event{}
+			duration = 0
+		case prop.name == "END" && prop.value == "VEVENT":
+			if cur == nil {
+				continue
+			}
+			if cur.Start.IsZero() {
+				return nil, fmt.Errorf("line %d: event %q has no DTSTART", n+1, cur.Summary)
+			}
+			if cur.End.IsZero() {
+				switch {
+				case duration > 0:
+					cur.End = cur.Start.Add(duration)
+				case cur.AllDay:
+					cur.End = cur.Start.AddDate(0, 0, 1)
+				default:
+					cur.End = cur.Start
+				}
+			}
+			events = append(events, *cur)
+			cur = nil
+		case cur == nil:
+		case prop.name == "SUMMARY":
+			cur.Summary = unescape(prop.value)
+		case prop.name == "DTSTART":
+			t, allDay, err := parseTime(prop)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			cur.Start, cur.AllDay = t, allDay
+		case prop.name == "DTEND":
+			t, _, err := parseTime(prop)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			cur.End = t
+		case prop.name == "DURATION":
+			d, err := parseDuration(prop.value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			duration = d
+		case prop.name == "RRULE":
+			rule, err := parseRule(prop.value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n+1, err)
+			}
+			cur.rule = rule
+		}
+	}
+
+	return events, nil
+}
+
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) (property, bool) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return property{}, false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := property{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string),
+		value:  line[colon+1:],
+	}
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			prop.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+
+	return prop, true
+}
+
+func parseTime(prop property) (time.Time, bool, error) {
+	v := prop.value
+	if prop.params["VALUE"] == "DATE" || len(v) == 8 {
+		t, err := time.ParseInLocation("20060102", v, time.Local)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse("20060102T150405Z", v)
+		return t, false, err
+	}
+
+	loc := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", v, loc)
+	return t, false, err
+}
+
+// parseDuration handles the RFC 5545 subset [+-]P[nW][nD][T[nH][nM][nS]].
+func parseDuration(v string) (time.Duration, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(v, "+"), "-")
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("invalid duration %q", v)
+	}
+
+	units := map[byte]time.Duration{
+		'W': 7 * 24 * time.Hour,
+		'D': 24 * time.Hour,
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+	}
+
+	var d time.Duration
+	num := ""
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == 'T':
+		case c >= '0' && c <= '9':
+			num += string(c)
+		default:
+			unit, ok := units[c]
+			if !ok || num == "" {
+				return 0, fmt.Errorf("invalid duration %q", v)
+			}
+			n, _ := strconv.Atoi(num)
+			d += time.Duration(n) * unit
+			num = ""
+		}
+	}
+
+	if strings.HasPrefix(v, "-") {
+		d = -d
+	}
+	return d, nil
+}
+
+func unescape(v string) string {
+	r := strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`)
+	return r.Replace(v)
+}
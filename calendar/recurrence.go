@@ -0,0 +1,162 @@
+package calendar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type recurrence struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time
+	byDay    []time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+func parseRule(v string) (*recurrence, error) {
+	r := &recurrence{interval: 1}
+
+	for _, part := range strings.Split(v, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.freq = strings.ToUpper(val)
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE interval %q", val)
+			}
+			r.interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE count %q", val)
+			}
+			r.count = n
+		case "UNTIL":
+			t, _, err := parseTime(property{value: val})
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE until %q", val)
+			}
+			r.until = t
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				// Ordinal prefixes like 1MO only make sense for monthly rules,
+				// which are expanded from DTSTART's day instead.
+				d = strings.TrimLeft(d, "+-0123456789")
+				wd, ok := weekdays[strings.ToUpper(d)]
+				if !ok {
+					return nil, fmt.Errorf("invalid RRULE day %q", d)
+				}
+				r.byDay = append(r.byDay, wd)
+			}
+		}
+	}
+
+	switch r.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf("unsupported RRULE frequency %q", r.freq)
+	}
+
+	return r, nil
+}
+
+// occurrences calls fn with each start time of a recurring event in order,
+// until fn returns false, the rule is exhausted, or a start passes limit.
+func (r *recurrence) occurrences(start, limit time.Time, fn func(time.Time) bool) {
+	n := 0
+	emit := func(t time.Time) bool {
+		if t.After(limit) || (!r.until.IsZero() && t.After(r.until)) {
+			return false
+		}
+		n++
+		if r.count > 0 && n > r.count {
+			return false
+		}
+		return fn(t)
+	}
+
+	switch r.freq {
+	case "DAILY":
+		for i := 0; ; i += r.interval {
+			if !emit(addDays(start, i)) {
+				return
+			}
+		}
+
+	case "WEEKLY":
+		days := r.byDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		weekStart := addDays(start, -int(start.Weekday()))
+		for week := 0; ; week += r.interval {
+			for d := time.Sunday; d <= time.Saturday; d++ {
+				if !containsDay(days, d) {
+					continue
+				}
+				t := addDays(weekStart, week*7+int(d))
+				if t.Before(start) {
+					continue
+				}
+				if !emit(t) {
+					return
+				}
+			}
+		}
+
+	case "MONTHLY":
+		for i := 0; ; i += r.interval {
+			t := start.AddDate(0, i, 0)
+			if t.Day() != start.Day() {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+
+	case "YEARLY":
+		for i := 0; ; i += r.interval {
+			t := start.AddDate(i, 0, 0)
+			if t.Day() != start.Day() {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// addDays keeps the wall-clock time stable across DST changes.
+func addDays(t time.Time, days int) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+days, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+}
+
+func containsDay(days []time.Weekday, d time.Weekday) bool {
+	for _, x := range days {
+		if x == d {
+			return true
+		}
+	}
+	return false
+}
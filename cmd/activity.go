@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/steenfuentes/pomo/activity"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/history"
+)
+
+// activityTracker samples idle time while a work phase runs unpaused, and
+// nowhere else, and gives the recorder each phase's counts as it ends.
+type activityTracker struct {
+	sampler  *activity.Sampler
+	recorder *history.Recorder
+}
+
+func newActivityTracker(recorder *history.Recorder, clock engine.Clock) *activityTracker {
+	return &activityTracker{
+		sampler:  activity.NewSampler(activityProber, clock, activity.DefaultInterval),
+		recorder: recorder,
+	}
+}
+
+func (t *activityTracker) Handle(e engine.TimerEvent) {
+	tick := e.Type == engine.EventTick
+	if tick && e.Phase == engine.PhaseWork && !e.Paused && e.Ended == "" {
+		t.sampler.Start()
+		return
+	}
+	t.sampler.Stop()
+	if (tick && e.Ended != "") || e.Type == engine.EventSessionEnded {
+		t.recorder.Activity(t.sampler.Take())
+	}
+}
+
+func (t *activityTracker) Close() error {
+	t.sampler.Stop()
+	return nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/steenfuentes/pomo/activity"
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// fakeProber has sessions ask f how long the user has been idle.
+func fakeProber(t *testing.T, f activity.Prober) {
+	t.Helper()
+	saved := newProber
+	newProber = func() (activity.Prober, error) { return f, nil }
+	t.Cleanup(func() { newProber = saved })
+}
+
+// TestActivityScore pauses 30s into a 3m work phase for 100s, over two
+// minute marks, so only minutes counted from the resume, at 190s and 250s
+// on the clock, are sampled, and nothing through the 100s of break after.
+func TestActivityScore(t *testing.T) {
+	isolate(t)
+	fake := &activity.Fake{Idles: []time.Duration{20 * time.Second, 3 * time.Minute}}
+	fakeProber(t, fake)
+	r := startSession(t, "-c", "2", "-p", "3", "-s", "3", "--activity-score")
+	c := &cobra.Command{}
+
+	togglePause := func(want string) {
+		t.Helper()
+		if reply, err := send(c, "toggle-pause"); err != nil || reply != want+"\n" {
+			t.Fatalf("toggle-pause: %q, %v, want %s", reply, err, want)
+		}
+	}
+	if _, ended := r.runFor(t, 30*time.Second); ended {
+		t.Fatal("session ended early")
+	}
+	togglePause("paused")
+	r.runFor(t, 100*time.Second)
+	togglePause("running")
+	if _, ended := r.runFor(t, 250*time.Second); ended {
+		t.Fatal("session ended early")
+	}
+	if _, err := send(c, "stop"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.wait(t); err != nil {
+		t.Fatalf("start: %v\nstderr:\n%s", err, r.stderr.String())
+	}
+
+	records := readRecords(t)
+	if len(records) != 2 {
+		t.Fatalf("%d records, want the work phase and the break", len(records))
+	}
+	work, brk := records[0], records[1]
+	if work.Phase != engine.PhaseWork || work.ActivitySamples != 2 || work.ActiveSamples != 1 {
+		t.Errorf("%s: %d of %d samples active, want 1 of 2", work.Phase, work.ActiveSamples, work.ActivitySamples)
+	}
+	if score, ok := work.Activity(); !ok || score != 0.5 {
+		t.Errorf("activity %v, %v, want 0.5", score, ok)
+	}
+	if brk.ActivitySamples != 0 || brk.ActiveSamples != 0 {
+		t.Errorf("%s sampled %d times, want none", brk.Phase, brk.ActivitySamples)
+	}
+}
+
+func TestActivityScoreOff(t *testing.T) {
+	isolate(t)
+	fakeProber(t, &activity.Fake{})
+	r := startSession(t, "-c", "1", "-p", "3", "-s", "1")
+	if err := r.wait(t); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	for _, rec := range readRecords(t) {
+		if _, ok := rec.Activity(); ok {
+			t.Errorf("%s has an activity score without --activity-score", rec.Phase)
+		}
+	}
+}
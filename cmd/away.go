@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/steenfuentes/pomo/activity"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/notify"
+	"github.com/steenfuentes/pomo/ui"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+const awayPollInterval = 10 * time.Second
+
+// awayWatcher pauses a phase no one has touched since it began, then stops
+// the session, as --idle-pause and --idle-stop set for its kind. Input
+// while paused resumes it. The time gone by before the pause is left out
+// of the phase's history.
+type awayWatcher struct {
+	prober   activity.Prober
+	policies map[engine.Phase]activity.AwayPolicy
+	control  *sessionControl
+	progress *ui.Progress
+	recorder *history.Recorder
+	clock    engine.Clock
+
+	watch activity.AwayWatch
+	start time.Time
+	// held is set once a tick shows the pause taken; ticks already on
+	// their way when it was asked for still show the phase running.
+	held bool
+	// done is set once the phase is resumed by other means than input,
+	// after which it is left alone.
+	done   bool
+	polled time.Time
+}
+
+func newAwayWatcher(control *sessionControl, progress *ui.Progress, recorder *history.Recorder, clock engine.Clock) *awayWatcher {
+	return &awayWatcher{
+		prober:   activityProber,
+		policies: awayPolicies,
+		control:  control,
+		progress: progress,
+		recorder: recorder,
+		clock:    clock,
+	}
+}
+
+func (w *awayWatcher) Handle(e engine.TimerEvent) {
+	if e.Type != engine.EventTick || e.Ended != "" {
+		return
+	}
+	if start := e.PhaseStartedAt.Add(-e.PausedTotal); !start.Equal(w.start) {
+		w.start, w.held, w.done = start, false, false
+		w.watch.Reset(w.policies[e.Phase])
+	}
+	stage := w.watch.Stage()
+	switch {
+	case w.done || w.policies[e.Phase].Off() || stage == activity.StageStopped:
+		return
+	case stage == activity.StagePresent && e.Paused:
+		// Paused by hand, which says someone was there.
+		return
+	case stage == activity.StagePaused && !e.Paused:
+		w.done = w.held
+		return
+	case stage == activity.StagePaused:
+		w.held = true
+	}
+
+	now := w.clock.Now()
+	if now.Sub(w.polled) < awayPollInterval {
+		return
+	}
+	w.polled = now
+	idle, err := w.prober.Idle()
+	if err != nil {
+		return
+	}
+
+	switch next := w.watch.Observe(e.Elapsed, idle); {
+	case next == stage:
+	case next == activity.StagePaused:
+		w.held = false
+		w.away(e.Elapsed)
+		w.control.command("pause")
+		w.alert(notify.KindAwayPaused, "Paused — are you still there?")
+	case next == activity.StagePresent:
+		w.control.command("resume")
+		w.progress.Logf("Welcome back, resumed")
+	case next == activity.StageStopped:
+		w.away(e.Elapsed)
+		w.control.abandon()
+		w.alert(notify.KindAwayStopped, fmt.Sprintf("Session stopped after %s idle", format.DurationHuman(idle)))
+	}
+}
+
+func (w *awayWatcher) away(d time.Duration) {
+	if w.recorder != nil {
+		w.recorder.Away(d)
+	}
+}
+
+func (w *awayWatcher) alert(kind notify.Kind, text string) {
+	w.progress.Nudge("%s", text)
+	if notifier != nil {
+		notifier.Send(notify.Message{Kind: kind, Text: text})
+	}
+}
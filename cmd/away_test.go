@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// lastInput is an idle source counting from the last input it was told
+// of, on the session's clock once it has one.
+type lastInput struct {
+	mu    sync.Mutex
+	clock *engine.MockClock
+	at    time.Time
+}
+
+func (l *lastInput) Idle() (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.clock == nil {
+		return 0, nil
+	}
+	return l.clock.Since(l.at), nil
+}
+
+// touch has there be input now, or start the clock.
+func (l *lastInput) touch(clock *engine.MockClock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock, l.at = clock, clock.Now()
+}
+
+// near is whether d is want, give or take the time the session takes to
+// see the polls of the idle source, which go at the pace of its ticks.
+func near(d, want time.Duration) bool {
+	return d >= want-awayPollInterval && d <= want+awayPollInterval
+}
+
+// TestAwayPausesThenStops leaves a session alone from the start: work
+// pauses 2m in, and 5m on, 7m on the clock, the session stops, with the 2m
+// left out of the work recorded.
+func TestAwayPausesThenStops(t *testing.T) {
+	isolate(t)
+	input := &lastInput{}
+	fakeProber(t, input)
+	r := startSession(t, "-c", "2", "-p", "10", "-s", "1", "--idle-pause", "work=2m", "--idle-stop", "work=5m")
+	input.touch(r.clock)
+
+	if _, ended := r.runFor(t, 7*time.Minute-awayPollInterval); ended {
+		t.Fatal("session stopped early")
+	}
+	if _, ended := r.runFor(t, 2*awayPollInterval); !ended {
+		t.Fatal("session still running 7m alone")
+	}
+
+	records := readRecords(t)
+	if len(records) != 1 {
+		t.Fatalf("%d records, want the work phase", len(records))
+	}
+	if rec := records[0]; rec.Phase != engine.PhaseWork || !near(rec.Away(), 2*time.Minute) || !near(rec.Paused(), 5*time.Minute) || rec.Actual() != 0 || rec.Ended != engine.EndInterrupted {
+		t.Errorf("%s recorded %s actual, %s away, %s paused, ended %s; want work, none actual, 2m away, 5m paused, interrupted",
+			rec.Phase, rec.Actual(), rec.Away(), rec.Paused(), rec.Ended)
+	}
+}
+
+// TestAwayResumesOnInput comes back 4m into a session paused 2m in for no
+// input, which resumes it, and then leaves it alone for longer than both
+// --idle-pause and --idle-stop: the watch is done with the phase.
+func TestAwayResumesOnInput(t *testing.T) {
+	isolate(t)
+	input := &lastInput{}
+	fakeProber(t, input)
+	r := startSession(t, "-c", "2", "-p", "10", "-s", "1", "--idle-pause", "work=2m", "--idle-stop", "work=5m")
+	input.touch(r.clock)
+
+	if _, ended := r.runFor(t, 4*time.Minute); ended {
+		t.Fatal("session ended early")
+	}
+	input.touch(r.clock)
+	if _, ended := r.runFor(t, 8*time.Minute); ended {
+		t.Fatal("session stopped after resuming")
+	}
+	if _, err := send(&cobra.Command{}, "stop"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.wait(t); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readRecords(t)
+	if len(records) != 1 {
+		t.Fatalf("%d records, want the work phase", len(records))
+	}
+	// Stopped 12m on the clock, paused 2m of it.
+	if rec := records[0]; !near(rec.Away(), 2*time.Minute) || !near(rec.Paused(), 2*time.Minute) || !near(rec.Actual(), 8*time.Minute) {
+		t.Errorf("recorded %s actual, %s away, %s paused; want 8m, 2m, 2m", rec.Actual(), rec.Away(), rec.Paused())
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/steenfuentes/pomo/calendar"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+// Infinite sessions are only checked against meetings within this window.
+const calendarHorizon = 12 * time.Hour
+
+func loadCalendar(ctx context.Context, env startEnv, src string) []calendar.Event {
+	events, err := calendar.Load(ctx, src)
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Warning: calendar unavailable, continuing without it: %v\n", err)
+		return nil
+	}
+	return events
+}
+
+// checkCalendar warns about every planned work phase that overlaps a
+// meeting. When there is a conflict it also reports how many cycles fit
+// before the first one.
+func checkCalendar(out io.Writer, events []calendar.Event, cfg engine.Config, now time.Time) (fits int, conflict bool) {
+	plan := engine.NewSession(cfg).Plan(calendarHorizon)
+	if len(plan) == 0 {
+		return 0, false
+	}
+
+	last := plan[len(plan)-1]
+	occurrences := calendar.Between(events, now, now.Add(last.Offset+last.Duration))
+
+	for _, p := range plan {
+		if p.Phase != engine.PhaseWork {
+			continue
+		}
+
+		start := now.Add(p.Offset)
+		end := start.Add(p.Duration)
+		for _, o := range occurrences {
+			if o.Start.Before(end) && o.End.After(start) {
+				fmt.Fprintf(out, "Warning: cycle %d overlaps %q at %s\n", p.Cycle, o.Summary, o.Start.Format("15:04"))
+				if !conflict {
+					fits, conflict = p.Cycle-1, true
+				}
+			}
+		}
+	}
+
+	return fits, conflict
+}
+
+// meetingWatcher warns as each work phase begins if a meeting starts
+// before it ends.
+type meetingWatcher struct {
+	progress  *ui.Progress
+	meetings  []calendar.Event
+	clock     engine.Clock
+	lastPhase engine.Phase
+	lastCycle int
+}
+
+func newMeetingWatcher(progress *ui.Progress, meetings []calendar.Event, clock engine.Clock) *meetingWatcher {
+	return &meetingWatcher{progress: progress, meetings: meetings, clock: clock, lastPhase: engine.Phase(-1)}
+}
+
+func (w *meetingWatcher) Handle(e engine.TimerEvent) {
+	if e.Type != engine.EventTick {
+		return
+	}
+	started := e.Phase == engine.PhaseWork && (w.lastPhase != engine.PhaseWork || e.CycleNum != w.lastCycle)
+	w.lastPhase, w.lastCycle = e.Phase, e.CycleNum
+	if !started {
+		return
+	}
+
+	now := w.clock.Now()
+	for _, o := range calendar.Between(w.meetings, now, now.Add(e.Remaining)) {
+		if o.Start.Before(now) {
+			continue
+		}
+		w.progress.Logf("Heads up: %q starts at %s, during this work phase", o.Summary, o.Start.Format("15:04"))
+	}
+}
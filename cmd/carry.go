@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/history"
+)
+
+// carryCadence picks up the long break cadence where sessions that ended
+// less than --continue-within ago left it, with --continue-cycle or once
+// the user agrees. It reports whether the cadence was carried.
+func carryCadence(env startEnv, cfg *engine.Config) bool {
+	if fresh || (cfg.LongBreakEvery <= 0 && cfg.LongBreakAfterWork <= 0) {
+		return false
+	}
+	path, err := history.Path()
+	if err != nil {
+		return false
+	}
+	records, _ := history.Read(path)
+	carry := history.CarryOver(records, planClock.Now(), continueWithin)
+	if carry.Cycles == 0 {
+		return false
+	}
+
+	if !continueCycle {
+		if f, ok := env.stdin.(*os.File); !ok || !isatty.IsTerminal(f.Fd()) {
+			return false
+		}
+		question := fmt.Sprintf("A session ended at %s with %s since the last long break. Continue its cycle? [y/N] ",
+			carry.End.Local().Format("15:04"), countCycles(carry.Cycles))
+		if !promptYes(env, question, promptTimeout) {
+			return false
+		}
+	}
+	cfg.CarriedCycles = carry.Cycles
+	cfg.CarriedWork = carry.Work
+	return true
+}
+
+// describeCarry is the start banner's note on a carried cadence, e.g.
+// "3 cycles completed, long break after next cycle".
+func describeCarry(cfg engine.Config) string {
+	s := countCycles(cfg.CarriedCycles) + " completed"
+	session := engine.NewSession(cfg)
+	switch left := session.UntilLongBreak(); {
+	case left == 1:
+		s += ", long break after next cycle"
+	case left > 1:
+		s += fmt.Sprintf(", long break after %d more cycles", left)
+	}
+	if work := session.WorkUntilLongBreak(); work >= 0 {
+		s += fmt.Sprintf(", long break after %s more work", shortDuration(work.Round(time.Minute)))
+	}
+	return s
+}
+
+func countCycles(n int) string {
+	if n == 1 {
+		return "1 cycle"
+	}
+	return fmt.Sprintf("%d cycles", n)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/notify"
+	"github.com/steenfuentes/pomo/state"
+	"github.com/steenfuentes/pomo/ui"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+var checkinCmd = &cobra.Command{
+	Use:   "checkin",
+	Short: "Start the work waiting after a break",
+	Long: `Start the work phase a session started with --checkin holds after each
+break until you are back. The wait is recorded as time away, with the work
+phase it held. Pressing any key in the session does the same.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		path, err := state.SocketPath()
+		if err != nil {
+			return err
+		}
+		reply, err := state.Send(path, "checkin")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), reply)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkinCmd)
+}
+
+// checkInReminder notifies as a break ends with --checkin and the work
+// after it waits, then again every interval for as long as it does, or
+// never again for an interval of 0.
+type checkInReminder struct {
+	progress *ui.Progress
+	interval time.Duration
+
+	waiting bool
+	next    time.Duration
+}
+
+func newCheckInReminder(progress *ui.Progress, interval time.Duration) *checkInReminder {
+	return &checkInReminder{progress: progress, interval: interval}
+}
+
+func (r *checkInReminder) Handle(e engine.TimerEvent) {
+	if e.Type != engine.EventAway {
+		return
+	}
+	if e.Ended != "" {
+		r.waiting = false
+		return
+	}
+	switch {
+	case !r.waiting:
+		r.waiting, r.next = true, r.interval
+		r.alert("Break over — press any key or run pomo checkin to start work")
+	case r.interval > 0 && e.Elapsed >= r.next:
+		r.next += r.interval
+		r.alert(fmt.Sprintf("Still away — %s", format.DurationHuman(e.Elapsed)))
+	}
+}
+
+func (r *checkInReminder) alert(text string) {
+	r.progress.Nudge("%s", text)
+	if notifier != nil {
+		notifier.Send(notify.Message{Kind: notify.KindCheckIn, Text: text})
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/notify"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+// sent is a notifier keeping what it is sent.
+type sent struct {
+	mu   sync.Mutex
+	msgs []notify.Message
+}
+
+func (s *sent) Name() string { return "sent" }
+
+func (s *sent) Notify(_ context.Context, m notify.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msgs = append(s.msgs, m)
+	return nil
+}
+
+// TestCheckInReminders waits 5m for a check-in after one break and 1m
+// after the next, checking who is told what, and when, at each interval.
+func TestCheckInReminders(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		want     []string
+	}{
+		{2 * time.Minute, []string{
+			"Break over — press any key or run pomo checkin to start work",
+			"Still away — 2m",
+			"Still away — 4m",
+			"Break over — press any key or run pomo checkin to start work",
+		}},
+		{0, []string{
+			"Break over — press any key or run pomo checkin to start work",
+			"Break over — press any key or run pomo checkin to start work",
+		}},
+	}
+	for _, tt := range tests {
+		got := &sent{}
+		saved := notifier
+		notifier = notify.NewDispatcher()
+		notifier.Register(got, notify.Limits{Queue: notify.DefaultQueue})
+
+		r := newCheckInReminder(ui.NewProgress(2, io.Discard), tt.interval)
+		for _, wait := range []time.Duration{5 * time.Minute, time.Minute} {
+			for elapsed := time.Duration(0); elapsed < wait; elapsed += time.Second {
+				r.Handle(engine.TimerEvent{Type: engine.EventAway, Elapsed: elapsed})
+			}
+			r.Handle(engine.TimerEvent{Type: engine.EventAway, Elapsed: wait, Ended: engine.EndCompleted})
+			r.Handle(engine.TimerEvent{Type: engine.EventTick, Phase: engine.PhaseWork})
+		}
+		if err := notifier.Shutdown(time.Second); err != nil {
+			t.Fatal(err)
+		}
+		notifier = saved
+
+		var texts []string
+		for _, m := range got.msgs {
+			if m.Kind != notify.KindCheckIn {
+				t.Errorf("sent %s, want %s", m.Kind, notify.KindCheckIn)
+			}
+			texts = append(texts, m.Text)
+		}
+		if !slices.Equal(texts, tt.want) {
+			t.Errorf("every %s: sent %q, want %q", tt.interval, texts, tt.want)
+		}
+	}
+}
+
+// TestCheckInCommand sends pomo checkin's command before, during, and
+// after a wait for it.
+func TestCheckInCommand(t *testing.T) {
+	c := &sessionControl{timer: engine.NewTimer(engine.Config{WorkDuration: time.Minute, ShortBreakDuration: time.Minute, TotalCycles: 2, CheckIn: true})}
+	checkIn := func() string {
+		reply, err := c.command("checkin")
+		if err != nil {
+			return err.Error()
+		}
+		return reply
+	}
+	const nothing = "nothing is waiting for a check-in"
+
+	c.Handle(engine.TimerEvent{Type: engine.EventTick, Phase: engine.PhaseShortBreak})
+	if got := checkIn(); got != nothing {
+		t.Errorf("during the break: %q, want %q", got, nothing)
+	}
+	c.Handle(engine.TimerEvent{Type: engine.EventAway, Elapsed: time.Minute})
+	if got := checkIn(); got != "checked in" {
+		t.Errorf("waiting: %q, want checked in", got)
+	}
+	if got := checkIn(); got != nothing {
+		t.Errorf("checking in twice: %q, want %q", got, nothing)
+	}
+	c.Handle(engine.TimerEvent{Type: engine.EventAway, Elapsed: 2 * time.Minute, Ended: engine.EndCompleted})
+	if got := checkIn(); got != nothing {
+		t.Errorf("once the wait is over: %q, want %q", got, nothing)
+	}
+}
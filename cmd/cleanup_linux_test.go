@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchCleanup reports, in the order they happened, each write of history
+// and each removal of the state file or control socket under dir.
+func watchCleanup(t *testing.T, dir string) func() []string {
+	t.Helper()
+	fd, err := unix.InotifyInit1(unix.IN_NONBLOCK | unix.IN_CLOEXEC)
+	if err != nil {
+		t.Skipf("inotify: %v", err)
+	}
+	t.Cleanup(func() { unix.Close(fd) })
+	watches := make(map[int32]string)
+	for sub, mask := range map[string]uint32{"history": unix.IN_CLOSE_WRITE, "state": unix.IN_DELETE} {
+		path := filepath.Join(dir, sub)
+		if err := os.MkdirAll(path, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		wd, err := unix.InotifyAddWatch(fd, path, mask)
+		if err != nil {
+			t.Fatal(err)
+		}
+		watches[int32(wd)] = sub
+	}
+
+	return func() []string {
+		var seen []string
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := unix.Read(fd, buf)
+			if err == unix.EAGAIN {
+				return seen
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for off := 0; off+unix.SizeofInotifyEvent <= n; {
+				e := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+				name := string(bytes.TrimRight(buf[off+unix.SizeofInotifyEvent:off+unix.SizeofInotifyEvent+int(e.Len)], "\x00"))
+				off += unix.SizeofInotifyEvent + int(e.Len)
+				switch {
+				case watches[e.Wd] == "history" && name == "history.jsonl":
+					seen = append(seen, "history written")
+				case watches[e.Wd] == "state" && (name == "state.json" || name == "pomo.sock"):
+					seen = append(seen, name+" removed")
+				}
+			}
+		}
+	}
+}
+
+// checkCleanup wants history written last before the state file and then
+// the socket are removed.
+func checkCleanup(t *testing.T, seen []string) {
+	t.Helper()
+	want := []string{"history written", "state.json removed", "pomo.sock removed"}
+	if len(seen) < len(want) || strings.Join(seen[len(seen)-len(want):], ", ") != strings.Join(want, ", ") {
+		t.Errorf("cleanup went %s, want it to end %s", strings.Join(seen, ", "), strings.Join(want, ", "))
+	}
+}
+
+func TestCleanupOrderOnCompletion(t *testing.T) {
+	dir := isolate(t)
+	seen := watchCleanup(t, dir)
+	r := startSession(t, "-c", "1", "-p", "1", "-s", "1")
+	if err := r.wait(t); err != nil {
+		t.Fatalf("start: %v\nstderr:\n%s", err, r.stderr.String())
+	}
+	checkCleanup(t, seen())
+}
+
+func TestCleanupOrderOnSignal(t *testing.T) {
+	dir := isolate(t)
+	seen := watchCleanup(t, dir)
+	r := startSession(t, "-c", "2", "-p", "1", "--porcelain")
+	if _, ended := r.runFor(t, 30*time.Second); ended {
+		t.Fatal("session ended before the interrupt")
+	}
+	r.signals <- syscall.SIGINT
+	r.wait(t)
+	checkCleanup(t, seen())
+	if records := readRecords(t); len(records) != 1 {
+		t.Errorf("%d records, want the interrupted work phase", len(records))
+	}
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/mqtt"
+	"github.com/steenfuentes/pomo/state"
+)
+
+// How long each MQTT publish may take, connecting included.
+const mqttTimeout = 5 * time.Second
+
+// loadPalette is state.DefaultPalette with the config file's [theme] on
+// top.
+func loadPalette() state.Palette {
+	p := state.DefaultPalette
+	cfg, err := loadConfig()
+	if err != nil {
+		return p
+	}
+	colors := map[string]*state.RGB{
+		"work": &p.Work, "short": &p.Short, "long": &p.Long,
+		"cooldown": &p.Cooldown, "paused": &p.Paused, "idle": &p.Idle,
+	}
+	for key, hex := range cfg.Theme.Colors {
+		if c, err := state.ParseRGB(hex); err == nil {
+			*colors[key] = c
+		}
+	}
+	p.Fade, p.MinBrightness = cfg.Theme.Fade, cfg.Theme.MinBrightness
+	return p
+}
+
+// colorPublisher keeps a retained MQTT topic at the session's color,
+// publishing off the timer's goroutine each time it changes. It outlives
+// each session's subscribers, so it is shut down with Shutdown.
+type colorPublisher struct {
+	broker  mqtt.Broker
+	topic   string
+	palette state.Palette
+	last    string
+	queue   chan string
+	done    chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+func newColorPublisher(broker mqtt.Broker, topic string, palette state.Palette) *colorPublisher {
+	p := &colorPublisher{
+		broker:  broker,
+		topic:   topic,
+		palette: palette,
+		queue:   make(chan string, 1),
+		done:    make(chan struct{}),
+	}
+	go p.work()
+	return p
+}
+
+func (p *colorPublisher) Handle(e engine.TimerEvent) {
+	if e.Type == engine.EventSessionStarted {
+		return
+	}
+	hex := p.palette.Color(&e).Hex()
+	if hex == p.last {
+		return
+	}
+	p.last = hex
+	// Only the newest color matters, so it replaces one still waiting.
+	for {
+		select {
+		case p.queue <- hex:
+			return
+		default:
+			select {
+			case <-p.queue:
+			default:
+			}
+		}
+	}
+}
+
+func (p *colorPublisher) work() {
+	defer close(p.done)
+	for hex := range p.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), mqttTimeout)
+		err := mqtt.Publish(ctx, p.broker, p.topic, []byte(hex), true)
+		cancel()
+		if err != nil {
+			slog.Warn("mqtt publish failed", "broker", p.broker.Addr, "topic", p.topic, "err", err)
+			p.mu.Lock()
+			p.err = err
+			p.mu.Unlock()
+			continue
+		}
+		slog.Debug("mqtt published", "topic", p.topic, "color", hex)
+	}
+}
+
+// Shutdown waits for the last color to go out and returns the latest
+// error publishing, if any.
+func (p *colorPublisher) Shutdown() error {
+	close(p.queue)
+	<-p.done
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+var configResolved bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect pomo's configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [profile [params...]]",
+	Short: "Show the effective start settings and where each comes from",
+	Long: `Show the value pomo start would use for every setting, and its source.
+Takes the same flags as pomo start, to see what they would change.
+
+Precedence, highest first: flags, profile, POMO_* environment variables
+(e.g. POMO_LONG_EVERY=3), the config file, and built-in defaults. Two
+settings that contradict each other, like --taper and --pomodoro, are an
+error when set in the same place; otherwise the higher-ranked one wins.
+
+With --resolved, shows the session pomo start would run instead, once
+every conflict is settled, without starting it.
+
+Examples:
+  pomo config show sprint 6
+  pomo config show --resolved --taper 50m,40m -c 4`,
+	ValidArgsFunction: completeProfiles,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		flags := startCmd.Flags()
+		merged, err := applySettings(cmd, args)
+		if err != nil {
+			return err
+		}
+		if configResolved {
+			opts, err := resolveStart(flags, merged)
+			if err != nil {
+				return err
+			}
+			if err := printResolved(cmd.OutOrStdout(), opts); err != nil {
+				return err
+			}
+			if o := overrides(merged); len(o) > 0 && porcelain == "" {
+				fmt.Fprintln(cmd.OutOrStdout(), "\nOverridden:")
+				ui.PrintOverrides(cmd.OutOrStdout(), o)
+			}
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		flags.VisitAll(func(f *pflag.Flag) {
+			value, source := f.DefValue, config.SourceDefault.String()
+			if s, ok := merged[f.Name]; ok {
+				value = strings.Join(s.Values, ",")
+				source = s.Source.String()
+				switch s.Source {
+				case config.SourceFlag:
+				case config.SourceProfile:
+					source += " " + s.Origin
+				default:
+					source += " (" + s.Origin + ")"
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", f.Name, value, source)
+		})
+		return w.Flush()
+	},
+}
+
+func init() {
+	configShowCmd.Flags().BoolVar(&configResolved, "resolved", false, "Show the session pomo start would run, with conflicts settled, instead of each setting")
+
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
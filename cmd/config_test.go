@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steenfuentes/pomo/config"
+)
+
+// TestConfigShowPrecedence sets each field in every combination of the
+// config file, a POMO_* variable, a profile, and a flag, and checks pomo
+// config show takes it from the highest-ranked, or the default if none.
+func TestConfigShowPrecedence(t *testing.T) {
+	sources := []config.Source{config.SourceFile, config.SourceEnv, config.SourceProfile, config.SourceFlag}
+	fields := []struct {
+		key, def string
+		// toml is each lower source's value as the config file and
+		// profile would write it; values is what pomo config show prints
+		// for each source, the last being the flag.
+		toml   []string
+		values []string
+	}{
+		{"pomodoro", "50", []string{"40", "", "42"}, []string{"40", "41", "42", "43"}},
+		{"warmup", "0s", []string{`"1m0s"`, "", `"3m0s"`}, []string{"1m0s", "2m0s", "3m0s", "4m0s"}},
+		{"on-complete", "exit", []string{`"prompt"`, "", `"exit"`}, []string{"prompt", "restart", "exit", "prompt"}},
+		{"gradient", "false", []string{"true", "", "true"}, []string{"true", "false", "true", "false"}},
+		{"write-file", "[]", []string{`"file.txt"`, "", `"profile.txt"`}, []string{"file.txt", "env.txt", "profile.txt", "[flag.txt]"}},
+	}
+	for _, f := range fields {
+		for mask := 0; mask < 1<<len(sources); mask++ {
+			var set []config.Source
+			for i, s := range sources {
+				if mask&(1<<i) != 0 {
+					set = append(set, s)
+				}
+			}
+			t.Run(fmt.Sprintf("%s/%v", f.key, set), func(t *testing.T) {
+				dir := isolate(t)
+				path := filepath.Join(dir, "pomo.toml")
+				var file, profile string
+				args := []string{"config", "show", "--config", path}
+				wantValue, wantSource := f.def, "default"
+				for _, s := range set {
+					i := indexOf(sources, s)
+					switch s {
+					case config.SourceFile:
+						file = fmt.Sprintf("%s = %s\n", f.key, f.toml[i])
+						wantSource = "config file (" + path + ")"
+					case config.SourceEnv:
+						t.Setenv(config.EnvName(f.key), f.values[i])
+						wantSource = "environment (" + config.EnvName(f.key) + ")"
+					case config.SourceProfile:
+						profile = fmt.Sprintf("%s = %s\n", f.key, f.toml[i])
+						args = append(args, "work")
+						wantSource = "profile work"
+					case config.SourceFlag:
+						args = append(args, "--"+f.key+"="+strings.Trim(f.values[i], "[]"))
+						wantSource = "flag"
+					}
+					wantValue = f.values[i]
+				}
+				toml := file + "\n[profiles.work]\n" + profile
+				if err := os.WriteFile(path, []byte(toml), 0o644); err != nil {
+					t.Fatal(err)
+				}
+
+				var out syncBuffer
+				env := startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}
+				if err := execute(t, env, args...); err != nil {
+					t.Fatalf("%v\n%s", err, out.String())
+				}
+				value, source, ok := showRow(out.String(), f.key)
+				if !ok {
+					t.Fatalf("no %s row in:\n%s", f.key, out.String())
+				}
+				if value != wantValue || source != wantSource {
+					t.Errorf("%s = %s from %s, want %s from %s", f.key, value, source, wantValue, wantSource)
+				}
+			})
+		}
+	}
+}
+
+func indexOf[T comparable](list []T, v T) int {
+	for i, item := range list {
+		if item == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// showRow finds key's value and source in pomo config show's output.
+func showRow(out, key string) (value, source string, ok bool) {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == key {
+			return fields[1], strings.Join(fields[2:], " "), true
+		}
+	}
+	return "", "", false
+}
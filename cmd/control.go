@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/state"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+const quitConfirmWindow = 5 * time.Second
+
+// sessionControl routes keypresses and interrupts to whichever session is
+// currently running. With confirm set, the first interrupt pauses the timer
+// and only a second one within quitConfirmWindow quits.
+type sessionControl struct {
+	confirm  bool
+	headless bool
+
+	mu          sync.Mutex
+	timer       *engine.Timer
+	progress    *ui.Progress
+	interactive bool
+	confirming  bool
+	deadline    time.Time
+	paused      bool
+	phase       engine.Phase
+	lost        bool
+	// snoozable is whether the last event was of a break or of what runs
+	// between it and the work after; snoozes counts its snoozes so far.
+	snoozable bool
+	snoozes   int
+	// extendable is whether the last event was of a break still running;
+	// bank is the break time banked, less extensions asked for since.
+	extendable bool
+	bank       time.Duration
+	// awaiting is whether the last event was of a wait for a check-in,
+	// which any key ends.
+	awaiting bool
+}
+
+func (c *sessionControl) attach(timer *engine.Timer, progress *ui.Progress, interactive bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timer, c.progress, c.interactive = timer, progress, interactive
+	c.confirming, c.paused = false, false
+	c.phase = engine.PhaseWork
+	c.snoozable, c.snoozes = false, 0
+	c.extendable, c.bank = false, 0
+	c.awaiting = false
+	if c.lost {
+		progress.Detach()
+	}
+}
+
+func (c *sessionControl) detach() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timer, c.progress, c.interactive = nil, nil, false
+}
+
+// interrupt reports whether the process should quit.
+func (c *sessionControl) interrupt(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.confirm || !c.interactive || c.timer == nil {
+		return true
+	}
+	if c.confirming && now.Before(c.deadline) {
+		return true
+	}
+
+	c.confirming = true
+	c.deadline = now.Add(quitConfirmWindow)
+	c.setPaused(true)
+	c.progress.Logf("Paused: press Ctrl-C again within %s to quit, any key to resume", quitConfirmWindow)
+	return false
+}
+
+// announce prints line above a session's bars, through the renderer so it
+// is not drawn over, or to out when no session is on screen.
+func (c *sessionControl) announce(out io.Writer, line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.progress != nil {
+		c.progress.Logf("%s", line)
+		return
+	}
+	fmt.Fprintln(out, line)
+}
+
+// terminalLost reports whether the process should quit because the terminal
+// went away. With headless set, rendering stops instead and the timer keeps
+// going for the state file and the control socket.
+func (c *sessionControl) terminalLost(now time.Time, cause error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.headless {
+		return true
+	}
+	if c.progress != nil {
+		c.progress.Detach()
+	}
+	if !c.lost {
+		c.lost = true
+		slog.Warn("terminal lost, continuing headless", "cause", cause, "at", now)
+	}
+	return false
+}
+
+func (c *sessionControl) key(b byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timer == nil {
+		return
+	}
+
+	if c.confirming {
+		c.confirming = false
+		c.setPaused(false)
+		c.progress.Logf("Resumed")
+		return
+	}
+	if c.awaiting {
+		c.checkIn()
+		return
+	}
+
+	switch b {
+	case 's':
+		c.timer.Skip()
+	case 'b':
+		if _, err := c.snooze(""); err != nil {
+			c.progress.Logf("Not snoozed: %v", err)
+		}
+	case '+':
+		if _, err := c.extend(""); err != nil {
+			c.progress.Logf("Not extended: %v", err)
+		}
+	}
+}
+
+func (c *sessionControl) Handle(e engine.TimerEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.awaiting = e.Type == engine.EventAway && e.Ended == ""
+	switch e.Type {
+	case engine.EventTick:
+		c.phase = e.Phase
+		c.snoozable = !e.Extra && (e.Phase == engine.PhaseShortBreak || e.Phase == engine.PhaseLongBreak)
+		c.extendable = c.snoozable && e.Ended == ""
+		c.bank = e.Bank
+	case engine.EventSnooze:
+		c.snoozable, c.extendable = true, false
+	case engine.EventTransition:
+		c.snoozable = c.snoozable && e.Phase == engine.PhaseWork
+		c.extendable = false
+	case engine.EventAway:
+		c.snoozable, c.extendable = false, false
+	default:
+		return
+	}
+	// A snooze asked for but not yet taken is still counted.
+	c.snoozes = max(c.snoozes, e.Snoozes)
+	if !c.snoozable {
+		c.snoozes = 0
+	}
+}
+
+// command serves requests from the control socket.
+func (c *sessionControl) command(name string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timer == nil {
+		return "", state.ErrNotRunning
+	}
+
+	switch kind, arg, _ := strings.Cut(name, " "); kind {
+	case "break", "work":
+		return c.inject(kind, arg)
+	case "snooze":
+		return c.snooze(arg)
+	case "extend":
+		return c.extend(arg)
+	}
+
+	switch name {
+	case "pause":
+		c.setPaused(true)
+	case "resume":
+		c.setPaused(false)
+	case "toggle-pause":
+		c.setPaused(!c.paused)
+	case "skip":
+		c.timer.Skip()
+		return "skipped", nil
+	case "checkin":
+		if !c.awaiting {
+			return "", errors.New("nothing is waiting for a check-in")
+		}
+		c.checkIn()
+		return "checked in", nil
+	case "stop":
+		c.timer.Stop()
+		return "stopping", nil
+	case "start-work":
+		c.setPaused(false)
+		if c.phase != engine.PhaseWork {
+			c.timer.Skip()
+		}
+		return "work", nil
+	default:
+		return "", fmt.Errorf("unknown command %q", name)
+	}
+
+	if c.paused {
+		return "paused", nil
+	}
+	return "running", nil
+}
+
+// inject splices an extra phase into the session, by default as long as
+// the session's own phases of that kind.
+func (c *sessionControl) inject(kind, arg string) (string, error) {
+	cfg := c.timer.Session().Config()
+	x := engine.Extra{Phase: engine.PhaseWork, Duration: cfg.WorkDuration}
+	if kind == "break" {
+		x = engine.Extra{Phase: engine.PhaseShortBreak, Duration: cfg.ShortBreakDuration}
+	}
+
+	if arg != "" {
+		d, err := time.ParseDuration(arg)
+		if err != nil || d <= 0 {
+			return "", fmt.Errorf("invalid duration %q", arg)
+		}
+		x.Duration = d
+	}
+	if x.Duration <= 0 {
+		return "", fmt.Errorf("%s needs a duration", kind)
+	}
+
+	c.setPaused(false)
+	c.timer.Inject(x)
+	return fmt.Sprintf("%s %s", kind, x.Duration), nil
+}
+
+// snooze puts off the work after the current break, by snoozeFor unless
+// arg gives a duration.
+func (c *sessionControl) snooze(arg string) (string, error) {
+	d := snoozeFor
+	if arg != "" {
+		var err error
+		if d, err = time.ParseDuration(arg); err != nil || d <= 0 {
+			return "", fmt.Errorf("invalid duration %q", arg)
+		}
+	}
+	limit := c.timer.Session().Config().MaxSnoozes
+	switch {
+	case limit == 0:
+		return "", errors.New("snoozing is off (see --max-snoozes)")
+	case !c.snoozable:
+		return "", errors.New("only a break can be snoozed")
+	case c.snoozes >= limit:
+		return "", fmt.Errorf("no snoozes left for this break (%d allowed)", limit)
+	}
+	c.snoozes++
+	c.timer.Snooze(d)
+	return fmt.Sprintf("snoozed %s", d), nil
+}
+
+// extend lengthens the current break by extendBy unless arg gives a
+// duration. The reply tells how much the bank pays for, as far as the
+// last event has it.
+func (c *sessionControl) extend(arg string) (string, error) {
+	d := extendBy
+	if arg != "" {
+		var err error
+		if d, err = time.ParseDuration(arg); err != nil || d <= 0 {
+			return "", fmt.Errorf("invalid duration %q", arg)
+		}
+	}
+	if !c.extendable {
+		return "", errors.New("only a running break can be extended")
+	}
+	c.timer.Extend(d)
+	if !c.timer.Session().Config().BankBreaks {
+		return fmt.Sprintf("extended %s", d), nil
+	}
+	paid := min(d, c.bank).Round(time.Second)
+	c.bank = max(c.bank-d, 0)
+	switch paid {
+	case d:
+		return fmt.Sprintf("extended %s from the bank", d), nil
+	case 0:
+		return fmt.Sprintf("extended %s past the bank", d), nil
+	}
+	return fmt.Sprintf("extended %s, %s from the bank", d, paid), nil
+}
+
+// checkIn starts the work a break has ended before, with --checkin. The
+// wait is over as far as keys go, whatever the next event says.
+func (c *sessionControl) checkIn() {
+	c.awaiting = false
+	c.timer.CheckIn()
+}
+
+// abandon ends the session at once, the phase cut short.
+func (c *sessionControl) abandon() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timer != nil {
+		c.timer.Abandon()
+	}
+}
+
+func (c *sessionControl) setPaused(paused bool) {
+	c.paused = paused
+	if paused {
+		c.timer.Pause()
+	} else {
+		c.timer.Resume()
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/engine/fanout"
+	"github.com/steenfuentes/pomo/state"
+)
+
+// salvage ends a session a panic on the event loop cut short, as in a
+// renderer or a subscriber, as far as it still can: the subscribers that
+// keep the session on disk see it end, interrupted, after last, so history
+// has the phase under way and the state file goes. The panic and stack go
+// to the log, and the error returned points there.
+func salvage(value any, stack []byte, last engine.TimerEvent, keep []fanout.Subscriber) (engine.SessionSummary, error) {
+	slog.Error("panic", "value", value, "stack", string(stack))
+
+	summary := engine.SessionSummary{
+		Ended:          engine.EndInterrupted,
+		CyclesComplete: max(last.CycleNum-1, 0),
+		PhasesComplete: max(last.PhaseNum-1, 0),
+		Work:           last.SessionWork,
+	}
+	ended := last
+	ended.Type = engine.EventSessionEnded
+	ended.Summary = &summary
+	for _, s := range keep {
+		// One that panics too must not keep the rest from their turn.
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					slog.Error("panic while salvaging the session", "value", r)
+				}
+			}()
+			s.Handle(ended)
+			if c, ok := s.(io.Closer); ok {
+				if err := c.Close(); err != nil {
+					slog.Warn("salvaging the session", "err", err)
+				}
+			}
+		}()
+	}
+
+	where := "the log"
+	if path, err := state.LogPath(); err == nil {
+		where = path
+	}
+	return summary, fmt.Errorf("pomo crashed: %v\nThe phase under way is in history as interrupted; the details are in %s", value, where)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/engine/fanout"
+	"github.com/steenfuentes/pomo/state"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+// TestRendererPanic has the renderer panic 30s into work, and checks the
+// terminal is put back, the phase is in history as interrupted, the state
+// file is gone, and the panic is in the log the error points to.
+func TestRendererPanic(t *testing.T) {
+	isolate(t)
+	saved := render
+	render = func(*ui.Progress) fanout.Subscriber {
+		return fanout.SubscriberFunc(func(e engine.TimerEvent) {
+			if e.Type == engine.EventTick && e.Elapsed >= 30*time.Second {
+				panic("renderer bug")
+			}
+		})
+	}
+	defer func() { render = saved }()
+	var restored atomic.Int32
+	defer ui.OnRestore(func() { restored.Add(1) })()
+
+	r := startSession(t, "-c", "2", "-p", "1", "-s", "1")
+	err := r.wait(t)
+
+	logPath, lerr := state.LogPath()
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	want := "pomo crashed: renderer bug\nThe phase under way is in history as interrupted; the details are in " + logPath
+	if err == nil || err.Error() != want {
+		t.Errorf("start: %v, want %q", err, want)
+	}
+	if n := restored.Load(); n != 1 {
+		t.Errorf("terminal restored %d times, want once", n)
+	}
+
+	records := readRecords(t)
+	if len(records) != 1 {
+		t.Fatalf("%d records, want the work phase under way", len(records))
+	}
+	// The tick the renderer panicked on never reached history, which has
+	// the phase as of the one before.
+	if rec := records[0]; rec.Phase != engine.PhaseWork || rec.Ended != engine.EndInterrupted || rec.Actual() != 29*time.Second {
+		t.Errorf("recorded %s %s after %s, want work interrupted after 29s", rec.Phase, rec.Ended, rec.Actual())
+	}
+	statePath, serr := state.Path()
+	if serr != nil {
+		t.Fatal(serr)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("state file left behind: %v", err)
+	}
+	log, lerr := os.ReadFile(logPath)
+	if lerr != nil {
+		t.Fatal(lerr)
+	}
+	if !strings.Contains(string(log), `value="renderer bug" stack="goroutine`) {
+		t.Errorf("panic not logged with its stack:\n%s", log)
+	}
+}
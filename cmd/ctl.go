@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/state"
+)
+
+// Exit codes shared by the ctl subcommands and pomo start --porcelain.
+const (
+	exitError      = 1
+	exitStopped    = 2
+	exitNotRunning = 3
+)
+
+var remainingSeconds bool
+
+var ctlCmd = &cobra.Command{
+	Use:   "ctl",
+	Short: "Control the running session from scripts",
+	Long: `Single-shot commands for automation (e.g. Apple Shortcuts "Run Shell Script").
+
+Each subcommand prints exactly one line to stdout with no color, and exits:
+  0  success
+  1  error (message on stderr)
+  3  no pomo session is running (message on stderr)`,
+}
+
+var ctlStartWorkCmd = &cobra.Command{
+	Use:   "start-work",
+	Short: "End the current break and start working",
+	Long: `End the current break and start the next work phase, resuming if paused.
+Does nothing but resume when a work phase is already running.
+
+Stdout: "work"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ctlSend(cmd, "start-work")
+	},
+}
+
+var ctlTogglePauseCmd = &cobra.Command{
+	Use:   "toggle-pause",
+	Short: "Pause or resume the running session",
+	Long: `Pause the running session, or resume it if paused.
+
+Stdout: "paused" or "running", the state after toggling`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ctlSend(cmd, "toggle-pause")
+	},
+}
+
+var ctlRemainingCmd = &cobra.Command{
+	Use:   "remaining",
+	Short: "Print the time left in the current phase",
+	Long: `Print the time left in the current phase.
+
+Stdout: "MM:SS", or with --seconds a whole number of seconds (e.g. "1499")`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := state.Path()
+		if err != nil {
+			return ctlFail(cmd, err)
+		}
+		s, err := readState(path)
+		if err != nil {
+			return ctlFail(cmd, err)
+		}
+
+		remaining := s.RemainingAt(time.Now())
+		if remainingSeconds {
+			fmt.Fprintln(cmd.OutOrStdout(), int64(remaining/time.Second))
+			return nil
+		}
+
+		remaining = remaining.Truncate(time.Second)
+		fmt.Fprintf(cmd.OutOrStdout(), "%02d:%02d\n", remaining/time.Minute, (remaining%time.Minute)/time.Second)
+		return nil
+	},
+}
+
+func init() {
+	ctlRemainingCmd.Flags().BoolVar(&remainingSeconds, "seconds", false, "Print whole seconds instead of MM:SS")
+
+	ctlCmd.AddCommand(ctlStartWorkCmd, ctlTogglePauseCmd, ctlRemainingCmd)
+	rootCmd.AddCommand(ctlCmd)
+}
+
+// ctlSend sends command to the running session and prints its reply.
+func ctlSend(cmd *cobra.Command, command string) error {
+	path, err := state.SocketPath()
+	if err != nil {
+		return ctlFail(cmd, err)
+	}
+
+	reply, err := state.Send(path, command)
+	if err != nil {
+		return ctlFail(cmd, err)
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), reply)
+	return nil
+}
+
+// ctlFail prints err on its own and returns the exit code it calls for:
+// exitNotRunning without a session, exitError otherwise.
+func ctlFail(cmd *cobra.Command, err error) error {
+	cmd.SilenceUsage = true
+	fmt.Fprintln(cmd.ErrOrStderr(), err)
+	if errors.Is(err, state.ErrNotRunning) {
+		return exitCode(exitNotRunning)
+	}
+	return exitCode(exitError)
+}
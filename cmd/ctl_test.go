@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/state"
+)
+
+// runCtl runs pomo with args as a script would, returning what it printed
+// and the exit code it ended with.
+func runCtl(t *testing.T, args ...string) (stdout, stderr string, code exitCode) {
+	t.Helper()
+	var out, errOut strings.Builder
+	err := execute(t, startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &errOut}, args...)
+	if err != nil && !errors.As(err, &code) {
+		t.Fatalf("pomo %s: %v, want an exit code", strings.Join(args, " "), err)
+	}
+	return out.String(), errOut.String(), code
+}
+
+func TestCtlWithoutSession(t *testing.T) {
+	for _, args := range [][]string{
+		{"ctl", "start-work"},
+		{"ctl", "toggle-pause"},
+		{"ctl", "remaining"},
+		{"stop"},
+		{"status"},
+	} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			isolate(t)
+			stdout, stderr, code := runCtl(t, args...)
+			if code != exitNotRunning {
+				t.Errorf("exit code %d, want %d", code, exitNotRunning)
+			}
+			if stdout != "" {
+				t.Errorf("stdout %q, want nothing", stdout)
+			}
+			if stderr != state.ErrNotRunning.Error()+"\n" {
+				t.Errorf("stderr %q, want only %q", stderr, state.ErrNotRunning)
+			}
+		})
+	}
+}
+
+func TestStatusColorHexWithoutSession(t *testing.T) {
+	isolate(t)
+	stdout, stderr, code := runCtl(t, "status", "--color-hex")
+	if code != 0 || stdout != "#000000\n" || stderr != "" {
+		t.Errorf("got %q, %q, exit code %d, want lights off and no error", stdout, stderr, code)
+	}
+}
+
+func TestStatusBadFormat(t *testing.T) {
+	isolate(t)
+	stdout, stderr, code := runCtl(t, "status", "--format", "{{.Phase")
+	if code != exitError || stdout != "" || !strings.HasPrefix(stderr, "invalid --format") {
+		t.Errorf("got %q, %q, exit code %d, want %d with the template error", stdout, stderr, code, exitError)
+	}
+}
+
+// send runs a ctl command against a session running alongside, bypassing
+// rootCmd, which the session's pomo start is using.
+func send(c *cobra.Command, command string) (string, error) {
+	var out strings.Builder
+	c.SetOut(&out)
+	c.SetErr(&out)
+	err := ctlSend(c, command)
+	return out.String(), err
+}
+
+func TestCtlControlsRunningSession(t *testing.T) {
+	isolate(t)
+	r := startSession(t, "-c", "1", "-p", "1", "-s", "1")
+	c := &cobra.Command{}
+
+	// The session listens once it has started, and has set where pomo
+	// keeps things by the time it prints anything.
+	var reply string
+	var err error
+	for waited := time.Duration(0); ; waited += time.Second {
+		if r.stdout.String() != "" {
+			if reply, err = send(c, "toggle-pause"); err == nil {
+				break
+			}
+		}
+		if waited > 30*time.Second {
+			t.Fatalf("toggle-pause: %q, %v", reply, err)
+		}
+		if _, ended := r.runFor(t, time.Second); ended {
+			t.Fatal("session ended before taking a command")
+		}
+	}
+	if reply != "paused\n" {
+		t.Errorf("toggle-pause replied %q, want paused", reply)
+	}
+	if reply, err := send(c, "toggle-pause"); err != nil || reply != "running\n" {
+		t.Errorf("toggle-pause again: %q, %v, want running", reply, err)
+	}
+	if reply, err := send(c, "stop"); err != nil || reply != "stopping\n" {
+		t.Errorf("stop: %q, %v, want stopping", reply, err)
+	}
+	if err := r.wait(t); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	reply, err = send(c, "start-work")
+	if code := exitCode(0); !errors.As(err, &code) || code != exitNotRunning {
+		t.Errorf("start-work once the session is over: %q, %v, want exit code %d", reply, err, exitNotRunning)
+	}
+}
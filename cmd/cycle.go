@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/provider"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+// cycleHookTimeout bounds each --on-cycle-complete command.
+const cycleHookTimeout = time.Minute
+
+// cycleHook runs --on-cycle-complete as each cycle completes, as
+// engine.TimerEvent.CycleCompleted has it, in the background like the
+// rewards command.
+type cycleHook struct {
+	command  string
+	progress *ui.Progress
+	clock    engine.Clock
+
+	// focusBase is the day's focus time from history as the session
+	// started, which the session's own work is added to.
+	day       time.Time
+	focusBase time.Duration
+	wg        sync.WaitGroup
+}
+
+func newCycleHook(command string, progress *ui.Progress, clock engine.Clock) *cycleHook {
+	h := &cycleHook{command: command, progress: progress, clock: clock}
+	now := clock.Now()
+	today, _ := summarizeToday(now)
+	h.day, h.focusBase = startOfDay(now), today.Focus
+	return h
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func (h *cycleHook) Handle(e engine.TimerEvent) {
+	cycle, ok := e.CycleCompleted()
+	if !ok {
+		return
+	}
+	now := h.clock.Now()
+	focus := h.focusBase + e.SessionWork
+	if !startOfDay(now).Equal(h.day) {
+		// Past midnight history has the day's work, this session's
+		// included, the recorder having written it ahead of this.
+		today, _ := summarizeToday(now)
+		focus = today.Focus
+	}
+
+	env := []string{
+		"POMO_CYCLE=" + strconv.Itoa(cycle),
+		"POMO_TOTAL_CYCLES=" + strconv.Itoa(e.TotalCycles),
+		"POMO_FOCUS_TODAY_MINUTES=" + strconv.Itoa(int(focus.Minutes())),
+		"POMO_LABEL=" + label,
+	}
+	// An infinite session has no cycles remaining or finish to project. A
+	// skipped break's remaining time is not to come.
+	if e.TotalCycles > 0 {
+		finish := now.Add(e.SessionRemaining - e.Remaining)
+		env = append(env,
+			"POMO_CYCLES_REMAINING="+strconv.Itoa(e.TotalCycles-cycle),
+			"POMO_FINISH="+finish.Format(time.RFC3339))
+	}
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), cycleHookTimeout)
+		defer cancel()
+		cmd := provider.Shell(ctx, h.command)
+		cmd.Env = append(os.Environ(), env...)
+		cmd.WaitDelay = time.Second
+		if out, err := cmd.CombinedOutput(); err != nil {
+			if ctx.Err() != nil {
+				err = fmt.Errorf("timed out after %s", cycleHookTimeout)
+			}
+			slog.Warn("cycle hook failed", "cycle", cycle, "err", err, "output", strings.TrimSpace(string(out)))
+			h.progress.Logf("Warning: --on-cycle-complete: %v", err)
+		}
+	}()
+}
+
+// Close waits for a command still running, up to its timeout.
+func (h *cycleHook) Close() error {
+	h.wg.Wait()
+	return nil
+}
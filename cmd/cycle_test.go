@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCycleHook runs three one-minute cycles, with a long break after the
+// second, and checks the hook ran once per cycle with what it was told.
+func TestCycleHook(t *testing.T) {
+	dir := isolate(t)
+	t.Setenv("HOOK_DIR", dir)
+	hook := `echo "$POMO_CYCLE of $POMO_TOTAL_CYCLES, $POMO_CYCLES_REMAINING left, ` +
+		`$POMO_FOCUS_TODAY_MINUTES focused, done at $POMO_FINISH" >> "$HOOK_DIR/cycle-$POMO_CYCLE"`
+	r := startSession(t, "-c", "3", "-p", "1", "-s", "1", "-l", "2", "-e", "2", "--on-cycle-complete", hook)
+	if err := r.wait(t); err != nil {
+		t.Fatalf("start: %v\nstderr:\n%s", err, r.stderr.String())
+	}
+
+	// Every cycle projects the same finish, 6m on from whenever the
+	// session's clock had got to as it started.
+	records := readRecords(t)
+	if len(records) == 0 {
+		t.Fatal("no records")
+	}
+	finish := records[0].Start.Add(6 * time.Minute).Format(time.RFC3339)
+	want := map[string]string{
+		"cycle-1": "1 of 3, 2 left, 1 focused, done at " + finish + "\n",
+		"cycle-2": "2 of 3, 1 left, 2 focused, done at " + finish + "\n",
+		"cycle-3": "3 of 3, 0 left, 3 focused, done at " + finish + "\n",
+	}
+	ran, err := filepath.Glob(filepath.Join(dir, "cycle-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != len(want) {
+		t.Errorf("hook ran for %v, want cycles 1 to 3", ran)
+	}
+	for name, line := range want {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("hook did not run for %s: %v", name, err)
+			continue
+		}
+		if string(got) != line {
+			t.Errorf("%s: hook got %q, want it once as %q", name, got, line)
+		}
+	}
+}
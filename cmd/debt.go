@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+// describeDebt renders owed as e.g. "Focus debt: 1h 40m — consider 1
+// extra cycle today", or "" when nothing is owed.
+func describeDebt(owed, work time.Duration, days int, plain bool) string {
+	if owed <= 0 {
+		return ""
+	}
+	dash := "—"
+	if plain {
+		dash = "-"
+	}
+	s := "Focus debt: " + format.DurationHuman(owed)
+	switch n := history.CatchUp(owed, work, days); n {
+	case 0:
+	case 1:
+		s += fmt.Sprintf(" %s consider 1 extra cycle today", dash)
+	default:
+		s += fmt.Sprintf(" %s consider %d extra cycles today", dash, n)
+	}
+	return s
+}
+
+// startupDebt is the --show-debt line for a session of work long phases,
+// or "" when nothing is owed or history cannot be read.
+func startupDebt(work time.Duration) string {
+	minWork, _ := minWorkDuration()
+	path, err := history.Path()
+	if err != nil {
+		return ""
+	}
+	records, err := history.Read(path)
+	if err != nil {
+		return ""
+	}
+	now := planClock.Now()
+	goal := time.Duration(dailyGoal) * work
+	_, _, owed := history.WeekDebt(records, now, goal, minWork, false)
+	return describeDebt(owed, work, history.DaysLeft(now), asciiOutput())
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDescribeDebt(t *testing.T) {
+	const work = 25 * time.Minute
+	tests := []struct {
+		owed  time.Duration
+		days  int
+		plain bool
+		want  string
+	}{
+		{0, 5, false, ""},
+		{-time.Hour, 5, false, ""},
+		{100 * time.Minute, 5, false, "Focus debt: 1h 40m — consider 1 extra cycle today"},
+		{100 * time.Minute, 1, false, "Focus debt: 1h 40m — consider 4 extra cycles today"},
+		{100 * time.Minute, 1, true, "Focus debt: 1h 40m - consider 4 extra cycles today"},
+		{100 * time.Minute, 0, false, "Focus debt: 1h 40m"},
+	}
+	for _, tt := range tests {
+		if got := describeDebt(tt.owed, work, tt.days, tt.plain); got != tt.want {
+			t.Errorf("describeDebt(%s, %d days, plain %v) = %q, want %q", tt.owed, tt.days, tt.plain, got, tt.want)
+		}
+	}
+}
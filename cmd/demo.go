@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// The demo session walks through every display state in under half a
+// minute: work with a pause, a short break that is skipped, a long break,
+// and the warning stretch at the end of phases.
+var (
+	demoStart  = time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC)
+	demoConfig = engine.Config{
+		WorkDuration:       6 * time.Second,
+		ShortBreakDuration: 4 * time.Second,
+		LongBreakDuration:  5 * time.Second,
+		LongBreakEvery:     2,
+		TotalCycles:        3,
+	}
+	demoWarnings = map[engine.Phase]time.Duration{
+		engine.PhaseWork:      2 * time.Second,
+		engine.PhaseLongBreak: 2 * time.Second,
+	}
+)
+
+// demoScript acts on the timer once the demo clock reaches each offset.
+var demoScript = []struct {
+	at     time.Duration
+	action func(*engine.Timer)
+}{
+	{2 * time.Second, (*engine.Timer).Pause},
+	{4 * time.Second, (*engine.Timer).Resume},
+	{9 * time.Second, (*engine.Timer).Skip},
+}
+
+// demoSleep paces the demo in real time; tests play it at once.
+var demoSleep = time.Sleep
+
+// playDemo passes events through while stepping clock in real time. It
+// only advances the clock or acts on the timer after a tick that leaves
+// the timer waiting, so each run sees exactly the same events.
+func playDemo(timer *engine.Timer, clock *engine.MockClock, in <-chan engine.TimerEvent) <-chan engine.TimerEvent {
+	out := make(chan engine.TimerEvent)
+
+	go func() {
+		defer close(out)
+		script := demoScript
+
+		for e := range in {
+			out <- e
+			if e.Type != engine.EventTick || e.Ended != "" {
+				continue
+			}
+
+			if len(script) > 0 && clock.Now().Sub(demoStart) >= script[0].at {
+				script[0].action(timer)
+				script = script[1:]
+				continue
+			}
+			if d, ok := clock.UntilNext(); ok {
+				demoSleep(d)
+				clock.Advance(d)
+			}
+		}
+	}()
+
+	return out
+}
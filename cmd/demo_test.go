@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// playDemoAtOnce runs pomo start --demo, with args, without its real-time
+// pacing, returning what it printed.
+func playDemoAtOnce(t *testing.T, args ...string) string {
+	t.Helper()
+	isolate(t)
+	saved, savedColor := demoSleep, color.NoColor
+	demoSleep, color.NoColor = func(time.Duration) {}, true
+	defer func() { demoSleep, color.NoColor = saved, savedColor }()
+
+	var stdout, stderr syncBuffer
+	env := startEnv{stdin: strings.NewReader(""), stdout: &stdout, stderr: &stderr}
+	if err := execute(t, env, append([]string{"start", "--demo", "--theme", "dark"}, args...)...); err != nil {
+		t.Fatalf("start --demo: %v\nstderr:\n%s", err, stderr.String())
+	}
+	return stdout.String()
+}
+
+// TestDemoGolden plays the demo, which must look the same every run and
+// change only when meant to: go test ./cmd -run Demo -update rewrites the
+// golden file.
+func TestDemoGolden(t *testing.T) {
+	out := playDemoAtOnce(t)
+
+	golden := filepath.Join("testdata", "demo.golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(out), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal([]byte(out), want) {
+		t.Errorf("output differs from %s:\n%q\nwant:\n%q", golden, out, want)
+	}
+}
+
+func TestDemoShowsSkippedBreak(t *testing.T) {
+	out := playDemoAtOnce(t)
+	frames := strings.Split(out, "\x1b[J")
+	final := frames[len(frames)-1]
+
+	// The break skipped a second in stays at that second, marked, for the
+	// rest of the run.
+	for line := range strings.Lines(final) {
+		if !strings.HasPrefix(line, "Short Break (1/3)") {
+			continue
+		}
+		if !strings.Contains(line, "00:01/00:04 skipped") {
+			t.Errorf("skipped break drawn as %q, want it frozen at 00:01 and marked skipped", line)
+		}
+		return
+	}
+	t.Errorf("final frame lacks the skipped break:\n%s", final)
+}
+
+// TestDemoNextUp checks the demo says what comes next under the phase bar,
+// down to the end of the session, but not with --no-next or --compact.
+func TestDemoNextUp(t *testing.T) {
+	out := playDemoAtOnce(t)
+	for _, want := range []string{"next: Short Break (4s) -> Work 2/3", "next: Long Break (5s) -> Work 3/3", "next: Work 3/3 (6s)", "next: session complete"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("demo never said %q", want)
+		}
+	}
+	for flag, bar := range map[string]string{"--no-next": "Work (3/3) ", "--compact": "> W ["} {
+		out := playDemoAtOnce(t, flag)
+		if !strings.Contains(out, bar) {
+			t.Errorf("demo with %s never drew %q", flag, bar)
+		}
+		if strings.Contains(out, "next:") {
+			t.Errorf("demo with %s said what comes next:\n%q", flag, out)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/digest"
+	"github.com/steenfuentes/pomo/fsutil"
+)
+
+var (
+	digestWeek   bool
+	digestOutput string
+	digestTo     string
+)
+
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Render a report of a week's focus",
+	Long: `Render a report of the last seven days' focus, or with --week of last
+week from Monday to Sunday: focus time against the week before, a bar per
+day, the busiest labels, and how many days in a row had a completed work
+phase. Work phases count as in pomo stats.
+
+The report is plain text, Markdown, or HTML. Each comes from a template
+that a file in ~/.config/pomo/ replaces: digest.txt.tmpl, digest.md.tmpl,
+or digest.html.tmpl, Go templates over the report's fields (see the
+README).
+
+Examples:
+  pomo digest --week --output html | mail -s "Last week" me@example.com
+  pomo digest --week --output md --to ~/notes/focus/$(date +%G-W%V).md`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if !slices.Contains(digest.Formats, digestOutput) {
+			return fmt.Errorf("invalid --output %q (want txt, md, or html)", digestOutput)
+		}
+
+		minWork, err := minWorkDuration()
+		if err != nil {
+			return err
+		}
+		records, err := readHistory(cmd)
+		if err != nil {
+			return err
+		}
+		dir, _ := config.Dir()
+
+		now := planClock.Now()
+		y, m, d := now.Date()
+		from := time.Date(y, m, d-6, 0, 0, 0, 0, time.Local)
+		if digestWeek {
+			sinceMonday := (int(now.Weekday()) + 6) % 7
+			from = time.Date(y, m, d-sinceMonday-7, 0, 0, 0, 0, time.Local)
+		}
+		report := digest.Build(records, from, minWork)
+
+		if digestTo == "" || digestTo == "-" {
+			return digest.Render(cmd.OutOrStdout(), report, digestOutput, dir)
+		}
+		var b strings.Builder
+		if err := digest.Render(&b, report, digestOutput, dir); err != nil {
+			return err
+		}
+		return fsutil.WriteFileAtomic(digestTo, []byte(b.String()), 0o644)
+	},
+}
+
+func init() {
+	digestCmd.Flags().BoolVar(&digestWeek, "week", false, "Cover last week, Monday to Sunday, instead of the last seven days")
+	digestCmd.Flags().StringVar(&digestOutput, "output", "txt", "Format: txt, md, or html")
+	digestCmd.Flags().StringVar(&digestTo, "to", "", "Write the report to this file instead of stdout")
+
+	rootCmd.AddCommand(digestCmd)
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/history"
+)
+
+var (
+	exportOutput   string
+	exportCompress bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write history out as JSON lines, to move it between machines",
+	Long: `Write every history record to stdout, or the file given with -o, one JSON
+object per line, streamed as it is read. pomo import reads it back.
+
+With --compress the output is gzipped, and .gz is added to an -o file
+name that lacks it.
+
+Examples:
+  pomo export -o history.jsonl
+  pomo export --compress -o ~/sync/pomo.jsonl   # Writes pomo.jsonl.gz`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		path, err := history.Path()
+		if err != nil {
+			return err
+		}
+		name := exportOutput
+		if exportCompress && name != "" && name != "-" && !strings.HasSuffix(name, ".gz") {
+			name += ".gz"
+		}
+
+		var w io.Writer = cmd.OutOrStdout()
+		var f *os.File
+		if name != "" && name != "-" {
+			if f, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600); err != nil {
+				return err
+			}
+			w = f
+		}
+		var zw *gzip.Writer
+		if exportCompress {
+			zw = gzip.NewWriter(w)
+			w = zw
+		}
+
+		n, err := history.Export(w, path)
+		var corrupt *history.CorruptError
+		if errors.As(err, &corrupt) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v (run pomo history --repair)\n", corrupt)
+			err = nil
+		}
+		if zw != nil {
+			err = errors.Join(err, zw.Close())
+		}
+		if f != nil {
+			err = errors.Join(err, f.Close())
+			if err != nil {
+				os.Remove(name)
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d record(s) to %s\n", n, name)
+		}
+		return err
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file|->",
+	Short: "Add records from an export to history",
+	Long: `Add the records in a file pomo export wrote, or stdin for -, to history,
+in order of start. Records history already has are left out, so importing
+the same export twice, or one from a machine that shares some history,
+adds nothing twice. Gzipped files, such as --compress exports and pomo
+history prune's archives, are read as they are.
+
+History as it was is backed up first; pomo history restore puts it back.
+
+Examples:
+  pomo import ~/sync/pomo.jsonl.gz`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		path, err := history.Path()
+		if err != nil {
+			return err
+		}
+		in := cmd.InOrStdin()
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+		added, dup, err := history.Import(path, in)
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Imported %d record(s), %d already in history\n", added, dup)
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "File to write, instead of stdout")
+	exportCmd.Flags().BoolVar(&exportCompress, "compress", false, "Gzip the output, adding .gz to the file name if missing")
+
+	rootCmd.AddCommand(exportCmd, importCmd)
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steenfuentes/pomo/history"
+)
+
+// historyFrom puts the testdata file name in place as history.
+func historyFrom(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := history.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkGolden compares got with the golden file, which -update rewrites.
+func checkGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+	golden = filepath.Join("testdata", golden)
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s:\n%q\nwant:\n%q", golden, got, want)
+	}
+}
+
+// TestExportGolden exports a history of old and new records, with a line
+// cut short, in each format: go test ./cmd -run Export -update rewrites
+// the golden files.
+func TestExportGolden(t *testing.T) {
+	const warning = "skipped 1 corrupt record(s) at line(s) 4 (run pomo history --repair)\n"
+
+	t.Run("json lines", func(t *testing.T) {
+		isolate(t)
+		historyFrom(t, "export-history.jsonl")
+		var stdout, stderr syncBuffer
+		env := startEnv{stdin: strings.NewReader(""), stdout: &stdout, stderr: &stderr}
+		if err := execute(t, env, "export"); err != nil {
+			t.Fatalf("export: %v\n%s", err, stderr.String())
+		}
+		checkGolden(t, "export.golden", []byte(stdout.String()))
+		if !strings.HasSuffix(stderr.String(), warning) {
+			t.Errorf("stderr %q, want a warning of the line cut short", stderr.String())
+		}
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		dir := isolate(t)
+		historyFrom(t, "export-history.jsonl")
+		var stdout, stderr syncBuffer
+		env := startEnv{stdin: strings.NewReader(""), stdout: &stdout, stderr: &stderr}
+		out := filepath.Join(dir, "pomo.jsonl")
+		if err := execute(t, env, "export", "--compress", "-o", out); err != nil {
+			t.Fatalf("export: %v\n%s", err, stderr.String())
+		}
+		data, err := os.ReadFile(out + ".gz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, "export.gz.golden", data)
+		if stdout.String() != "" {
+			t.Errorf("stdout %q, want nothing with -o", stdout.String())
+		}
+		if want := "Exported 4 record(s) to " + out + ".gz\n"; !strings.HasSuffix(stderr.String(), want) {
+			t.Errorf("stderr %q, want it to end %q", stderr.String(), want)
+		}
+	})
+}
+
+// TestExportImportCompressed moves history from one data directory to
+// another through pomo export --compress and pomo import, and checks it
+// exports there just as the golden file has it.
+func TestExportImportCompressed(t *testing.T) {
+	dir := isolate(t)
+	historyFrom(t, "export-history.jsonl")
+	archive := filepath.Join(dir, "pomo.jsonl.gz")
+	var out syncBuffer
+	env := startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}
+	if err := execute(t, env, "export", "--compress", "-o", archive); err != nil {
+		t.Fatalf("export: %v\n%s", err, out.String())
+	}
+
+	isolate(t)
+	var imported syncBuffer
+	env.stdout, env.stderr = &imported, &imported
+	if err := execute(t, env, "import", archive); err != nil {
+		t.Fatalf("import: %v\n%s", err, imported.String())
+	}
+	if got := imported.String(); got != "Imported 4 record(s), 0 already in history\n" {
+		t.Errorf("import printed %q", got)
+	}
+	var exported, stderr syncBuffer
+	env.stdout, env.stderr = &exported, &stderr
+	if err := execute(t, env, "export"); err != nil {
+		t.Fatalf("export: %v\n%s", err, stderr.String())
+	}
+	checkGolden(t, "export.golden", []byte(exported.String()))
+	if stderr.String() != "" {
+		t.Errorf("stderr %q, want nothing from an imported history", stderr.String())
+	}
+}
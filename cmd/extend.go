@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/state"
+)
+
+var extendCmd = &cobra.Command{
+	Use:   "extend [duration]",
+	Short: "Make the current break longer",
+	Long: `Extend the current break by the session's --extend (default 5m) or the
+given duration. With --bank-breaks the time comes from the bank of break
+time left over from breaks skipped or cut short, as far as it goes; past
+that the break is extended all the same, and shown and recorded as such.
+Pressing + in the session does the same.
+
+Examples:
+  pomo extend
+  pomo extend 2m`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		command := "extend"
+		if len(args) == 1 {
+			if d, err := time.ParseDuration(args[0]); err != nil || d <= 0 {
+				return fmt.Errorf("invalid duration %q", args[0])
+			}
+			command += " " + args[0]
+		}
+		path, err := state.SocketPath()
+		if err != nil {
+			return err
+		}
+		reply, err := state.Send(path, command)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), reply)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(extendCmd)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// TestExtendReplies asks a break to be extended from a bank of 3m until
+// it runs out, and without banking, checking what pomo extend is told.
+func TestExtendReplies(t *testing.T) {
+	cfg := engine.Config{WorkDuration: 25 * time.Minute, ShortBreakDuration: 5 * time.Minute, TotalCycles: 2}
+	for _, banking := range []bool{true, false} {
+		cfg.BankBreaks = banking
+		c := &sessionControl{timer: engine.NewTimer(cfg), extendable: true, bank: 3 * time.Minute}
+		steps := []struct{ arg, banked, plain string }{
+			{"2m", "extended 2m0s from the bank", "extended 2m0s"},
+			{"2m", "extended 2m0s, 1m0s from the bank", "extended 2m0s"},
+			{"", "extended 5m0s past the bank", "extended 5m0s"},
+		}
+		for _, s := range steps {
+			want := s.plain
+			if banking {
+				want = s.banked
+			}
+			if reply, err := c.extend(s.arg); err != nil || reply != want {
+				t.Errorf("banking %v, extend %q: %q, %v, want %q", banking, s.arg, reply, err, want)
+			}
+		}
+		if c.bank != 0 && banking {
+			t.Errorf("bank %s once spent", c.bank)
+		}
+	}
+
+	c := &sessionControl{timer: engine.NewTimer(cfg), extendable: true}
+	for _, arg := range []string{"soon", "0s", "-1m"} {
+		if _, err := c.extend(arg); err == nil || err.Error() != "invalid duration \""+arg+"\"" {
+			t.Errorf("extend %q: %v", arg, err)
+		}
+	}
+	c.extendable = false
+	if _, err := c.extend("2m"); err == nil || err.Error() != "only a running break can be extended" {
+		t.Errorf("extend outside a break: %v", err)
+	}
+}
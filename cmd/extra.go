@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/state"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+// Lengths of a standalone break or work phase given no duration, matching
+// the start defaults.
+const (
+	defaultExtraBreak = 10 * time.Minute
+	defaultExtraWork  = 50 * time.Minute
+)
+
+var breakCmd = &cobra.Command{
+	Use:   "break [duration]",
+	Short: "Take a break now, in or outside a session",
+	Long: `Take a break now. With a session running, the current phase ends and a
+break of the given length (default: the session's short break) runs in its
+place, after which the schedule carries on. Otherwise a single break runs
+on its own. Either way it is recorded in history as an extra break.
+
+Examples:
+  pomo break                # A short break, 10m outside a session
+  pomo break 15m`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExtra(cmd, args, "break", engine.PhaseShortBreak, defaultExtraBreak)
+	},
+}
+
+var workCmd = &cobra.Command{
+	Use:   "work [duration]",
+	Short: "Start working now, in or outside a session",
+	Long: `Start a work phase now. With a session running, the current phase ends and
+a work phase of the given length (default: the session's work duration)
+runs in its place, after which the schedule carries on without counting it
+as a cycle. Otherwise a single work phase runs on its own. Either way it
+counts as focus time in stats.
+
+Examples:
+  pomo work                 # 50m outside a session
+  pomo work 20m`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runExtra(cmd, args, "work", engine.PhaseWork, defaultExtraWork)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(breakCmd, workCmd)
+}
+
+func runExtra(cmd *cobra.Command, args []string, kind string, phase engine.Phase, fallback time.Duration) error {
+	cmd.SilenceUsage = true
+
+	x := engine.Extra{Phase: phase, Duration: fallback}
+	command := kind
+	if len(args) == 1 {
+		d, err := time.ParseDuration(args[0])
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid duration %q", args[0])
+		}
+		x.Duration = d
+		command += " " + args[0]
+	}
+
+	path, err := state.SocketPath()
+	if err != nil {
+		return err
+	}
+	reply, err := state.Send(path, command)
+	if err == nil {
+		fmt.Fprintln(cmd.OutOrStdout(), reply)
+		return nil
+	}
+	if !errors.Is(err, state.ErrNotRunning) {
+		return err
+	}
+
+	ui.UseTheme(withContrast(ui.DetectTheme()))
+	env := newStartEnv(cmd)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	control := &sessionControl{}
+	go watchSignals(env, control, cancel)
+	defer serveControl(env, control)()
+
+	timer := engine.NewExtraTimer(x, env.clock, engine.DefaultTickInterval)
+	if _, err := runSession(ctx, env, timer, control, nil); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/focuswatch"
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/ui"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+const focusPollInterval = 2 * time.Second
+
+// distractionWatcher nudges when a blocked app stays in front for longer
+// than the grace period during a work phase, once per visit, and counts it
+// in history.
+type distractionWatcher struct {
+	poller   *focuswatch.Poller
+	blocked  focuswatch.Blocklist
+	grace    time.Duration
+	progress *ui.Progress
+	recorder *history.Recorder
+	clock    engine.Clock
+
+	app     string
+	since   time.Time
+	counted bool
+}
+
+func newDistractionWatcher(progress *ui.Progress, recorder *history.Recorder, clock engine.Clock) *distractionWatcher {
+	return &distractionWatcher{
+		poller:   focuswatch.Poll(focusWatcher, focusPollInterval),
+		blocked:  focusBlocklist,
+		grace:    focusGrace,
+		progress: progress,
+		recorder: recorder,
+		clock:    clock,
+	}
+}
+
+func (w *distractionWatcher) Handle(e engine.TimerEvent) {
+	if e.Type != engine.EventTick {
+		return
+	}
+	app, err := w.poller.Frontmost()
+	if err != nil || e.Phase != engine.PhaseWork || e.Paused || e.Ended != "" || !w.blocked.Match(app) {
+		w.app = ""
+		return
+	}
+
+	now := w.clock.Now()
+	if app != w.app {
+		w.app, w.since, w.counted = app, now, false
+	}
+	if w.counted || now.Sub(w.since) < w.grace {
+		return
+	}
+	w.counted = true
+	w.progress.Nudge("%s has been in front for %s, back to work?", app, format.DurationPrecise(now.Sub(w.since)))
+	if w.recorder != nil {
+		w.recorder.Distracted()
+	}
+}
+
+func (w *distractionWatcher) Close() error {
+	w.poller.Stop()
+	return nil
+}
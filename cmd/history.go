@@ -0,0 +1,343 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+var (
+	historyRepair bool
+	historyYes    bool
+	historyLabel  string
+	historyTags   []string
+	historyNote   string
+	historyBefore string
+	hyperlinks    string
+)
+
+// historyRecent is how many of the last records pomo history shows.
+const historyRecent = 5
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Check, repair, or amend the history file",
+	Long: `Print where history is kept, how many records it holds, and the last few
+with their IDs.
+
+A record cut short by a crash is skipped by every command that reads
+history. With --repair, pomo rewrites the file without such records.
+
+pomo history undo deletes the last record, and pomo history edit changes a
+record's label, tags, or note. pomo log --ids shows older records' IDs.
+
+Whatever rewrites the file backs it up first, keeping the last 10 copies,
+which pomo history restore lists and puts back. pomo history prune moves
+old records into a compressed archive.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		out := cmd.OutOrStdout()
+		link, err := historyLink(out)
+		if err != nil {
+			return err
+		}
+		path, err := history.Path()
+		if err != nil {
+			return err
+		}
+		records, err := history.Read(path)
+		var corrupt *history.CorruptError
+		if err != nil && !errors.As(err, &corrupt) {
+			return err
+		}
+
+		fmt.Fprintf(out, "%s: %d records\n", path, len(records))
+		if len(records) > 0 {
+			fmt.Fprintln(out)
+			ui.PrintTimeline(out, records[max(len(records)-historyRecent, 0):], 0, true, link)
+		}
+		if corrupt == nil {
+			return nil
+		}
+		fmt.Fprintln(out, corrupt)
+
+		if !historyRepair {
+			fmt.Fprintln(out, "Run pomo history --repair to remove them")
+			return nil
+		}
+		if !confirm(cmd, fmt.Sprintf("Remove %d corrupt line(s)? [y/N] ", len(corrupt.Lines))) {
+			return nil
+		}
+
+		removed, err := history.Repair(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Removed %d corrupt line(s)\n", removed)
+		return nil
+	},
+}
+
+var historyUndoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Delete the last record in history",
+	Long: `Show the last record in history, such as a false start, and delete it once
+confirmed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		out := cmd.OutOrStdout()
+		link, err := historyLink(out)
+		if err != nil {
+			return err
+		}
+		path, err := history.Path()
+		if err != nil {
+			return err
+		}
+		records, err := history.Read(path)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return errors.New("history is empty")
+		}
+		last := records[len(records)-1]
+
+		ui.PrintTimeline(out, []history.Record{last}, 0, true, link)
+		if !confirm(cmd, "Delete this record? [y/N] ") {
+			return nil
+		}
+		if _, err := history.Delete(path, last.ID()); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Deleted %s\n", last.ID())
+		return nil
+	},
+}
+
+var historyEditCmd = &cobra.Command{
+	Use:   "edit <id|last>",
+	Short: "Change a record's label, tags, or note",
+	Long: `Change the label, tags, or note of the record with the given ID, or of
+the last one, leaving its times alone. The start of an ID will do. An
+empty value clears the field.
+
+Examples:
+  pomo history edit last --label writing
+  pomo history edit 3f9a0c1 --tags deep,draft --note "first pass at ch. 2"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		flags := cmd.Flags()
+		if !flags.Changed("label") && !flags.Changed("tags") && !flags.Changed("note") {
+			return errors.New("nothing to change (want --label, --tags, or --note)")
+		}
+		link, err := historyLink(cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		path, err := history.Path()
+		if err != nil {
+			return err
+		}
+		r, err := history.Update(path, args[0], func(r *history.Record) {
+			if flags.Changed("label") {
+				r.Label = historyLabel
+			}
+			if flags.Changed("tags") {
+				r.Tags = historyTags
+			}
+			if flags.Changed("note") {
+				r.Note = historyNote
+			}
+		})
+		if err != nil {
+			return err
+		}
+		ui.PrintTimeline(cmd.OutOrStdout(), []history.Record{r}, 0, true, link)
+		return nil
+	},
+}
+
+var historyPruneCmd = &cobra.Command{
+	Use:   "prune --before <date>",
+	Short: "Move old records into a compressed archive",
+	Long: `Move every record that started before the given day out of history and
+into archive/history-before-<date>.jsonl.gz beside it, which zcat reads.
+Pruning again before the same day adds to the same archive.
+
+Examples:
+  pomo history prune --before 2023-01-01`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if historyBefore == "" {
+			return errors.New("want --before, as YYYY-MM-DD")
+		}
+		before, err := time.ParseInLocation(time.DateOnly, historyBefore, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --before %q (want YYYY-MM-DD)", historyBefore)
+		}
+		path, err := history.Path()
+		if err != nil {
+			return err
+		}
+		records, err := history.Read(path)
+		if err != nil {
+			return err
+		}
+		n := 0
+		for _, r := range records {
+			if r.Start.Before(before) {
+				n++
+			}
+		}
+		out := cmd.OutOrStdout()
+		if n == 0 {
+			fmt.Fprintf(out, "No records before %s\n", historyBefore)
+			return nil
+		}
+		if !confirm(cmd, fmt.Sprintf("Archive and remove %d of %d record(s)? [y/N] ", n, len(records))) {
+			return nil
+		}
+
+		archive, n, err := history.Prune(path, before)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Moved %d record(s) to %s\n", n, archive)
+		return nil
+	},
+}
+
+var historyRestoreCmd = &cobra.Command{
+	Use:   "restore [backup|latest]",
+	Short: "List the history file's backups, or put one back",
+	Long: `With no argument, list the backups taken each time history was rewritten,
+oldest first, with how many records each holds. Given one, by the start
+of its name or as latest, replace history with it once confirmed. History
+as it was is backed up first, so a restore can itself be undone.
+
+Examples:
+  pomo history restore
+  pomo history restore latest`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		path, err := history.Path()
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		if len(args) == 0 {
+			backups, err := history.Backups(path)
+			if err != nil {
+				return err
+			}
+			if len(backups) == 0 {
+				fmt.Fprintf(out, "No backups in %s\n", history.BackupDir(path))
+				return nil
+			}
+			for _, b := range backups {
+				records, _ := history.Read(b.Path)
+				fmt.Fprintf(out, "%s  %s  %d records\n", filepath.Base(b.Path), b.Taken.Format(time.DateTime), len(records))
+			}
+			return nil
+		}
+
+		b, err := history.FindBackup(path, args[0])
+		if err != nil {
+			return err
+		}
+		records, err := history.Read(b.Path)
+		var corrupt *history.CorruptError
+		if err != nil && !errors.As(err, &corrupt) {
+			return err
+		}
+		q := fmt.Sprintf("Replace history with %s, from %s, holding %d records? [y/N] ", filepath.Base(b.Path), b.Taken.Format(time.DateTime), len(records))
+		if !confirm(cmd, q) {
+			return nil
+		}
+		if err := history.Restore(path, b); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Restored %s\n", filepath.Base(b.Path))
+		return nil
+	},
+}
+
+func init() {
+	historyCmd.Flags().BoolVar(&historyRepair, "repair", false, "Rewrite the file without corrupt records")
+	historyCmd.PersistentFlags().BoolVarP(&historyYes, "yes", "y", false, "Repair, undo, prune, or restore without asking for confirmation")
+	historyCmd.PersistentFlags().StringVar(&hyperlinks, "hyperlinks", "auto", "Link record IDs to stats.link in the terminal: auto (where known to work), always, or never")
+
+	historyEditCmd.Flags().StringVar(&historyLabel, "label", "", "Label to give the record")
+	historyEditCmd.Flags().StringSliceVar(&historyTags, "tags", nil, "Tags to give the record, replacing its own")
+	historyEditCmd.Flags().StringVar(&historyNote, "note", "", "Note to give the record")
+
+	historyPruneCmd.Flags().StringVar(&historyBefore, "before", "", "Move records that started before this day, as YYYY-MM-DD")
+
+	historyCmd.AddCommand(historyUndoCmd, historyEditCmd, historyPruneCmd, historyRestoreCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+// historyLink is the template record IDs shown on out link to under
+// --hyperlinks, stats.link from the config file, or "" for none.
+func historyLink(out io.Writer) (string, error) {
+	mode, err := ui.ParseLinkMode(hyperlinks)
+	if err != nil {
+		return "", fmt.Errorf("invalid --hyperlinks %q (want auto, always, or never)", hyperlinks)
+	}
+	if !mode.Links(out) {
+		return "", nil
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	return cfg.Stats.Link, nil
+}
+
+// confirm asks question unless --yes was given, taking only "y" or "yes"
+// as a yes.
+func confirm(cmd *cobra.Command, question string) bool {
+	if historyYes {
+		return true
+	}
+	fmt.Fprint(cmd.OutOrStdout(), question)
+	line, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	a := strings.ToLower(strings.TrimSpace(line))
+	return a == "y" || a == "yes"
+}
+
+// readHistory reads all of history, warning about and skipping corrupt
+// records rather than failing.
+func readHistory(cmd *cobra.Command) ([]history.Record, error) {
+	path, err := history.Path()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := history.Read(path)
+	var corrupt *history.CorruptError
+	if errors.As(err, &corrupt) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v (run pomo history --repair)\n", corrupt)
+		return records, nil
+	}
+	return records, err
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steenfuentes/pomo/history"
+)
+
+// TestHistoryRestoreAfterCorruption runs a session, undoes its last phase,
+// corrupts the file, and restores the backup the undo took, answering the
+// confirmation as a user would.
+func TestHistoryRestoreAfterCorruption(t *testing.T) {
+	isolate(t)
+	if err := startSession(t, "-c", "2", "-p", "1", "-s", "1").wait(t); err != nil {
+		t.Fatal(err)
+	}
+	path, err := history.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(stdin string, args ...string) string {
+		t.Helper()
+		var out syncBuffer
+		if err := execute(t, startEnv{stdin: strings.NewReader(stdin), stdout: &out, stderr: &out}, args...); err != nil {
+			t.Fatalf("%v: %v\n%s", args, err, out.String())
+		}
+		return out.String()
+	}
+	run("", "history", "undo", "-y")
+	if err := os.WriteFile(path, []byte("{\"start\":\n\x00\x00"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Declined, nothing changes.
+	if out := run("n\n", "history", "restore", "latest"); !strings.Contains(out, "holding 3 records? [y/N]") || strings.Contains(out, "Restored") {
+		t.Errorf("declined restore printed:\n%s", out)
+	}
+	if out := run("y\n", "history", "restore", "latest"); !strings.Contains(out, "Restored history-") {
+		t.Errorf("restore printed:\n%s", out)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, before) {
+		t.Errorf("restored to\n%s\nwant\n%s", after, before)
+	}
+	if records := readRecords(t); len(records) != 3 {
+		t.Errorf("%d records, want the session's 3", len(records))
+	}
+}
+
+// TestHistoryHyperlinks lists a session's records with each --hyperlinks
+// mode, linking IDs to stats.link only where asked.
+func TestHistoryHyperlinks(t *testing.T) {
+	dir := isolate(t)
+	if err := startSession(t, "-c", "1", "-p", "1", "-s", "1").wait(t); err != nil {
+		t.Fatal(err)
+	}
+	records := readRecords(t)
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		var out syncBuffer
+		err := execute(t, startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}, args...)
+		return out.String(), err
+	}
+	linked := func(template string) func(id string) string {
+		return func(id string) string {
+			return "\x1b]8;;" + strings.ReplaceAll(template, "{id}", id) + "\x1b\\" + id + "\x1b]8;;\x1b\\ "
+		}
+	}
+
+	out, err := run("history", "--hyperlinks=always")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		if want := linked("pomo://history/{id}")(r.ID()); !strings.Contains(out, want) {
+			t.Errorf("--hyperlinks=always: no %q in\n%q", want, out)
+		}
+	}
+	for _, mode := range []string{"never", "auto"} {
+		out, err := run("history", "--hyperlinks="+mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(out, "\x1b]8") {
+			t.Errorf("--hyperlinks=%s linked IDs written to a buffer:\n%q", mode, out)
+		}
+		for _, r := range records {
+			if !strings.Contains(out, r.ID()+" ") {
+				t.Errorf("--hyperlinks=%s: no ID %s in\n%q", mode, r.ID(), out)
+			}
+		}
+	}
+
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("[stats]\nlink = \"notes://pomo/{id}\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = run("history", "--hyperlinks=always", "--config", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := linked("notes://pomo/{id}")(records[0].ID()); !strings.Contains(out, want) {
+		t.Errorf("stats.link: no %q in\n%q", want, out)
+	}
+
+	if _, err := run("history", "--hyperlinks=yes"); err == nil || err.Error() != `invalid --hyperlinks "yes" (want auto, always, or never)` {
+		t.Errorf("--hyperlinks=yes: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/fsutil"
+	"github.com/steenfuentes/pomo/history"
+)
+
+var (
+	initDefaults bool
+	initForce    bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a config file by answering a few questions",
+	Long: `Ask for the work and break lengths, how often to take a long break, the
+day's goal, and whether to ring the bell, then write them to the config
+file as the settings pomo start uses by default. Enter takes the default
+shown in brackets.
+
+pomo start offers this the first time it runs on a terminal, before any
+history or config file exists.
+
+Examples:
+  pomo init
+  pomo init --defaults    # Write pomo's defaults without asking`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		path, err := initPath()
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err == nil && !initForce {
+			return fmt.Errorf("%s already exists (--force to replace it)", path)
+		}
+
+		a := defaultAnswers()
+		if !initDefaults {
+			if a, err = askSetup(newLineReader(cmd.InOrStdin()), cmd.OutOrStdout()); err != nil {
+				return err
+			}
+		}
+		if err := writeSetup(path, a); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s\n", path)
+		return nil
+	},
+}
+
+func init() {
+	initCmd.Flags().BoolVar(&initDefaults, "defaults", false, "Write pomo's defaults without asking")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Replace an existing config file")
+
+	rootCmd.AddCommand(initCmd)
+}
+
+// setupAnswers is what pomo init asks for.
+type setupAnswers struct {
+	work, short, long int
+	longEvery         int
+	dailyGoal         int
+	bell              bool
+}
+
+// defaultAnswers are pomo start's own defaults.
+func defaultAnswers() setupAnswers {
+	return setupAnswers{work: 50, short: 10, long: 30, longEvery: 4, dailyGoal: 8, bell: true}
+}
+
+// config is the schedule the answers make, for Validate.
+func (a setupAnswers) config() engine.Config {
+	return engine.Config{
+		WorkDuration:       time.Duration(a.work) * time.Minute,
+		ShortBreakDuration: time.Duration(a.short) * time.Minute,
+		LongBreakDuration:  time.Duration(a.long) * time.Minute,
+		LongBreakEvery:     a.longEvery,
+	}
+}
+
+// askSetup asks each question in turn until it gets a valid answer, an
+// empty one taking the default. The answers together have to make a
+// schedule Config.Validate accepts.
+func askSetup(r *lineReader, out io.Writer) (setupAnswers, error) {
+	a := defaultAnswers()
+	questions := []struct {
+		text string
+		into *int
+		min  int
+	}{
+		{"Work length in minutes", &a.work, 1},
+		{"Short break length in minutes", &a.short, 1},
+		{"Long break length in minutes", &a.long, 1},
+		{"Long break every how many work phases (0 = never)", &a.longEvery, 0},
+		{"Pomodoros to aim for each day (0 = just count them)", &a.dailyGoal, 0},
+	}
+	for _, q := range questions {
+		for {
+			answer, err := askLine(r, out, fmt.Sprintf("%s [%d]: ", q.text, *q.into))
+			if err != nil {
+				return a, err
+			}
+			if answer == "" {
+				break
+			}
+			n, err := strconv.Atoi(answer)
+			if err == nil && n >= q.min {
+				*q.into = n
+				break
+			}
+			fmt.Fprintf(out, "Want a whole number, %d or more\n", q.min)
+		}
+	}
+	for {
+		answer, err := askLine(r, out, "Ring the terminal bell when a phase ends? [Y/n]: ")
+		if err != nil {
+			return a, err
+		}
+		if answer == "" || answer == "y" || answer == "yes" {
+			break
+		}
+		if answer == "n" || answer == "no" {
+			a.bell = false
+			break
+		}
+		fmt.Fprintln(out, "Want y or n")
+	}
+	return a, a.config().Validate()
+}
+
+// askLine returns the next line of in, trimmed and in lower case. EOF
+// before any answer is an error, so a closed stdin does not loop.
+func askLine(r *lineReader, out io.Writer, question string) (string, error) {
+	fmt.Fprint(out, question)
+	line, err := r.next(0)
+	if err != nil {
+		fmt.Fprintln(out)
+		return "", errors.New("setup cancelled")
+	}
+	return strings.ToLower(strings.TrimSpace(line)), nil
+}
+
+// writeSetup writes a as top-level settings, with a profile to copy from
+// commented out below.
+func writeSetup(path string, a setupAnswers) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Written by pomo init. Flags given to pomo start override these;\n")
+	fmt.Fprintf(&b, "# pomo config show lists every setting and where it comes from.\n\n")
+	fmt.Fprintf(&b, "pomodoro = %d\n", a.work)
+	fmt.Fprintf(&b, "short = %d\n", a.short)
+	fmt.Fprintf(&b, "long = %d\n", a.long)
+	fmt.Fprintf(&b, "long-every = %d\n", a.longEvery)
+	fmt.Fprintf(&b, "daily-goal = %d\n", a.dailyGoal)
+	if !a.bell {
+		fmt.Fprintf(&b, "quiet-hours = [\"00:00-00:00\"]   # No bell, all day\n")
+	}
+	fmt.Fprintf(&b, `
+# A profile bundles settings under a name, run with pomo start sprint 6:
+#
+# [profiles.sprint]
+# params = ["n:int=4"]
+# pomodoro = 25
+# short = 5
+# cycles = "{n}"
+`)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, []byte(b.String()), 0o600)
+}
+
+// initPath is the config file pomo init writes: --config or POMO_CONFIG if
+// given, else the default.
+func initPath() (string, error) {
+	if configPath != "" {
+		return configPath, nil
+	}
+	if path := os.Getenv(config.EnvConfig); path != "" {
+		return path, nil
+	}
+	return config.Path()
+}
+
+// offerSetup runs pomo init's questions ahead of the first session on a
+// terminal, when there is neither a config file nor any history yet.
+// Declining leaves everything as it was.
+func offerSetup(env startEnv) {
+	if f, ok := env.stdin.(*os.File); !ok || !isatty.IsTerminal(f.Fd()) {
+		return
+	}
+	path, err := initPath()
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return
+	}
+	if hpath, err := history.Path(); err != nil {
+		return
+	} else if _, err := os.Stat(hpath); !os.IsNotExist(err) {
+		return
+	}
+
+	if !promptYes(env, "No config file yet. Answer a few questions to set one up? [y/N] ", promptTimeout) {
+		fmt.Fprint(env.stdout, "Run pomo init any time to set one up.\n\n")
+		return
+	}
+	a, err := askSetup(env.lines, env.stdout)
+	if err == nil {
+		err = writeSetup(path, a)
+	}
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Warning: no config written: %v\n\n", err)
+		return
+	}
+	fmt.Fprintf(env.stdout, "Wrote %s\n\n", path)
+}
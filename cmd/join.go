@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/keys"
+	"github.com/steenfuentes/pomo/share"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+var (
+	joinBell   bool
+	joinRecord bool
+)
+
+var joinCmd = &cobra.Command{
+	Use:   "join <url>",
+	Short: "Follow a session shared with pomo start --share",
+	Long: `Show a session running on another machine, as shared with
+pomo start --share, e.g. pomo join http://host:7657. The times shown are the
+host's, so the two machines' clocks need not agree.
+
+Press s to skip the phase or p to pause and resume, if the host allows it
+with --share-control. When the connection drops, pomo join says so, keeps
+reconnecting, and catches up without ringing the bell or recording anything
+twice. It exits when the session ends.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		base := args[0]
+		out := cmd.OutOrStdout()
+
+		warnings, err := parseWarnings(warnBefore)
+		if err != nil {
+			return err
+		}
+		opts := []ui.Option{ui.WithWarnings(warnings)}
+		if !joinBell {
+			opts = append(opts, ui.WithQuiet(func() bool { return true }))
+		}
+		var recordPath string
+		if joinRecord {
+			if recordPath, err = history.Path(); err != nil {
+				return err
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		var mu sync.Mutex
+		var progress *ui.Progress
+		logf := func(format string, args ...any) {
+			mu.Lock()
+			defer mu.Unlock()
+			if progress != nil {
+				progress.Logf(format, args...)
+			}
+		}
+
+		if f, ok := cmd.InOrStdin().(*os.File); ok {
+			if listener, err := keys.Listen(f, func(b byte) {
+				defer ui.RestoreOnPanic()
+				command := map[byte]string{'s': "skip", 'p': "toggle-pause", 'b': "snooze"}[b]
+				if command == "" {
+					return
+				}
+				if _, err := share.Send(base, command); err != nil {
+					logf("Could not %s: %v", command, err)
+				}
+			}); err == nil {
+				defer listener.Stop()
+				defer ui.OnRestore(func() { listener.Restore() })()
+			}
+		}
+
+		var recorder *history.Recorder
+		follower := share.NewFollower(base)
+		err = follower.Run(ctx, func(m share.Message) {
+			e := m.Event
+			mu.Lock()
+			defer mu.Unlock()
+
+			if e.Type == engine.EventSessionStarted {
+				if progress != nil {
+					progress.Abort()
+				}
+				fmt.Fprintf(out, "Following the session at %s\n\n", base)
+				progress = ui.NewProgress(e.TotalPhases, out, opts...)
+				if joinRecord {
+					recorder = history.NewRecorder(recordPath, engine.RealClock{}, "")
+				}
+			}
+			if progress == nil {
+				return
+			}
+			progress.Update(e)
+			if recorder != nil {
+				recorder.Handle(e)
+			}
+
+			if e.Type == engine.EventSessionEnded {
+				if e.Summary != nil && e.Summary.Ended == engine.EndInterrupted {
+					progress.Abort()
+				} else {
+					progress.Wait()
+				}
+				progress = nil
+				fmt.Fprintln(out, "\nSession over")
+				stop()
+			}
+		}, func(err error) {
+			if err != nil {
+				logf("Connection lost (%v), reconnecting...", err)
+			} else {
+				logf("Reconnected")
+			}
+		})
+
+		mu.Lock()
+		defer mu.Unlock()
+		if progress != nil {
+			progress.Abort()
+		}
+		return err
+	},
+}
+
+func init() {
+	joinCmd.Flags().BoolVar(&joinBell, "bell", true, "Ring the bell here too, e.g. as a phase is about to end")
+	joinCmd.Flags().BoolVar(&joinRecord, "record", false, "Also record the phases in this machine's history")
+
+	rootCmd.AddCommand(joinCmd)
+}
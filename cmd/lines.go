@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"time"
+)
+
+// lineReader reads the answers to the questions a command asks, a line at
+// a time, from one goroutine and one buffer. An answer given up on is kept
+// for the next question rather than lost, and so is anything typed or
+// pasted ahead. The goroutine only reads while a question waits, leaving
+// stdin to the key listener while a session runs, but for a read a
+// question gave up on, which is still waiting for its line.
+type lineReader struct {
+	want  chan struct{}
+	lines chan line
+	// pending is whether a read has been asked for whose line has not been
+	// taken yet. err sticks once stdin is done.
+	pending bool
+	err     error
+}
+
+type line struct {
+	text string
+	err  error
+}
+
+func newLineReader(in io.Reader) *lineReader {
+	r := &lineReader{want: make(chan struct{}), lines: make(chan line, 1)}
+	go func() {
+		br := bufio.NewReader(in)
+		for range r.want {
+			text, err := br.ReadString('\n')
+			if err != nil && text != "" {
+				// A last line without a newline is still an answer.
+				err = nil
+			}
+			r.lines <- line{text, err}
+		}
+	}()
+	return r
+}
+
+// errTimeout is what next returns for a question left unanswered.
+var errTimeout = errors.New("no answer")
+
+// next returns the next line, line ending and all, waiting at most timeout
+// for it, or for ever with a timeout of 0. Once stdin has ended, every line
+// is an error.
+func (r *lineReader) next(timeout time.Duration) (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	if !r.pending {
+		r.want <- struct{}{}
+		r.pending = true
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+	select {
+	case l := <-r.lines:
+		r.pending = false
+		r.err = l.err
+		return l.text, l.err
+	case <-expired:
+		return "", errTimeout
+	}
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineReaderKeepsAnswerGivenUpOn(t *testing.T) {
+	in, w := io.Pipe()
+	r := newLineReader(in)
+
+	if _, err := r.next(10 * time.Millisecond); !errors.Is(err, errTimeout) {
+		t.Fatalf("next with nothing typed: err = %v, want errTimeout", err)
+	}
+	go w.Write([]byte("y\n"))
+	if got, err := r.next(time.Second); err != nil || got != "y\n" {
+		t.Fatalf("next after a timeout = %q, %v, want the line typed since", got, err)
+	}
+}
+
+func TestLineReaderKeepsTypeAhead(t *testing.T) {
+	r := newLineReader(strings.NewReader("4\nfine\nlast"))
+	for _, want := range []string{"4\n", "fine\n", "last"} {
+		if got, err := r.next(time.Second); err != nil || got != want {
+			t.Fatalf("next = %q, %v, want %q", got, err, want)
+		}
+	}
+	for range 2 {
+		if _, err := r.next(time.Second); !errors.Is(err, io.EOF) {
+			t.Fatalf("next at the end: err = %v, want io.EOF", err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+var (
+	logDate string
+	logJSON bool
+	logGap  time.Duration
+	logIDs  bool
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log [today|yesterday]",
+	Short: "Show a timeline of the phases run on a day",
+	Long: `Show every phase recorded in history on a day, in order, with its start
+time, actual and planned duration, label, and pauses.
+
+Examples:
+  pomo log                   # Today
+  pomo log yesterday
+  pomo log --date 2024-05-01
+  pomo log --json`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: []string{"today", "yesterday"},
+	RunE:      runLog,
+}
+
+func init() {
+	logCmd.Flags().StringVar(&logDate, "date", "", "Day to show, as YYYY-MM-DD")
+	logCmd.Flags().BoolVar(&logJSON, "json", false, "Print the day's records as JSON")
+	logCmd.Flags().DurationVar(&logGap, "gap", 30*time.Minute, "Mark breaks between phases longer than this (0 = never)")
+	logCmd.Flags().BoolVar(&logIDs, "ids", false, "Start each row with the record's ID, for pomo history edit")
+	logCmd.Flags().StringVar(&hyperlinks, "hyperlinks", "auto", "With --ids, link them to stats.link in the terminal: auto (where known to work), always, or never")
+
+	rootCmd.AddCommand(logCmd)
+}
+
+func runLog(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	day := planClock.Now()
+	if len(args) > 0 {
+		switch args[0] {
+		case "today":
+		case "yesterday":
+			day = day.AddDate(0, 0, -1)
+		default:
+			return fmt.Errorf("unknown day %q (want today or yesterday, or use --date)", args[0])
+		}
+	}
+	if logDate != "" {
+		d, err := time.ParseInLocation(time.DateOnly, logDate, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --date %q (want YYYY-MM-DD)", logDate)
+		}
+		day = d
+	}
+
+	link, err := historyLink(cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+	records, err := readHistory(cmd)
+	if err != nil {
+		return err
+	}
+	records = history.On(records, day)
+
+	out := cmd.OutOrStdout()
+	if logJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if records == nil {
+			records = []history.Record{}
+		}
+		return enc.Encode(records)
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintf(out, "Nothing recorded on %s\n", day.Format(time.DateOnly))
+		return nil
+	}
+	ui.PrintTimeline(out, records, logGap, logIDs, link)
+	return nil
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/logfile"
+	"github.com/steenfuentes/pomo/state"
+)
+
+// How often pomo logs --follow looks for new lines.
+const logPollInterval = 500 * time.Millisecond
+
+var (
+	logsFollow bool
+	logsLines  int
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the diagnostics log pomo start writes",
+	Long: `Show the end of the diagnostics log, where pomo start notes each phase,
+provider and notification results, connections to the control socket and
+to --share, and errors. It lives in ~/.local/state/pomo/log/ and rotates
+at 1 MiB, keeping the last 3 files.
+
+How much is logged is set with --log-level or log-level in the config file.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		path, err := state.LogPath()
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		f, err := os.Open(path)
+		if os.IsNotExist(err) && !logsFollow {
+			return fmt.Errorf("no log yet at %s", path)
+		}
+		if err == nil {
+			err = printTail(out, f, logsLines)
+			f.Close()
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if !logsFollow {
+			return nil
+		}
+		return followLog(cmd, out, path)
+	},
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing lines as they are logged")
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 50, "How many of the last lines to show (0 = all)")
+
+	rootCmd.AddCommand(logsCmd)
+}
+
+func printTail(out io.Writer, r io.Reader, n int) error {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if n > 0 && len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	for _, line := range lines {
+		fmt.Fprintln(out, line)
+	}
+	return scanner.Err()
+}
+
+// followLog prints what is appended to path from here on, starting over
+// when the file is rotated.
+func followLog(cmd *cobra.Command, out io.Writer, path string) error {
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+	ctx := cmd.Context()
+	ticker := time.NewTicker(logPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if info, err := os.Stat(path); err == nil {
+			if info.Size() < offset {
+				offset = 0
+			}
+			if info.Size() > offset {
+				f, err := os.Open(path)
+				if err != nil {
+					return err
+				}
+				f.Seek(offset, io.SeekStart)
+				n, err := io.Copy(out, f)
+				f.Close()
+				offset += n
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// parseLogLevel takes a level as e.g. "info" or "debug".
+func parseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("invalid --log-level %q (want debug, info, warn, or error)", level)
+	}
+	return l, nil
+}
+
+// setupLogging points slog at the log file for the rest of the process.
+// Writes go straight to the file, so nothing is lost however it exits.
+// Debug logging notes where in the source each line came from.
+func setupLogging(level slog.Level) error {
+	path, err := state.LogPath()
+	if err != nil {
+		return err
+	}
+	f, err := logfile.Open(path, logfile.DefaultMaxSize, logfile.DefaultKeep)
+	if err != nil {
+		return err
+	}
+	handler := slog.NewTextHandler(f, &slog.HandlerOptions{Level: level, AddSource: level <= slog.LevelDebug})
+	slog.SetDefault(slog.New(handler).With("pid", os.Getpid()))
+	return nil
+}
+
+// eventLogger is a subscriber that logs the start and end of the session
+// and of each phase.
+type eventLogger struct {
+	lastComplete bool
+	started      bool
+}
+
+func (l *eventLogger) Handle(e engine.TimerEvent) {
+	if e.ClockJump > 0 {
+		slog.Warn("system clock went back", "by", e.ClockJump.Round(time.Second), "phase", e.Phase)
+	}
+	switch e.Type {
+	case engine.EventSessionStarted:
+		c := e.Config
+		slog.Info("session started", "work", c.WorkDuration, "short", c.ShortBreakDuration, "long", c.LongBreakDuration,
+			"long_every", c.LongBreakEvery, "cycles", c.TotalCycles, "label", label, "profile", profileName)
+		return
+	case engine.EventSessionEnded:
+		s := e.Summary
+		level := slog.LevelInfo
+		if s.Ended == engine.EndInterrupted || s.Capped {
+			level = slog.LevelWarn
+		}
+		slog.Log(context.Background(), level, "session ended", "ended", s.Ended, "stopped", s.Stopped, "capped", s.Capped, "cycles", s.CyclesComplete, "work", s.Work.Round(time.Second))
+		return
+	case engine.EventSnooze:
+		if e.Ended != "" {
+			slog.Info("snooze ended", "ended", e.Ended, "snoozed", e.Elapsed.Round(time.Second), "snoozes", e.Snoozes, "next", e.Phase)
+		}
+		return
+	case engine.EventAway:
+		if e.Ended != "" {
+			slog.Info("checked in", "ended", e.Ended, "away", e.Elapsed.Round(time.Second), "next", e.Phase)
+		}
+		return
+	case engine.EventTick:
+	default:
+		return
+	}
+
+	if !l.started || l.lastComplete {
+		slog.Info("phase started", "phase", e.Phase, "cycle", e.CycleNum, "planned", e.Total, "extra", e.Extra)
+	}
+	if e.Ended != "" {
+		slog.Info("phase ended", "phase", e.Phase, "ended", e.Ended, "elapsed", e.Elapsed.Round(time.Second), "paused", e.PausedTotal.Round(time.Second))
+	}
+	l.started = true
+	l.lastComplete = e.PhaseComplete
+}
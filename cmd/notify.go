@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/notify"
+	"github.com/steenfuentes/pomo/sound"
+	"github.com/steenfuentes/pomo/webhook"
+)
+
+// notifyChannels are the channels pomo notify test can try.
+var notifyChannels = []string{"heartbeat", "sound"}
+
+var notifyChannel string
+
+var errNotSetUp = errors.New("not set up")
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Check how pomo reaches you",
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test [profile [params...]]",
+	Short: "Send a sample notification through each channel",
+	Long: `Send what a work phase ending sends through each channel set up, the way
+pomo start would, and report how each went: the heartbeat's GET with its
+HTTP status, and every cue in the [sounds] table played in turn, bells
+included. Quiet hours are ignored. Takes the same flags and profile as
+pomo start, to try settings before using them.
+
+Exits non-zero if any channel tried fails, or if one named with --channel
+is not set up.
+
+Examples:
+  pomo notify test
+  pomo notify test --channel heartbeat --ping https://hc-ping.com/<uuid>`,
+	ValidArgsFunction: completeProfiles,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		channels := notifyChannels
+		if notifyChannel != "all" {
+			if !slices.Contains(notifyChannels, notifyChannel) {
+				return fmt.Errorf("invalid --channel %q (want %s, or all)", notifyChannel, strings.Join(notifyChannels, ", "))
+			}
+			channels = []string{notifyChannel}
+		}
+		if _, err := applySettings(cmd, args); err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		var failed []string
+		for _, name := range channels {
+			var err error
+			switch name {
+			case "heartbeat":
+				err = testHeartbeat(out)
+			case "sound":
+				err = testSounds(out)
+			}
+			switch {
+			case errors.Is(err, errNotSetUp) && notifyChannel == "all":
+				fmt.Fprintf(out, "%s: %v\n", name, err)
+			case err != nil:
+				fmt.Fprintf(out, "%s: failed: %v\n", name, err)
+				failed = append(failed, name)
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("%s failed", strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+func init() {
+	notifyTestCmd.Flags().StringVar(&notifyChannel, "channel", "all", "Channel to try: "+strings.Join(notifyChannels, ", ")+", or all")
+
+	notifyCmd.AddCommand(notifyTestCmd)
+	rootCmd.AddCommand(notifyCmd)
+}
+
+// testHeartbeat sends a work phase's ping through a dispatcher of its own,
+// without retries, and waits for it.
+func testHeartbeat(out io.Writer) error {
+	if pingURL == "" && pingSuccessURL == "" {
+		return fmt.Errorf("%w (want --ping or --ping-success)", errNotSetUp)
+	}
+	p := webhook.NewPinger(pingURL, pingSuccessURL, pingFailURL)
+	d := notify.NewDispatcher()
+	d.Register(p, notify.Limits{Timeout: pingTimeout, Queue: 1})
+	d.Handle(engine.TimerEvent{Type: engine.EventTick, Phase: engine.PhaseWork, PhaseComplete: true})
+	if err := d.Shutdown(pingTimeout + notifyDrainTimeout); err != nil {
+		// The dispatcher names the channel, as this report does already.
+		return errors.New(strings.TrimPrefix(err.Error(), p.Name()+": "))
+	}
+	fmt.Fprintln(out, "heartbeat: sent")
+	return nil
+}
+
+// testSounds plays every cue in turn, waiting for each.
+func testSounds(out io.Writer) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	set, err := sound.New(cfg.Sounds.Cues, cfg.Sounds.Volume, nil)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, cue := range sound.Cues {
+		bell, err := set.Play(cue)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", cue, err))
+		case bell:
+			fmt.Fprintf(out, "sound: %s: bell\a\n", cue)
+		case set.Sound(cue) == sound.None:
+			fmt.Fprintf(out, "sound: %s: none\n", cue)
+		default:
+			fmt.Fprintf(out, "sound: %s: played %s\n", cue, set.Sound(cue))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// porcelainVersion names the format of pomo start --porcelain's line. A
+// version's fields and their order never change; adding one means a new
+// version.
+const porcelainVersion = "v1"
+
+// exitCode ends the process with its code and no message.
+type exitCode int
+
+func (c exitCode) Error() string { return fmt.Sprintf("exit status %d", int(c)) }
+
+// finishStart ends pomo start after the session s summarizes. With
+// --porcelain it prints the done line, e.g.
+//
+//	pomo: done cycles=4/4 focused=200m interrupted=false reason=completed porcelain=v1
+//
+// and exits exitStopped unless every planned cycle was completed.
+func finishStart(out io.Writer, cfg engine.Config, s engine.SessionSummary) error {
+	if porcelain == "" {
+		return nil
+	}
+	reason := "completed"
+	switch {
+	case s.Ended == engine.EndInterrupted:
+		reason = "interrupted"
+	case s.Capped:
+		reason = "capped"
+	case s.Stopped || cfg.TotalCycles == 0:
+		reason = "stopped"
+	}
+	fmt.Fprintf(out, "pomo: done cycles=%d/%d focused=%dm interrupted=%t reason=%s porcelain=%s\n",
+		s.CyclesComplete, cfg.TotalCycles, int(s.Work.Minutes()), reason != "completed", reason, porcelainVersion)
+	if reason != "completed" {
+		return exitCode(exitStopped)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+func TestFinishStartPorcelain(t *testing.T) {
+	saved := porcelain
+	porcelain = porcelainVersion
+	defer func() { porcelain = saved }()
+
+	finite := engine.Config{TotalCycles: 4}
+	tests := []struct {
+		name    string
+		cfg     engine.Config
+		summary engine.SessionSummary
+		line    string
+		code    exitCode
+	}{
+		{
+			name:    "completed",
+			cfg:     finite,
+			summary: engine.SessionSummary{Ended: engine.EndCompleted, CyclesComplete: 4, Work: 200 * time.Minute},
+			line:    "pomo: done cycles=4/4 focused=200m interrupted=false reason=completed porcelain=v1",
+		},
+		{
+			name:    "stopped",
+			cfg:     finite,
+			summary: engine.SessionSummary{Ended: engine.EndCompleted, Stopped: true, CyclesComplete: 2, Work: 100*time.Minute + 59*time.Second},
+			line:    "pomo: done cycles=2/4 focused=100m interrupted=true reason=stopped porcelain=v1",
+			code:    exitStopped,
+		},
+		{
+			name:    "infinite",
+			summary: engine.SessionSummary{Ended: engine.EndCompleted, Stopped: true, CyclesComplete: 7, Work: 175 * time.Minute},
+			line:    "pomo: done cycles=7/0 focused=175m interrupted=true reason=stopped porcelain=v1",
+			code:    exitStopped,
+		},
+		{
+			name:    "capped",
+			cfg:     finite,
+			summary: engine.SessionSummary{Ended: engine.EndCompleted, Stopped: true, Capped: true, CyclesComplete: 1, Work: 25 * time.Minute},
+			line:    "pomo: done cycles=1/4 focused=25m interrupted=true reason=capped porcelain=v1",
+			code:    exitStopped,
+		},
+		{
+			name:    "interrupted",
+			cfg:     finite,
+			summary: engine.SessionSummary{Ended: engine.EndInterrupted, CyclesComplete: 0, Work: 90 * time.Second},
+			line:    "pomo: done cycles=0/4 focused=1m interrupted=true reason=interrupted porcelain=v1",
+			code:    exitStopped,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			err := finishStart(&out, tt.cfg, tt.summary)
+			if got := out.String(); got != tt.line+"\n" {
+				t.Errorf("printed %q, want %q", got, tt.line+"\n")
+			}
+			var code exitCode
+			switch {
+			case tt.code == 0 && err != nil:
+				t.Errorf("err = %v, want none", err)
+			case tt.code != 0 && (!errors.As(err, &code) || code != tt.code):
+				t.Errorf("err = %v, want exit code %d", err, tt.code)
+			}
+		})
+	}
+}
+
+func TestFinishStartWithoutPorcelain(t *testing.T) {
+	var out strings.Builder
+	err := finishStart(&out, engine.Config{TotalCycles: 4}, engine.SessionSummary{Ended: engine.EndInterrupted})
+	if err != nil || out.Len() > 0 {
+		t.Errorf("printed %q, err = %v, want nothing", out.String(), err)
+	}
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/steenfuentes/pomo/config"
+)
+
+var configPath string
+
+func loadConfig() (*config.File, error) {
+	path := configPath
+	if path == "" {
+		path = os.Getenv(config.EnvConfig)
+	}
+	if path != "" {
+		// A file named explicitly has to exist.
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+		return config.Load(path)
+	}
+
+	path, err := config.Path()
+	if err != nil {
+		return nil, err
+	}
+	return config.Load(path)
+}
+
+// settingLayers gathers everything that ranks below explicit flags: the
+// config file, POMO_* variables, and the profile named by args[0] with
+// args[1:] as its parameters. Keys are rewritten to full flag names.
+func settingLayers(flags *pflag.FlagSet, args []string) ([]config.Layer, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	layers := []config.Layer{cfg.Layer(), config.EnvLayer(os.Environ())}
+
+	if len(args) > 0 {
+		profile, ok := cfg.Profiles[args[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q", args[0])
+		}
+		l, err := profile.Layer(args[1:])
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, l)
+	}
+
+	for i, l := range layers {
+		if layers[i], err = canonical(flags, l); err != nil {
+			return nil, err
+		}
+	}
+	return layers, nil
+}
+
+// startSetting is the value pomo start would take for the flag key with no
+// flags or profile given: the config file's or POMO_* variable's, else the
+// flag's default. It is for other commands wanting, say, the daily goal.
+func startSetting(key string) (string, error) {
+	flags := startCmd.Flags()
+	layers, err := settingLayers(flags, nil)
+	if err != nil {
+		return flags.Lookup(key).DefValue, err
+	}
+	if s, ok := config.Merge(layers...)[key]; ok && len(s.Values) > 0 {
+		return s.Values[len(s.Values)-1], nil
+	}
+	return flags.Lookup(key).DefValue, nil
+}
+
+func canonical(flags *pflag.FlagSet, l config.Layer) (config.Layer, error) {
+	out := config.Layer{Source: l.Source, Values: make(map[string][]string), Origin: make(map[string]string)}
+	for _, key := range sortedKeys(l.Values) {
+		flag := flags.Lookup(key)
+		if flag == nil && len(key) == 1 {
+			flag = flags.ShorthandLookup(key)
+		}
+		if flag == nil {
+			return out, fmt.Errorf("%s: unknown setting %q", describe(l.Source, l.Origin[key]), key)
+		}
+		out.Values[flag.Name] = l.Values[key]
+		out.Origin[flag.Name] = l.Origin[key]
+	}
+	return out, nil
+}
+
+// scheduleFlags shape the session itself. Giving any of them, like naming
+// a profile, means pomo start skips the [defaults] table.
+var scheduleFlags = []string{
+	"pomodoro", "short", "long", "taper", "taper-step", "taper-floor",
+	"long-every", "long-after", "until", "cycles", "max-duration", "warmup",
+}
+
+// defaultProfile is the profile the [defaults] table picks for a session
+// starting at now, with the rule that picked it for the banner, or "" if
+// it picks none or a schedule flag was given.
+func defaultProfile(now time.Time, explicit map[string]bool) (name, rule string, err error) {
+	for _, f := range scheduleFlags {
+		if explicit[f] {
+			return "", "", nil
+		}
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return "", "", err
+	}
+	name, weekday := cfg.Defaults.ForDay(now.Weekday())
+	rule = "default profile"
+	if weekday {
+		rule = now.Weekday().String() + " default"
+	}
+	return name, rule, nil
+}
+
+// applySettings fills every flag the user did not set explicitly, in order
+// of precedence: profile, environment, config file. It returns where each
+// setting that is not a default came from.
+func applySettings(cmd *cobra.Command, args []string) (map[string]config.Setting, error) {
+	flags := cmd.Flags()
+	layers, err := settingLayers(flags, args)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := config.Merge(layers...)
+	visitChanged(flags, func(f *pflag.Flag) {
+		s := config.Setting{Key: f.Name, Values: []string{f.Value.String()}, Source: config.SourceFlag}
+		if prev, ok := merged[f.Name]; ok {
+			s = s.Over(prev)
+		}
+		merged[f.Name] = s
+	})
+	for _, key := range sortedKeys(merged) {
+		s := merged[key]
+		if s.Source == config.SourceFlag {
+			continue
+		}
+		for _, v := range s.Values {
+			if err := flags.Set(key, v); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", describe(s.Source, s.Origin), key, err)
+			}
+		}
+	}
+	return merged, nil
+}
+
+// visitChanged calls fn for each flag in flags that is set, leaving out
+// those resetFlag has put back, which flags.Visit would still include.
+func visitChanged(flags *pflag.FlagSet, fn func(*pflag.Flag)) {
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			fn(f)
+		}
+	})
+}
+
+func describe(source config.Source, origin string) string {
+	switch source {
+	case config.SourceProfile:
+		return fmt.Sprintf("profile %q", origin)
+	case config.SourceFile, config.SourceEnv:
+		return origin
+	default:
+		return source.String()
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	if len(args) > 0 {
+		if p, ok := cfg.Profiles[args[0]]; ok && len(p.Params) > 0 {
+			return cobra.AppendActiveHelp(nil, "parameters: "+p.Signature()), cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, name := range cfg.ProfileNames() {
+		names = append(names, name+"\t"+cfg.Profiles[name].Signature())
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
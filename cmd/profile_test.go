@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/config"
+)
+
+const weekdayConfig = `
+[defaults]
+monday = "short"
+thursday = "deep"
+profile = "everyday"
+
+[profiles.short]
+pomodoro = 25
+short = 5
+
+[profiles.deep]
+pomodoro = 50
+short = 10
+
+[profiles.everyday]
+pomodoro = 30
+short = 5
+`
+
+// TestWeekdayDefaults starts a plain session on each day of the week
+// 6 to 12 January 2025, a Monday to a Sunday, at --now, and checks the
+// profile it ran and the rule the banner names.
+func TestWeekdayDefaults(t *testing.T) {
+	tests := []struct {
+		day    int
+		args   []string
+		work   string
+		banner string
+	}{
+		{6, nil, "25m work, 5m short break", "Using profile short (Monday default)"},
+		{7, nil, "30m work, 5m short break", "Using profile everyday (default profile)"},
+		{8, nil, "30m work, 5m short break", "Using profile everyday (default profile)"},
+		{9, nil, "50m work, 10m short break", "Using profile deep (Thursday default)"},
+		{10, nil, "30m work, 5m short break", "Using profile everyday (default profile)"},
+		{11, nil, "30m work, 5m short break", "Using profile everyday (default profile)"},
+		{12, nil, "30m work, 5m short break", "Using profile everyday (default profile)"},
+
+		// A profile or any schedule flag given explicitly leaves the table
+		// out, so the rest of the schedule is the built-in default.
+		{9, []string{"short"}, "25m work, 5m short break", ""},
+		{9, []string{"-p", "20"}, "20m work, 10m short break", ""},
+		{6, []string{"-s", "3"}, "50m work, 3m short break", ""},
+	}
+	for _, tt := range tests {
+		now := time.Date(2025, 1, tt.day, 9, 0, 0, 0, time.Local)
+		t.Run(now.Weekday().String()+strings.Join(tt.args, " "), func(t *testing.T) {
+			dir := isolate(t)
+			path := filepath.Join(dir, "config.toml")
+			if err := os.WriteFile(path, []byte(weekdayConfig), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv(config.EnvConfig, path)
+
+			r := startSession(t, append([]string{"--now", now.Format(time.RFC3339)}, tt.args...)...)
+			if err, ended := r.runFor(t, time.Second); ended {
+				t.Fatalf("start: %v\nstderr:\n%s", err, r.stderr.String())
+			}
+			r.signals <- syscall.SIGINT
+			r.wait(t)
+
+			out := r.stdout.String()
+			if !strings.Contains(out, "Starting pomodoro: "+tt.work) {
+				t.Errorf("banner does not start %q:\n%s", tt.work, out)
+			}
+			switch {
+			case tt.banner == "" && strings.Contains(out, "Using profile"):
+				t.Errorf("banner names a default rule when given %v:\n%s", tt.args, out)
+			case tt.banner != "" && !strings.Contains(out, tt.banner+"\n"):
+				t.Errorf("banner does not say %q:\n%s", tt.banner, out)
+			}
+		})
+	}
+}
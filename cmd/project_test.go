@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSessionProject runs sessions in a git repository and out of one, and
+// checks what each records and pomo stats --by-project makes of it.
+func TestSessionProject(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	isolate(t)
+	checkout := filepath.Join(t.TempDir(), "checkout")
+	sub := filepath.Join(checkout, "docs")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"remote", "add", "origin", "git@github.com:steenfuentes/pomo.git"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = checkout
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL="+os.DevNull, "GIT_CONFIG_SYSTEM="+os.DevNull)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	plain := t.TempDir()
+
+	tests := []struct {
+		name          string
+		dir           string
+		args          []string
+		project, want string
+	}{
+		{"in the repository", sub, nil, "pomo", sub},
+		{"outside one", plain, nil, "", plain},
+		{"--project", sub, []string{"--project", "thesis"}, "thesis", sub},
+		{"--no-record-dir", sub, []string{"--no-record-dir"}, "", ""},
+		{"--project and --no-record-dir", sub, []string{"--project", "thesis", "--no-record-dir"}, "thesis", ""},
+	}
+	seen := 0
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(tt.dir)
+			args := append([]string{"-c", "1", "-p", "1", "-s", "1"}, tt.args...)
+			if err := startSession(t, args...).wait(t); err != nil {
+				t.Fatal(err)
+			}
+			records := readRecords(t)
+			if len(records) == seen {
+				t.Fatal("session recorded nothing")
+			}
+			for _, r := range records[seen:] {
+				if r.Project != tt.project || r.Dir != tt.want {
+					t.Errorf("%s recorded project %q in %q, want %q in %q", r.Phase, r.Project, r.Dir, tt.project, tt.want)
+				}
+			}
+			seen = len(records)
+		})
+	}
+
+	var out syncBuffer
+	if err := execute(t, startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}, "stats", "--by-project", "--include-short", "--now", "2025-01-06T10:00:00Z"); err != nil {
+		t.Fatalf("stats: %v\n%s", err, out.String())
+	}
+	_, table, ok := strings.Cut(out.String(), "  By project\n")
+	if !ok {
+		t.Fatalf("no projects in\n%s", out.String())
+	}
+	// Most focus time first, then by name, with the directory standing in
+	// outside a repository and sessions with neither last.
+	want := []string{
+		"thesis 2m 2 completed",
+		plain + " 1m 1 completed",
+		"pomo 1m 1 completed",
+		"(none recorded) 1m 1 completed",
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(table), "\n") {
+		got = append(got, strings.Join(strings.Fields(line), " "))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("by project:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
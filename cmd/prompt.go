@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/overlay"
+	"github.com/steenfuentes/pomo/state"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+// The prompt never waits longer than this for the state file.
+const promptBudget = 50 * time.Millisecond
+
+var (
+	promptFormat string
+	promptShell  string
+)
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print a short status for a shell prompt",
+	Long: `Print the running session as a short string for a shell prompt, without a
+trailing newline. Prints nothing when no session is running, a note like
+"stale — last update 2d ago" for a state file no session has updated within
+stale_after, and gives up silently if it cannot be read within 50ms.
+
+The format is the same as --write-format's, placeholders or a template.
+
+Examples:
+  PROMPT='$(pomo prompt --shell zsh) %~ %# '          # zsh
+  PS1='$(pomo prompt --shell bash) \w \$ '           # bash
+  [custom.pomo]                                      # starship
+  command = "pomo prompt"
+  when = true`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var wrap func(string) string
+		switch promptShell {
+		case "none":
+		case "zsh":
+			wrap = func(esc string) string { return "%{" + esc + "%}" }
+		case "bash":
+			wrap = func(esc string) string { return `\[` + esc + `\]` }
+		case "fish":
+			wrap = func(esc string) string { return esc }
+		default:
+			return fmt.Errorf("invalid --shell %q (want zsh, bash, fish, or none)", promptShell)
+		}
+
+		format, err := overlay.Parse(promptFormat)
+		if err != nil {
+			return fmt.Errorf("invalid --format: %w", err)
+		}
+		path, err := state.Path()
+		if err != nil {
+			return nil
+		}
+		type result struct {
+			s   state.State
+			err error
+		}
+		read := make(chan result, 1)
+		go func() {
+			s, err := readState(path)
+			read <- result{s, err}
+		}()
+
+		var s state.State
+		select {
+		case r := <-read:
+			var stale *state.StaleError
+			var newer *state.VersionError
+			if errors.As(r.err, &stale) || errors.As(r.err, &newer) {
+				fmt.Fprint(cmd.OutOrStdout(), r.err.Error())
+			}
+			if r.err != nil {
+				return nil
+			}
+			s = r.s
+		case <-time.After(promptBudget):
+			return nil
+		}
+
+		now := time.Now()
+		text, err := format.Execute(overlay.FieldsOf(s.EventAt(now), s.Label, now))
+		if err != nil {
+			return err
+		}
+		if wrap != nil {
+			text = colorize(text, s, wrap)
+		}
+		fmt.Fprint(cmd.OutOrStdout(), text)
+		return nil
+	},
+}
+
+func init() {
+	promptCmd.Flags().StringVar(&promptFormat, "format", "{icon} {minutes}m", "Output format, with the placeholders or template of --write-format")
+	promptCmd.Flags().StringVar(&promptShell, "shell", "none", "Color the output, wrapping escapes for zsh, bash, or fish (none = plain)")
+
+	rootCmd.AddCommand(promptCmd)
+}
+
+// colorize wraps text in the phase color, passing each escape sequence
+// through wrap so the shell does not count it toward the prompt width.
+func colorize(text string, s state.State, wrap func(string) string) string {
+	phase, _ := engine.ParsePhase(s.Phase)
+	c := *ui.PhaseColor(phase)
+	c.EnableColor()
+
+	start, end, _ := strings.Cut(c.Sprint("\x00"), "\x00")
+	return wrap(start) + text + wrap(end)
+}
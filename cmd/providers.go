@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/provider"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "List the integrations defined in the config file",
+	Long: `List the providers defined as [providers.NAME] tables in the config file,
+and which of on_work, on_break, and on_done each one handles.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		for _, name := range cfg.ProviderNames() {
+			p := cfg.Providers[name]
+			var events []string
+			for _, ev := range provider.Events {
+				if provider.Command(p, ev) != "" {
+					events = append(events, string(ev))
+				}
+			}
+			status := "enabled"
+			if !p.Enabled {
+				status = "disabled"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", name, status, strings.Join(events, ", "))
+		}
+		return w.Flush()
+	},
+}
+
+var (
+	providersTestEvent string
+	providersTestDry   bool
+)
+
+var providersTestCmd = &cobra.Command{
+	Use:   "test <name>",
+	Short: "Run each of a provider's commands once",
+	Long: `Run the provider's on_work, on_break, and on_done commands once each, in
+that order, with the values of a sample session, and print how each went
+along with its output. Disabled providers run too.
+
+--event picks one of them, and --dry-run prints each command and the
+variables it would get without running it, filled in just as pomo start
+would.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProviders,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		p, ok := cfg.Providers[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown provider %q", args[0])
+		}
+
+		events := provider.Events
+		if providersTestEvent != "" {
+			ev, err := parseProviderEvent(providersTestEvent)
+			if err != nil {
+				return err
+			}
+			if provider.Command(p, ev) == "" {
+				return fmt.Errorf("provider %q has no %s command", p.Name, ev)
+			}
+			events = []provider.Event{ev}
+		}
+
+		out := cmd.OutOrStdout()
+		ran, failed := 0, 0
+		for _, ev := range events {
+			if provider.Command(p, ev) == "" {
+				continue
+			}
+			ran++
+			if providersTestDry {
+				res := provider.Preview(p, ev, sampleEvent(ev), "", planClock.Now())
+				fmt.Fprintln(out, withEnv(res))
+				if res.Err != nil {
+					failed++
+				}
+				continue
+			}
+			res := provider.Run(p, ev, sampleEvent(ev), "", planClock)
+			fmt.Fprintln(out, res)
+			if output := bytes.TrimRight(res.Output, "\n"); len(output) > 0 {
+				fmt.Fprintf(out, "%s\n", output)
+			}
+			if res.Err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("provider %q: %d of %d commands failed", p.Name, failed, ran)
+		}
+		return nil
+	},
+}
+
+func init() {
+	providersTestCmd.Flags().StringVar(&providersTestEvent, "event", "", "Only this event: work, break, or done")
+	providersTestCmd.Flags().BoolVar(&providersTestDry, "dry-run", false, "Print the commands and their variables without running them")
+
+	providersCmd.AddCommand(providersTestCmd)
+	rootCmd.AddCommand(providersCmd)
+}
+
+// sampleEvent stands in for what a session with the default settings
+// would pass at ev.
+func sampleEvent(ev provider.Event) engine.TimerEvent {
+	e := engine.TimerEvent{Type: engine.EventTick, Phase: engine.PhaseWork, Total: 50 * time.Minute, CycleNum: 1, TotalCycles: 4}
+	switch ev {
+	case provider.EventBreak:
+		e.Phase, e.Total, e.CycleNum = engine.PhaseShortBreak, 10*time.Minute, 2
+	case provider.EventDone:
+		e.Type, e.CycleNum = engine.EventSessionEnded, 4
+	}
+	if e.Type == engine.EventTick {
+		e.PhaseStartedAt = planClock.Now()
+	}
+	e.Remaining = e.Total
+	return e
+}
+
+// parseProviderEvent takes an event as work, break, or done, or as its key
+// in the config file.
+func parseProviderEvent(s string) (provider.Event, error) {
+	for _, ev := range provider.Events {
+		if s == string(ev) || "on_"+s == string(ev) {
+			return ev, nil
+		}
+	}
+	return "", fmt.Errorf("invalid --event %q (want work, break, or done)", s)
+}
+
+// enabledProviders are the providers pomo start runs.
+func enabledProviders() ([]config.Provider, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	var enabled []config.Provider
+	for _, name := range cfg.ProviderNames() {
+		p := cfg.Providers[name]
+		if !p.Enabled {
+			continue
+		}
+		if err := provider.Check(p); err != nil {
+			return nil, err
+		}
+		enabled = append(enabled, p)
+	}
+	return enabled, nil
+}
+
+// reportProvider shows failed commands above the bars, and with --verbose
+// every command. Dry runs show what would have run instead.
+func reportProvider(progress *ui.Progress) func(provider.Result) {
+	return func(res provider.Result) {
+		if res.Err != nil {
+			slog.Warn("provider failed", "result", res.String())
+		} else {
+			slog.Info("provider ran", "result", res.String(), "dry_run", res.DryRun)
+		}
+		switch {
+		case res.DryRun:
+			progress.Logf("%s", withEnv(res))
+		case res.Err != nil:
+			progress.Logf("Warning: %s", res)
+		case verbose:
+			progress.Logf("%s", res)
+		}
+	}
+}
+
+// withEnv follows res with the variables its command gets, one per line.
+func withEnv(res provider.Result) string {
+	var b strings.Builder
+	b.WriteString(res.String())
+	for _, v := range res.Invocation.Env {
+		fmt.Fprintf(&b, "\n    %s", v)
+	}
+	return b.String()
+}
+
+func completeProviders(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := loadConfig()
+	if err != nil || len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return cfg.ProviderNames(), cobra.ShellCompDirectiveNoFileComp
+}
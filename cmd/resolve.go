@@ -0,0 +1,474 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/steenfuentes/pomo/activity"
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/focuswatch"
+	"github.com/steenfuentes/pomo/mqtt"
+	"github.com/steenfuentes/pomo/overlay"
+	"github.com/steenfuentes/pomo/quiet"
+	"github.com/steenfuentes/pomo/sound"
+	"github.com/steenfuentes/pomo/ui"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+// settingConflicts are pairs of settings that cannot both apply, e.g.
+// --taper, which sets every work duration, and --pomodoro.
+var settingConflicts = [][2]string{
+	{"taper", "pomodoro"},
+	{"taper", "taper-step"},
+	{"long-after", "long-every"},
+	{"fresh", "continue-cycle"},
+	{"until", "cycles"},
+}
+
+// A settingNeed is a setting that does nothing unless another is on.
+type settingNeed struct {
+	flag  string
+	needs string
+	met   func() bool
+}
+
+var settingNeeds = []settingNeed{
+	{"yes", "--suggest", func() bool { return suggest }},
+	{"calendar-shrink", "--calendar", func() bool { return calendarSrc != "" }},
+	{"share-control", "--share", func() bool { return shareAddr != "" }},
+	{"cooldown", "--on-complete restart", func() bool { return onComplete == "restart" }},
+	{"min-break", "--proportional-breaks", func() bool { return proportional }},
+	{"taper-floor", "--taper-step", func() bool { return taperStep != 0 }},
+	{"enforce-long-break", "--long-break-guard above 0", func() bool { return longBreakGuard > 0 }},
+	{"gradient-thresholds", "--gradient", func() bool { return gradient }},
+	{"focus-grace", "--focus-apps", func() bool { return len(focusApps) > 0 }},
+	{"otel-endpoint", "--otel", func() bool { return otel }},
+	{"otel-timeout", "--otel", func() bool { return otel }},
+	{"mqtt-topic", "--mqtt", func() bool { return mqttBroker != "" }},
+	{"until-fill", "--until", func() bool { return until != "" }},
+	{"until-fill-min", "--until-fill", func() bool { return untilFill }},
+	{"strict-max-pause", "--strict", func() bool { return strict }},
+	{"strict-retries", "--strict", func() bool { return strict }},
+	{"ping-timeout", "--ping, --ping-success, or --ping-fail", pinging},
+	{"ping-retries", "--ping, --ping-success, or --ping-fail", pinging},
+	{"checkin-remind", "--checkin", func() bool { return checkIn }},
+	{"lunch-duration", "--lunch", func() bool { return lunchWindow != "" }},
+	{"auto-lunch", "--lunch", func() bool { return lunchWindow != "" }},
+}
+
+func pinging() bool {
+	return pingURL != "" || pingSuccessURL != "" || pingFailURL != ""
+}
+
+// startOptions is everything pomo start settles before running: the
+// engine's config and how the session is shown and reported.
+type startOptions struct {
+	cfg           engine.Config
+	theme         string
+	logLevel      slog.Level
+	warnings      map[engine.Phase]time.Duration
+	away          map[engine.Phase]activity.AwayPolicy
+	timeStyle     format.Style
+	overallCounts ui.OverallCounts
+	quietHours    quiet.Hours
+	focus         focuswatch.Blocklist
+	providers     []config.Provider
+	writeFormats  []*overlay.Format
+	rewards       config.Rewards
+	sounds        *sound.Set
+	// deadline is when --until has the session end by, if given.
+	deadline time.Time
+	broker   mqtt.Broker
+}
+
+// resolveStart settles the start flags once applySettings has filled them
+// in from sources. Conflicting settings from the same place are an error;
+// from different places, the higher-ranked one wins as it would for a
+// single setting. A setting that needs another is only an error when given
+// as a flag, since config files and profiles set them ahead of time. Every
+// problem found is reported, not just the first.
+func resolveStart(flags *pflag.FlagSet, sources map[string]config.Setting) (startOptions, error) {
+	var errs []error
+	for _, pair := range settingConflicts {
+		a, aSet := sources[pair[0]]
+		b, bSet := sources[pair[1]]
+		if !aSet || !bSet {
+			continue
+		}
+		if a.Source == b.Source && (a.Source == config.SourceEnv || a.Origin == b.Origin) {
+			errs = append(errs, conflictError(a, b))
+			continue
+		}
+		lower := a
+		if a.Source > b.Source {
+			lower = b
+		}
+		resetFlag(flags.Lookup(lower.Key))
+		delete(sources, lower.Key)
+	}
+	conflicts := len(errs)
+	for _, n := range settingNeeds {
+		if s, ok := sources[n.flag]; ok && s.Source == config.SourceFlag && !n.met() {
+			errs = append(errs, fmt.Errorf("--%s needs %s", n.flag, n.needs))
+		}
+	}
+
+	if longBreakAfter > 0 && !flags.Changed("long-every") {
+		longBreakEvery = 0
+	}
+
+	opts := startOptions{theme: theme}
+	switch onComplete {
+	case "exit", "prompt", "restart":
+	default:
+		errs = append(errs, fmt.Errorf("invalid --on-complete %q (want exit, prompt, or restart)", onComplete))
+	}
+	var err error
+	if mqttBroker != "" {
+		if opts.broker, err = mqtt.ParseBroker(mqttBroker); err != nil {
+			errs = append(errs, fmt.Errorf("--mqtt: %w", err))
+		}
+	}
+	if until != "" {
+		if opts.deadline, err = parseUntil(until, planClock.Now()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if compactBar < ui.MinCompactBar {
+		errs = append(errs, fmt.Errorf("invalid --compact-bar %d (want %d or more)", compactBar, ui.MinCompactBar))
+	}
+	if maxWidth < 0 {
+		errs = append(errs, fmt.Errorf("invalid --max-width %d (want 0 or more)", maxWidth))
+	}
+	if dailyGoal < 0 {
+		errs = append(errs, fmt.Errorf("invalid --daily-goal %d (want 0 or more)", dailyGoal))
+	}
+	if checkInRemind < 0 {
+		errs = append(errs, fmt.Errorf("invalid --checkin-remind %s (want 0 or more)", checkInRemind))
+	}
+	if porcelain != "" && porcelain != porcelainVersion {
+		errs = append(errs, fmt.Errorf("invalid --porcelain %q (want %s)", porcelain, porcelainVersion))
+	}
+	switch theme {
+	case "auto", "dark", "light":
+	default:
+		errs = append(errs, fmt.Errorf("invalid --theme %q (want auto, dark, or light)", theme))
+	}
+	if opts.timeStyle, err = format.ParseStyle(timeStyle); err != nil {
+		errs = append(errs, fmt.Errorf("invalid --time-style %q (want clock or human)", timeStyle))
+	}
+	if opts.overallCounts, err = ui.ParseOverallCounts(overallCounts); err != nil {
+		errs = append(errs, fmt.Errorf("invalid --overall-counts %q (want phases or work)", overallCounts))
+	}
+	if opts.logLevel, err = parseLogLevel(logLevel); err != nil {
+		errs = append(errs, err)
+	}
+	if len(gradientAt) != 2 || gradientAt[0] < 0 || gradientAt[0] > gradientAt[1] || gradientAt[1] > 1 {
+		errs = append(errs, errors.New("--gradient-thresholds needs two increasing fractions between 0 and 1"))
+	}
+	if opts.warnings, err = parseWarnings(warnBefore); err != nil {
+		errs = append(errs, err)
+	}
+	if opts.away, err = parseAway(idlePause, idleStop); err != nil {
+		errs = append(errs, err)
+	}
+	if opts.quietHours, err = quiet.Parse(quietSpecs); err != nil {
+		errs = append(errs, err)
+	}
+	if opts.focus, err = focuswatch.Compile(focusApps); err != nil {
+		errs = append(errs, fmt.Errorf("--focus-apps: %w", err))
+	}
+	if opts.providers, err = enabledProviders(); err != nil {
+		errs = append(errs, err)
+	}
+	if opts.writeFormats, err = parseFormats(writeFormats); err != nil {
+		errs = append(errs, err)
+	}
+	var lunch engine.Lunch
+	if lunchWindow != "" {
+		if lunch, err = parseLunch(lunchWindow); err != nil {
+			errs = append(errs, err)
+		} else {
+			lunch.Duration, lunch.Auto = lunchDuration, autoLunch
+		}
+	}
+	if file, err := loadConfig(); err == nil {
+		opts.rewards = file.Rewards
+		if opts.sounds, err = sound.New(file.Sounds.Cues, file.Sounds.Volume, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	opts.cfg = engine.Config{
+		WorkDuration:       time.Duration(workMinutes) * time.Minute,
+		ShortBreakDuration: time.Duration(shortBreakMinutes) * time.Minute,
+		LongBreakDuration:  time.Duration(longBreakMinutes) * time.Minute,
+		LongBreakEvery:     longBreakEvery,
+		LongBreakAfterWork: longBreakAfter,
+		TotalCycles:        cycles,
+		ProportionalBreaks: proportional,
+		MinBreakDuration:   minBreak,
+		MaxDuration:        maxDuration,
+		HardCap:            hardCap,
+		WorkTaper:          taper,
+		WorkTaperStep:      taperStep,
+		WorkTaperFloor:     taperFloor,
+		LongBreakGuard:     longBreakGuard,
+		EnforceLongBreak:   enforceLongBreak,
+		WarmupDuration:     warmup,
+		TransitionDuration: transition,
+		MaxSnoozes:         maxSnoozes,
+		StrictPomodoro:     strict,
+		BankBreaks:         bankBreaks,
+		CheckIn:            checkIn,
+		Lunch:              lunch,
+	}
+	if len(taper) > 0 {
+		opts.cfg.WorkDuration = taper[0]
+	}
+	if onComplete == "restart" {
+		opts.cfg.CooldownDuration = cooldown
+	}
+	if strict {
+		opts.cfg.StrictMaxPause, opts.cfg.StrictMaxRetries = strictMaxPause, strictRetries
+	}
+	// Validate would only repeat most conflicts, less clearly.
+	if conflicts == 0 {
+		if err := opts.cfg.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return opts, errors.Join(errs...)
+}
+
+// conflictError names a and b, set in the same place, as flags, e.g.
+// "--taper cannot be combined with --pomodoro (both set in profile "x")".
+func conflictError(a, b config.Setting) error {
+	err := fmt.Sprintf("--%s cannot be combined with --%s", a.Key, b.Key)
+	switch a.Source {
+	case config.SourceFlag:
+		return errors.New(err)
+	case config.SourceEnv:
+		return fmt.Errorf("%s (both set in the environment, as %s and %s)", err, a.Origin, b.Origin)
+	default:
+		return fmt.Errorf("%s (both set in %s)", err, describe(a.Source, a.Origin))
+	}
+}
+
+// resetFlag puts f back to its default, as if it had never been set.
+func resetFlag(f *pflag.Flag) {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		sv.Replace(nil)
+	} else {
+		f.Value.Set(f.DefValue)
+	}
+	f.Changed = false
+}
+
+// overrides lists the settings in sources given in more than one place,
+// with the values they overrode that differ from theirs.
+func overrides(sources map[string]config.Setting) []ui.Override {
+	var list []ui.Override
+	for _, key := range sortedKeys(sources) {
+		s := sources[key]
+		o := ui.Override{Key: key, Value: settingValue(s), Source: sourceName(s)}
+		for _, prev := range s.Overrides {
+			if v := settingValue(prev); v != o.Value {
+				o.Beaten = append(o.Beaten, ui.Beaten{Source: sourceName(prev), Value: v})
+			}
+		}
+		if len(o.Beaten) > 0 {
+			list = append(list, o)
+		}
+	}
+	return list
+}
+
+// settingValue is s's values as one, the way a list flag would print them
+// but without brackets.
+func settingValue(s config.Setting) string {
+	v := strings.Join(s.Values, ",")
+	if s.Source == config.SourceFlag {
+		v = strings.TrimSuffix(strings.TrimPrefix(v, "["), "]")
+	}
+	return v
+}
+
+// sourceName is where s came from, briefly: the profile's or variable's
+// name rather than the file's path.
+func sourceName(s config.Setting) string {
+	switch s.Source {
+	case config.SourceProfile:
+		return "profile " + s.Origin
+	case config.SourceEnv:
+		return s.Origin
+	default:
+		return s.Source.String()
+	}
+}
+
+// printResolved lists what a session started with opts would run with.
+func printResolved(out io.Writer, opts startOptions) error {
+	c := opts.cfg
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	row := func(name string, value any) { fmt.Fprintf(w, "%s\t%v\n", name, value) }
+	duration := func(d time.Duration, zero string) string {
+		if d == 0 {
+			return zero
+		}
+		return shortDuration(d)
+	}
+
+	row("work", describeWork(c))
+	row("short break", shortDuration(c.ShortBreakDuration))
+	switch {
+	case c.LongBreakAfterWork > 0:
+		row("long break", fmt.Sprintf("%s after %s of work", shortDuration(c.LongBreakDuration), shortDuration(c.LongBreakAfterWork)))
+	case c.LongBreakEvery > 0:
+		row("long break", fmt.Sprintf("%s every %d cycles", shortDuration(c.LongBreakDuration), c.LongBreakEvery))
+	default:
+		row("long break", "none")
+	}
+	if c.ProportionalBreaks {
+		row("breaks", "proportional, at least "+shortDuration(c.MinBreakDuration))
+	}
+	row("cycles", describeCycles(c.TotalCycles))
+	if until != "" {
+		fill := ""
+		if untilFill {
+			fill = fmt.Sprintf(", then a last work phase of at least %s", shortDuration(untilFillMin))
+		}
+		row("until", fmt.Sprintf("whole cycles ending by %s%s", until, fill))
+	}
+	row("max duration", duration(c.MaxDuration, "none"))
+	row("hard cap", duration(c.HardCap, "none"))
+	row("on complete", onComplete)
+	if reviewOnEnd {
+		row("review", "asked as the session ends, waiting "+shortDuration(promptTimeout))
+	} else {
+		row("review", "off")
+	}
+	if c.CooldownDuration > 0 {
+		row("cooldown", shortDuration(c.CooldownDuration))
+	}
+	row("warmup", duration(c.WarmupDuration, "none"))
+	row("transition", duration(c.TransitionDuration, "none"))
+	row("snoozes", fmt.Sprintf("%d of %s per break", c.MaxSnoozes, shortDuration(snoozeFor)))
+	if c.BankBreaks {
+		row("break bank", fmt.Sprintf("on, extending by %s", shortDuration(extendBy)))
+	} else {
+		row("break bank", "off")
+	}
+	if l := c.Lunch; l.Duration > 0 {
+		how := "offered"
+		if l.Auto {
+			how = "automatic"
+		}
+		row("lunch", fmt.Sprintf("%s between %s, %s", shortDuration(l.Duration), lunchWindow, how))
+	} else {
+		row("lunch", "off")
+	}
+	switch {
+	case c.CheckIn && checkInRemind > 0:
+		row("check-in", "after each break, reminding every "+shortDuration(checkInRemind))
+	case c.CheckIn:
+		row("check-in", "after each break, reminding once")
+	default:
+		row("check-in", "off")
+	}
+	guard := duration(c.LongBreakGuard, "off")
+	if c.EnforceLongBreak && c.LongBreakGuard > 0 {
+		guard += ", enforced"
+	}
+	row("long break guard", guard)
+	if c.StrictPomodoro {
+		row("strict", fmt.Sprintf("voids work paused over %.0f%%, retried up to %d times", c.StrictMaxPause*100, c.StrictMaxRetries))
+	} else {
+		row("strict", "off")
+	}
+
+	if highContrast || ui.LimitedAttributes() {
+		row("theme", opts.theme+", high contrast")
+	} else {
+		row("theme", opts.theme)
+	}
+	row("time style", opts.timeStyle)
+	row("overall counts", opts.overallCounts)
+	if compact {
+		row("layout", "compact")
+	} else {
+		row("layout", fmt.Sprintf("compact below %d columns", ui.CompactBelow))
+	}
+	if maxWidth > 0 {
+		row("max width", maxWidth)
+	}
+	row("log level", strings.ToLower(opts.logLevel.String()))
+	var warns []string
+	for _, kind := range sortedKeys(phaseKinds) {
+		if d := opts.warnings[phaseKinds[kind]]; d > 0 {
+			warns = append(warns, kind+"="+shortDuration(d))
+		}
+	}
+	row("warn before", cmp.Or(strings.Join(warns, ","), "none"))
+	var away []string
+	for _, kind := range sortedKeys(phaseKinds) {
+		p, ok := opts.away[phaseKinds[kind]]
+		switch {
+		case !ok:
+		case p.PauseAfter == 0:
+			away = append(away, fmt.Sprintf("%s: stop after %s", kind, shortDuration(p.StopAfter)))
+		case p.StopAfter == 0:
+			away = append(away, fmt.Sprintf("%s: pause after %s", kind, shortDuration(p.PauseAfter)))
+		default:
+			away = append(away, fmt.Sprintf("%s: pause after %s, stop %s later", kind, shortDuration(p.PauseAfter), shortDuration(p.StopAfter)))
+		}
+	}
+	if len(away) > 0 {
+		row("when idle", strings.Join(away, "; "))
+	}
+	if quietOff {
+		row("quiet hours", "off")
+	} else {
+		row("quiet hours", cmp.Or(strings.Join(quietSpecs, ","), "none"))
+	}
+	if len(focusApps) > 0 {
+		row("focus apps", fmt.Sprintf("%s after %s", strings.Join(focusApps, ","), shortDuration(focusGrace)))
+	}
+	for i, path := range writeFiles {
+		format := overlay.DefaultFormat
+		if i < len(writeFormats) {
+			format = writeFormats[i]
+		}
+		row("write file", fmt.Sprintf("%s %q", path, format))
+	}
+	if metricsTextfile != "" {
+		row("metrics textfile", metricsTextfile)
+	}
+	names := make([]string, len(opts.providers))
+	for i, p := range opts.providers {
+		names[i] = p.Name
+	}
+	row("providers", cmp.Or(strings.Join(names, ","), "none"))
+	if opts.rewards.Every > 0 {
+		row("rewards", fmt.Sprintf("every %d pomodoros of the day", opts.rewards.Every))
+	} else {
+		row("rewards", "off")
+	}
+	row("on cycle complete", cmp.Or(onCycleComplete, "none"))
+	return w.Flush()
+}
+
+func describeCycles(n int) string {
+	if n == 0 {
+		return "infinite"
+	}
+	return fmt.Sprint(n)
+}
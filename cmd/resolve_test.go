@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steenfuentes/pomo/config"
+)
+
+// resolveArgs runs pomo config show --resolved with args, which settles
+// the start flags as pomo start would, and returns its error.
+func resolveArgs(t *testing.T, args ...string) error {
+	t.Helper()
+	var out syncBuffer
+	env := startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}
+	return execute(t, env, append([]string{"config", "show", "--resolved"}, args...)...)
+}
+
+func TestSettingConflicts(t *testing.T) {
+	flags := map[[2]string][]string{
+		{"taper", "pomodoro"}:        {"--taper=50m,40m", "--pomodoro=25"},
+		{"taper", "taper-step"}:      {"--taper=50m", "--taper-step=-5m"},
+		{"long-after", "long-every"}: {"--long-after=2h", "--long-every=3"},
+		{"fresh", "continue-cycle"}:  {"--fresh", "--continue-cycle"},
+		{"until", "cycles"}:          {"--until=23:59", "--cycles=4"},
+	}
+	for _, pair := range settingConflicts {
+		t.Run(pair[0]+"/"+pair[1], func(t *testing.T) {
+			args, ok := flags[pair]
+			if !ok {
+				t.Fatal("no test for this conflict")
+			}
+			isolate(t)
+			err := resolveArgs(t, args...)
+			want := "--" + pair[0] + " cannot be combined with --" + pair[1]
+			if err == nil || err.Error() != want {
+				t.Errorf("got %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+// TestSettingConflictSources sets --taper and --pomodoro in two places or
+// the same one.
+func TestSettingConflictSources(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{"both in the config file", "taper = [\"50m\"]\npomodoro = 25\n", nil, nil,
+			"--taper cannot be combined with --pomodoro (both set in {path})"},
+		{"both in the environment", "", map[string]string{"POMO_TAPER": "50m", "POMO_POMODORO": "25"}, nil,
+			"--taper cannot be combined with --pomodoro (both set in the environment, as POMO_TAPER and POMO_POMODORO)"},
+		{"both in a profile", "[profiles.x]\ntaper = [\"50m\"]\npomodoro = 25\n", nil, []string{"x"},
+			`--taper cannot be combined with --pomodoro (both set in profile "x")`},
+		{"flag over the config file", "taper = [\"50m\"]\n", nil, []string{"--pomodoro=25"}, ""},
+		{"environment over the config file", "pomodoro = 25\n", map[string]string{"POMO_TAPER": "50m"}, nil, ""},
+		{"profile over the environment", "[profiles.x]\npomodoro = 25\n", map[string]string{"POMO_TAPER": "50m"}, []string{"x"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolate(t)
+			path := filepath.Join(dir, "pomo.toml")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			err := resolveArgs(t, append([]string{"--config", path}, tt.args...)...)
+			want := strings.ReplaceAll(tt.want, "{path}", path)
+			switch {
+			case want == "" && err != nil:
+				t.Errorf("got %v, want the higher-ranked one to win", err)
+			case want != "" && (err == nil || err.Error() != want):
+				t.Errorf("got %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+func TestSettingNeeds(t *testing.T) {
+	flags := map[string][]string{
+		"yes":                 {"--yes"},
+		"calendar-shrink":     {"--calendar-shrink"},
+		"share-control":       {"--share-control"},
+		"cooldown":            {"--cooldown=1m"},
+		"min-break":           {"--min-break=1m"},
+		"taper-floor":         {"--taper-floor=5m"},
+		"enforce-long-break":  {"--enforce-long-break", "--long-break-guard=0"},
+		"gradient-thresholds": {"--gradient-thresholds=0.5,0.8"},
+		"focus-grace":         {"--focus-grace=1m"},
+		"otel-endpoint":       {"--otel-endpoint=localhost:4318"},
+		"otel-timeout":        {"--otel-timeout=1s"},
+		"mqtt-topic":          {"--mqtt-topic=pomo"},
+		"until-fill":          {"--until-fill"},
+		"until-fill-min":      {"--until-fill-min=5m"},
+		"strict-max-pause":    {"--strict-max-pause=0.5"},
+		"strict-retries":      {"--strict-retries=1"},
+		"ping-timeout":        {"--ping-timeout=1s"},
+		"ping-retries":        {"--ping-retries=1"},
+		"checkin-remind":      {"--checkin-remind=1m"},
+		"lunch-duration":      {"--lunch-duration=30m"},
+		"auto-lunch":          {"--auto-lunch"},
+	}
+	for _, n := range settingNeeds {
+		t.Run(n.flag, func(t *testing.T) {
+			args, ok := flags[n.flag]
+			if !ok {
+				t.Fatal("no test for this setting")
+			}
+			isolate(t)
+			err := resolveArgs(t, args...)
+			if want := "--" + n.flag + " needs " + n.needs; err == nil || err.Error() != want {
+				t.Errorf("got %v, want %q", err, want)
+			}
+		})
+	}
+}
+
+// TestSettingNeedsOnlyFlags leaves a setting that needs another to the
+// config file, which sets it ahead of time.
+func TestSettingNeedsOnlyFlags(t *testing.T) {
+	dir := isolate(t)
+	path := filepath.Join(dir, "pomo.toml")
+	if err := os.WriteFile(path, []byte("min-break = \"1m\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvName("auto-lunch"), "true")
+	if err := resolveArgs(t, "--config", path); err != nil {
+		t.Errorf("got %v, want settings from elsewhere let be", err)
+	}
+}
+
+func TestSettingProblemsAllReported(t *testing.T) {
+	isolate(t)
+	err := resolveArgs(t, "--fresh", "--continue-cycle", "--yes", "--theme=purple")
+	want := strings.Join([]string{
+		"--fresh cannot be combined with --continue-cycle",
+		"--yes needs --suggest",
+		`invalid --theme "purple" (want auto, dark, or light)`,
+	}, "\n")
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v, want:\n%s", err, want)
+	}
+}
+
+// TestTimeStyle sets --time-style each way it can be, checking what pomo
+// config show --resolved makes of it.
+func TestTimeStyle(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     string
+		file    string
+		want    string
+		wantErr string
+	}{
+		{name: "default", want: "clock"},
+		{name: "flag", args: []string{"--time-style=human"}, want: "human"},
+		{name: "flag clock", args: []string{"--time-style", "clock"}, want: "clock"},
+		{name: "environment", env: "human", want: "human"},
+		{name: "config file", file: `time-style = "human"`, want: "human"},
+		{name: "flag over file", args: []string{"--time-style=clock"}, file: `time-style = "human"`, want: "clock"},
+		{name: "unknown", args: []string{"--time-style=digital"}, wantErr: `invalid --time-style "digital" (want clock or human)`},
+		{name: "capitalized", args: []string{"--time-style=Human"}, wantErr: `invalid --time-style "Human" (want clock or human)`},
+		{name: "empty", args: []string{"--time-style="}, wantErr: `invalid --time-style "" (want clock or human)`},
+		{name: "unknown in environment", env: "words", wantErr: `invalid --time-style "words" (want clock or human)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolate(t)
+			args := []string{"config", "show", "--resolved"}
+			if tt.file != "" {
+				path := filepath.Join(dir, "pomo.toml")
+				if err := os.WriteFile(path, []byte(tt.file+"\n"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				args = append(args, "--config", path)
+			}
+			if tt.env != "" {
+				t.Setenv("POMO_TIME_STYLE", tt.env)
+			}
+			var out syncBuffer
+			err := execute(t, startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}, append(args, tt.args...)...)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%v\n%s", err, out.String())
+			}
+			var style string
+			for _, line := range strings.Split(out.String(), "\n") {
+				if rest, ok := strings.CutPrefix(line, "time style"); ok {
+					style = strings.TrimSpace(rest)
+				}
+			}
+			if style != tt.want {
+				t.Errorf("time style %q, want %s, in:\n%s", style, tt.want, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+var reviewOnEnd bool
+
+var reviewCmd = &cobra.Command{
+	Use:   "review <id|last> <rating> [retro]",
+	Short: "Rate how focused a session was",
+	Long: fmt.Sprintf(`Rate how focused the session the record with the given ID is in was, from
+1 to %d, optionally with a line on how it went, replacing any rating it has.
+The start of an ID will do, and last names the latest session. pomo start
+--review asks for the same as each session ends, and pomo stats reports the
+ratings.
+
+Examples:
+  pomo review last 4
+  pomo review 3f9a0c1 2 "kept checking chat"`, history.MaxRating),
+	Args: cobra.RangeArgs(2, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		rating, err := parseRating(args[1])
+		if err != nil {
+			return err
+		}
+		link, err := historyLink(cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		path, err := history.Path()
+		if err != nil {
+			return err
+		}
+		records, err := history.Read(path)
+		if err != nil {
+			return err
+		}
+		i, err := history.Find(records, args[0])
+		if err != nil {
+			return err
+		}
+		r, err := history.Update(path, records[history.ReviewTarget(records, i)].ID(), func(r *history.Record) {
+			r.Rating = rating
+			if len(args) > 2 {
+				r.Retro = args[2]
+			}
+		})
+		if err != nil {
+			return err
+		}
+		ui.PrintTimeline(cmd.OutOrStdout(), []history.Record{r}, 0, true, link)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reviewCmd)
+}
+
+func parseRating(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > history.MaxRating {
+		return 0, fmt.Errorf("invalid rating %q (want 1 to %d)", s, history.MaxRating)
+	}
+	return n, nil
+}
+
+// reviewSession asks, with --review, how focused the session that started
+// at started was and how it went, and records the answers on its last work
+// phase. It asks nothing unless there is a terminal to answer on, and gives
+// up on a question left --prompt-timeout unanswered.
+func reviewSession(env startEnv, started time.Time) {
+	if !reviewOnEnd || demo {
+		return
+	}
+	if f, ok := env.stdin.(*os.File); !ok || !isatty.IsTerminal(f.Fd()) {
+		return
+	}
+	path, err := history.Path()
+	if err != nil {
+		return
+	}
+	records, _ := history.Read(path)
+	target := -1
+	for i, r := range records {
+		if r.Start.Before(started) {
+			continue
+		}
+		if target < 0 || r.Phase == engine.PhaseWork || records[target].Phase != engine.PhaseWork {
+			target = i
+		}
+	}
+	if target < 0 {
+		return
+	}
+
+	var rating int
+	for rating == 0 {
+		a := askAsTyped(env, fmt.Sprintf("How focused was that session, 1-%d? (Enter to skip) ", history.MaxRating), promptTimeout)
+		if a == "" {
+			return
+		}
+		if rating, err = parseRating(a); err != nil {
+			fmt.Fprintf(env.stdout, "Want a whole number from 1 to %d\n", history.MaxRating)
+		}
+	}
+	retro := askAsTyped(env, "How did it go, in a line? (Enter to skip) ", promptTimeout)
+
+	r, err := history.Update(path, records[target].ID(), func(r *history.Record) {
+		r.Rating, r.Retro = rating, retro
+	})
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Warning: review: %v\n", err)
+		return
+	}
+	fmt.Fprintf(env.stdout, "Rated %d/%d, change it with: pomo review %s <rating>\n", r.Rating, history.MaxRating, r.ID())
+}
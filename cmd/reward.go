@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/notify"
+	"github.com/steenfuentes/pomo/provider"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+// rewarder celebrates every rewards.every-th pomodoro of the day, counting
+// those history has from earlier sessions. Nothing it does waits on the
+// next phase: the command runs in the background.
+type rewarder struct {
+	rewards  config.Rewards
+	progress *ui.Progress
+	clock    engine.Clock
+	minWork  time.Duration
+
+	day   time.Time
+	count int
+	wg    sync.WaitGroup
+}
+
+func newRewarder(rewards config.Rewards, progress *ui.Progress, clock engine.Clock) *rewarder {
+	r := &rewarder{rewards: rewards, progress: progress, clock: clock}
+	r.minWork, _ = minWorkDuration()
+	r.recount(clock.Now())
+	return r
+}
+
+// recount takes the day's count from history, which has every pomodoro
+// finished so far.
+func (r *rewarder) recount(now time.Time) {
+	y, m, d := now.Date()
+	r.day, r.count = time.Date(y, m, d, 0, 0, 0, 0, now.Location()), 0
+	path, err := history.Path()
+	if err != nil {
+		return
+	}
+	records, _ := history.Read(path)
+	r.count = history.Summarize(history.On(records, now), r.minWork).Completed
+}
+
+func (r *rewarder) Handle(e engine.TimerEvent) {
+	if e.Type != engine.EventTick || e.Phase != engine.PhaseWork || e.Ended != engine.EndCompleted || e.Voided || e.Total < r.minWork {
+		return
+	}
+	// The recorder has written this phase by now, so a new day's count
+	// already has it.
+	if now := r.clock.Now(); now.Before(r.day) || !now.Before(r.day.AddDate(0, 0, 1)) {
+		r.recount(now)
+	} else {
+		r.count++
+	}
+	if r.count > 0 && r.count%r.rewards.Every == 0 {
+		r.celebrate()
+	}
+}
+
+func (r *rewarder) celebrate() {
+	messages := r.rewards.Messages
+	text := messages[(r.count/r.rewards.Every-1)%len(messages)]
+	text = strings.ReplaceAll(text, "{count}", strconv.Itoa(r.count))
+	r.progress.Celebrate(text)
+	slog.Info("milestone", "count", r.count, "label", label)
+	if notifier != nil {
+		notifier.Send(notify.Message{Kind: notify.KindMilestone, Text: fmt.Sprintf("%d pomodoros today", r.count)})
+	}
+	if r.rewards.Command == "" {
+		return
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), r.rewards.Timeout)
+		defer cancel()
+		cmd := provider.Shell(ctx, r.rewards.Command)
+		cmd.Env = append(os.Environ(),
+			"POMO_MILESTONE="+strconv.Itoa(r.count),
+			"POMO_MESSAGE="+text,
+			"POMO_LABEL="+label)
+		cmd.WaitDelay = time.Second
+		if out, err := cmd.CombinedOutput(); err != nil {
+			if ctx.Err() != nil {
+				err = fmt.Errorf("timed out after %s", r.rewards.Timeout)
+			}
+			slog.Warn("reward command failed", "err", err, "output", strings.TrimSpace(string(out)))
+			r.progress.Logf("Warning: rewards.command: %v", err)
+		}
+	}()
+}
+
+// Close waits for a reward command still running, up to its timeout.
+func (r *rewarder) Close() error {
+	r.wg.Wait()
+	return nil
+}
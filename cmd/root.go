@@ -1,21 +1,82 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/paths"
+	"github.com/steenfuentes/pomo/ui"
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "pomo",
-	Short: "A CLI pomodoro timer",
-	Long:  `A command-line pomodoro timer with configurable work and break durations.`,
+	Use:           "pomo",
+	Short:         "A CLI pomodoro timer",
+	Long:          `A command-line pomodoro timer with configurable work and break durations.`,
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		paths.SetDataDir(dataDir)
+		return setNow()
+	},
+}
+
+var (
+	dataDir string
+	nowFlag string
+)
+
+// planClock is what planning and reports take the time from: the real
+// clock, or one stopped at --now or POMO_NOW, so they can be scripted. A
+// session's timer keeps to the real clock unless --demo starts it there.
+var planClock engine.Clock = engine.RealClock{}
+
+// setNow stops planClock at --now, else POMO_NOW, if either is given.
+func setNow() error {
+	value, source := nowFlag, "--now"
+	if value == "" {
+		value, source = os.Getenv(config.EnvNow), config.EnvNow
+	}
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q (want a time like 2025-01-06T09:00:00+01:00)", source, value)
+	}
+	planClock = engine.NewMockClock(t.Local())
+	demoStart = planClock.Now()
+	return nil
+}
+
+func init() {
+	// Only pomo start logs, once it has opened the log file.
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file to use instead of ~/.config/pomo/config.toml (or set POMO_CONFIG)")
+	rootCmd.PersistentFlags().StringVar(&nowFlag, "now", "", "Plan and report as if it were this time, e.g. 2025-01-06T09:00:00+01:00 (or set POMO_NOW)")
+	rootCmd.PersistentFlags().MarkHidden("now")
+	rootCmd.PersistentFlags().StringVar(&dataDir, "data-dir", "", "Keep config, history, state, and logs all under this directory instead of the usual places (or set POMO_DATA_DIR)")
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
+	defer ui.RestoreOnPanic()
+
+	err := rootCmd.Execute()
+	// os.Exit skips deferred calls, and an interrupted session may have
+	// left the terminal mid-frame.
+	ui.RestoreTerminal()
+	var code exitCode
+	if errors.As(err, &code) {
+		os.Exit(int(code))
+	}
+	if err != nil {
+		slog.Error("command failed", "args", strings.Join(os.Args[1:], " "), "err", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
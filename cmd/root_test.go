@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/config"
+)
+
+// TestNow reports on the day --now or POMO_NOW gives, the flag winning,
+// and checks neither outlives the command.
+func TestNow(t *testing.T) {
+	at := func(day int) string {
+		return time.Date(2025, time.March, day, 12, 0, 0, 0, time.Local).Format(time.RFC3339)
+	}
+	tests := []struct {
+		name     string
+		env      string
+		args     []string
+		want     string
+		mistaken string
+	}{
+		{"flag", "", []string{"--now", at(4)}, "Nothing recorded on 2025-03-04\n", ""},
+		{"env", at(5), nil, "Nothing recorded on 2025-03-05\n", ""},
+		{"flag over env", at(5), []string{"--now", at(4)}, "Nothing recorded on 2025-03-04\n", ""},
+		{"yesterday", "", []string{"yesterday", "--now", at(4)}, "Nothing recorded on 2025-03-03\n", ""},
+		{"bad flag", "", []string{"--now", "tomorrow"}, "", `invalid --now "tomorrow" (want a time like 2025-01-06T09:00:00+01:00)`},
+		{"bad env", "2025-03-04 12:00", nil, "", `invalid POMO_NOW "2025-03-04 12:00" (want a time like 2025-01-06T09:00:00+01:00)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			t.Setenv(config.EnvNow, tt.env)
+			var out syncBuffer
+			err := execute(t, startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}, append([]string{"log"}, tt.args...)...)
+			if tt.mistaken != "" {
+				if err == nil || err.Error() != tt.mistaken {
+					t.Errorf("log: %v, want %q", err, tt.mistaken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("log printed %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+
+	isolate(t)
+	var out syncBuffer
+	if err := execute(t, startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}, "log"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "2025-03-0") {
+		t.Errorf("log after --now printed %q, still on its day", out.String())
+	}
+}
+
+// TestNowLeavesTimer plans a session from --now while its timer keeps to
+// its own clock, as the real one would.
+func TestNowLeavesTimer(t *testing.T) {
+	isolate(t)
+	now := time.Date(2030, time.June, 3, 9, 0, 0, 0, time.Local)
+	r := startSession(t, "--now", now.Format(time.RFC3339), "--until", "10:00", "-p", "25", "-s", "5")
+	if err, ended := r.runFor(t, time.Second); ended {
+		t.Fatalf("start: %v\nstderr:\n%s", err, r.stderr.String())
+	}
+	r.signals <- syscall.SIGINT
+	r.wait(t)
+	// Two cycles, 55m, end by 10:00 from 09:00 on --now.
+	if want := "(2 cycles)\n"; !strings.Contains(r.stdout.String(), want) {
+		t.Errorf("no %q in\n%s", want, r.stdout.String())
+	}
+	records := readRecords(t)
+	if len(records) != 1 || records[0].Start.Before(testStart) || records[0].Start.After(r.clock.Now()) {
+		t.Errorf("records %+v, want one started on the timer's clock, after %s", records, testStart)
+	}
+}
@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/steenfuentes/pomo/calendar"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/engine/fanout"
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/keys"
+	"github.com/steenfuentes/pomo/metrics"
+	"github.com/steenfuentes/pomo/notify"
+	"github.com/steenfuentes/pomo/overlay"
+	"github.com/steenfuentes/pomo/project"
+	"github.com/steenfuentes/pomo/provider"
+	"github.com/steenfuentes/pomo/state"
+	"github.com/steenfuentes/pomo/ui"
+)
+
+// render is the subscriber that draws the session; tests give one that
+// panics instead.
+var render = func(p *ui.Progress) fanout.Subscriber { return fanout.SubscriberFunc(p.Update) }
+
+func runSession(ctx context.Context, env startEnv, timer *engine.Timer, control *sessionControl, meetings []calendar.Event, subscribers ...fanout.Subscriber) (summary engine.SessionSummary, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan engine.TimerEvent)
+
+	opts := []ui.Option{ui.WithWarnings(warnings)}
+	if !quietOff {
+		opts = append(opts, ui.WithQuiet(func() bool { return quietHours.Contains(env.clock.Now()) }))
+	}
+	if demo {
+		opts = append(opts, ui.WithStepping())
+	} else {
+		opts = append(opts, ui.WithSounds(sounds))
+		today, minWork := summarizeToday(env.clock.Now())
+		if timer.Session().TotalCycles() == 0 {
+			opts = append(opts, ui.WithFocusedToday(today.Focus))
+		}
+		if !noHeader {
+			opts = append(opts, ui.WithToday(ui.Today{Done: today.Completed, Goal: dailyGoal, MinWork: minWork, ASCII: asciiOutput()}))
+		}
+	}
+	opts = append(opts, ui.WithTimeStyle(timeStyled), ui.WithOverallCounts(overallCounted))
+	if !noNext {
+		opts = append(opts, ui.WithNextUp())
+	}
+	opts = append(opts, ui.WithLayout(ui.Layout{Compact: compact, Below: ui.CompactBelow, BarWidth: compactBar, MaxWidth: maxWidth, ASCII: asciiOutput()}))
+	if gradient {
+		opts = append(opts, ui.WithGradient(ui.TrafficLight(gradientAt[0], gradientAt[1])))
+	}
+	session := timer.Session()
+	progress := ui.NewProgress(overallCounted.Total(session.TotalPhases(), session.TotalCycles()), env.stdout, opts...)
+
+	var listener *keys.Listener
+	err = keys.ErrNotTerminal
+	if f, ok := env.stdin.(*os.File); ok && !demo {
+		listener, err = keys.Listen(f, func(b byte) {
+			defer ui.RestoreOnPanic()
+			control.key(b)
+		})
+	}
+	control.attach(timer, progress, err == nil)
+	defer control.detach()
+	if err == nil {
+		defer listener.Stop()
+		defer ui.OnRestore(func() { listener.Restore() })()
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		defer ui.RestoreOnPanic()
+		errChan <- timer.Run(ctx, events)
+	}()
+
+	go func() {
+		select {
+		case err := <-progress.Failed():
+			if control.terminalLost(env.clock.Now(), err) {
+				cancel()
+			}
+		case <-ctx.Done():
+		}
+	}()
+
+	// The demo steps its clock in time with the renderer, so it must see
+	// every tick.
+	var feed <-chan engine.TimerEvent
+	var coalescer *fanout.Coalescer
+	if demo {
+		feed = playDemo(timer, env.clock.(*engine.MockClock), events)
+	} else {
+		feed, coalescer = fanout.Coalesce(events)
+	}
+
+	// last is kept for salvage, should the loop panic.
+	var last engine.TimerEvent
+	var bus fanout.Broadcaster
+	bus.Subscribe(fanout.SubscriberFunc(func(e engine.TimerEvent) {
+		last = e
+		if e.Type == engine.EventSessionEnded {
+			summary = *e.Summary
+		}
+	}))
+	bus.Subscribe(render(progress))
+	bus.Subscribe(control)
+	var keep []fanout.Subscriber
+	if !demo {
+		keep = subscribeSideEffects(&bus, env, control, progress, meetings)
+	}
+	for _, sub := range subscribers {
+		bus.Subscribe(sub)
+	}
+
+	// Subscribers run on this goroutine, the renderer among them, so a
+	// panic in one is caught here rather than taking the session with it.
+	defer func() {
+		if r := recover(); r != nil {
+			progress.Detach()
+			ui.RestoreTerminal()
+			cancel()
+			summary, err = salvage(r, debug.Stack(), last, keep)
+		}
+	}()
+
+	subErr := bus.Run(feed)
+	err = <-errChan
+	if err != nil || summary.Capped {
+		progress.Abort()
+	} else {
+		progress.Wait()
+	}
+
+	if subErr != nil {
+		fmt.Fprintf(env.stderr, "Warning: %v\n", subErr)
+	}
+	if verbose && coalescer != nil {
+		fmt.Fprintf(env.stderr, "Display: %d ticks and countdowns coalesced, at most %d events waiting\n", coalescer.Coalesced(), coalescer.MaxQueued())
+	}
+
+	return summary, err
+}
+
+// summarizeToday is what history has recorded since midnight, leaving out
+// work phases under stats.min_work_duration, which it also returns.
+func summarizeToday(now time.Time) (history.Summary, time.Duration) {
+	minWork, _ := minWorkDuration()
+	path, err := history.Path()
+	if err != nil {
+		return history.Summary{}, minWork
+	}
+	records, _ := history.Read(path)
+	return history.Summarize(history.On(records, now), minWork), minWork
+}
+
+// sessionPlace is what the session is for and where it started, for
+// history: --project, else the git repository the working directory is
+// in, and that directory, neither of which --no-record-dir looks at.
+func sessionPlace() (name, dir string) {
+	if noRecordDir {
+		return projectOverride, ""
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return projectOverride, ""
+	}
+	if projectOverride != "" {
+		return projectOverride, dir
+	}
+	return project.Detect(dir), dir
+}
+
+// subscribeSideEffects adds the subscribers that write outside the
+// terminal: the state file, history, the log, rewards, overlay files, and
+// the metrics textfile. It returns those keeping the session on disk,
+// history's and the state file's, for salvage.
+func subscribeSideEffects(bus *fanout.Broadcaster, env startEnv, control *sessionControl, progress *ui.Progress, meetings []calendar.Event) (keep []fanout.Subscriber) {
+	bus.Subscribe(&eventLogger{})
+	var recorder *history.Recorder
+	if path, err := history.Path(); err == nil {
+		recorder = history.NewRecorder(path, env.clock, label)
+		recorder.Place(sessionPlace())
+		// The score goes in ahead of the recorder writing the phase.
+		if activityScore && activityProber != nil {
+			bus.Subscribe(newActivityTracker(recorder, env.clock))
+		}
+	}
+	// Ahead of the recorder too, so the time away is left out of the phase
+	// that ends.
+	if len(awayPolicies) > 0 && activityProber != nil {
+		bus.Subscribe(newAwayWatcher(control, progress, recorder, env.clock))
+	}
+	if recorder != nil {
+		bus.Subscribe(recorder)
+		keep = append(keep, recorder)
+	}
+	// After history, so that once the state file is gone, as the session
+	// ends, the phase it showed is in history.
+	if path, err := state.Path(); err == nil {
+		if w, err := state.NewWriter(path, label, profileName); err == nil {
+			bus.Subscribe(w)
+			keep = append(keep, w)
+		}
+	}
+	if rewards.Every > 0 {
+		bus.Subscribe(newRewarder(rewards, progress, env.clock))
+	}
+	if checkIn {
+		bus.Subscribe(newCheckInReminder(progress, checkInRemind))
+	}
+	if onCycleComplete != "" {
+		bus.Subscribe(newCycleHook(onCycleComplete, progress, env.clock))
+	}
+	if len(focusBlocklist) > 0 {
+		bus.Subscribe(newDistractionWatcher(progress, recorder, env.clock))
+	}
+	for _, p := range providers {
+		bus.Subscribe(provider.NewRunner(p, label, env.clock, providersDryRun, reportProvider(progress)))
+	}
+	if len(meetings) > 0 {
+		bus.Subscribe(newMeetingWatcher(progress, meetings, env.clock))
+	}
+	for i, path := range writeFiles {
+		bus.Subscribe(overlay.NewFileWriter(path, writeFormat(i), label))
+	}
+	if metricsTextfile != "" {
+		var notifications func() []notify.Stats
+		if notifier != nil {
+			notifications = notifier.Stats
+		}
+		bus.Subscribe(metrics.NewTextfileWriter(metricsTextfile, notifications))
+	}
+	return keep
+}
+
+// writeFormat pairs --write-format values with --write-file values by
+// position; files past the last format reuse it.
+func writeFormat(i int) *overlay.Format {
+	return writeParsed[min(i, len(writeParsed)-1)]
+}
+
+func startAnother(env startEnv) bool {
+	switch onComplete {
+	case "restart":
+		return true
+	case "prompt":
+		return promptYes(env, "Start another session? [y/N] ", promptTimeout)
+	default:
+		return false
+	}
+}
+
+// promptYes treats a timeout, EOF, or anything but "y"/"yes" as no, so an
+// unattended terminal falls through to exiting.
+func promptYes(env startEnv, question string, timeout time.Duration) bool {
+	a := ask(env, question, timeout)
+	return a == "y" || a == "yes"
+}
+
+// promptNotNo is promptYes for a question that defaults to yes: only "n" or
+// "no" declines.
+func promptNotNo(env startEnv, question string, timeout time.Duration) bool {
+	a := ask(env, question, timeout)
+	return a != "n" && a != "no"
+}
+
+// ask returns the answer in lower case, or "" after timeout.
+func ask(env startEnv, question string, timeout time.Duration) string {
+	return strings.ToLower(askAsTyped(env, question, timeout))
+}
+
+// askAsTyped returns the answer as typed, trimmed of spaces, or "" after
+// timeout or at the end of stdin.
+func askAsTyped(env startEnv, question string, timeout time.Duration) string {
+	fmt.Fprint(env.stdout, question)
+	a, err := env.lines.next(timeout)
+	if errors.Is(err, errTimeout) {
+		fmt.Fprintln(env.stdout)
+	}
+	return strings.TrimSpace(a)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/state"
+)
+
+var snoozeCmd = &cobra.Command{
+	Use:   "snooze [duration]",
+	Short: "Put off the work after the current break",
+	Long: `Put off the work phase after the current break, by the session's --snooze
+(default 3m) or the given duration. Snoozing counts as neither break nor
+work: the break is recorded as planned, and the snooze with the work phase
+it put off. Each break can be snoozed --max-snoozes times, including while
+its snooze runs, which extends it. Pressing b in the session does the same.
+
+Examples:
+  pomo snooze
+  pomo snooze 5m`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		command := "snooze"
+		if len(args) == 1 {
+			if d, err := time.ParseDuration(args[0]); err != nil || d <= 0 {
+				return fmt.Errorf("invalid duration %q", args[0])
+			}
+			command += " " + args[0]
+		}
+		path, err := state.SocketPath()
+		if err != nil {
+			return err
+		}
+		reply, err := state.Send(path, command)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), reply)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(snoozeCmd)
+}
@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/paths"
+)
+
+var testStart = time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+// testRun is one pomo start against buffers, a MockClock, and a signal
+// channel the test sends on.
+type testRun struct {
+	stdout, stderr syncBuffer
+	clock          *engine.MockClock
+	signals        chan os.Signal
+	done           chan error
+}
+
+// isolate points every path pomo uses at a fresh directory and keeps the
+// environment from setting anything.
+func isolate(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("POMO_DATA_DIR", dir)
+	t.Setenv("HOME", dir)
+	for _, kv := range os.Environ() {
+		if name, _, _ := strings.Cut(kv, "="); strings.HasPrefix(name, "POMO_") && name != "POMO_DATA_DIR" {
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+	return dir
+}
+
+// execute runs pomo with args, with stdin and the start environment as
+// given, and puts every flag back as it was once it returns.
+func execute(t *testing.T, env startEnv, args ...string) error {
+	t.Helper()
+	saved := newStartEnv
+	newStartEnv = func(*cobra.Command) startEnv { return env }
+	defer func() { newStartEnv = saved }()
+	defer resetFlags(rootCmd)
+	// --data-dir is kept in paths, and --now in planClock, not just their
+	// flags.
+	defer paths.SetDataDir("")
+	defer func(clock engine.Clock, start time.Time) { planClock, demoStart = clock, start }(planClock, demoStart)
+
+	rootCmd.SetArgs(args)
+	rootCmd.SetIn(env.stdin)
+	rootCmd.SetOut(env.stdout)
+	rootCmd.SetErr(env.stderr)
+	defer func() {
+		rootCmd.SetIn(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+	}()
+	return rootCmd.Execute()
+}
+
+// resetFlags puts every flag cmd and its subcommands have set back to its
+// default, as a fresh process would have it.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+		if unset(f.Value) {
+			if s, ok := f.Value.(pflag.SliceValue); ok {
+				if def := strings.Trim(f.DefValue, "[]"); def != "" {
+					s.Replace(strings.Split(def, ","))
+				}
+			}
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// unset empties a slice or map flag, reporting whether v is one. Once set,
+// these add to what they hold rather than replacing it, by a mark pflag
+// keeps unexported along with the value, so both are cleared by reflection.
+func unset(v pflag.Value) bool {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	changed, value := rv.Elem().FieldByName("changed"), rv.Elem().FieldByName("value")
+	if changed.Kind() != reflect.Bool || value.Kind() != reflect.Pointer {
+		return false
+	}
+	writable := func(f reflect.Value) reflect.Value {
+		return reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+	}
+	writable(changed).SetBool(false)
+	held := writable(value).Elem()
+	held.Set(reflect.Zero(held.Type()))
+	return true
+}
+
+// startSession runs pomo start with args in the background.
+func startSession(t *testing.T, args ...string) *testRun {
+	t.Helper()
+	r := &testRun{
+		clock:   engine.NewMockClock(testStart),
+		signals: make(chan os.Signal, 1),
+		done:    make(chan error, 1),
+	}
+	env := startEnv{
+		stdin:   strings.NewReader(""),
+		stdout:  &r.stdout,
+		stderr:  &r.stderr,
+		clock:   r.clock,
+		signals: r.signals,
+	}
+	args = append([]string{"start", "--theme", "dark", "--no-header", "--transition", "0"}, args...)
+	go func() { r.done <- execute(t, env, args...) }()
+	return r
+}
+
+// runFor advances the clock a second at a time, until the session has
+// run for d or ended, giving the timer a moment to see each step.
+func (r *testRun) runFor(t *testing.T, d time.Duration) (error, bool) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for moved := time.Duration(0); d == 0 || moved < d; moved += time.Second {
+		select {
+		case err := <-r.done:
+			return err, true
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("session still running after %s on the clock\nstdout:\n%s", moved, r.stdout.String())
+		}
+		r.clock.Advance(time.Second)
+		time.Sleep(time.Millisecond)
+	}
+	return nil, false
+}
+
+// wait runs the session to its end.
+func (r *testRun) wait(t *testing.T) error {
+	t.Helper()
+	err, _ := r.runFor(t, 0)
+	return err
+}
+
+func readRecords(t *testing.T) []history.Record {
+	t.Helper()
+	path, err := history.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := history.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+// syncBuffer lets the renderer write while the test reads.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func TestStartCompletesSession(t *testing.T) {
+	isolate(t)
+	r := startSession(t, "-c", "2", "-p", "1", "-s", "1")
+	if err := r.wait(t); err != nil {
+		t.Fatalf("start: %v\nstderr:\n%s", err, r.stderr.String())
+	}
+
+	if out := r.stdout.String(); !strings.Contains(out, "Session complete!") {
+		t.Errorf("stdout lacks \"Session complete!\":\n%s", out)
+	}
+	var phases []string
+	for _, rec := range readRecords(t) {
+		if rec.Ended != engine.EndCompleted {
+			t.Errorf("%s ended %s, want completed", rec.Phase, rec.Ended)
+		}
+		phases = append(phases, rec.Phase.String())
+	}
+	if got, want := strings.Join(phases, ", "), "Work, Short Break, Work"; got != want {
+		t.Errorf("history has %s, want %s", got, want)
+	}
+}
+
+func TestStartInterruptedBySIGINT(t *testing.T) {
+	isolate(t)
+	r := startSession(t, "-c", "2", "-p", "1", "--porcelain")
+	if _, ended := r.runFor(t, 30*time.Second); ended {
+		t.Fatal("session ended before the interrupt")
+	}
+	r.signals <- syscall.SIGINT
+	err := r.wait(t)
+
+	var code exitCode
+	if !errors.As(err, &code) || code != exitStopped {
+		t.Fatalf("start: %v, want exit code %d", err, exitStopped)
+	}
+	if out := r.stdout.String(); !strings.Contains(out, "reason=interrupted") {
+		t.Errorf("stdout lacks the interrupted done line:\n%s", out)
+	}
+	records := readRecords(t)
+	if len(records) != 1 || records[0].Phase != engine.PhaseWork || records[0].Ended != engine.EndInterrupted {
+		t.Fatalf("history %+v, want one interrupted work phase", records)
+	}
+	if got := records[0].Actual(); got < 29*time.Second || got > 31*time.Second {
+		t.Errorf("interrupted work phase ran %s, want about 30s", got)
+	}
+}
+
+func TestStartInfiniteSessionStopsOnSIGTERM(t *testing.T) {
+	isolate(t)
+	r := startSession(t, "-p", "1", "-s", "1")
+	r.runFor(t, 2*time.Minute+30*time.Second)
+	r.signals <- syscall.SIGTERM
+	if err := r.wait(t); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	if out := r.stdout.String(); !strings.Contains(out, "1 cycle") {
+		t.Errorf("stdout lacks the cycle total:\n%s", out)
+	}
+	if n := len(readRecords(t)); n != 3 {
+		t.Errorf("%d records, want work, break, and the interrupted work", n)
+	}
+}
+
+func TestStartHardCap(t *testing.T) {
+	isolate(t)
+	r := startSession(t, "-c", "4", "-p", "1", "-s", "1", "--hard-cap", "90s", "--porcelain")
+	err := r.wait(t)
+
+	var code exitCode
+	if !errors.As(err, &code) || code != exitStopped {
+		t.Fatalf("start: %v, want exit code %d", err, exitStopped)
+	}
+	if !strings.Contains(r.stderr.String(), "hard cap") {
+		t.Errorf("stderr lacks the hard cap warning:\n%s", r.stderr.String())
+	}
+	if out := r.stdout.String(); !strings.Contains(out, "cycles=1/4") || !strings.Contains(out, "reason=capped") {
+		t.Errorf("stdout lacks the capped done line:\n%s", out)
+	}
+	records := readRecords(t)
+	if last := records[len(records)-1]; !last.Suspicious {
+		t.Errorf("last record %+v not flagged suspicious", last)
+	}
+}
+
+func TestStartRejectsBadFlags(t *testing.T) {
+	dir := isolate(t)
+	r := startSession(t, "--theme", "purple")
+	err := r.wait(t)
+
+	var code exitCode
+	if err == nil || errors.As(err, &code) {
+		t.Fatalf("start: %v, want an error with a message", err)
+	}
+	if !strings.Contains(err.Error(), `--theme "purple"`) {
+		t.Errorf("error %q does not name the bad --theme", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "history", "history.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("history written for a session that never started: %v", err)
+	}
+}
+
+func TestStartUntilTooSoon(t *testing.T) {
+	isolate(t)
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.Local).Format(time.RFC3339)
+	r := startSession(t, "--now", now, "--until", "09:30")
+	err := r.wait(t)
+	if err == nil || err.Error() != "not even one cycle fits before 09:30" {
+		t.Errorf("start: %v, want none fitting before 09:30", err)
+	}
+}
+
+// TestStartCreatesDirectories runs a session with --data-dir naming
+// directories that do not exist yet, and checks each made is private.
+func TestStartCreatesDirectories(t *testing.T) {
+	root := filepath.Join(isolate(t), "usb", "pomo")
+	r := startSession(t, "--data-dir", root, "-c", "1", "-p", "1", "-s", "1")
+	if err := r.wait(t); err != nil {
+		t.Fatalf("start: %v\nstderr:\n%s", err, r.stderr.String())
+	}
+	for _, dir := range []string{filepath.Dir(root), root, filepath.Join(root, "state"), filepath.Join(root, "history")} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Errorf("not made: %v", err)
+			continue
+		}
+		if mode := info.Mode().Perm(); mode != 0o700 {
+			t.Errorf("%s made %#o, want 0700", dir, mode)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "history", "history.jsonl")); err != nil {
+		t.Errorf("history not kept under --data-dir: %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/state"
+)
+
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Describe the state file scripts read",
+}
+
+var stateSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON schema of the state file",
+	Long: `Print the JSON schema of the state file, ~/.local/state/pomo/state.json,
+at the version this pomo writes. The file's "version" field says which one
+it follows. Fields are only added within a version, so a reader should
+ignore those it does not know; pomo commands refuse a file of a newer
+version rather than misread it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, err := cmd.OutOrStdout().Write(state.Schema)
+		return err
+	},
+}
+
+func init() {
+	stateCmd.AddCommand(stateSchemaCmd)
+	rootCmd.AddCommand(stateCmd)
+}
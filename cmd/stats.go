@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/ui"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+var (
+	statsDays         int
+	statsIncludeShort bool
+	statsDebt         bool
+	statsStrictDebt   bool
+	statsByProject    bool
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize recent work phases from history",
+	Long: `Summarize the work phases recorded over the last few days: focus time,
+how many ran to completion, and how far actual durations strayed from plan.
+Breaks and cooldowns do not count as focus time, and neither do work phases
+planned shorter than stats.min_work_duration in the config file (default
+10m) unless --include-short is given. --by-project adds focus time for
+each project: what pomo start --project named, else the git repository, or
+the directory, each session started in. Sessions rated with pomo start
+--review or pomo review add their mean focus rating, by session length and
+by the time of day they started.
+
+With --debt, show the focus debt this week instead: how far focus time
+falls short of the daily goal (daily-goal pomodoros of pomodoro minutes
+each, as pomo start would take them) for each day since Monday. A week
+without a single record counts as time away and owes nothing unless
+--strict-debt is given.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if statsDays < 1 {
+			return fmt.Errorf("invalid --days %d (want at least 1)", statsDays)
+		}
+
+		minWork, err := minWorkDuration()
+		if err != nil {
+			return err
+		}
+		if statsIncludeShort {
+			minWork = 0
+		}
+		records, err := readHistory(cmd)
+		if err != nil {
+			return err
+		}
+
+		if statsDebt {
+			return printDebt(cmd, records, minWork)
+		}
+
+		y, m, d := planClock.Now().Date()
+		from := time.Date(y, m, d-statsDays+1, 0, 0, 0, 0, time.Local)
+		s := history.Summarize(history.Since(records, from), minWork)
+
+		out := cmd.OutOrStdout()
+		if statsDays == 1 {
+			fmt.Fprintln(out, "Today")
+		} else {
+			fmt.Fprintf(out, "Last %d days\n", statsDays)
+		}
+		fmt.Fprintf(out, "  Focus time       %s\n", format.DurationHuman(s.Focus))
+		fmt.Fprintf(out, "  Completion rate  %s (%d of %d work phases)\n", format.Percent(s.CompletionRate()), s.Completed, s.Work)
+		fmt.Fprintf(out, "  Avg vs. plan     %s\n", formatDeviation(s.Deviation))
+		if statsDays > 1 {
+			perDay := make([]int, statsDays)
+			for i := range perDay {
+				perDay[i] = history.Summarize(history.On(records, from.AddDate(0, 0, i)), minWork).Completed
+			}
+			fmt.Fprintf(out, "  Per day          %s (completed, oldest first)\n", ui.Sparkline(perDay, asciiOutput()))
+		}
+		if s.Voided > 0 {
+			fmt.Fprintf(out, "  Voided           %d (%s, not in focus time)\n", s.Voided, format.DurationHuman(s.VoidedTime))
+		}
+		if s.Distractions > 0 {
+			fmt.Fprintf(out, "  Distractions     %d\n", s.Distractions)
+		}
+		if a, ok := s.Activity(); ok {
+			fmt.Fprintf(out, "  Activity         %s of %d sampled minutes had input\n", format.Percent(a), s.ActivitySamples)
+		}
+		if s.Snoozed > 0 {
+			fmt.Fprintf(out, "  Snoozed          %s\n", format.DurationPrecise(s.Snoozed))
+		}
+		if s.CheckInWait > 0 {
+			fmt.Fprintf(out, "  Away             %s (waiting to check in after breaks)\n", format.DurationPrecise(s.CheckInWait))
+		}
+		if s.Short > 0 {
+			unit := "phases"
+			if s.Short == 1 {
+				unit = "phase"
+			}
+			fmt.Fprintf(out, "  Excluded         %s (%d work %s under %s, see --include-short)\n",
+				format.DurationHuman(s.Excluded), s.Short, unit, strings.TrimSuffix(minWork.String(), "0s"))
+		}
+		if rs := history.SummarizeReviews(history.Reviews(history.Since(records, from)), time.Local); rs.Reviews > 0 {
+			printReviews(out, rs)
+		}
+		if statsByProject {
+			return printByProject(out, history.Since(records, from), minWork)
+		}
+		return nil
+	},
+}
+
+func init() {
+	statsCmd.Flags().IntVar(&statsDays, "days", 7, "Number of days to cover, including today")
+	statsCmd.Flags().BoolVar(&ascii, "ascii", false, "Draw the per-day chart as plain digits, as without a UTF-8 locale")
+	statsCmd.Flags().BoolVar(&statsIncludeShort, "include-short", false, "Count work phases shorter than stats.min_work_duration")
+	statsCmd.Flags().BoolVar(&statsByProject, "by-project", false, "Break focus time down by project: the git repository, or directory, each session started in")
+	statsCmd.Flags().BoolVar(&statsDebt, "debt", false, "Show the focus debt against the daily goal this week instead")
+	statsCmd.Flags().BoolVar(&statsStrictDebt, "strict-debt", false, "With --debt, count weeks without a single record too")
+
+	rootCmd.AddCommand(statsCmd)
+}
+
+// printReviews reports the mean focus rating, then by session length and
+// by the part of the day sessions started in.
+func printReviews(out io.Writer, s history.ReviewSummary) {
+	unit := "sessions"
+	if s.Reviews == 1 {
+		unit = "session"
+	}
+	fmt.Fprintf(out, "  Focus rating     %.1f of %d (%d rated %s)\n", s.Mean, history.MaxRating, s.Reviews, unit)
+	buckets := func(bs []history.RatingBucket) string {
+		parts := make([]string, len(bs))
+		for i, b := range bs {
+			parts[i] = fmt.Sprintf("%s %.1f (%d)", b.Name, b.Mean, b.Reviews)
+		}
+		return strings.Join(parts, ", ")
+	}
+	fmt.Fprintf(out, "    by length      %s\n", buckets(s.ByLength))
+	fmt.Fprintf(out, "    by time        %s\n", buckets(s.ByPart))
+}
+
+// printByProject breaks records' focus time down by project, leaving out
+// projects with no work phase that counts.
+func printByProject(out io.Writer, records []history.Record, minWork time.Duration) error {
+	fmt.Fprintln(out, "  By project")
+	home, _ := os.UserHomeDir()
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, p := range history.ByProject(records, minWork) {
+		if p.Work == 0 {
+			continue
+		}
+		name := p.Name
+		switch {
+		case name == "":
+			name = "(none recorded)"
+		case home != "" && strings.HasPrefix(name, home+string(filepath.Separator)):
+			name = "~" + name[len(home):]
+		}
+		fmt.Fprintf(w, "    %s\t%s\t%d completed\n", name, format.DurationHuman(p.Focus), p.Completed)
+	}
+	return w.Flush()
+}
+
+// printDebt prints the focus debt this week, day by day.
+func printDebt(cmd *cobra.Command, records []history.Record, minWork time.Duration) error {
+	goal, work, err := dailyGoalSetting()
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if goal == 0 {
+		fmt.Fprintln(out, "No daily goal, so no focus debt (see daily-goal)")
+		return nil
+	}
+
+	now := planClock.Now()
+	debt, today, owed := history.WeekDebt(records, now, goal, minWork, statsStrictDebt)
+	fmt.Fprintf(out, "This week, against %s a day\n", format.DurationHuman(goal))
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, d := range debt.Days {
+		if d.Excluded {
+			fmt.Fprintf(w, "  %s\t-\taway, see --strict-debt\n", d.Day.Format("Mon"))
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", d.Day.Format("Mon"), format.DurationHuman(d.Focus), describeOwed(d.Owed))
+	}
+	fmt.Fprintf(w, "  Today\t%s\t%s\n", format.DurationHuman(today), describeOwed(owed))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if line := describeDebt(owed, work, history.DaysLeft(now), asciiOutput()); line != "" {
+		fmt.Fprintf(out, "\n%s\n", line)
+	}
+	return nil
+}
+
+// describeOwed renders a running debt as e.g. "owed 1h 40m" or "ahead 20m".
+func describeOwed(owed time.Duration) string {
+	switch {
+	case owed > 0:
+		return "owed " + format.DurationHuman(owed)
+	case owed < 0:
+		return "ahead " + format.DurationHuman(-owed)
+	default:
+		return "even"
+	}
+}
+
+// dailyGoalSetting is the daily goal pomo start would take as focus time,
+// with the work duration it is counted in.
+func dailyGoalSetting() (goal, work time.Duration, err error) {
+	var n, minutes int
+	for key, v := range map[string]*int{"daily-goal": &n, "pomodoro": &minutes} {
+		s, err := startSetting(key)
+		if err != nil {
+			return 0, 0, err
+		}
+		if *v, err = strconv.Atoi(s); err != nil {
+			return 0, 0, fmt.Errorf("invalid %s %q: %w", key, s, err)
+		}
+	}
+	work = time.Duration(minutes) * time.Minute
+	return time.Duration(n) * work, work, nil
+}
+
+// minWorkDuration is stats.min_work_duration from the config file.
+func minWorkDuration() (time.Duration, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return config.DefaultMinWorkDuration, err
+	}
+	return cfg.Stats.MinWorkDuration, nil
+}
+
+// asciiOutput is whether charts should be drawn as plain digits.
+func asciiOutput() bool {
+	return ascii || !ui.UnicodeLocale()
+}
+
+// formatDeviation renders e.g. "-3m10s (under)" or "+45s (over)".
+func formatDeviation(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < 0:
+		return fmt.Sprintf("-%s (under)", format.DurationPrecise(-d))
+	case d > 0:
+		return fmt.Sprintf("+%s (over)", format.DurationPrecise(d))
+	default:
+		return "on plan"
+	}
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/overlay"
+	"github.com/steenfuentes/pomo/state"
+)
+
+const defaultStatusFormat = `{{.PhaseIcon}} {{.Phase}} {{.Remaining}} left{{if .TotalCycles}}, cycle {{.Cycle}} of {{.TotalCycles}}{{end}}{{if eq .State "paused"}} (paused){{end}}`
+
+var (
+	statusFormat   string
+	statusColorHex bool
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Print the running session, optionally through a template",
+	Long: `Print the running session on one line. --format takes a Go template over
+these fields, or the {placeholder} shorthands of --write-format:
+
+  .Phase             "Work", "Short Break", "Long Break", or "Cooldown"
+  .PhaseIcon         e.g. "🍅"
+  .Elapsed           time into the phase, as "MM:SS", or "H:MM:SS" from an hour
+  .Remaining         time left in the phase, likewise
+  .Total             the phase's length, likewise
+  .ElapsedSeconds    .Elapsed in whole seconds, also .RemainingSeconds
+                     and .TotalSeconds
+  .Minutes           time left, rounded up to whole minutes
+  .Percent           how far into the phase, from 0 to 100
+  .Cycle             the current cycle, counting from 1
+  .TotalCycles       cycles in the session, 0 when it has no end
+  .UntilLong         work phases, or work time, left before the next long break
+  .Label             the session's --label
+  .StartedAt         when the phase started, moved on by any time paused
+  .EndsAt            when the phase ends, a time: {{.EndsAt.Format "15:04"}}
+  .State             "running" or "paused"
+
+With --color-hex, prints instead the color lights could show for the
+session as "#rrggbb": red for work, green for a short break, blue for a
+long one, amber while paused, and black (off) with no session running,
+which is not an error then. The [theme] table in the config file changes
+them.
+
+Example:
+  pomo status --format '{{.Phase}} {{.RemainingSeconds}} {{printf "%.0f" .Percent}}'
+
+Exits like the ctl subcommands.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := overlay.Parse(statusFormat)
+		if err != nil {
+			return ctlFail(cmd, fmt.Errorf("invalid --format: %w", err))
+		}
+		path, err := state.Path()
+		if err != nil {
+			return ctlFail(cmd, err)
+		}
+		s, err := readState(path)
+		if statusColorHex {
+			var e *engine.TimerEvent
+			if err == nil {
+				at := s.EventAt(time.Now())
+				e = &at
+			} else if !errors.Is(err, state.ErrNotRunning) {
+				return ctlFail(cmd, err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), loadPalette().Color(e).Hex())
+			return nil
+		}
+		if err != nil {
+			return ctlFail(cmd, err)
+		}
+
+		now := time.Now()
+		text, err := format.Execute(overlay.FieldsOf(s.EventAt(now), s.Label, now))
+		if err != nil {
+			return ctlFail(cmd, err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), text)
+		return nil
+	},
+}
+
+// readState reads the state file, taking one not updated within
+// state.stale_after from the config file as left behind.
+func readState(path string) (state.State, error) {
+	after := config.DefaultStaleAfter
+	if cfg, err := loadConfig(); err == nil {
+		after = cfg.State.StaleAfter
+	}
+	return state.ReadFresh(path, after, time.Now())
+}
+
+func init() {
+	statusCmd.Flags().StringVar(&statusFormat, "format", defaultStatusFormat, "Output format, a Go template or --write-format placeholders")
+	statusCmd.Flags().BoolVar(&statusColorHex, "color-hex", false, "Print the session's color for lights as #rrggbb, off (#000000) when none is running")
+
+	rootCmd.AddCommand(statusCmd)
+}
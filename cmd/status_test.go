@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/state"
+)
+
+// publish has a state file show e, as pomo start's writer would.
+func publish(t *testing.T, e engine.TimerEvent) {
+	t.Helper()
+	path, err := state.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := state.NewWriter(path, "essay", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Type = engine.EventTick
+	w.Handle(e)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pausedWork is 10m30s into the second of four 25m work phases, paused so
+// the time left stays put.
+func pausedWork() engine.TimerEvent {
+	return engine.TimerEvent{
+		Phase:          engine.PhaseWork,
+		Elapsed:        10*time.Minute + 30*time.Second,
+		Remaining:      14*time.Minute + 30*time.Second,
+		Total:          25 * time.Minute,
+		Paused:         true,
+		CycleNum:       2,
+		TotalCycles:    4,
+		UntilLongBreak: 2,
+		PhaseStartedAt: time.Now().Add(-10*time.Minute - 30*time.Second),
+	}
+}
+
+func TestStatusFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{defaultStatusFormat, "🍅 Work 14:30 left, cycle 2 of 4 (paused)"},
+		{"{{.Phase}} {{.RemainingSeconds}} {{printf \"%.0f\" .Percent}}", "Work 870 42"},
+		{"{{.PhaseIcon}} {{.Elapsed}}/{{.Total}} {{.Minutes}}m", "🍅 10:30/25:00 15m"},
+		{"{{.Cycle}}/{{.TotalCycles}} {{.UntilLong}} {{.State}} [{{.Label}}]", "2/4 2 paused [essay]"},
+		{"{{if .EndsAt.After .StartedAt}}ends later{{end}}", "ends later"},
+		{"{icon} {phase} {remaining} ({cycle}/{cycles}) {label}", "🍅 Work 14:30 (2/4) essay"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			isolate(t)
+			publish(t, pausedWork())
+			stdout, stderr, code := runCtl(t, "status", "--format", tt.format)
+			if code != 0 || stderr != "" {
+				t.Fatalf("exit code %d: %s", code, stderr)
+			}
+			if stdout != tt.want+"\n" {
+				t.Errorf("printed %q, want %q", stdout, tt.want)
+			}
+		})
+	}
+}
+
+// TestFormatErrorsSayWhere checks both status and prompt report a bad
+// template with where it goes wrong, as they share one parser.
+func TestFormatErrorsSayWhere(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"{{.Phase", `invalid --format: template: format:1: unclosed action`},
+		{"x {{.Nope}}", `invalid --format: template: format:1:4: executing "format" at <.Nope>: can't evaluate field Nope in type overlay.Fields`},
+		{"{{.Phase}}\n{{end}}", `invalid --format: template: format:2: unexpected {{end}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			isolate(t)
+			publish(t, pausedWork())
+			_, stderr, code := runCtl(t, "status", "--format", tt.format)
+			if code != exitError || stderr != tt.want+"\n" {
+				t.Errorf("status: exit code %d, %q, want %d, %q", code, stderr, exitError, tt.want)
+			}
+
+			var out strings.Builder
+			err := execute(t, startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}, "prompt", "--format", tt.format)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("prompt: %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptSharesStatusFormat(t *testing.T) {
+	isolate(t)
+	publish(t, pausedWork())
+	format := "{{.Phase}} {{.RemainingSeconds}} {remaining}"
+	stdout, _, _ := runCtl(t, "status", "--format", format)
+
+	var out strings.Builder
+	if err := execute(t, startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}, "prompt", "--shell", "none", "--format", format); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Work 870 {remaining}"; stdout != want+"\n" || out.String() != want {
+		t.Errorf("status printed %q and prompt %q, want both %q", stdout, out.String(), want)
+	}
+}
+
+// TestStatusColorHex checks the color pomo status --color-hex prints in
+// each state a session can be in, with the default palette and with one
+// from the [theme] table.
+func TestStatusColorHex(t *testing.T) {
+	running := func(phase engine.Phase) *engine.TimerEvent {
+		e := pausedWork()
+		e.Phase, e.Paused = phase, false
+		return &e
+	}
+	tests := []struct {
+		name  string
+		e     *engine.TimerEvent
+		stale bool
+		want  string
+		theme string
+	}{
+		{name: "idle", want: "#000000", theme: "#101010"},
+		{name: "work", e: running(engine.PhaseWork), want: "#ff0000", theme: "#aa0000"},
+		{name: "paused", e: func() *engine.TimerEvent { e := pausedWork(); return &e }(), want: "#ffbf00", theme: "#aaaa00"},
+		{name: "short break", e: running(engine.PhaseShortBreak), want: "#00ff00", theme: "#00aa00"},
+		{name: "long break", e: running(engine.PhaseLongBreak), want: "#0000ff", theme: "#0000aa"},
+		{name: "stale", e: running(engine.PhaseWork), stale: true, want: "#000000", theme: "#101010"},
+	}
+	keys := map[string]string{"idle": "idle", "work": "work", "paused": "paused", "short break": "short", "long break": "long", "stale": "idle"}
+	for _, tt := range tests {
+		for _, themed := range []bool{false, true} {
+			name := tt.name
+			if themed {
+				name += " themed"
+			}
+			t.Run(name, func(t *testing.T) {
+				dir := isolate(t)
+				want := tt.want
+				if themed {
+					path := filepath.Join(dir, "pomo.toml")
+					toml := fmt.Sprintf("[theme]\n%s = %q\n", keys[tt.name], tt.theme)
+					if err := os.WriteFile(path, []byte(toml), 0o644); err != nil {
+						t.Fatal(err)
+					}
+					t.Setenv(config.EnvConfig, path)
+					want = tt.theme
+				}
+				if tt.e != nil {
+					publish(t, *tt.e)
+				}
+				if tt.stale {
+					path, _ := state.Path()
+					old := time.Now().Add(-49 * time.Hour)
+					if err := os.Chtimes(path, old, old); err != nil {
+						t.Fatal(err)
+					}
+				}
+				stdout, stderr, code := runCtl(t, "status", "--color-hex")
+				if code != 0 || stderr != "" {
+					t.Fatalf("exit code %d: %s", code, stderr)
+				}
+				if stdout != want+"\n" {
+					t.Errorf("printed %q, want %s", stdout, want)
+				}
+			})
+		}
+	}
+}
+
+// TestStatusColorHexFades checks theme.fade dims work 10m30s into 25m, in
+// tenths, to 1-0.4*(1-0.5) of full.
+func TestStatusColorHexFades(t *testing.T) {
+	dir := isolate(t)
+	path := filepath.Join(dir, "pomo.toml")
+	if err := os.WriteFile(path, []byte("[theme]\nfade = true\nmin_brightness = 0.5\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.EnvConfig, path)
+	e := pausedWork()
+	e.Paused = false
+	publish(t, e)
+	stdout, _, _ := runCtl(t, "status", "--color-hex")
+	if want := "#cc0000\n"; stdout != want {
+		t.Errorf("printed %q, want %q", stdout, want)
+	}
+}
+
+// TestStatusReadsEveryVersion has pomo status and pomo prompt read the
+// state file fixtures of each version, and refuse one newer than they know.
+func TestStatusReadsEveryVersion(t *testing.T) {
+	format := "{{.Phase}} {{.RemainingSeconds}} {{.Cycle}}/{{.TotalCycles}} {{.State}} [{{.Label}}]"
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"v0-first.json", "Work 870 2/4 paused []"},
+		{"v0.json", "Work 870 2/4 paused [essay]"},
+		{"v1.json", "Work 870 2/4 paused [essay]"},
+		{"v1-added.json", "Work 870 2/4 paused [essay]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			isolate(t)
+			copyState(t, tt.file)
+			stdout, stderr, code := runCtl(t, "status", "--format", format)
+			if code != 0 || stdout != tt.want+"\n" {
+				t.Errorf("status: %q, %q, exit code %d, want %q", stdout, stderr, code, tt.want)
+			}
+			var out strings.Builder
+			if err := execute(t, startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}, "prompt", "--shell", "none", "--format", format); err != nil || out.String() != tt.want {
+				t.Errorf("prompt: %q, %v, want %q", out.String(), err, tt.want)
+			}
+		})
+	}
+
+	t.Run("v2.json", func(t *testing.T) {
+		isolate(t)
+		copyState(t, "v2.json")
+		want := "state file is version 2, newer than the 1 this pomo reads — upgrade pomo\n"
+		if stdout, stderr, code := runCtl(t, "status"); code != exitError || stdout != "" || stderr != want {
+			t.Errorf("status: %q, %q, exit code %d, want %q and exit code %d", stdout, stderr, code, want, exitError)
+		}
+	})
+}
+
+// copyState makes the state file fixture name the running session's.
+func copyState(t *testing.T, name string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "state", "testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := state.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "End the running session after the current phase",
+	Long: `End the running session once the current phase is over. The phase is
+recorded as completed, and with --on-complete restart no new session starts.
+
+Stdout: "stopping". Exits like the ctl subcommands.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return ctlSend(cmd, "stop")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(stopCmd)
+}
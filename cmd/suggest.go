@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/history"
+)
+
+// suggestPlan offers the session history suggests for this time of week,
+// leaving alone whatever was given on the command line. explicit holds
+// those flags' names.
+func suggestPlan(env startEnv, cfg *engine.Config, explicit map[string]bool) {
+	out := env.stdout
+	path, err := history.Path()
+	if err != nil {
+		return
+	}
+	records, _ := history.Read(path)
+	minWork, _ := minWorkDuration()
+
+	s, err := history.Suggest(records, planClock.Now(), minWork)
+	if err != nil {
+		fmt.Fprintf(out, "No suggestion, %v; using the usual settings\n", err)
+		return
+	}
+
+	p := suggestedPlan(s, plan{workMinutes, shortBreakMinutes, cycles}, explicit, len(cfg.WorkTaper) > 0)
+	question := fmt.Sprintf("You usually manage %s on %s — start %d×%d/%d? [Y/n] ", countCycles(s.Cycles), s.Slot, p.cycles, p.work, p.short)
+	if suggestYes {
+		fmt.Fprintln(out, question+"y")
+	} else if !promptNotNo(env, question, promptTimeout) {
+		return
+	}
+
+	workMinutes, shortBreakMinutes, cycles = p.work, p.short, p.cycles
+	if len(cfg.WorkTaper) == 0 {
+		cfg.WorkDuration = time.Duration(p.work) * time.Minute
+	}
+	cfg.ShortBreakDuration = time.Duration(p.short) * time.Minute
+	cfg.TotalCycles = p.cycles
+}
+
+// plan is a session's work and short break minutes and its cycles.
+type plan struct {
+	work, short, cycles int
+}
+
+// suggestedPlan is given with what s suggests in place of each part, but
+// for those set explicitly, the work minutes when tapered, and lengths s
+// found no phase of.
+func suggestedPlan(s history.Suggestion, given plan, explicit map[string]bool, tapered bool) plan {
+	p := given
+	if !explicit["cycles"] {
+		p.cycles = s.Cycles
+	}
+	if s.Work > 0 && !explicit["pomodoro"] && !tapered {
+		p.work = int(s.Work.Round(time.Minute) / time.Minute)
+	}
+	if s.Break > 0 && !explicit["short"] {
+		p.short = int(s.Break.Round(time.Minute) / time.Minute)
+	}
+	return p
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/history"
+)
+
+func TestSuggestedPlan(t *testing.T) {
+	s := history.Suggestion{Cycles: 3, Work: 25 * time.Minute, Break: 5 * time.Minute}
+	given := plan{work: 50, short: 10, cycles: 0}
+	tests := []struct {
+		name     string
+		s        history.Suggestion
+		explicit []string
+		tapered  bool
+		want     plan
+	}{
+		{"all suggested", s, nil, false, plan{25, 5, 3}},
+		{"cycles given", s, []string{"cycles"}, false, plan{25, 5, 0}},
+		{"work given", s, []string{"pomodoro"}, false, plan{50, 5, 3}},
+		{"break given", s, []string{"short"}, false, plan{25, 10, 3}},
+		{"all given", s, []string{"cycles", "pomodoro", "short"}, false, plan{50, 10, 0}},
+		{"tapered", s, nil, true, plan{50, 5, 3}},
+		{"no work found", history.Suggestion{Cycles: 2, Break: 5 * time.Minute}, nil, false, plan{50, 5, 2}},
+		{"no break found", history.Suggestion{Cycles: 2, Work: 25 * time.Minute}, nil, false, plan{25, 10, 2}},
+		{"rounded to the minute", history.Suggestion{Cycles: 1, Work: 24*time.Minute + 40*time.Second, Break: 5*time.Minute + 20*time.Second}, nil, false, plan{25, 5, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explicit := make(map[string]bool)
+			for _, name := range tt.explicit {
+				explicit[name] = true
+			}
+			if got := suggestedPlan(tt.s, given, explicit, tt.tapered); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
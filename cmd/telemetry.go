@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/fsutil"
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/state"
+	"github.com/steenfuentes/pomo/telemetry"
+)
+
+var telemetryCmd = &cobra.Command{
+	Use:   "telemetry",
+	Short: "Check or turn off the opt-in usage ping",
+	Long: `pomo sends no usage data unless the config file turns it on:
+
+  [telemetry]
+  enabled = true
+  endpoint = "https://example.com/pomo"
+
+pomo never sets this, or asks to. Once on, pomo start POSTs at most once
+a day a random install ID, pomo's version, and how many pomodoros were
+completed since the last ping: no durations, labels, or times. pomo start
+--verbose prints the body before sending it.`,
+}
+
+var telemetryStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the usage ping is on, and when it last went",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		path, err := telemetryStatePath()
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		if !cfg.Telemetry.Enabled {
+			fmt.Fprintln(out, "Telemetry: off")
+			return nil
+		}
+		s, err := telemetry.ReadState(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Telemetry: on, to %s\n", cfg.Telemetry.Endpoint)
+		if s.LastSent.IsZero() {
+			fmt.Fprintln(out, "Last sent: never")
+		} else {
+			fmt.Fprintf(out, "Last sent: %s\n", s.LastSent.Local().Format(time.DateTime))
+		}
+		if s.InstallID != "" {
+			fmt.Fprintf(out, "Install ID: %s\n", s.InstallID)
+		}
+		return nil
+	},
+}
+
+var telemetryOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: "Turn the usage ping off and forget the install ID",
+	Long: `Set enabled = false in the config file's [telemetry] table, and remove
+the install ID, so pings sent if it is ever turned back on cannot be tied
+to earlier ones.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		path, err := telemetryStatePath()
+		if err != nil {
+			return err
+		}
+		if err := telemetry.Forget(path); err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		if !cfg.Telemetry.Enabled {
+			fmt.Fprintln(out, "Telemetry is already off")
+			return nil
+		}
+		if err := disableTelemetry(cfg.Path); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Telemetry off: set telemetry.enabled = false in %s\n", cfg.Path)
+		return nil
+	},
+}
+
+func init() {
+	telemetryCmd.AddCommand(telemetryStatusCmd, telemetryOffCmd)
+	rootCmd.AddCommand(telemetryCmd)
+}
+
+func telemetryStatePath() (string, error) {
+	dir, err := state.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "telemetry.json"), nil
+}
+
+// telemetryEnabled matches the enabled line of a [telemetry] table, up to
+// the next table.
+var telemetryEnabled = regexp.MustCompile(`(?m)(^\[telemetry\][ \t]*(?:#.*)?\n(?:[^\[].*\n|\n)*?[ \t]*enabled[ \t]*=[ \t]*)true\b`)
+
+// disableTelemetry rewrites the enabled line in place, leaving the rest of
+// the file, comments and all, as it was.
+func disableTelemetry(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !telemetryEnabled.Match(data) {
+		return fmt.Errorf("%s: no enabled = true line in [telemetry] to change; set it to false by hand", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data = telemetryEnabled.ReplaceAll(data, []byte("${1}false"))
+	return fsutil.WriteFileAtomic(path, data, info.Mode().Perm())
+}
+
+// reportUsage sends the usage ping if telemetry is on and one is due,
+// printing its body first with --verbose. It returns at once; the wait it
+// returns blocks until the ping is sent, or gives up after
+// telemetry.Timeout.
+func reportUsage(env startEnv, cfg *config.File) (wait func()) {
+	path, err := telemetryStatePath()
+	if err != nil {
+		return func() {}
+	}
+	r := telemetry.New(cfg.Telemetry.Enabled, cfg.Telemetry.Endpoint, path)
+	now := env.clock.Now()
+	body, err := r.Next(now, func(since time.Time) int {
+		path, err := history.Path()
+		if err != nil {
+			return 0
+		}
+		// Corrupt lines are skipped, as everywhere else history is read.
+		records, _ := history.Read(path)
+		return history.Summarize(history.Since(records, since), cfg.Stats.MinWorkDuration).Completed
+	})
+	if err != nil {
+		fmt.Fprintf(env.stderr, "Warning: telemetry: %v\n", err)
+		return func() {}
+	}
+	if body == nil {
+		return func() {}
+	}
+	if verbose {
+		fmt.Fprintf(env.stderr, "Telemetry: POST %s %s\n", cfg.Telemetry.Endpoint, body)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), telemetry.Timeout)
+		defer cancel()
+		// The session is likely on screen by now, so failures go to the log.
+		if err := r.Send(ctx, now, body); err != nil {
+			slog.Warn("telemetry failed", "err", err)
+		}
+	}()
+	return func() { <-done }
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/steenfuentes/pomo/config"
+)
+
+// TestStartTelemetry runs a session with telemetry off, and on, against a
+// server that fails the test if reached while off.
+func TestStartTelemetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		sends  bool
+	}{
+		{"no table", "", false},
+		{"off with an endpoint", "[telemetry]\nenabled = false\nendpoint = \"%s\"\n", false},
+		{"endpoint alone", "[telemetry]\nendpoint = \"%s\"\n", false},
+		{"on", "[telemetry]\nenabled = true\nendpoint = \"%s\"\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := isolate(t)
+			var mu sync.Mutex
+			var got []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if !tt.sends {
+					t.Errorf("telemetry off, but got %s %s", body, r.URL)
+				}
+				mu.Lock()
+				got = append(got, string(body))
+				mu.Unlock()
+			}))
+			defer srv.Close()
+			path := filepath.Join(dir, "config.toml")
+			if err := os.WriteFile(path, []byte(strings.ReplaceAll(tt.config, "%s", srv.URL)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv(config.EnvConfig, path)
+
+			r := startSession(t, "-c", "1", "-p", "1", "-s", "1", "--verbose")
+			if err := r.wait(t); err != nil {
+				t.Fatalf("start: %v\nstderr:\n%s", err, r.stderr.String())
+			}
+			statePath, err := telemetryStatePath()
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = os.Stat(statePath)
+			stderr := r.stderr.String()
+			if !tt.sends {
+				if !os.IsNotExist(err) {
+					t.Errorf("telemetry state written with telemetry off: %v", err)
+				}
+				if strings.Contains(stderr, "Telemetry") {
+					t.Errorf("--verbose printed a ping with telemetry off:\n%s", stderr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("no telemetry state after a ping: %v", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(got) != 1 {
+				t.Fatalf("server got %d pings, want 1", len(got))
+			}
+			if want := "Telemetry: POST " + srv.URL + " " + got[0] + "\n"; !strings.Contains(stderr, want) {
+				t.Errorf("--verbose did not print the body sent, %q:\n%s", want, stderr)
+			}
+		})
+	}
+}
+
+func TestTelemetryOff(t *testing.T) {
+	dir := isolate(t)
+	path := filepath.Join(dir, "config.toml")
+	toml := "# mine\n[telemetry]\nenabled = true # for the team\nendpoint = \"http://127.0.0.1:9\"\n\n[stats]\n"
+	if err := os.WriteFile(path, []byte(toml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	statePath, err := telemetryStatePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(statePath), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(statePath, []byte(`{"install_id":"abc"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) string {
+		t.Helper()
+		var out syncBuffer
+		env := startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}
+		if err := execute(t, env, append(args, "--config", path)...); err != nil {
+			t.Fatalf("%s: %v\n%s", strings.Join(args, " "), err, out.String())
+		}
+		return out.String()
+	}
+
+	if got, want := run("telemetry", "status"), "Telemetry: on, to http://127.0.0.1:9\nLast sent: never\nInstall ID: abc\n"; got != want {
+		t.Errorf("status printed %q, want %q", got, want)
+	}
+	if got, want := run("telemetry", "off"), "Telemetry off: set telemetry.enabled = false in "+path+"\n"; got != want {
+		t.Errorf("off printed %q, want %q", got, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(toml, "enabled = true", "enabled = false", 1); string(data) != want {
+		t.Errorf("config after off:\n%s\nwant:\n%s", data, want)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("install ID kept after off: %v", err)
+	}
+	if got := run("telemetry", "status"); got != "Telemetry: off\n" {
+		t.Errorf("status after off printed %q", got)
+	}
+	if got := run("telemetry", "off"); got != "Telemetry is already off\n" {
+		t.Errorf("off again printed %q", got)
+	}
+}
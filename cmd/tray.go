@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"fyne.io/systray"
+	"github.com/spf13/cobra"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/state"
+	"github.com/steenfuentes/pomo/tray"
+)
+
+const (
+	trayPollInterval = time.Second
+	// A session restarting with --on-complete restart briefly has no state
+	// file, so the tray only gives up after this many polls find none.
+	trayMissesToExit = 3
+)
+
+var errNoDisplay = errors.New("no display to show a tray icon on (is DISPLAY or WAYLAND_DISPLAY set?)")
+
+var trayCmd = &cobra.Command{
+	Use:   "tray",
+	Short: "Show the running session in the system tray",
+	Long: `Show the running session as a tray icon: a circle in the phase's color
+with the minutes left, and a menu to pause, skip, or stop. The icon goes
+away when the session ends.
+
+On Linux this needs a tray that supports StatusNotifierItem, such as KDE,
+or GNOME with the AppIndicator extension.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if !displayAvailable() {
+			return errNoDisplay
+		}
+		statePath, err := state.Path()
+		if err != nil {
+			return err
+		}
+		socketPath, err := state.SocketPath()
+		if err != nil {
+			return err
+		}
+		if _, err := readState(statePath); err != nil {
+			return err
+		}
+
+		failed := make(chan error, 1)
+		systray.Run(func() {
+			runTray(statePath, func(command string) {
+				if _, err := state.Send(socketPath, command); err != nil && !errors.Is(err, state.ErrNotRunning) {
+					select {
+					case failed <- err:
+					default:
+					}
+					systray.Quit()
+				}
+			})
+		}, nil)
+		select {
+		case err := <-failed:
+			return err
+		default:
+			return nil
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(trayCmd)
+}
+
+func displayAvailable() bool {
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		return true
+	default:
+		return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+	}
+}
+
+// runTray builds the menu and keeps the icon in step with the state file
+// until the session is gone.
+func runTray(statePath string, send func(command string)) {
+	pause := systray.AddMenuItem("Pause", "Pause or resume the session")
+	skip := systray.AddMenuItem("Skip", "Skip to the next phase")
+	stop := systray.AddMenuItem("Stop", "End the session after the current phase")
+	systray.AddSeparator()
+	quit := systray.AddMenuItem("Close tray icon", "Leave the session running without the icon")
+
+	go func() {
+		for {
+			select {
+			case <-pause.ClickedCh:
+				send("toggle-pause")
+			case <-skip.ClickedCh:
+				send("skip")
+			case <-stop.ClickedCh:
+				send("stop")
+			case <-quit.ClickedCh:
+				systray.Quit()
+				return
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(trayPollInterval)
+		defer ticker.Stop()
+
+		var shown trayView
+		misses := 0
+		for ; ; <-ticker.C {
+			s, err := readState(statePath)
+			if err != nil {
+				if misses++; misses >= trayMissesToExit {
+					systray.Quit()
+					return
+				}
+				continue
+			}
+			misses = 0
+
+			v := viewOf(s, time.Now())
+			if v == shown {
+				continue
+			}
+			if v.icon != shown.icon {
+				systray.SetIcon(tray.Icon(v.icon.phase, v.icon.minutes, v.icon.paused))
+			}
+			systray.SetTitle(v.title)
+			systray.SetTooltip(v.tooltip)
+			if v.icon.paused {
+				pause.SetTitle("Resume")
+			} else {
+				pause.SetTitle("Pause")
+			}
+			shown = v
+		}
+	}()
+}
+
+type trayIcon struct {
+	phase   engine.Phase
+	minutes int
+	paused  bool
+}
+
+// trayView is what the tray shows, compared between polls so the icon is
+// only redrawn when it changes.
+type trayView struct {
+	icon    trayIcon
+	title   string
+	tooltip string
+}
+
+func viewOf(s state.State, now time.Time) trayView {
+	e := s.EventAt(now)
+	minutes := int((e.Remaining + time.Minute - 1) / time.Minute)
+
+	tooltip := fmt.Sprintf("%s, %dm left", e.Phase, minutes)
+	if e.Paused {
+		tooltip += " (paused)"
+	}
+	return trayView{
+		icon:    trayIcon{phase: e.Phase, minutes: minutes, paused: e.Paused},
+		title:   fmt.Sprintf("%dm", minutes),
+		tooltip: tooltip,
+	}
+}
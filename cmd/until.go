@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// Most cycles --until plans, however far off it is.
+const maxUntilCycles = 100
+
+// parseUntil takes a time of day like 17:30 as its next occurrence after
+// now.
+func parseUntil(s string, now time.Time) (time.Time, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --until %q (want a time of day like 17:30)", s)
+	}
+	y, m, d := now.Date()
+	deadline := time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !deadline.After(now) {
+		deadline = deadline.AddDate(0, 0, 1)
+	}
+	return deadline, nil
+}
+
+// fitUntil gives cfg as many whole cycles as end by deadline. With fill it
+// then adds a last, shorter work phase in the time left, unless that is
+// under minFill.
+func fitUntil(cfg engine.Config, now, deadline time.Time, fill bool, minFill time.Duration) engine.Config {
+	left := deadline.Sub(now)
+	cfg.FinalWorkDuration = 0
+	cfg.TotalCycles = 0
+	for n := 1; n <= maxUntilCycles; n++ {
+		next := cfg
+		next.TotalCycles = n
+		start, end := lastWork(next)
+		if end <= left {
+			cfg = next
+			continue
+		}
+		if fill && left-start >= minFill {
+			cfg.TotalCycles, cfg.FinalWorkDuration = n, left-start
+		}
+		break
+	}
+	return cfg
+}
+
+// lastWork is when the last work phase in cfg's plan starts and ends, from
+// the start of the session.
+func lastWork(cfg engine.Config) (start, end time.Duration) {
+	plan := engine.NewSession(cfg).Plan(0)
+	for i := len(plan) - 1; i >= 0; i-- {
+		if p := plan[i]; p.Phase == engine.PhaseWork {
+			return p.Offset, p.Offset + p.Duration
+		}
+	}
+	return 0, 0
+}
+
+// parseLunch reads a --lunch window like "12:00-13:30" into an
+// engine.Lunch with only its times of day set.
+func parseLunch(s string) (engine.Lunch, error) {
+	invalid := fmt.Errorf("invalid --lunch %q (want a window of the day like 12:00-13:30)", s)
+	from, until, ok := strings.Cut(s, "-")
+	if !ok {
+		return engine.Lunch{}, invalid
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return engine.Lunch{}, invalid
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(until))
+	if err != nil || !end.After(start) {
+		return engine.Lunch{}, invalid
+	}
+	midnight := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+	return engine.Lunch{From: start.Sub(midnight), Until: end.Sub(midnight)}, nil
+}
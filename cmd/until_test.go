@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+func TestParseLunch(t *testing.T) {
+	for s, want := range map[string]engine.Lunch{
+		"12:00-13:30":     {From: 12 * time.Hour, Until: 13*time.Hour + 30*time.Minute},
+		" 11:45 - 13:00 ": {From: 11*time.Hour + 45*time.Minute, Until: 13 * time.Hour},
+		"00:00-23:59":     {Until: 23*time.Hour + 59*time.Minute},
+	} {
+		got, err := parseLunch(s)
+		if err != nil || got != want {
+			t.Errorf("parseLunch(%q) = %+v, %v, want %+v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"12:00", "noon-13:00", "12:00-1pm", "13:30-12:00", "12:00-12:00", "25:00-26:00", ""} {
+		if _, err := parseLunch(s); err == nil || err.Error() != "invalid --lunch \""+s+"\" (want a window of the day like 12:00-13:30)" {
+			t.Errorf("parseLunch(%q): %v", s, err)
+		}
+	}
+}
+
+// TestStartLunch runs sessions whose second long break, 4m in, starts in
+// the lunch window, or that never reach one, taking lunch, offering it,
+// and doing neither.
+func TestStartLunch(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		lunch []bool
+		longs []time.Duration
+	}{
+		{"auto", []string{"--lunch", "09:02-09:30", "--lunch-duration", "10m", "--auto-lunch"}, []bool{false, true}, []time.Duration{2 * time.Minute, 10 * time.Minute}},
+		// Offered, but never extended to.
+		{"offered", []string{"--lunch", "09:02-09:30", "--lunch-duration", "10m"}, []bool{false, false}, []time.Duration{2 * time.Minute, 2 * time.Minute}},
+		{"window missed", []string{"--lunch", "12:00-13:30", "--lunch-duration", "10m", "--auto-lunch"}, []bool{false, false}, []time.Duration{2 * time.Minute, 2 * time.Minute}},
+		{"no window", nil, []bool{false, false}, []time.Duration{2 * time.Minute, 2 * time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolate(t)
+			args := append([]string{"-c", "3", "-p", "1", "-s", "1", "-l", "2", "-e", "1"}, tt.args...)
+			r := startSession(t, args...)
+			if err := r.wait(t); err != nil {
+				t.Fatalf("start: %v\nstderr:\n%s", err, r.stderr.String())
+			}
+			var lunch []bool
+			var longs []time.Duration
+			for _, rec := range readRecords(t) {
+				if rec.Phase == engine.PhaseLongBreak {
+					lunch = append(lunch, rec.Lunch)
+					longs = append(longs, rec.Actual())
+				} else if rec.Lunch {
+					t.Errorf("%s recorded as lunch", rec.Phase)
+				}
+			}
+			if !slices.Equal(lunch, tt.lunch) || !slices.Equal(longs, tt.longs) {
+				t.Errorf("long breaks ran %v, lunch %v, want %v, %v", longs, lunch, tt.longs, tt.lunch)
+			}
+
+			var out syncBuffer
+			if err := execute(t, startEnv{stdin: strings.NewReader(""), stdout: &out, stderr: &out}, "history"); err != nil {
+				t.Fatal(err)
+			}
+			want := 0
+			for _, l := range tt.lunch {
+				if l {
+					want++
+				}
+			}
+			if got := strings.Count(out.String(), "lunch"); got != want {
+				t.Errorf("history notes lunch on %d rows, want %d:\n%s", got, want, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,525 @@
+// Package config loads pomo's TOML configuration file.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/steenfuentes/pomo/paths"
+)
+
+// File holds the config file's top-level settings, which apply whenever
+// nothing more specific sets them, and its named profiles.
+type File struct {
+	Path      string
+	Values    map[string][]string
+	Profiles  map[string]Profile
+	Stats     Stats
+	State     State
+	Rewards   Rewards
+	Theme     Theme
+	Sounds    Sounds
+	Defaults  Defaults
+	Telemetry Telemetry
+	Providers map[string]Provider
+}
+
+// DefaultMinWorkDuration keeps test runs out of stats.
+const DefaultMinWorkDuration = 10 * time.Minute
+
+// Stats holds the [stats] table, which tunes how history is reported.
+type Stats struct {
+	// MinWorkDuration is the shortest planned work phase that counts as
+	// focus time (0 = all).
+	MinWorkDuration time.Duration
+	// Link is where a record's ID links to in a terminal that supports
+	// hyperlinks, with {id} as the ID.
+	Link string
+}
+
+// DefaultHistoryLink is the link a record's ID gets unless stats.link
+// says otherwise.
+const DefaultHistoryLink = "pomo://history/{id}"
+
+// DefaultStaleAfter leaves room for a few of the state file's refreshes to
+// be missed, e.g. while the machine sleeps.
+const DefaultStaleAfter = 5 * time.Minute
+
+// State holds the [state] table, which tunes how the state file is read.
+type State struct {
+	// StaleAfter is how old the state file can be before it is taken to be
+	// left behind by a session that died (0 = never).
+	StaleAfter time.Duration
+}
+
+// Rewards holds the [rewards] table, which celebrates every Every-th
+// pomodoro completed in a day (0 = never).
+type Rewards struct {
+	Every int
+	// Messages are shown in turn, with {count} as the day's pomodoros.
+	Messages []string
+	// Command runs at each milestone, killed after Timeout.
+	Command string
+	Timeout time.Duration
+}
+
+// Theme holds the [theme] table, the colors lights are told to show:
+// "#rrggbb" values keyed work, short, long, cooldown, paused, and idle, with
+// missing ones left at their defaults. Fade dims a phase's color as it
+// goes, down to MinBrightness.
+type Theme struct {
+	Colors        map[string]string
+	Fade          bool
+	MinBrightness float64
+}
+
+const DefaultMinBrightness = 0.2
+
+// Sounds holds the [sounds] table: what plays for each cue, keyed
+// work_start, break_start, long_break_start, session_done, and warning,
+// each "bell", "none", or the path of a sound file. Volume, from 0 to 1,
+// is passed to players that take one.
+type Sounds struct {
+	Cues   map[string]string
+	Volume float64
+}
+
+const DefaultVolume = 1.0
+
+// Defaults holds the [defaults] table: the profile pomo start runs when
+// given neither a profile nor a schedule, by weekday, e.g. monday =
+// "short", falling back to Profile for the days not named.
+type Defaults struct {
+	Weekdays map[time.Weekday]string
+	Profile  string
+}
+
+// ForDay is the profile Defaults picks on day, if any, and whether a
+// rule for that weekday picked it.
+func (d Defaults) ForDay(day time.Weekday) (profile string, weekday bool) {
+	if p, ok := d.Weekdays[day]; ok {
+		return p, true
+	}
+	return d.Profile, false
+}
+
+// Telemetry holds the [telemetry] table. The daily usage ping is sent to
+// Endpoint only if Enabled is set, which pomo never does on its own.
+type Telemetry struct {
+	Enabled  bool
+	Endpoint string
+}
+
+var themeColors = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// DefaultRewardMessages are the messages when the [rewards] table names
+// none.
+var DefaultRewardMessages = []string{
+	"{count} pomodoros today, nicely done!",
+	"{count} down today, keep it going!",
+	"{count} today, that's a streak!",
+}
+
+// Dir is where the config file is looked for, as paths.Config has it.
+func Dir() (string, error) {
+	return paths.Config()
+}
+
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.toml"), nil
+}
+
+// Load reads the config at path. A missing file is not an error and yields
+// an empty config.
+func Load(path string) (*File, error) {
+	var raw map[string]any
+	if _, err := toml.DecodeFile(path, &raw); err != nil {
+		if os.IsNotExist(err) {
+			return &File{Path: path, Stats: Stats{MinWorkDuration: DefaultMinWorkDuration, Link: DefaultHistoryLink}, State: State{StaleAfter: DefaultStaleAfter}, Rewards: defaultRewards(), Theme: Theme{MinBrightness: DefaultMinBrightness}, Sounds: Sounds{Volume: DefaultVolume}}, nil
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	f := &File{
+		Path:      path,
+		Values:    make(map[string][]string),
+		Profiles:  make(map[string]Profile),
+		Stats:     Stats{MinWorkDuration: DefaultMinWorkDuration, Link: DefaultHistoryLink},
+		State:     State{StaleAfter: DefaultStaleAfter},
+		Rewards:   defaultRewards(),
+		Theme:     Theme{MinBrightness: DefaultMinBrightness},
+		Sounds:    Sounds{Volume: DefaultVolume},
+		Providers: make(map[string]Provider),
+	}
+	for key, v := range raw {
+		if key == "stats" {
+			if err := f.Stats.parse(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if key == "state" {
+			if err := f.State.parse(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if key == "rewards" {
+			if err := f.Rewards.parse(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if key == "theme" {
+			if err := f.Theme.parse(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if key == "sounds" {
+			if err := f.Sounds.parse(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if key == "defaults" {
+			if err := f.Defaults.parse(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if key == "telemetry" {
+			if err := f.Telemetry.parse(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if key == "providers" {
+			if err := f.parseProviders(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			continue
+		}
+		if key != "profiles" {
+			f.Values[key] = stringValues(v)
+			continue
+		}
+
+		profiles, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: profiles must be a table", path)
+		}
+		for name, t := range profiles {
+			table, ok := t.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: profile %q must be a table", path, name)
+			}
+			p, err := parseProfile(name, table)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			f.Profiles[name] = p
+		}
+	}
+	if err := f.checkDefaults(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// checkDefaults makes sure every profile [defaults] names exists, which
+// can only be known once the whole file is read.
+func (f *File) checkDefaults() error {
+	check := func(key, name string) error {
+		if _, ok := f.Profiles[name]; name != "" && !ok {
+			return fmt.Errorf("defaults.%s: unknown profile %q", key, name)
+		}
+		return nil
+	}
+	if err := check("profile", f.Defaults.Profile); err != nil {
+		return err
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if err := check(strings.ToLower(day.String()), f.Defaults.Weekdays[day]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *File) parseProviders(v any) error {
+	providers, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("providers must be a table")
+	}
+	for name, t := range providers {
+		table, ok := t.(map[string]any)
+		if !ok {
+			return fmt.Errorf("provider %q must be a table", name)
+		}
+		p, err := parseProvider(name, table)
+		if err != nil {
+			return err
+		}
+		f.Providers[name] = p
+	}
+	return nil
+}
+
+func (s *Stats) parse(v any) error {
+	table, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("stats must be a table")
+	}
+	for key, v := range table {
+		str, _ := v.(string)
+		switch key {
+		case "min_work_duration":
+			d, err := time.ParseDuration(str)
+			if err != nil || d < 0 {
+				return fmt.Errorf("invalid stats.min_work_duration %v (want a duration like \"10m\")", v)
+			}
+			s.MinWorkDuration = d
+		case "link":
+			if !strings.Contains(str, "{id}") {
+				return fmt.Errorf("invalid stats.link %v (want a URL with {id} in it)", v)
+			}
+			s.Link = str
+		default:
+			return fmt.Errorf("unknown setting stats.%s", key)
+		}
+	}
+	return nil
+}
+
+func (s *State) parse(v any) error {
+	table, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("state must be a table")
+	}
+	for key, v := range table {
+		if key != "stale_after" {
+			return fmt.Errorf("unknown setting state.%s", key)
+		}
+		str, _ := v.(string)
+		d, err := time.ParseDuration(str)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid state.stale_after %v (want a duration like \"5m\")", v)
+		}
+		s.StaleAfter = d
+	}
+	return nil
+}
+
+func defaultRewards() Rewards {
+	return Rewards{Messages: DefaultRewardMessages, Timeout: DefaultProviderTimeout}
+}
+
+func (r *Rewards) parse(v any) error {
+	table, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("rewards must be a table")
+	}
+	for key, v := range table {
+		switch key {
+		case "every":
+			n, ok := v.(int64)
+			if !ok || n < 0 {
+				return fmt.Errorf("invalid rewards.every %v (want a whole number, 0 = off)", v)
+			}
+			r.Every = int(n)
+		case "messages":
+			list, ok := v.([]any)
+			if !ok || len(list) == 0 {
+				return fmt.Errorf("rewards.messages must be a list of strings")
+			}
+			r.Messages = nil
+			for _, m := range list {
+				s, ok := m.(string)
+				if !ok {
+					return fmt.Errorf("rewards.messages must be a list of strings")
+				}
+				r.Messages = append(r.Messages, s)
+			}
+		case "command":
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("rewards.command must be a string")
+			}
+			r.Command = s
+		case "timeout":
+			s, _ := v.(string)
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("invalid rewards.timeout %v (want a duration like \"10s\")", v)
+			}
+			r.Timeout = d
+		default:
+			return fmt.Errorf("unknown setting rewards.%s", key)
+		}
+	}
+	return nil
+}
+
+func (t *Theme) parse(v any) error {
+	table, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("theme must be a table")
+	}
+	for key, v := range table {
+		switch key {
+		case "work", "short", "long", "cooldown", "paused", "idle":
+			s, _ := v.(string)
+			if !themeColors.MatchString(s) {
+				return fmt.Errorf("invalid theme.%s %v (want a color like \"#ff0000\")", key, v)
+			}
+			if t.Colors == nil {
+				t.Colors = make(map[string]string)
+			}
+			t.Colors[key] = s
+		case "fade":
+			b, ok := v.(bool)
+			if !ok {
+				return fmt.Errorf("theme.fade must be true or false")
+			}
+			t.Fade = b
+		case "min_brightness":
+			f, ok := v.(float64)
+			if i, isInt := v.(int64); isInt {
+				f, ok = float64(i), true
+			}
+			if !ok || f < 0 || f > 1 {
+				return fmt.Errorf("invalid theme.min_brightness %v (want a fraction between 0 and 1)", v)
+			}
+			t.MinBrightness = f
+		default:
+			return fmt.Errorf("unknown setting theme.%s", key)
+		}
+	}
+	return nil
+}
+
+func (d *Defaults) parse(v any) error {
+	table, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("defaults must be a table")
+	}
+	for key, v := range table {
+		name, ok := v.(string)
+		if !ok || name == "" {
+			return fmt.Errorf("defaults.%s must be a profile name", key)
+		}
+		if key == "profile" {
+			d.Profile = name
+			continue
+		}
+		day, ok := weekdays[key]
+		if !ok {
+			return fmt.Errorf("unknown setting defaults.%s (want a weekday, e.g. monday, or profile)", key)
+		}
+		if d.Weekdays == nil {
+			d.Weekdays = make(map[time.Weekday]string)
+		}
+		d.Weekdays[day] = name
+	}
+	return nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+func (s *Sounds) parse(v any) error {
+	table, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("sounds must be a table")
+	}
+	for key, v := range table {
+		switch key {
+		case "work_start", "break_start", "long_break_start", "session_done", "warning":
+			str, ok := v.(string)
+			if !ok || str == "" {
+				return fmt.Errorf("invalid sounds.%s %v (want \"bell\", \"none\", or a file path)", key, v)
+			}
+			if s.Cues == nil {
+				s.Cues = make(map[string]string)
+			}
+			s.Cues[key] = str
+		case "volume":
+			f, ok := v.(float64)
+			if i, isInt := v.(int64); isInt {
+				f, ok = float64(i), true
+			}
+			if !ok || f < 0 || f > 1 {
+				return fmt.Errorf("invalid sounds.volume %v (want a fraction between 0 and 1)", v)
+			}
+			s.Volume = f
+		default:
+			return fmt.Errorf("unknown setting sounds.%s", key)
+		}
+	}
+	return nil
+}
+
+func (t *Telemetry) parse(v any) error {
+	table, ok := v.(map[string]any)
+	if !ok {
+		return fmt.Errorf("telemetry must be a table")
+	}
+	for key, v := range table {
+		switch key {
+		case "enabled":
+			b, ok := v.(bool)
+			if !ok {
+				return fmt.Errorf("invalid telemetry.enabled %v (want true or false)", v)
+			}
+			t.Enabled = b
+		case "endpoint":
+			str, ok := v.(string)
+			if !ok || !strings.HasPrefix(str, "https://") && !strings.HasPrefix(str, "http://") {
+				return fmt.Errorf("invalid telemetry.endpoint %v (want an http or https URL)", v)
+			}
+			t.Endpoint = str
+		default:
+			return fmt.Errorf("unknown setting telemetry.%s", key)
+		}
+	}
+	if t.Enabled && t.Endpoint == "" {
+		return fmt.Errorf("telemetry.enabled needs telemetry.endpoint")
+	}
+	return nil
+}
+
+func (f *File) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f *File) ProviderNames() []string {
+	names := make([]string, 0, len(f.Providers))
+	for name := range f.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/steenfuentes/pomo/paths"
+)
+
+// EnvPrefix marks environment variables that override config settings,
+// e.g. POMO_LONG_EVERY=3 for long-every.
+const EnvPrefix = "POMO_"
+
+// EnvConfig names the config file, like the --config flag.
+const EnvConfig = EnvPrefix + "CONFIG"
+
+// EnvNow stands in for the current time when planning, like the --now
+// flag.
+const EnvNow = EnvPrefix + "NOW"
+
+// Source is where a setting came from. Later sources take precedence.
+type Source int
+
+const (
+	SourceDefault Source = iota
+	SourceFile
+	SourceEnv
+	SourceProfile
+	SourceFlag
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceFile:
+		return "config file"
+	case SourceEnv:
+		return "environment"
+	case SourceProfile:
+		return "profile"
+	case SourceFlag:
+		return "flag"
+	default:
+		return "unknown"
+	}
+}
+
+// Layer is one source's settings. Origin says where exactly, e.g. the file
+// path, variable name, or profile name, keyed like Values.
+type Layer struct {
+	Source Source
+	Values map[string][]string
+	Origin map[string]string
+}
+
+type Setting struct {
+	Key    string
+	Values []string
+	Source Source
+	Origin string
+	// Overrides are the settings of the same key this one took precedence
+	// over, highest first.
+	Overrides []Setting
+}
+
+// Over is s having taken precedence over prev, which it keeps in
+// Overrides along with what prev itself overrode.
+func (s Setting) Over(prev Setting) Setting {
+	overrides := prev.Overrides
+	prev.Overrides = nil
+	s.Overrides = append([]Setting{prev}, overrides...)
+	return s
+}
+
+// Merge picks each key's values from the highest-precedence layer that sets
+// it. Layers of the same source are applied in order, later ones winning.
+func Merge(layers ...Layer) map[string]Setting {
+	sorted := make([]Layer, len(layers))
+	copy(sorted, layers)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Source < sorted[j].Source })
+
+	merged := make(map[string]Setting)
+	for _, l := range sorted {
+		for key, values := range l.Values {
+			s := Setting{Key: key, Values: values, Source: l.Source, Origin: l.Origin[key]}
+			if prev, ok := merged[key]; ok {
+				s = s.Over(prev)
+			}
+			merged[key] = s
+		}
+	}
+	return merged
+}
+
+func (f *File) Layer() Layer {
+	l := Layer{Source: SourceFile, Values: f.Values, Origin: make(map[string]string, len(f.Values))}
+	for key := range f.Values {
+		l.Origin[key] = f.Path
+	}
+	return l
+}
+
+func (p Profile) Layer(args []string) (Layer, error) {
+	values, err := p.Resolve(args)
+	if err != nil {
+		return Layer{}, err
+	}
+
+	l := Layer{Source: SourceProfile, Values: values, Origin: make(map[string]string, len(values))}
+	for key := range values {
+		l.Origin[key] = p.Name
+	}
+	return l, nil
+}
+
+// EnvLayer reads POMO_* variables from environ (as from os.Environ), mapping
+// POMO_LONG_EVERY to the key long-every. POMO_CONFIG, POMO_DATA_DIR, and
+// POMO_NOW are not settings.
+func EnvLayer(environ []string) Layer {
+	l := Layer{Source: SourceEnv, Values: make(map[string][]string), Origin: make(map[string]string)}
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) || name == EnvConfig || name == EnvNow || name == paths.EnvDataDir {
+			continue
+		}
+
+		key := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, EnvPrefix), "_", "-"))
+		if key == "" {
+			continue
+		}
+		l.Values[key] = []string{value}
+		l.Origin[key] = name
+	}
+	return l
+}
+
+// EnvName is the variable that overrides key.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
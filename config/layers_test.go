@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// TestMergePrecedence sets one key in every combination of sources, each
+// layer to its own source's name, and checks the highest wins, keeping the
+// rest as overrides highest first.
+func TestMergePrecedence(t *testing.T) {
+	sources := []Source{SourceFile, SourceEnv, SourceProfile, SourceFlag}
+	for mask := 1; mask < 1<<len(sources); mask++ {
+		var set []Source
+		for i, s := range sources {
+			if mask&(1<<i) != 0 {
+				set = append(set, s)
+			}
+		}
+		t.Run(fmt.Sprint(set), func(t *testing.T) {
+			// Given lowest first and highest first, to show order of
+			// arguments does not matter.
+			for _, reversed := range []bool{false, true} {
+				layers := make([]Layer, len(set))
+				for i, s := range set {
+					if reversed {
+						i = len(set) - 1 - i
+					}
+					layers[i] = Layer{
+						Source: s,
+						Values: map[string][]string{"pomodoro": {s.String()}},
+						Origin: map[string]string{"pomodoro": "origin of " + s.String()},
+					}
+				}
+
+				got, ok := Merge(layers...)["pomodoro"]
+				if !ok {
+					t.Fatal("pomodoro not merged")
+				}
+				want := set[len(set)-1]
+				if got.Source != want || got.Values[0] != want.String() || got.Origin != "origin of "+want.String() {
+					t.Errorf("reversed=%v: got %s=%v from %q, want %s", reversed, got.Source, got.Values, got.Origin, want)
+				}
+				var beaten []Source
+				for _, o := range got.Overrides {
+					if o.Overrides != nil {
+						t.Errorf("reversed=%v: override %s keeps its own overrides", reversed, o.Source)
+					}
+					beaten = append(beaten, o.Source)
+				}
+				var wantBeaten []Source
+				for i := len(set) - 2; i >= 0; i-- {
+					wantBeaten = append(wantBeaten, set[i])
+				}
+				if !reflect.DeepEqual(beaten, wantBeaten) {
+					t.Errorf("reversed=%v: overrides %v, want %v", reversed, beaten, wantBeaten)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeKeepsKeysApart(t *testing.T) {
+	got := Merge(
+		Layer{Source: SourceFlag, Values: map[string][]string{"short": {"3"}}},
+		Layer{Source: SourceFile, Values: map[string][]string{"pomodoro": {"50"}, "short": {"10"}}},
+		Layer{Source: SourceEnv, Values: map[string][]string{"cycles": {"4"}}},
+	)
+	want := map[string]Source{"pomodoro": SourceFile, "short": SourceFlag, "cycles": SourceEnv}
+	if len(got) != len(want) {
+		t.Fatalf("merged %d keys, want %d", len(got), len(want))
+	}
+	for key, source := range want {
+		if got[key].Source != source {
+			t.Errorf("%s from %s, want %s", key, got[key].Source, source)
+		}
+	}
+	if len(got["pomodoro"].Overrides) != 0 {
+		t.Errorf("pomodoro overrides %v, want none", got["pomodoro"].Overrides)
+	}
+}
+
+func TestMergeSameSourceLaterWins(t *testing.T) {
+	got := Merge(
+		Layer{Source: SourceProfile, Values: map[string][]string{"pomodoro": {"25"}}, Origin: map[string]string{"pomodoro": "a"}},
+		Layer{Source: SourceProfile, Values: map[string][]string{"pomodoro": {"30"}}, Origin: map[string]string{"pomodoro": "b"}},
+	)["pomodoro"]
+	if got.Origin != "b" || got.Values[0] != "30" {
+		t.Errorf("got %v from %q, want [30] from b", got.Values, got.Origin)
+	}
+}
+
+func TestEnvLayer(t *testing.T) {
+	l := EnvLayer([]string{
+		"POMO_POMODORO=30",
+		"POMO_LONG_EVERY=3",
+		"POMO_CONFIG=/tmp/pomo.toml",
+		"POMO_NOW=09:00",
+		"POMO_DATA_DIR=/tmp/pomo",
+		"POMO_=x",
+		"HOME=/root",
+		"POMO_BROKEN",
+	})
+	want := map[string][]string{"pomodoro": {"30"}, "long-every": {"3"}}
+	if !reflect.DeepEqual(l.Values, want) {
+		t.Errorf("values %v, want %v", l.Values, want)
+	}
+	if l.Origin["long-every"] != "POMO_LONG_EVERY" {
+		t.Errorf("long-every from %q, want POMO_LONG_EVERY", l.Origin["long-every"])
+	}
+	for key := range want {
+		if name := EnvName(key); name != l.Origin[key] {
+			t.Errorf("EnvName(%q) = %q, want %q", key, name, l.Origin[key])
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Profile is a named set of start flag values, keyed by flag name or
+// shorthand. Values may reference declared parameters as {name}, which are
+// filled from the arguments following the profile name.
+type Profile struct {
+	Name   string
+	Params []Param
+	Values map[string][]string
+}
+
+type Param struct {
+	Name       string
+	Type       string
+	Default    string
+	HasDefault bool
+}
+
+var placeholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+func parseProfile(name string, table map[string]any) (Profile, error) {
+	p := Profile{Name: name, Values: make(map[string][]string)}
+
+	for key, v := range table {
+		if key == "params" {
+			decls, ok := v.([]any)
+			if !ok {
+				return p, fmt.Errorf("profile %q: params must be a list of strings", name)
+			}
+			for _, d := range decls {
+				s, ok := d.(string)
+				if !ok {
+					return p, fmt.Errorf("profile %q: params must be a list of strings", name)
+				}
+				param, err := parseParam(s)
+				if err != nil {
+					return p, fmt.Errorf("profile %q: %w", name, err)
+				}
+				p.Params = append(p.Params, param)
+			}
+			continue
+		}
+
+		p.Values[key] = stringValues(v)
+	}
+
+	for key, values := range p.Values {
+		for _, v := range values {
+			for _, m := range placeholder.FindAllStringSubmatch(v, -1) {
+				if _, ok := p.param(m[1]); !ok {
+					return p, fmt.Errorf("profile %q: %s uses undeclared parameter %q", name, key, m[1])
+				}
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// stringValues flattens a TOML value into flag values, one per list item.
+func stringValues(v any) []string {
+	list, ok := v.([]any)
+	if !ok {
+		return []string{fmt.Sprint(v)}
+	}
+	values := make([]string, len(list))
+	for i, item := range list {
+		values[i] = fmt.Sprint(item)
+	}
+	return values
+}
+
+// parseParam reads declarations of the form name[:type][=default], where
+// type is int, duration, or string (the default).
+func parseParam(decl string) (Param, error) {
+	var p Param
+	decl, p.Default, p.HasDefault = strings.Cut(decl, "=")
+	p.Name, p.Type, _ = strings.Cut(decl, ":")
+	if p.Type == "" {
+		p.Type = "string"
+	}
+
+	if !placeholder.MatchString("{" + p.Name + "}") {
+		return p, fmt.Errorf("invalid parameter name %q", p.Name)
+	}
+	switch p.Type {
+	case "int", "duration", "string":
+	default:
+		return p, fmt.Errorf("parameter %q: unknown type %q (want int, duration, or string)", p.Name, p.Type)
+	}
+	if p.HasDefault {
+		if err := p.check(p.Default); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+func (p Param) check(v string) error {
+	switch p.Type {
+	case "int":
+		if _, err := strconv.Atoi(v); err != nil {
+			return fmt.Errorf("parameter %q: %q is not an int", p.Name, v)
+		}
+	case "duration":
+		if _, err := time.ParseDuration(v); err != nil {
+			return fmt.Errorf("parameter %q: %q is not a duration", p.Name, v)
+		}
+	}
+	return nil
+}
+
+func (p Param) String() string {
+	s := p.Name + ":" + p.Type
+	if p.HasDefault {
+		return "[" + s + "=" + p.Default + "]"
+	}
+	return "<" + s + ">"
+}
+
+func (p Profile) param(name string) (Param, bool) {
+	for _, param := range p.Params {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return Param{}, false
+}
+
+// Signature describes the profile's parameters, e.g. "<n:int> [work:duration=25m]".
+func (p Profile) Signature() string {
+	parts := make([]string, len(p.Params))
+	for i, param := range p.Params {
+		parts[i] = param.String()
+	}
+	return strings.Join(parts, " ")
+}
+
+// Resolve binds args, given positionally in declaration order or as
+// name=value, and returns the profile's values with parameters filled in.
+func (p Profile) Resolve(args []string) (map[string][]string, error) {
+	bound := make(map[string]string)
+	next := 0
+
+	for _, arg := range args {
+		name, value, named := strings.Cut(arg, "=")
+		if !named {
+			for next < len(p.Params) && bound[p.Params[next].Name] != "" {
+				next++
+			}
+			if next >= len(p.Params) {
+				return nil, fmt.Errorf("profile %q takes %d parameter(s), got extra argument %q", p.Name, len(p.Params), arg)
+			}
+			name, value = p.Params[next].Name, arg
+			next++
+		}
+
+		param, ok := p.param(name)
+		if !ok {
+			return nil, fmt.Errorf("profile %q has no parameter %q", p.Name, name)
+		}
+		if err := param.check(value); err != nil {
+			return nil, err
+		}
+		bound[name] = value
+	}
+
+	for _, param := range p.Params {
+		if _, ok := bound[param.Name]; ok {
+			continue
+		}
+		if !param.HasDefault {
+			return nil, fmt.Errorf("profile %q: missing parameter %q (%s)", p.Name, param.Name, param.Type)
+		}
+		bound[param.Name] = param.Default
+	}
+
+	values := make(map[string][]string, len(p.Values))
+	for key, vs := range p.Values {
+		for _, v := range vs {
+			v = placeholder.ReplaceAllStringFunc(v, func(m string) string {
+				return bound[m[1:len(m)-1]]
+			})
+			values[key] = append(values[key], v)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const DefaultProviderTimeout = 10 * time.Second
+
+// Provider is a named integration from a [providers.NAME] table: shell
+// commands to run as work starts, as a break starts, and as the session
+// ends, with extra environment variables.
+type Provider struct {
+	Name    string
+	OnWork  string
+	OnBreak string
+	OnDone  string
+	Env     map[string]string
+	Enabled bool
+	// Timeout bounds each command, which is killed once it runs over.
+	Timeout time.Duration
+}
+
+func parseProvider(name string, table map[string]any) (Provider, error) {
+	p := Provider{Name: name, Env: make(map[string]string), Enabled: true, Timeout: DefaultProviderTimeout}
+
+	for key, v := range table {
+		switch key {
+		case "on_work", "on_break", "on_done":
+			s, ok := v.(string)
+			if !ok {
+				return p, fmt.Errorf("provider %q: %s must be a string", name, key)
+			}
+			switch key {
+			case "on_work":
+				p.OnWork = s
+			case "on_break":
+				p.OnBreak = s
+			default:
+				p.OnDone = s
+			}
+		case "env":
+			env, ok := v.(map[string]any)
+			if !ok {
+				return p, fmt.Errorf("provider %q: env must be a table", name)
+			}
+			for k, v := range env {
+				p.Env[k] = fmt.Sprint(v)
+			}
+		case "enabled":
+			b, ok := v.(bool)
+			if !ok {
+				return p, fmt.Errorf("provider %q: enabled must be true or false", name)
+			}
+			p.Enabled = b
+		case "timeout":
+			s, _ := v.(string)
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				return p, fmt.Errorf("provider %q: invalid timeout %v (want a duration like \"10s\")", name, v)
+			}
+			p.Timeout = d
+		default:
+			return p, fmt.Errorf("provider %q: unknown setting %s", name, key)
+		}
+	}
+
+	if p.OnWork == "" && p.OnBreak == "" && p.OnDone == "" {
+		return p, fmt.Errorf("provider %q: needs at least one of on_work, on_break, or on_done", name)
+	}
+	return p, nil
+}
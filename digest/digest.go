@@ -0,0 +1,177 @@
+// Package digest renders a report of a week's focus from history, as plain
+// text, Markdown, or HTML, from templates that can be replaced.
+package digest
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+// Formats are the outputs there are templates for.
+var Formats = []string{"txt", "md", "html"}
+
+//go:embed templates
+var templates embed.FS
+
+// How many labels the report lists, busiest first.
+const topLabels = 5
+
+// Report is what templates render.
+type Report struct {
+	// From is the first day covered and To the day after the last.
+	From, To time.Time
+	history.Summary
+	Days     []Day
+	Labels   []Label
+	Previous history.Summary
+	// Streak is how many days in a row, up to the last day covered, had a
+	// completed work phase. A last day without one yet, such as today, does
+	// not end it.
+	Streak int
+}
+
+type Day struct {
+	Date      time.Time
+	Focus     time.Duration
+	Completed int
+	// Share is Focus as a fraction of the busiest day's.
+	Share float64
+}
+
+type Label struct {
+	Name  string
+	Focus time.Duration
+}
+
+// Change is focus time against the week before, as a fraction; 0 with
+// nothing to compare against.
+func (r Report) Change() float64 {
+	if r.Previous.Focus == 0 {
+		return 0
+	}
+	return float64(r.Focus-r.Previous.Focus) / float64(r.Previous.Focus)
+}
+
+// Build covers the seven days from from, counting work phases as
+// history.Summarize does with minWork.
+func Build(records []history.Record, from time.Time, minWork time.Duration) Report {
+	to := from.AddDate(0, 0, 7)
+	r := Report{
+		From:     from,
+		To:       to,
+		Summary:  history.Summarize(between(records, from, to), minWork),
+		Previous: history.Summarize(between(records, from.AddDate(0, 0, -7), from), minWork),
+	}
+
+	var busiest time.Duration
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		s := history.Summarize(history.On(records, day), minWork)
+		r.Days = append(r.Days, Day{Date: day, Focus: s.Focus, Completed: s.Completed})
+		busiest = max(busiest, s.Focus)
+	}
+	for i := range r.Days {
+		if busiest > 0 {
+			r.Days[i].Share = float64(r.Days[i].Focus) / float64(busiest)
+		}
+	}
+
+	byLabel := make(map[string]time.Duration)
+	for _, rec := range between(records, from, to) {
+		if rec.Label != "" {
+			if focus := history.Summarize([]history.Record{rec}, minWork).Focus; focus > 0 {
+				byLabel[rec.Label] += focus
+			}
+		}
+	}
+	for name, focus := range byLabel {
+		r.Labels = append(r.Labels, Label{name, focus})
+	}
+	sort.Slice(r.Labels, func(i, j int) bool {
+		if r.Labels[i].Focus != r.Labels[j].Focus {
+			return r.Labels[i].Focus > r.Labels[j].Focus
+		}
+		return r.Labels[i].Name < r.Labels[j].Name
+	})
+	r.Labels = r.Labels[:min(len(r.Labels), topLabels)]
+
+	day := to.AddDate(0, 0, -1)
+	if r.Days[len(r.Days)-1].Completed == 0 {
+		day = day.AddDate(0, 0, -1)
+	}
+	for ; history.Summarize(history.On(records, day), minWork).Completed > 0; day = day.AddDate(0, 0, -1) {
+		r.Streak++
+	}
+	return r
+}
+
+func between(records []history.Record, from, to time.Time) []history.Record {
+	var out []history.Record
+	for _, r := range history.Since(records, from) {
+		if r.Start.Before(to) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Render writes r in format, from dir/digest.<format>.tmpl when there is
+// one and the built-in template otherwise. HTML templates escape what they
+// fill in.
+func Render(w io.Writer, r Report, format, dir string) error {
+	name := "digest." + format + ".tmpl"
+	text, err := []byte(nil), os.ErrNotExist
+	if dir != "" {
+		text, err = os.ReadFile(filepath.Join(dir, name))
+	}
+	if os.IsNotExist(err) {
+		text, err = templates.ReadFile("templates/" + name)
+		if err != nil {
+			return fmt.Errorf("unknown digest format %q", format)
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if format == "html" {
+		t, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(funcs)).Parse(string(text))
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, r)
+	}
+	t, err := template.New(name).Funcs(funcs).Parse(string(text))
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, r)
+}
+
+var funcs = template.FuncMap{
+	"hours":   format.DurationHuman,
+	"bar":     bar,
+	"percent": func(f float64) string { return fmt.Sprintf("%+.0f%%", f*100) },
+	"width":   func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
+	"plural": func(n int, one, many string) string {
+		if n == 1 {
+			return one
+		}
+		return many
+	},
+}
+
+// bar draws share of width as a row of blocks.
+func bar(share float64, width int) string {
+	n := int(share*float64(width) + 0.5)
+	return strings.Repeat("#", n) + strings.Repeat(".", width-n)
+}
@@ -0,0 +1,107 @@
+package digest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/history"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// fixtureReport is the week from Monday 6 January 2025 in
+// testdata/history.jsonl, which also has two pomodoros the week before.
+func fixtureReport(t *testing.T) Report {
+	t.Helper()
+	records, err := history.Read(filepath.Join("testdata", "history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Build(records, time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC), 10*time.Minute)
+}
+
+// TestRenderGolden renders every built-in template, which must not change
+// unless meant to: go test ./digest -update rewrites the golden files.
+func TestRenderGolden(t *testing.T) {
+	r := fixtureReport(t)
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Render(&out, r, format, ""); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", "digest."+format+".golden")
+			if *update {
+				if err := os.WriteFile(golden, out.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), want) {
+				t.Errorf("output differs from %s:\n%s\nwant:\n%s", golden, out.Bytes(), want)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	r := fixtureReport(t)
+	if r.Completed != 12 || r.Work != 13 || r.Short != 1 {
+		t.Errorf("completed %d of %d work phases with %d short, want 12 of 13 with 1", r.Completed, r.Work, r.Short)
+	}
+	if want := 380 * time.Minute; r.Focus != want {
+		t.Errorf("focus %s, want %s", r.Focus, want)
+	}
+	if want := 50 * time.Minute; r.Previous.Focus != want {
+		t.Errorf("previous focus %s, want %s", r.Previous.Focus, want)
+	}
+	// Wednesday had nothing, so the streak runs Thursday to Sunday.
+	if r.Streak != 4 {
+		t.Errorf("streak %d, want 4", r.Streak)
+	}
+	if len(r.Labels) != topLabels || r.Labels[0].Name != "<review> & merge" {
+		t.Errorf("labels %v, want the top %d, <review> & merge first", r.Labels, topLabels)
+	}
+}
+
+func TestRenderEscapesHTML(t *testing.T) {
+	var out bytes.Buffer
+	if err := Render(&out, fixtureReport(t), "html", ""); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "<review>") || !strings.Contains(out.String(), "&lt;review&gt; &amp; merge") {
+		t.Errorf("label not escaped:\n%s", out.String())
+	}
+}
+
+func TestRenderTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "digest.txt.tmpl"), []byte("{{.Completed}} done, {{hours .Focus}}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := fixtureReport(t)
+
+	var out bytes.Buffer
+	if err := Render(&out, r, "txt", dir); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "12 done, 6h 20m\n"; got != want {
+		t.Errorf("replaced template rendered %q, want %q", got, want)
+	}
+	// Formats it has no template for still use the built-in one.
+	out.Reset()
+	if err := Render(&out, r, "md", dir); err != nil || out.Len() == 0 {
+		t.Errorf("built-in md alongside a replaced txt: %v, %q", err, out.String())
+	}
+	if err := Render(&out, r, "pdf", dir); err == nil {
+		t.Error("rendered an unknown format")
+	}
+}
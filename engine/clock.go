@@ -1,41 +1,86 @@
 package engine
 
-import "time"
-
+import (
+	"sync"
+	"time"
+)
+
+// Clock is where a Timer takes the time from, so it can be run on a
+// MockClock. Since is the time since t, which Now returned, on the clock's
+// monotonic reading if it has one, so the wall clock being set does not
+// move it.
 type Clock interface {
 	Now() time.Time
+	Since(t time.Time) time.Duration
 	NewTicker(d time.Duration) Ticker
+	After(d time.Duration) <-chan time.Time
 	Sleep(d time.Duration)
 }
 
+// Ticker is a time.Ticker on a Clock.
 type Ticker interface {
 	C() <-chan time.Time
 	Stop()
 }
 
+// RealClock is the system clock.
 type RealClock struct{}
 
-func (RealClock) Now() time.Time                   { return time.Now() }
-func (RealClock) NewTicker(d time.Duration) Ticker { return &realTicker{time.NewTicker(d)} }
-func (RealClock) Sleep(d time.Duration)            { time.Sleep(d) }
+func (RealClock) Now() time.Time                         { return time.Now() }
+func (RealClock) Since(t time.Time) time.Duration        { return time.Since(t) }
+func (RealClock) NewTicker(d time.Duration) Ticker       { return &realTicker{time.NewTicker(d)} }
+func (RealClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (RealClock) Sleep(d time.Duration)                  { time.Sleep(d) }
 
 type realTicker struct{ *time.Ticker }
 
 func (t *realTicker) C() <-chan time.Time { return t.Ticker.C }
 
+// MockClock only moves when advanced. It is safe for concurrent use, so
+// one goroutine can drive it while a Timer runs on it. It has no monotonic
+// reading: Since is wall-clock arithmetic, which sees the jumps Jump makes
+// as a time stripped of its monotonic reading would.
 type MockClock struct {
+	mu      sync.Mutex
 	current time.Time
+	// skew is how far Jump has set the wall clock off current, which
+	// tickers keep to.
+	skew    time.Duration
 	tickers []*MockTicker
 }
 
+// NewMockClock stands still at start until advanced.
 func NewMockClock(start time.Time) *MockClock {
 	return &MockClock{current: start}
 }
 
-func (m *MockClock) Now() time.Time { return m.current }
+func (m *MockClock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.current.Add(m.skew)
+}
+
+func (m *MockClock) Since(t time.Time) time.Duration {
+	return m.Now().Sub(t)
+}
+
+// Jump sets the wall clock d forward, or back if d is negative, as NTP or
+// someone changing the time would. Tickers carry on as they were.
+func (m *MockClock) Jump(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.skew += d
+}
 
 func (m *MockClock) NewTicker(d time.Duration) Ticker {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.newTicker(d)
+}
+
+func (m *MockClock) newTicker(d time.Duration) *MockTicker {
 	t := &MockTicker{
+		clock:    m,
 		interval: d,
 		ch:       make(chan time.Time, 1),
 		nextTick: m.current.Add(d),
@@ -45,22 +90,41 @@ func (m *MockClock) NewTicker(d time.Duration) Ticker {
 	return t
 }
 
+// After fires once, d from now, like a ticker that stops itself.
+func (m *MockClock) After(d time.Duration) <-chan time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.newTicker(d)
+	t.once = true
+	return t.ch
+}
+
+// Sleep advances the clock by d rather than waiting.
 func (m *MockClock) Sleep(d time.Duration) {
 	m.Advance(d)
 }
 
+// UntilNext reports how far the clock must advance for the next running
+// ticker to fire.
+func (m *MockClock) UntilNext() (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t := m.earliest(); t != nil {
+		return t.nextTick.Sub(m.current), true
+	}
+	return 0, false
+}
+
+// Advance moves the clock forward by d, firing every ticker due on the way
+// in order. A ticker whose last tick has not been taken skips the next.
 func (m *MockClock) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Tickers due at target fire too, including ones due right now.
 	target := m.current.Add(d)
-	for m.current.Before(target) {
-		var earliest *MockTicker
-		for _, t := range m.tickers {
-			if t.stopped {
-				continue
-			}
-			if earliest == nil || t.nextTick.Before(earliest.nextTick) {
-				earliest = t
-			}
-		}
+	for {
+		earliest := m.earliest()
 
 		if earliest == nil || earliest.nextTick.After(target) {
 			m.current = target
@@ -69,19 +133,85 @@ func (m *MockClock) Advance(d time.Duration) {
 
 		m.current = earliest.nextTick
 		select {
-		case earliest.ch <- m.current:
+		case earliest.ch <- m.current.Add(m.skew):
 		default:
 		}
 		earliest.nextTick = earliest.nextTick.Add(earliest.interval)
+		earliest.stopped = earliest.once
+	}
+}
+
+func (m *MockClock) earliest() *MockTicker {
+	var earliest *MockTicker
+	for _, t := range m.tickers {
+		if t.stopped {
+			continue
+		}
+		if earliest == nil || t.nextTick.Before(earliest.nextTick) {
+			earliest = t
+		}
 	}
+	return earliest
 }
 
+// MockTicker is a Ticker on a MockClock.
 type MockTicker struct {
+	clock    *MockClock
 	interval time.Duration
 	ch       chan time.Time
 	nextTick time.Time
 	stopped  bool
+	once     bool
 }
 
 func (t *MockTicker) C() <-chan time.Time { return t.ch }
-func (t *MockTicker) Stop()               { t.stopped = true }
+
+func (t *MockTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.stopped = true
+}
+
+// ClockJumpThreshold is how far the wall clock has to go back before a
+// Timer warns of it, so NTP's small corrections pass unremarked.
+const ClockJumpThreshold = time.Second
+
+// stopwatch times a stretch from start on a Clock. Its readings never go
+// back: one that does, on a Clock without a monotonic reading, is clamped
+// to the last, and the time lost carried as skew from then on.
+type stopwatch struct {
+	clock Clock
+	start time.Time
+	skew  time.Duration
+	last  time.Duration
+	// warned is how far behind the wall clock was last warned of.
+	warned time.Duration
+}
+
+func newStopwatch(clock Clock) *stopwatch {
+	return &stopwatch{clock: clock, start: clock.Now()}
+}
+
+func (w *stopwatch) elapsed() time.Duration {
+	d := w.clock.Since(w.start) + w.skew
+	if d < w.last {
+		w.skew += w.last - d
+		d = w.last
+	}
+	w.last = d
+	return d
+}
+
+// jumped is how far the wall clock has gone back since the stopwatch
+// started, or last reported it, if that is past ClockJumpThreshold, and
+// otherwise 0.
+func (w *stopwatch) jumped() time.Duration {
+	wall := w.clock.Now().Round(0).Sub(w.start.Round(0))
+	behind := w.elapsed() - wall
+	if behind-w.warned < ClockJumpThreshold {
+		return 0
+	}
+	jump := behind - w.warned
+	w.warned = behind
+	return jump
+}
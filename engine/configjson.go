@@ -0,0 +1,38 @@
+package engine
+
+import "time"
+
+// ConfigJSON is a session's settings as published to scripts, in the state
+// file and the --share stream. Durations are whole milliseconds.
+type ConfigJSON struct {
+	WorkMS         int64  `json:"work_ms"`
+	ShortBreakMS   int64  `json:"short_break_ms"`
+	LongBreakMS    int64  `json:"long_break_ms"`
+	LongBreakEvery int    `json:"long_break_every"`
+	TotalCycles    int    `json:"total_cycles"`
+	Label          string `json:"label,omitempty"`
+	Profile        string `json:"profile,omitempty"`
+}
+
+func (c Config) JSON(label, profile string) ConfigJSON {
+	return ConfigJSON{
+		WorkMS:         c.WorkDuration.Milliseconds(),
+		ShortBreakMS:   c.ShortBreakDuration.Milliseconds(),
+		LongBreakMS:    c.LongBreakDuration.Milliseconds(),
+		LongBreakEvery: c.LongBreakEvery,
+		TotalCycles:    c.TotalCycles,
+		Label:          label,
+		Profile:        profile,
+	}
+}
+
+// Config is the inverse of Config.JSON for the settings it carries.
+func (j ConfigJSON) Config() Config {
+	return Config{
+		WorkDuration:       time.Duration(j.WorkMS) * time.Millisecond,
+		ShortBreakDuration: time.Duration(j.ShortBreakMS) * time.Millisecond,
+		LongBreakDuration:  time.Duration(j.LongBreakMS) * time.Millisecond,
+		LongBreakEvery:     j.LongBreakEvery,
+		TotalCycles:        j.TotalCycles,
+	}
+}
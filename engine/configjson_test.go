@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigJSONRoundTrip(t *testing.T) {
+	for _, cfg := range []Config{
+		{WorkDuration: 50 * time.Minute, ShortBreakDuration: 10 * time.Minute, LongBreakDuration: 30 * time.Minute, LongBreakEvery: 4, TotalCycles: 6},
+		{WorkDuration: 1500 * time.Millisecond, ShortBreakDuration: time.Millisecond, LongBreakDuration: 0, LongBreakEvery: 0, TotalCycles: 0},
+		{},
+	} {
+		data, err := json.Marshal(cfg.JSON("write tests", "sprint"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var j ConfigJSON
+		if err := json.Unmarshal(data, &j); err != nil {
+			t.Fatal(err)
+		}
+		if j.Label != "write tests" || j.Profile != "sprint" {
+			t.Errorf("%s: label %q and profile %q, want write tests and sprint", data, j.Label, j.Profile)
+		}
+		if got := j.Config(); !reflect.DeepEqual(got, cfg) {
+			t.Errorf("%s: came back as %+v, want %+v", data, got, cfg)
+		}
+	}
+}
+
+func TestConfigJSONCarriesOnlyItsSettings(t *testing.T) {
+	cfg := Config{WorkDuration: 25 * time.Minute, ShortBreakDuration: 5 * time.Minute, TotalCycles: 2, WarmupDuration: time.Minute, StrictPomodoro: true}
+	got := cfg.JSON("", "").Config()
+	want := Config{WorkDuration: 25 * time.Minute, ShortBreakDuration: 5 * time.Minute, TotalCycles: 2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("came back as %+v, want %+v", got, want)
+	}
+}
+
+func TestConfigJSONFields(t *testing.T) {
+	data, err := json.Marshal(Config{WorkDuration: 25 * time.Minute, LongBreakEvery: 4}.JSON("", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Label and profile are left out when empty.
+	want := `{"work_ms":1500000,"short_break_ms":0,"long_break_ms":0,"long_break_every":4,"total_cycles":0}`
+	if string(data) != want {
+		t.Errorf("marshalled %s, want %s", data, want)
+	}
+}
+
+func TestConfigJSONIgnoresUnknownFields(t *testing.T) {
+	data := `{"work_ms":1500000,"short_break_ms":300000,"long_break_ms":900000,"long_break_every":4,"total_cycles":4,` +
+		`"label":"x","warmup_ms":60000,"future":{"nested":[1,2]}}`
+	var j ConfigJSON
+	if err := json.Unmarshal([]byte(data), &j); err != nil {
+		t.Fatalf("unknown fields rejected: %v", err)
+	}
+	want := ConfigJSON{WorkMS: 1500000, ShortBreakMS: 300000, LongBreakMS: 900000, LongBreakEvery: 4, TotalCycles: 4, Label: "x"}
+	if j != want {
+		t.Errorf("read %+v, want %+v", j, want)
+	}
+}
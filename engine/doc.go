@@ -0,0 +1,11 @@
+// Package engine runs pomodoro sessions: Config describes a schedule,
+// Session steps through it, and Timer runs it on a Clock, reporting every
+// tick, transition, and snooze as a TimerEvent on a channel.
+//
+// Config, Session, Timer, TimerEvent, and the clocks are stable: from v1 on,
+// they only change in backward-compatible ways within a major version, with
+// new Config fields off at their zero value. Fanning events out to several
+// consumers is left to the experimental engine/fanout package.
+//
+// See the examples directory for programs that use it.
+package engine
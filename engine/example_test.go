@@ -0,0 +1,50 @@
+package engine_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// Run a session on a MockClock, advancing it as soon as the timer waits on
+// it, so two cycles go by in an instant.
+func ExampleTimer_Run() {
+	clock := engine.NewMockClock(time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC))
+	cfg := engine.Config{
+		WorkDuration:       25 * time.Minute,
+		ShortBreakDuration: 5 * time.Minute,
+		LongBreakDuration:  15 * time.Minute,
+		TotalCycles:        2,
+	}
+	timer := engine.NewTimerWithClock(cfg, clock, time.Second)
+
+	events := make(chan engine.TimerEvent)
+	done := make(chan error, 1)
+	go func() { done <- timer.Run(context.Background(), events) }()
+
+	for e := range events {
+		switch e.Type {
+		case engine.EventSessionStarted:
+			fmt.Printf("%d phases planned\n", len(e.Plan))
+		case engine.EventSessionEnded:
+			fmt.Printf("%d cycles, %s of work, by %s\n", e.Summary.CyclesComplete, e.Summary.Work, clock.Now().Format("15:04"))
+		case engine.EventTick:
+			if e.Ended != "" {
+				fmt.Printf("%s-%s %s %s\n", e.PhaseStartedAt.Format("15:04"), clock.Now().Format("15:04"), e.Phase, e.Ended)
+			} else if d, ok := clock.UntilNext(); ok {
+				clock.Advance(d)
+			}
+		}
+	}
+	if err := <-done; err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// 3 phases planned
+	// 09:00-09:25 Work completed
+	// 09:25-09:30 Short Break completed
+	// 09:30-09:55 Work completed
+	// 2 cycles, 50m0s of work, by 09:55
+}
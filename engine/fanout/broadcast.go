@@ -0,0 +1,52 @@
+// Package fanout delivers a timer's events to more than one consumer. It is
+// experimental: unlike the engine package, its API may change between minor
+// versions.
+package fanout
+
+import (
+	"errors"
+	"io"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// Subscriber consumes timer events. Handle runs on the broadcaster's
+// goroutine, so slow work holds up every other subscriber and the timer.
+type Subscriber interface {
+	Handle(engine.TimerEvent)
+}
+
+// SubscriberFunc lets a function be a Subscriber.
+type SubscriberFunc func(engine.TimerEvent)
+
+func (f SubscriberFunc) Handle(e engine.TimerEvent) { f(e) }
+
+// Broadcaster fans timer events out to subscribers in registration order.
+type Broadcaster struct {
+	subscribers []Subscriber
+}
+
+// Subscribe must be called before Run.
+func (b *Broadcaster) Subscribe(s Subscriber) {
+	b.subscribers = append(b.subscribers, s)
+}
+
+// Run delivers events until the channel is closed, then closes every
+// subscriber implementing io.Closer.
+func (b *Broadcaster) Run(events <-chan engine.TimerEvent) error {
+	for e := range events {
+		for _, s := range b.subscribers {
+			s.Handle(e)
+		}
+	}
+
+	var errs []error
+	for _, s := range b.subscribers {
+		if c, ok := s.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
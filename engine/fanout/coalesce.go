@@ -0,0 +1,102 @@
+package fanout
+
+import (
+	"sync/atomic"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// Coalescer stands between a timer and subscribers that may fall behind,
+// such as a renderer on a slow terminal, so they never hold up the timer.
+// Events that must all arrive, session events, phase ends, the ends of
+// transitions, snoozes, and waits for a check-in, and pause changes, queue
+// up in order; of the plain ticks and countdowns between them only the
+// latest waits to be delivered.
+type Coalescer struct {
+	coalesced atomic.Int64
+	maxQueued atomic.Int64
+}
+
+// Coalesce forwards in until it is closed and drained, then closes the
+// returned channel.
+func Coalesce(in <-chan engine.TimerEvent) (<-chan engine.TimerEvent, *Coalescer) {
+	out := make(chan engine.TimerEvent)
+	c := &Coalescer{}
+	go c.run(in, out)
+	return out, c
+}
+
+// Coalesced counts the ticks and countdowns that were superseded before
+// delivery.
+func (c *Coalescer) Coalesced() int64 { return c.coalesced.Load() }
+
+// MaxQueued is the most events, the pending tick included, that were ever
+// waiting at once.
+func (c *Coalescer) MaxQueued() int64 { return c.maxQueued.Load() }
+
+func (c *Coalescer) run(in <-chan engine.TimerEvent, out chan<- engine.TimerEvent) {
+	defer close(out)
+
+	// pending is a tick or countdown newer than everything queued.
+	var queue []engine.TimerEvent
+	var pending *engine.TimerEvent
+	paused := false
+	for in != nil || len(queue) > 0 || pending != nil {
+		var send chan<- engine.TimerEvent
+		var next engine.TimerEvent
+		switch {
+		case len(queue) > 0:
+			send, next = out, queue[0]
+		case pending != nil:
+			send, next = out, *pending
+		}
+
+		select {
+		case e, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			if pending != nil {
+				c.coalesced.Add(1)
+				pending = nil
+			}
+			if coalescable(e, paused) {
+				pending = &e
+			} else {
+				queue = append(queue, e)
+			}
+			if e.Type == engine.EventTick {
+				paused = e.Paused
+			}
+			waiting := int64(len(queue))
+			if pending != nil {
+				waiting++
+			}
+			if waiting > c.maxQueued.Load() {
+				c.maxQueued.Store(waiting)
+			}
+
+		case send <- next:
+			if len(queue) > 0 {
+				queue = queue[1:]
+			} else {
+				pending = nil
+			}
+		}
+	}
+}
+
+// coalescable reports whether e can be superseded by the event after it:
+// a tick that neither ends its phase nor pauses or resumes it, or a
+// countdown that has not ended.
+func coalescable(e engine.TimerEvent, paused bool) bool {
+	switch e.Type {
+	case engine.EventTick:
+		return !e.PhaseComplete && e.Ended == "" && e.Paused == paused
+	case engine.EventTransition, engine.EventSnooze, engine.EventAway:
+		return e.Ended == ""
+	default:
+		return false
+	}
+}
@@ -0,0 +1,99 @@
+package fanout
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+func TestCoalesceKeepsBoundaries(t *testing.T) {
+	sent := []engine.TimerEvent{
+		{Type: engine.EventSessionStarted},
+		{Type: engine.EventTransition, Elapsed: time.Second},
+		{Type: engine.EventTransition, Elapsed: 2 * time.Second},
+		{Type: engine.EventTick, Phase: engine.PhaseWork},
+		{Type: engine.EventTick, Phase: engine.PhaseWork, Elapsed: time.Second, Paused: true},
+		{Type: engine.EventTick, Phase: engine.PhaseWork, Elapsed: time.Second, Ended: engine.EndCompleted, PhaseComplete: true},
+		{Type: engine.EventSnooze, Elapsed: time.Second},
+		{Type: engine.EventSnooze, Elapsed: 2 * time.Second, Ended: engine.EndCompleted},
+		{Type: engine.EventAway, Elapsed: time.Second},
+		{Type: engine.EventAway, Elapsed: 2 * time.Second},
+		{Type: engine.EventSessionEnded},
+	}
+	in := make(chan engine.TimerEvent, len(sent))
+	for _, e := range sent {
+		in <- e
+	}
+	close(in)
+	out, c := Coalesce(in)
+	// Nothing is taken until every event is in, as with a renderer that
+	// has fallen behind.
+	for len(in) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	var got []engine.TimerEvent
+	for e := range out {
+		got = append(got, e)
+	}
+	want := []engine.TimerEvent{sent[0], sent[4], sent[5], sent[7], sent[10]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("delivered\n%+v\nwant\n%+v", got, want)
+	}
+	if n := c.Coalesced(); n != 6 {
+		t.Errorf("%d coalesced, want 6", n)
+	}
+}
+
+// TestCoalesceSlowRenderer runs a session on the real clock into a
+// renderer far slower than its ticks and countdowns, which it would fall
+// seconds behind without coalescing.
+func TestCoalesceSlowRenderer(t *testing.T) {
+	cfg := engine.Config{
+		WorkDuration:       200 * time.Millisecond,
+		ShortBreakDuration: 100 * time.Millisecond,
+		TotalCycles:        2,
+		TransitionDuration: 100 * time.Millisecond,
+	}
+	const planned = 700 * time.Millisecond
+	timer := engine.NewTimerWithClock(cfg, engine.RealClock{}, 5*time.Millisecond)
+	events := make(chan engine.TimerEvent)
+	out, c := Coalesce(events)
+
+	started := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- timer.Run(context.Background(), events) }()
+
+	var boundaries []string
+	for e := range out {
+		switch {
+		case e.Type == engine.EventSessionStarted:
+			boundaries = append(boundaries, "session started")
+		case e.Type == engine.EventSessionEnded:
+			boundaries = append(boundaries, "session ended")
+		case e.Ended != "":
+			boundaries = append(boundaries, e.Phase.String()+" "+string(e.Ended))
+		}
+		time.Sleep(60 * time.Millisecond)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// Each boundary waits out the render before it, and the last two
+	// render after the session, which uncoalesced would take over 8s.
+	if took := time.Since(started); took > planned+300*time.Millisecond {
+		t.Errorf("session took %s to render, planned %s", took, planned)
+	}
+	want := []string{"session started", "Work completed", "Short Break completed", "Work completed", "session ended"}
+	if !slices.Equal(boundaries, want) {
+		t.Errorf("boundaries %q, want %q", boundaries, want)
+	}
+	if c.Coalesced() == 0 {
+		t.Error("nothing coalesced")
+	}
+}
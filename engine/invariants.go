@@ -0,0 +1,74 @@
+package engine
+
+import "fmt"
+
+// CheckInvariants reports the first thing found wrong with the session's
+// state. Builds with -tags debug check after every phase and panic on a
+// violation, so transition bugs surface where they happen.
+func (s *Session) CheckInvariants() error {
+	c := s.config
+	switch {
+	case s.currentPhase < PhaseWork || s.currentPhase > PhaseDone:
+		return fmt.Errorf("unknown phase %d", s.currentPhase)
+	case s.cyclesComplete < 0 || s.phasesComplete < 0:
+		return fmt.Errorf("negative progress: %d cycles, %d phases", s.cyclesComplete, s.phasesComplete)
+	case c.TotalCycles > 0 && s.cyclesComplete > c.TotalCycles:
+		return fmt.Errorf("%d cycles complete of %d", s.cyclesComplete, c.TotalCycles)
+	case c.TotalCycles > 0 && s.phasesComplete > s.totalPhases:
+		return fmt.Errorf("%d phases complete of %d", s.phasesComplete, s.totalPhases)
+	case s.currentPhase == PhaseCooldown && (c.CooldownDuration <= 0 || s.cyclesComplete < c.TotalCycles):
+		return fmt.Errorf("cooldown after %d of %d cycles with cooldown %s", s.cyclesComplete, c.TotalCycles, c.CooldownDuration)
+	case s.currentPhase == PhaseWarmup && (c.WarmupDuration <= 0 || s.cyclesComplete > 0):
+		return fmt.Errorf("warmup after %d cycles with warmup %s", s.cyclesComplete, c.WarmupDuration)
+	case s.currentPhase != PhaseLongBreak && s.enforcedAfter > 0:
+		return fmt.Errorf("enforced long break still marked during %s", s.currentPhase)
+	case s.currentPhase != PhaseLongBreak && (s.lunch || s.lunchOffer > 0):
+		return fmt.Errorf("lunch still marked during %s", s.currentPhase)
+	case s.workSinceLong < 0:
+		return fmt.Errorf("negative work since long break: %s", s.workSinceLong)
+	case s.retries > c.StrictMaxRetries:
+		return fmt.Errorf("%d work phases retried of at most %d", s.retries, c.StrictMaxRetries)
+	case s.retrying && s.currentPhase != PhaseWork:
+		return fmt.Errorf("retry still marked during %s", s.currentPhase)
+	case s.voidBreak && s.currentPhase != PhaseShortBreak:
+		return fmt.Errorf("break after voided work still marked during %s", s.currentPhase)
+	case s.bank < 0 || s.fromBank < 0 || s.fromBank > s.extension:
+		return fmt.Errorf("bank %s, %s of the %s extension from it", s.bank, s.fromBank, s.extension)
+	case s.breakScale < 0 || s.breakScale > 1:
+		return fmt.Errorf("break scale %g outside [0, 1]", s.breakScale)
+	}
+	return s.checkPlan()
+}
+
+// checkPlan checks a finite session's plan against where it stands: it
+// starts with the current phase, unless that is to be passed over, and
+// runs one work phase for each cycle left.
+func (s *Session) checkPlan() error {
+	c := s.config
+	if c.TotalCycles == 0 || s.currentPhase == PhaseDone {
+		return nil
+	}
+	plan := s.Plan(0)
+	if s.PhaseDuration() > 0 && (len(plan) == 0 || plan[0].Phase != s.currentPhase) {
+		return fmt.Errorf("plan does not start with the current %s", s.currentPhase)
+	}
+	work := 0
+	for _, p := range plan {
+		if p.Phase == PhaseWork {
+			work++
+		}
+	}
+	if left := c.TotalCycles - s.cyclesComplete; c.WorkDuration > 0 && work != left {
+		return fmt.Errorf("plan has %d work phases for %d cycles left", work, left)
+	}
+	return nil
+}
+
+func (s *Session) mustHoldInvariants() {
+	if s.simulated {
+		return
+	}
+	if err := s.CheckInvariants(); err != nil {
+		panic("engine: session invariant violated: " + err.Error())
+	}
+}
@@ -0,0 +1,85 @@
+package engine
+
+import "time"
+
+// PlannedPhase is one phase of a schedule worked out ahead by Plan, Offset
+// from the start of the first.
+type PlannedPhase struct {
+	Phase    Phase
+	Cycle    int
+	Offset   time.Duration
+	Duration time.Duration
+}
+
+// Plan simulates the remaining schedule starting with the current phase,
+// without mutating the session. Offsets leave room for the transitions
+// between phases. Infinite sessions stop once a phase would
+// start at or beyond horizon; finite sessions ignore horizon when it is 0.
+func (s *Session) Plan(horizon time.Duration) []PlannedPhase {
+	return s.PlanAt(time.Time{}, horizon)
+}
+
+// PlanAt is Plan for a current phase that starts at start, which also
+// projects Config.Lunch onto the long breaks by the time of day each would
+// start at. A zero start projects no lunch, as Plan.
+func (s *Session) PlanAt(start time.Time, horizon time.Duration) []PlannedPhase {
+	if s.config.TotalCycles == 0 && horizon <= 0 {
+		return nil
+	}
+
+	sim := *s
+	sim.simulated = true
+	var plan []PlannedPhase
+	var offset time.Duration
+
+	for sim.currentPhase != PhaseDone {
+		if horizon > 0 && offset >= horizon {
+			break
+		}
+
+		cycle := sim.cyclesComplete + 1
+		if sim.currentPhase != PhaseWork {
+			cycle = sim.cyclesComplete
+		}
+
+		if !start.IsZero() && len(plan) > 0 {
+			sim.StartLunch(start.Add(offset + sim.config.TransitionDuration))
+		}
+		d := sim.PhaseDuration()
+		if d > 0 {
+			if len(plan) > 0 {
+				offset += sim.config.TransitionDuration
+			}
+			plan = append(plan, PlannedPhase{
+				Phase:    sim.currentPhase,
+				Cycle:    cycle,
+				Offset:   offset,
+				Duration: d,
+			})
+			offset += d
+		}
+		sim.NextPhase()
+	}
+
+	return plan
+}
+
+// plannedDuration is the planned time from the start of the current phase
+// to the end of the last.
+func (s *Session) plannedDuration() time.Duration {
+	plan := s.Plan(0)
+	if len(plan) == 0 {
+		return 0
+	}
+	last := plan[len(plan)-1]
+	return last.Offset + last.Duration
+}
+
+// upcomingDuration is the planned time after the current phase.
+func (s *Session) upcomingDuration() time.Duration {
+	plan := s.Plan(0)
+	if len(plan) == 0 {
+		return 0
+	}
+	return s.plannedDuration() - plan[0].Duration
+}
@@ -1,13 +1,21 @@
 package engine
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
+// Phase is the kind of phase a session is in. PhaseDone follows the last
+// one.
 type Phase int
 
 const (
 	PhaseWork Phase = iota
 	PhaseShortBreak
 	PhaseLongBreak
+	PhaseCooldown
+	PhaseWarmup
 	PhaseDone
 )
 
@@ -19,6 +27,10 @@ func (p Phase) String() string {
 		return "Short Break"
 	case PhaseLongBreak:
 		return "Long Break"
+	case PhaseCooldown:
+		return "Cooldown"
+	case PhaseWarmup:
+		return "Warmup"
 	case PhaseDone:
 		return "Done"
 	default:
@@ -26,27 +38,212 @@ func (p Phase) String() string {
 	}
 }
 
+// ParsePhase is the inverse of Phase.String.
+func ParsePhase(s string) (Phase, error) {
+	for p := PhaseWork; p <= PhaseDone; p++ {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown phase %q", s)
+}
+
+// MarshalText and UnmarshalText use Phase.String, e.g. "Short Break".
+func (p Phase) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Phase) UnmarshalText(text []byte) error {
+	parsed, err := ParsePhase(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// Config is a session's schedule. Past the three durations, a zero field
+// turns its feature off.
 type Config struct {
 	WorkDuration       time.Duration
 	ShortBreakDuration time.Duration
 	LongBreakDuration  time.Duration
 	LongBreakEvery     int
+	LongBreakAfterWork time.Duration
 	TotalCycles        int
+	CooldownDuration   time.Duration
+	// WarmupDuration is a phase before the first work phase, e.g. to plan
+	// it, that is neither work nor a break.
+	WarmupDuration     time.Duration
+	ProportionalBreaks bool
+	MinBreakDuration   time.Duration
+	// MaxDuration stops the session at the end of the first phase that
+	// finishes this long after it started. Zero means no limit.
+	MaxDuration time.Duration
+	// HardCap stops the session the moment it has run this long, paused or
+	// not, as a failsafe for one left running. Zero means no cap.
+	HardCap time.Duration
+	// WorkTaper gives successive cycles their work durations, the last one
+	// repeating. Otherwise a nonzero WorkTaperStep changes WorkDuration by
+	// that much each cycle, never going below WorkTaperFloor.
+	WorkTaper      []time.Duration
+	WorkTaperStep  time.Duration
+	WorkTaperFloor time.Duration
+	// FinalWorkDuration shortens the last cycle's work phase of a finite
+	// session, e.g. to end by a deadline. It still counts as a cycle.
+	FinalWorkDuration time.Duration
+	// LongBreakGuard flags a session that has gone this much work without
+	// a long break, e.g. because breaks were skipped. With EnforceLongBreak
+	// the next break is then a long one, whatever the cadence.
+	LongBreakGuard   time.Duration
+	EnforceLongBreak bool
+	// CarriedCycles and CarriedWork are the cycles and work since the last
+	// long break in earlier sessions, so the long break cadence carries on
+	// from them. Neither counts toward TotalCycles.
+	CarriedCycles int
+	CarriedWork   time.Duration
+	// TransitionDuration is a countdown between phases, counted toward
+	// neither but toward the session's planned length.
+	TransitionDuration time.Duration
+	// MaxSnoozes is how many times each break can be snoozed; zero turns
+	// snoozing off.
+	MaxSnoozes int
+	// StrictPomodoro voids a work phase that is skipped, interrupted, or
+	// paused for more than StrictMaxPause of its planned length: it does
+	// not count, and the cycle's work runs again, at once up to
+	// StrictMaxRetries times a session. Past that, a short break comes
+	// first, counted toward neither cycles nor phases.
+	StrictPomodoro   bool
+	StrictMaxPause   float64
+	StrictMaxRetries int
+	// BankBreaks banks the time a break is skipped or cut short by, which
+	// extending a later break draws on first.
+	BankBreaks bool
+	// CheckIn holds the work after each break until Timer.CheckIn, the
+	// wait counted as away rather than as either phase.
+	CheckIn bool
+	// Lunch stretches the session's first long break to start within its
+	// window.
+	Lunch Lunch
+}
+
+// Lunch is a window of the day, From and Until being times since
+// midnight, a long break that starts within which is stretched to
+// Duration, once a session. Without Auto it is only offered, for
+// extending the break by the difference. A zero Duration turns it off.
+type Lunch struct {
+	From     time.Duration
+	Until    time.Duration
+	Duration time.Duration
+	Auto     bool
 }
 
-// Handles state transitions
+// Covers reports whether t's time of day falls within the window.
+func (l Lunch) Covers(t time.Time) bool {
+	y, m, d := t.Date()
+	since := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, t.Location()))
+	return since >= l.From && since < l.Until
+}
+
+// Validate reports settings that contradict each other or are out of range.
+func (c Config) Validate() error {
+	if c.LongBreakEvery > 0 && c.LongBreakAfterWork > 0 {
+		return errors.New("long break every N cycles and long break after accumulated work cannot both be set")
+	}
+	if len(c.WorkTaper) > 0 && c.WorkTaperStep != 0 {
+		return errors.New("a taper list and a taper step cannot both be set")
+	}
+	for _, d := range c.WorkTaper {
+		if d <= 0 {
+			return fmt.Errorf("invalid taper duration %s (want more than 0)", d)
+		}
+	}
+	if c.FinalWorkDuration < 0 {
+		return fmt.Errorf("invalid final work duration %s (want 0 or more)", c.FinalWorkDuration)
+	}
+	if c.FinalWorkDuration > 0 && c.TotalCycles == 0 {
+		return errors.New("a final work duration needs a finite number of cycles")
+	}
+	if c.WorkTaperStep != 0 && c.WorkTaperFloor <= 0 {
+		return errors.New("a taper step needs a floor above 0")
+	}
+	if c.CarriedCycles < 0 || c.CarriedWork < 0 {
+		return errors.New("carried cycles and work cannot be negative")
+	}
+	if c.WarmupDuration < 0 {
+		return fmt.Errorf("invalid warmup %s (want 0 or more)", c.WarmupDuration)
+	}
+	if c.TransitionDuration < 0 {
+		return fmt.Errorf("invalid transition %s (want 0 or more)", c.TransitionDuration)
+	}
+	if c.HardCap < 0 {
+		return fmt.Errorf("invalid hard cap %s (want 0 or more)", c.HardCap)
+	}
+	if c.MaxSnoozes < 0 {
+		return fmt.Errorf("invalid max snoozes %d (want 0 or more)", c.MaxSnoozes)
+	}
+	if c.StrictMaxPause < 0 || c.StrictMaxPause > 1 {
+		return fmt.Errorf("invalid strict max pause %g (want a fraction between 0 and 1)", c.StrictMaxPause)
+	}
+	if c.StrictMaxRetries < 0 {
+		return fmt.Errorf("invalid strict retries %d (want 0 or more)", c.StrictMaxRetries)
+	}
+	if l := c.Lunch; l.Duration < 0 {
+		return fmt.Errorf("invalid lunch duration %s (want 0 or more)", l.Duration)
+	} else if l.Duration > 0 && (l.From < 0 || l.From >= l.Until || l.Until > 24*time.Hour) {
+		return errors.New("a lunch window has to start before it ends, within the day")
+	}
+	return nil
+}
+
+// Session is where a schedule stands, advanced a phase at a time. A Timer
+// runs one; on its own, it can work out a schedule without waiting for it.
 type Session struct {
 	config         Config
 	currentPhase   Phase
 	cyclesComplete int
 	totalPhases    int
 	phasesComplete int
+	workSinceLong  time.Duration
+	breakScale     float64
+	// enforcedAfter is the work that brought on the current long break
+	// when the guard forced it.
+	enforcedAfter time.Duration
+	// retries counts the work phases voided and run again; retrying marks
+	// the current one as such a repeat.
+	retries  int
+	retrying bool
+	// voidBreak marks the break after work voided past the retries, which
+	// the plan never had and which does not count.
+	voidBreak bool
+	// bank is the break time banked so far; extension is how much the
+	// current break has been extended by, fromBank how much of that the
+	// bank paid for.
+	bank      time.Duration
+	extension time.Duration
+	fromBank  time.Duration
+	// lunch marks the current long break as stretched to Config.Lunch;
+	// lunchOffer is how much extending it would do the same, when only
+	// offered. lunched marks lunch as had or offered this session.
+	lunch      bool
+	lunchOffer time.Duration
+	lunched    bool
+	// simulated marks a copy run ahead to plan with, whose invariants are
+	// those of the session it was copied from.
+	simulated bool
 }
 
+// NewSession starts at the warmup, if cfg has one, or else the first work
+// phase. cfg should be valid.
 func NewSession(cfg Config) *Session {
 	s := &Session{
-		config:       cfg,
-		currentPhase: PhaseWork,
+		config:        cfg,
+		currentPhase:  PhaseWork,
+		workSinceLong: cfg.CarriedWork,
+		breakScale:    1,
+	}
+	if cfg.WarmupDuration > 0 {
+		s.currentPhase = PhaseWarmup
 	}
 	s.totalPhases = s.calculateTotalPhases()
 	return s
@@ -57,64 +254,357 @@ func (s *Session) calculateTotalPhases() int {
 		return 0
 	}
 
+	// The number of long breaks depends on accumulated work time, so only
+	// work cycles can be counted up front.
+	if s.workOnly() {
+		return s.config.TotalCycles
+	}
+
+	// A warmup and a cooldown bracket the cycles, one phase each.
+	brackets := 0
+	if s.config.CooldownDuration > 0 {
+		brackets++
+	}
+	if s.config.WarmupDuration > 0 {
+		brackets++
+	}
+
 	cycles := s.config.TotalCycles
 	phases := cycles
 
-	if s.config.LongBreakEvery > 0 {
-		longBreaks := (cycles - 1) / s.config.LongBreakEvery
+	if n := s.config.LongBreakEvery; n > 0 {
+		carried := s.config.CarriedCycles
+		longBreaks := (cycles-1+carried)/n - carried/n
 		shortBreaks := cycles - 1 - longBreaks
 		phases += longBreaks + shortBreaks
 	} else {
 		phases += cycles - 1
 	}
 
-	return phases
+	return phases + brackets
 }
 
+func (s *Session) workOnly() bool {
+	return s.config.LongBreakAfterWork > 0
+}
+
+// Counted reports whether completing the current phase advances PhasesComplete.
+func (s *Session) Counted() bool {
+	return !s.voidBreak && (!s.workOnly() || s.currentPhase == PhaseWork)
+}
+
+func (s *Session) Config() Config      { return s.config }
 func (s *Session) CurrentPhase() Phase { return s.currentPhase }
 func (s *Session) CyclesComplete() int { return s.cyclesComplete }
 func (s *Session) TotalCycles() int    { return s.config.TotalCycles }
 func (s *Session) TotalPhases() int    { return s.totalPhases }
 func (s *Session) PhasesComplete() int { return s.phasesComplete }
 
+// Retrying reports whether the current phase repeats a voided one.
+func (s *Session) Retrying() bool { return s.retrying }
+
+// CanRetry reports whether a work phase voided now would be run again.
+func (s *Session) CanRetry() bool {
+	return s.currentPhase == PhaseWork && s.retries < s.config.StrictMaxRetries
+}
+
+// PhaseDuration is the current phase's planned length, its break scaled by
+// Config.ProportionalBreaks.
 func (s *Session) PhaseDuration() time.Duration {
 	switch s.currentPhase {
 	case PhaseWork:
-		return s.config.WorkDuration
+		return s.workDuration()
 	case PhaseShortBreak:
-		return s.config.ShortBreakDuration
+		return s.scaleBreak(s.config.ShortBreakDuration)
 	case PhaseLongBreak:
-		return s.config.LongBreakDuration
+		if s.lunch && s.config.Lunch.Auto {
+			return max(s.scaleBreak(s.config.LongBreakDuration), s.config.Lunch.Duration)
+		}
+		return s.scaleBreak(s.config.LongBreakDuration)
+	case PhaseCooldown:
+		return s.config.CooldownDuration
+	case PhaseWarmup:
+		return s.config.WarmupDuration
 	default:
 		return 0
 	}
 }
 
+// workDuration is the planned length of the current cycle's work phase.
+func (s *Session) workDuration() time.Duration {
+	c := s.config
+	switch {
+	case s.finalWork():
+		return c.FinalWorkDuration
+	case len(c.WorkTaper) > 0:
+		return c.WorkTaper[min(s.cyclesComplete, len(c.WorkTaper)-1)]
+	case c.WorkTaperStep != 0:
+		return max(c.WorkDuration+time.Duration(s.cyclesComplete)*c.WorkTaperStep, c.WorkTaperFloor)
+	default:
+		return c.WorkDuration
+	}
+}
+
+// finalWork reports whether the current cycle is the last, and
+// Config.FinalWorkDuration shortens its work.
+func (s *Session) finalWork() bool {
+	c := s.config
+	return c.FinalWorkDuration > 0 && c.TotalCycles > 0 && s.cyclesComplete == c.TotalCycles-1
+}
+
+// FinalWork is Config.FinalWorkDuration while the last cycle's work is to
+// come or running, and 0 otherwise.
+func (s *Session) FinalWork() time.Duration {
+	if s.currentPhase != PhaseWork || !s.finalWork() {
+		return 0
+	}
+	return s.config.FinalWorkDuration
+}
+
+// scaleBreak shrinks a break in proportion to how much of the preceding work
+// phase was actually worked, never below MinBreakDuration.
+func (s *Session) scaleBreak(d time.Duration) time.Duration {
+	if !s.config.ProportionalBreaks {
+		return d
+	}
+
+	scaled := time.Duration(float64(d) * s.breakScale)
+	if scaled < s.config.MinBreakDuration {
+		scaled = min(s.config.MinBreakDuration, d)
+	}
+	return scaled
+}
+
+// PeekNext is the phase after the current one and its planned length,
+// should the current one complete as planned, without mutating the
+// session. Phases planned at zero length are passed over, as they are
+// never run; at the end of the session it is PhaseDone.
+func (s *Session) PeekNext() (Phase, time.Duration) {
+	sim := *s
+	sim.simulated = true
+	sim.NextPhase()
+	for sim.currentPhase != PhaseDone && sim.PhaseDuration() == 0 {
+		sim.NextPhase()
+	}
+	return sim.currentPhase, sim.PhaseDuration()
+}
+
+// NextPhase completes the current phase as planned.
 func (s *Session) NextPhase() Phase {
+	return s.CompletePhase(s.PhaseDuration())
+}
+
+// CompletePhase advances past the current phase, which ran for elapsed. It
+// differs from PhaseDuration when the phase was skipped.
+func (s *Session) CompletePhase(elapsed time.Duration) Phase {
+	if debug {
+		defer s.mustHoldInvariants()
+	}
 	if s.currentPhase == PhaseDone {
 		return PhaseDone
 	}
 
-	s.phasesComplete++
+	if s.Counted() {
+		s.phasesComplete++
+	}
+	retry := s.voidBreak
+	s.retrying, s.voidBreak = false, false
+	s.bank += s.Unused(elapsed)
+	s.extension, s.fromBank = 0, 0
+	s.lunch, s.lunchOffer = false, 0
 
 	switch s.currentPhase {
 	case PhaseWork:
+		s.scaleBreaks(elapsed)
 		s.cyclesComplete++
+		s.workSinceLong += elapsed
 
 		if s.config.TotalCycles > 0 && s.cyclesComplete >= s.config.TotalCycles {
-			s.currentPhase = PhaseDone
+			if s.config.CooldownDuration > 0 {
+				s.currentPhase = PhaseCooldown
+			} else {
+				s.currentPhase = PhaseDone
+			}
 			return s.currentPhase
 		}
 
-		if s.config.LongBreakEvery > 0 && s.cyclesComplete%s.config.LongBreakEvery == 0 {
+		switch {
+		case s.longBreakDue():
 			s.currentPhase = PhaseLongBreak
-		} else {
+			s.workSinceLong = 0
+		case s.config.EnforceLongBreak && s.Overdue() > 0:
+			s.currentPhase = PhaseLongBreak
+			s.enforcedAfter = s.workSinceLong
+			s.workSinceLong = 0
+		default:
 			s.currentPhase = PhaseShortBreak
 		}
 
 	case PhaseShortBreak, PhaseLongBreak:
 		s.currentPhase = PhaseWork
+		s.enforcedAfter = 0
+		s.retrying = retry
+
+	case PhaseWarmup:
+		s.currentPhase = PhaseWork
+
+	case PhaseCooldown:
+		s.currentPhase = PhaseDone
 	}
 
 	return s.currentPhase
 }
+
+// VoidPhase ends the current work phase without counting it, to be run
+// again, as Config.StrictPomodoro has it. The time worked still counts
+// toward the next long break. Past StrictMaxRetries the work runs again
+// after a short break rather than at once. Any other phase it completes.
+func (s *Session) VoidPhase(elapsed time.Duration) Phase {
+	if s.currentPhase != PhaseWork {
+		return s.CompletePhase(elapsed)
+	}
+	if debug {
+		defer s.mustHoldInvariants()
+	}
+	s.workSinceLong += elapsed
+	if s.CanRetry() {
+		s.retries++
+		s.retrying = true
+		return s.currentPhase
+	}
+	s.scaleBreaks(elapsed)
+	s.retrying = false
+	s.voidBreak = true
+	s.currentPhase = PhaseShortBreak
+	return s.currentPhase
+}
+
+// scaleBreaks sets how far Config.ProportionalBreaks shrinks the break
+// after the current work phase, which ran for elapsed.
+func (s *Session) scaleBreaks(elapsed time.Duration) {
+	planned := s.workDuration()
+	s.breakScale = 1
+	if planned > 0 && elapsed < planned {
+		s.breakScale = float64(elapsed) / float64(planned)
+	}
+}
+
+// Bank is the break time banked so far, with Config.BankBreaks.
+func (s *Session) Bank() time.Duration { return s.bank }
+
+// Extension is how much the current break has been extended by, and how
+// much of that came from the bank.
+func (s *Session) Extension() (total, fromBank time.Duration) {
+	return s.extension, s.fromBank
+}
+
+// Extend lengthens the current break by d, paid for from the bank as far
+// as it goes, and returns how much the bank paid. The rest is a true
+// extension. Outside a break it does nothing.
+func (s *Session) Extend(d time.Duration) time.Duration {
+	if d <= 0 || s.currentPhase != PhaseShortBreak && s.currentPhase != PhaseLongBreak {
+		return 0
+	}
+	paid := min(d, s.bank)
+	s.bank -= paid
+	s.extension += d
+	s.fromBank += paid
+	if s.lunchOffer > 0 && s.extension >= s.lunchOffer {
+		s.lunch, s.lunchOffer = true, 0
+	}
+	return paid
+}
+
+// StartLunch decides whether the current phase, a long break about to
+// start at start, is lunch: stretched to Config.Lunch.Duration with
+// Config.Lunch.Auto, else offered. Only the first long break in the
+// window is, and only if lunch would make it longer.
+func (s *Session) StartLunch(start time.Time) {
+	l := s.config.Lunch
+	if s.currentPhase != PhaseLongBreak || l.Duration <= 0 || s.lunched || !l.Covers(start) {
+		return
+	}
+	longer := l.Duration - s.PhaseDuration()
+	if longer <= 0 {
+		return
+	}
+	s.lunched = true
+	if l.Auto {
+		s.lunch = true
+	} else {
+		s.lunchOffer = longer
+	}
+}
+
+// Lunch reports whether the current phase is a long break stretched, or
+// extended, to lunch, and otherwise how much extending it would make it
+// lunch, if offered.
+func (s *Session) Lunch() (lunch bool, offer time.Duration) {
+	return s.lunch, s.lunchOffer
+}
+
+// Unused is the break time completing the current phase after elapsed
+// would bank: what is left of a break, extension included, with
+// Config.BankBreaks, and nothing otherwise.
+func (s *Session) Unused(elapsed time.Duration) time.Duration {
+	if !s.config.BankBreaks || s.currentPhase != PhaseShortBreak && s.currentPhase != PhaseLongBreak {
+		return 0
+	}
+	return max(s.PhaseDuration()+s.extension-elapsed, 0)
+}
+
+// Overdue is the work done since the last long break once it reaches the
+// guard, and 0 before that. During an enforced long break it is the work
+// that led to it.
+func (s *Session) Overdue() time.Duration {
+	if s.enforcedAfter > 0 {
+		return s.enforcedAfter
+	}
+	if s.config.LongBreakGuard > 0 && s.workSinceLong >= s.config.LongBreakGuard {
+		return s.workSinceLong
+	}
+	return 0
+}
+
+// Enforced reports whether the current phase is a long break the guard
+// forced.
+func (s *Session) Enforced() bool {
+	return s.enforcedAfter > 0
+}
+
+// UntilLongBreak counts the work phases left to finish, the current one
+// included, before the next long break. It is -1 unless long breaks come
+// every few cycles, or when the session ends first.
+func (s *Session) UntilLongBreak() int {
+	n := s.config.LongBreakEvery
+	if n <= 0 {
+		return -1
+	}
+	left := n - s.cadence()%n
+	if s.config.TotalCycles > 0 && s.cyclesComplete+left >= s.config.TotalCycles {
+		return -1
+	}
+	return left
+}
+
+// WorkUntilLongBreak is how much more work, as of the start of the current
+// phase, brings on the next long break. It is -1 unless long breaks come
+// after accumulated work.
+func (s *Session) WorkUntilLongBreak() time.Duration {
+	if s.config.LongBreakAfterWork <= 0 {
+		return -1
+	}
+	return max(s.config.LongBreakAfterWork-s.workSinceLong, 0)
+}
+
+func (s *Session) longBreakDue() bool {
+	if s.config.LongBreakAfterWork > 0 {
+		return s.workSinceLong >= s.config.LongBreakAfterWork
+	}
+	return s.config.LongBreakEvery > 0 && s.cadence()%s.config.LongBreakEvery == 0
+}
+
+// cadence counts the cycles long breaks every few cycles are spaced by.
+func (s *Session) cadence() int {
+	return s.cyclesComplete + s.config.CarriedCycles
+}
@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// Operations on a session, one per byte of a fuzzed sequence: the rest of
+// the byte is how much of the phase ran before a void or a skip.
+const (
+	opComplete = iota
+	opVoid
+	opSkip
+	numOps
+)
+
+// fuzzConfig turns fuzzed numbers into a valid finite schedule, bit by bit
+// of flags turning a feature on.
+func fuzzConfig(cycles, every, carried, flags uint8) Config {
+	cfg := Config{
+		WorkDuration:       25 * time.Minute,
+		ShortBreakDuration: 5 * time.Minute,
+		LongBreakDuration:  15 * time.Minute,
+		TotalCycles:        1 + int(cycles%12),
+		CarriedCycles:      int(carried % 8),
+		TransitionDuration: 10 * time.Second,
+	}
+	if flags&1 != 0 {
+		cfg.LongBreakAfterWork = time.Duration(1+every%4) * 25 * time.Minute
+		cfg.CarriedWork = time.Duration(carried) * time.Minute
+	} else {
+		cfg.LongBreakEvery = int(every % 6)
+	}
+	if flags&2 != 0 {
+		cfg.CooldownDuration = 5 * time.Minute
+	}
+	if flags&4 != 0 {
+		cfg.WarmupDuration = 3 * time.Minute
+	}
+	if flags&8 != 0 {
+		cfg.ProportionalBreaks = true
+		cfg.MinBreakDuration = time.Duration(every%3) * time.Minute
+	}
+	if flags&16 != 0 {
+		cfg.LongBreakGuard = 50 * time.Minute
+		cfg.EnforceLongBreak = true
+	}
+	if flags&32 != 0 {
+		cfg.StrictPomodoro = true
+		cfg.StrictMaxRetries = int(cycles % 3)
+	}
+	if flags&64 != 0 {
+		cfg.WorkTaperStep = -5 * time.Minute
+		cfg.WorkTaperFloor = 10 * time.Minute
+	}
+	if flags&128 != 0 {
+		cfg.FinalWorkDuration = 10 * time.Minute
+		cfg.BankBreaks = true
+	}
+	return cfg
+}
+
+// shifted is plan without its first phase, as it would be planned from the
+// start of the second.
+func shifted(plan []PlannedPhase, transition time.Duration) []PlannedPhase {
+	if len(plan) == 0 {
+		return nil
+	}
+	by := plan[0].Duration + transition
+	var out []PlannedPhase
+	for _, p := range plan[1:] {
+		p.Offset -= by
+		out = append(out, p)
+	}
+	return out
+}
+
+func FuzzSession(f *testing.F) {
+	// The long break boundaries: carried cycles landing on, just before,
+	// and just after the cadence, a cadence of 1, and one long break
+	// reached after the last cycle.
+	f.Add(uint8(4), uint8(4), uint8(3), uint8(0), []byte{0, 0, 0, 0, 0, 0, 0, 0})
+	f.Add(uint8(4), uint8(4), uint8(4), uint8(0), []byte{0, 0, 0, 0, 0, 0, 0, 0})
+	f.Add(uint8(5), uint8(4), uint8(5), uint8(2), []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	f.Add(uint8(3), uint8(1), uint8(0), uint8(6), []byte{0, 0, 0, 0, 0, 0, 0})
+	f.Add(uint8(4), uint8(4), uint8(0), uint8(0), []byte{0, 0, 0, 0, 0, 0, 0})
+	// Work accumulated toward a long break, carried in or cut short.
+	f.Add(uint8(5), uint8(1), uint8(40), uint8(1), []byte{0, 2 + 3*40, 0, 0, 0, 0, 0})
+	// Skipped work bringing on the guard's long break, and a proportional
+	// break scaled to nothing.
+	f.Add(uint8(6), uint8(0), uint8(0), uint8(16+8), []byte{2, 0, 2, 0, 0, 2, 0, 0, 0})
+	// Voids within and past the retries, with tapered and final work.
+	f.Add(uint8(4), uint8(2), uint8(0), uint8(32+64+128), []byte{1, 1, 1, 4, 0, 1, 0, 0, 0, 0})
+
+	f.Fuzz(func(t *testing.T, cycles, every, carried, flags uint8, ops []byte) {
+		cfg := fuzzConfig(cycles, every, carried, flags)
+		if err := cfg.Validate(); err != nil {
+			t.Skip(err)
+		}
+		s := NewSession(cfg)
+		if err := s.CheckInvariants(); err != nil {
+			t.Fatalf("new session: %v", err)
+		}
+
+		// Whatever the fuzzed ops, the session runs to its end as planned.
+		ops = append(ops, make([]byte, 4*cfg.TotalCycles+4)...)
+		for i, b := range ops {
+			before := *s
+			plan := s.Plan(0)
+			elapsed := s.PhaseDuration() * time.Duration(b/numOps) / 85
+
+			switch b % numOps {
+			case opComplete:
+				s.NextPhase()
+			case opVoid:
+				s.VoidPhase(elapsed)
+			case opSkip:
+				s.CompletePhase(elapsed)
+			}
+
+			if err := s.CheckInvariants(); err != nil {
+				t.Fatalf("op %d (%d) from %s: %v", i, b%numOps, before.currentPhase, err)
+			}
+			if before.currentPhase == PhaseDone && (s.currentPhase != PhaseDone || s.cyclesComplete != before.cyclesComplete || s.phasesComplete != before.phasesComplete) {
+				t.Fatalf("op %d moved the session on from PhaseDone", i)
+			}
+			if s.phasesComplete > s.totalPhases {
+				t.Fatalf("op %d: %d phases complete of %d", i, s.phasesComplete, s.totalPhases)
+			}
+			switch d := s.cyclesComplete - before.cyclesComplete; {
+			case d == 0:
+			case d == 1 && before.currentPhase == PhaseWork && !s.retrying:
+			default:
+				t.Fatalf("op %d from %s: cycles went %d to %d", i, before.currentPhase, before.cyclesComplete, s.cyclesComplete)
+			}
+
+			if b%numOps != opComplete {
+				continue
+			}
+			want := plan
+			if before.PhaseDuration() > 0 {
+				if plan[0].Phase != before.currentPhase || plan[0].Duration != before.PhaseDuration() {
+					t.Fatalf("op %d: ran %s for %s, planned %+v", i, before.currentPhase, before.PhaseDuration(), plan[0])
+				}
+				want = shifted(plan, cfg.TransitionDuration)
+			}
+			if got := s.Plan(0); !slices.Equal(got, want) {
+				t.Fatalf("op %d from %s: plan went\n%+v\nto\n%+v", i, before.currentPhase, want, got)
+			}
+		}
+		if s.currentPhase != PhaseDone {
+			t.Fatalf("session still at %s after running every phase", s.currentPhase)
+		}
+		if s.cyclesComplete != cfg.TotalCycles {
+			t.Fatalf("%d cycles complete of %d", s.cyclesComplete, cfg.TotalCycles)
+		}
+	})
+}
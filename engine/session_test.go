@@ -0,0 +1,555 @@
+package engine
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestVoidPastRetries(t *testing.T) {
+	s := NewSession(Config{
+		WorkDuration:       25 * time.Minute,
+		ShortBreakDuration: 5 * time.Minute,
+		TotalCycles:        2,
+		StrictPomodoro:     true,
+		StrictMaxRetries:   1,
+	})
+
+	steps := []struct {
+		void     bool
+		phase    Phase
+		retrying bool
+		counted  bool
+		cycles   int
+	}{
+		// Within the retries the work runs again at once, past them after
+		// a break that does not count.
+		{true, PhaseWork, true, true, 0},
+		{true, PhaseShortBreak, false, false, 0},
+		{false, PhaseWork, true, true, 0},
+		{false, PhaseShortBreak, false, true, 1},
+		{false, PhaseWork, false, true, 1},
+		{true, PhaseShortBreak, false, false, 1},
+		{false, PhaseWork, true, true, 1},
+		{false, PhaseDone, false, true, 2},
+	}
+	for i, step := range steps {
+		if step.void {
+			s.VoidPhase(10 * time.Minute)
+		} else {
+			s.NextPhase()
+		}
+		if err := s.CheckInvariants(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if s.CurrentPhase() != step.phase || s.Retrying() != step.retrying || s.Counted() != step.counted || s.CyclesComplete() != step.cycles {
+			t.Fatalf("step %d: at %s, retrying %t, counted %t, %d cycles; want %s, %t, %t, %d",
+				i, s.CurrentPhase(), s.Retrying(), s.Counted(), s.CyclesComplete(),
+				step.phase, step.retrying, step.counted, step.cycles)
+		}
+	}
+	if s.PhasesComplete() != s.TotalPhases() {
+		t.Errorf("%d phases complete of %d", s.PhasesComplete(), s.TotalPhases())
+	}
+}
+
+func TestLongBreakBoundaries(t *testing.T) {
+	const work = 25 * time.Minute
+	tests := []struct {
+		name string
+		cfg  Config
+		// worked is how long each work phase ran, breaks running as
+		// planned, and after is the phase each led to.
+		worked []time.Duration
+		after  []Phase
+	}{
+		{
+			name:   "every 2 cycles",
+			cfg:    Config{LongBreakEvery: 2, TotalCycles: 4},
+			worked: []time.Duration{work, work, work, work},
+			after:  []Phase{PhaseShortBreak, PhaseLongBreak, PhaseShortBreak, PhaseDone},
+		},
+		{
+			name:   "a skipped phase still counts as a cycle",
+			cfg:    Config{LongBreakEvery: 2, TotalCycles: 4},
+			worked: []time.Duration{work, time.Minute, work, work},
+			after:  []Phase{PhaseShortBreak, PhaseLongBreak, PhaseShortBreak, PhaseDone},
+		},
+		{
+			name:   "work landing exactly on the threshold",
+			cfg:    Config{LongBreakAfterWork: 2 * work, TotalCycles: 4},
+			worked: []time.Duration{work, work, work, work},
+			after:  []Phase{PhaseShortBreak, PhaseLongBreak, PhaseShortBreak, PhaseDone},
+		},
+		{
+			name:   "work a minute short of the threshold",
+			cfg:    Config{LongBreakAfterWork: 2*work + time.Minute, TotalCycles: 5},
+			worked: []time.Duration{work, work, work, work, work},
+			after:  []Phase{PhaseShortBreak, PhaseShortBreak, PhaseLongBreak, PhaseShortBreak, PhaseDone},
+		},
+		{
+			name:   "skipped work counts only what was worked",
+			cfg:    Config{LongBreakAfterWork: 2 * work, TotalCycles: 4},
+			worked: []time.Duration{work, 10 * time.Minute, work, work},
+			after:  []Phase{PhaseShortBreak, PhaseShortBreak, PhaseLongBreak, PhaseDone},
+		},
+		{
+			name:   "a long break due after the last cycle",
+			cfg:    Config{LongBreakEvery: 2, TotalCycles: 2},
+			worked: []time.Duration{work, work},
+			after:  []Phase{PhaseShortBreak, PhaseDone},
+		},
+		{
+			name:   "a long break due after the last cycle, with cooldown",
+			cfg:    Config{LongBreakAfterWork: 2 * work, TotalCycles: 2, CooldownDuration: 5 * time.Minute},
+			worked: []time.Duration{work, work},
+			after:  []Phase{PhaseShortBreak, PhaseCooldown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.WorkDuration = work
+			tt.cfg.ShortBreakDuration = 5 * time.Minute
+			tt.cfg.LongBreakDuration = 15 * time.Minute
+			s := NewSession(tt.cfg)
+			for i, worked := range tt.worked {
+				if s.CurrentPhase() != PhaseWork {
+					t.Fatalf("work phase %d: at %s", i+1, s.CurrentPhase())
+				}
+				if got := s.CompletePhase(worked); got != tt.after[i] {
+					t.Fatalf("work phase %d, %s of it: then %s, want %s", i+1, worked, got, tt.after[i])
+				}
+				if err := s.CheckInvariants(); err != nil {
+					t.Fatalf("work phase %d: %v", i+1, err)
+				}
+				if s.CurrentPhase() == PhaseShortBreak || s.CurrentPhase() == PhaseLongBreak {
+					s.NextPhase()
+				}
+			}
+		})
+	}
+}
+
+func TestFinalPhase(t *testing.T) {
+	base := Config{
+		WorkDuration:       25 * time.Minute,
+		ShortBreakDuration: 5 * time.Minute,
+		LongBreakDuration:  15 * time.Minute,
+		LongBreakEvery:     4,
+		TotalCycles:        2,
+		BankBreaks:         true,
+	}
+	withCooldown := base
+	withCooldown.CooldownDuration = 5 * time.Minute
+
+	tests := []struct {
+		name string
+		cfg  Config
+		// end ends the last phase, after the session has run as planned up
+		// to it, returning the phase the session moves to.
+		end  func(*Session) Phase
+		want Phase
+	}{
+		{
+			name: "completing the final work",
+			cfg:  base,
+			end:  (*Session).NextPhase,
+			want: PhaseDone,
+		},
+		{
+			name: "skipping the final work",
+			cfg:  base,
+			end:  func(s *Session) Phase { return s.CompletePhase(time.Minute) },
+			want: PhaseDone,
+		},
+		{
+			name: "skipping the final work at once",
+			cfg:  base,
+			end:  func(s *Session) Phase { return s.CompletePhase(0) },
+			want: PhaseDone,
+		},
+		{
+			name: "extending the final work does nothing",
+			cfg:  base,
+			end: func(s *Session) Phase {
+				if paid := s.Extend(10 * time.Minute); paid != 0 {
+					t.Errorf("extending work paid %s from the bank", paid)
+				}
+				if total, fromBank := s.Extension(); total != 0 || fromBank != 0 {
+					t.Errorf("work extended by %s, %s from the bank", total, fromBank)
+				}
+				return s.NextPhase()
+			},
+			want: PhaseDone,
+		},
+		{
+			name: "skipping the final work before cooldown",
+			cfg:  withCooldown,
+			end:  func(s *Session) Phase { return s.CompletePhase(time.Minute) },
+			want: PhaseCooldown,
+		},
+		{
+			name: "skipping the final cooldown",
+			cfg:  withCooldown,
+			end: func(s *Session) Phase {
+				s.NextPhase()
+				return s.CompletePhase(time.Minute)
+			},
+			want: PhaseDone,
+		},
+		{
+			name: "extending the final cooldown does nothing",
+			cfg:  withCooldown,
+			end: func(s *Session) Phase {
+				s.NextPhase()
+				if paid := s.Extend(10 * time.Minute); paid != 0 {
+					t.Errorf("extending cooldown paid %s from the bank", paid)
+				}
+				return s.NextPhase()
+			},
+			want: PhaseDone,
+		},
+		{
+			name: "ending a session already done",
+			cfg:  base,
+			end: func(s *Session) Phase {
+				s.NextPhase()
+				return s.CompletePhase(time.Minute)
+			},
+			want: PhaseDone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession(tt.cfg)
+			s.NextPhase()
+			// The break before the final work banks what was not taken of
+			// it, which the final work cannot spend.
+			s.Extend(2 * time.Minute)
+			s.CompletePhase(3 * time.Minute)
+			if s.CurrentPhase() != PhaseWork || s.CyclesComplete() != 1 {
+				t.Fatalf("at %s after %d cycles, want the final work", s.CurrentPhase(), s.CyclesComplete())
+			}
+			if bank := s.Bank(); bank != 4*time.Minute {
+				t.Fatalf("banked %s, want 4m", bank)
+			}
+
+			if got := tt.end(s); got != tt.want {
+				t.Fatalf("then %s, want %s", got, tt.want)
+			}
+			if err := s.CheckInvariants(); err != nil {
+				t.Fatal(err)
+			}
+			if s.CyclesComplete() != 2 {
+				t.Errorf("%d cycles complete, want 2", s.CyclesComplete())
+			}
+			if s.CurrentPhase() == PhaseDone && s.PhasesComplete() != s.TotalPhases() {
+				t.Errorf("%d phases complete of %d", s.PhasesComplete(), s.TotalPhases())
+			}
+			if s.Bank() != 4*time.Minute {
+				t.Errorf("bank %s after the final phase, want the 4m banked before", s.Bank())
+			}
+		})
+	}
+}
+
+func TestEveryPhaseHasAName(t *testing.T) {
+	seen := map[string]Phase{}
+	for p := PhaseWork; p <= PhaseDone; p++ {
+		name := p.String()
+		if name == "Unknown" {
+			t.Errorf("phase %d has no name", p)
+			continue
+		}
+		if q, ok := seen[name]; ok {
+			t.Errorf("phases %d and %d are both %q", q, p, name)
+		}
+		seen[name] = p
+
+		if got, err := ParsePhase(name); err != nil || got != p {
+			t.Errorf("ParsePhase(%q) = %d, %v, want %d", name, got, err, p)
+		}
+		text, _ := p.MarshalText()
+		var back Phase
+		if err := back.UnmarshalText(text); err != nil || back != p {
+			t.Errorf("phase %d as text %q came back %d, %v", p, text, back, err)
+		}
+	}
+	for _, p := range []Phase{-1, PhaseDone + 1} {
+		if name := p.String(); name != "Unknown" {
+			t.Errorf("phase %d named %q", p, name)
+		}
+	}
+	if _, err := ParsePhase("Unknown"); err == nil {
+		t.Error("ParsePhase took Unknown")
+	}
+}
+
+// TestBank runs the first break of a session banking breaks in different
+// ways, and checks what the bank holds after it and what extending the
+// second one draws.
+func TestBank(t *testing.T) {
+	const short = 5 * time.Minute
+	tests := []struct {
+		name    string
+		banking bool
+		// extend and elapsed are how much the first break is extended by
+		// and how long it runs.
+		extend, elapsed time.Duration
+		// bank is what the bank holds after the first break; extend the
+		// second by 10m, and the bank pays paid.
+		bank, paid time.Duration
+	}{
+		{"taken in full", true, 0, short, 0, 0},
+		{"skipped at once", true, 0, 0, short, short},
+		{"cut short", true, 0, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute},
+		{"extended then cut short", true, 4 * time.Minute, 6 * time.Minute, 3 * time.Minute, 3 * time.Minute},
+		{"extended and taken", true, 4 * time.Minute, 9 * time.Minute, 0, 0},
+		{"overrun", true, 0, 7 * time.Minute, 0, 0},
+		{"not banking", false, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession(Config{
+				WorkDuration:       25 * time.Minute,
+				ShortBreakDuration: short,
+				TotalCycles:        3,
+				BankBreaks:         tt.banking,
+			})
+			if got := s.Unused(2 * time.Minute); got != 0 {
+				t.Errorf("work would bank %s", got)
+			}
+			s.NextPhase()
+			if paid := s.Extend(tt.extend); paid != 0 {
+				t.Errorf("extending the first break paid %s from an empty bank", paid)
+			}
+			s.CompletePhase(tt.elapsed)
+			if got := s.Bank(); got != tt.bank {
+				t.Fatalf("banked %s, want %s", got, tt.bank)
+			}
+			if total, fromBank := s.Extension(); total != 0 || fromBank != 0 {
+				t.Errorf("work starts extended by %s, %s from the bank", total, fromBank)
+			}
+
+			s.NextPhase()
+			if paid := s.Extend(10 * time.Minute); paid != tt.paid {
+				t.Errorf("extending 10m paid %s from the bank, want %s", paid, tt.paid)
+			}
+			if total, fromBank := s.Extension(); total != 10*time.Minute || fromBank != tt.paid {
+				t.Errorf("extended by %s, %s from the bank, want 10m, %s", total, fromBank, tt.paid)
+			}
+			if got := s.Bank(); got != tt.bank-tt.paid || got < 0 {
+				t.Errorf("bank %s after extending, want %s", got, tt.bank-tt.paid)
+			}
+			if err := s.CheckInvariants(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// TestPeekNext walks sessions of every shape phase by phase, checking that
+// PeekNext names the phase NextPhase goes on to, and its length, without
+// changing the session.
+func TestPeekNext(t *testing.T) {
+	base := Config{
+		WorkDuration:       25 * time.Minute,
+		ShortBreakDuration: 5 * time.Minute,
+		LongBreakDuration:  15 * time.Minute,
+		LongBreakEvery:     2,
+		TotalCycles:        4,
+	}
+	type step struct {
+		phase Phase
+		d     time.Duration
+	}
+	tests := []struct {
+		name string
+		cfg  func(*Config)
+		want []step
+	}{
+		{"long break every 2", nil, []step{
+			{PhaseWork, 25 * time.Minute}, {PhaseShortBreak, 5 * time.Minute},
+			{PhaseWork, 25 * time.Minute}, {PhaseLongBreak, 15 * time.Minute},
+			{PhaseWork, 25 * time.Minute}, {PhaseShortBreak, 5 * time.Minute},
+			{PhaseWork, 25 * time.Minute}, {PhaseDone, 0},
+		}},
+		{"warmup and cooldown", func(c *Config) {
+			c.WarmupDuration, c.CooldownDuration, c.TotalCycles = 3*time.Minute, 5*time.Minute, 2
+		}, []step{
+			{PhaseWarmup, 3 * time.Minute}, {PhaseWork, 25 * time.Minute}, {PhaseShortBreak, 5 * time.Minute},
+			{PhaseWork, 25 * time.Minute}, {PhaseCooldown, 5 * time.Minute}, {PhaseDone, 0},
+		}},
+		{"taper", func(c *Config) {
+			c.WorkTaper, c.TotalCycles = []time.Duration{50 * time.Minute, 40 * time.Minute}, 3
+		}, []step{
+			{PhaseWork, 50 * time.Minute}, {PhaseShortBreak, 5 * time.Minute},
+			{PhaseWork, 40 * time.Minute}, {PhaseLongBreak, 15 * time.Minute},
+			{PhaseWork, 40 * time.Minute}, {PhaseDone, 0},
+		}},
+		{"no short breaks", func(c *Config) {
+			c.ShortBreakDuration, c.TotalCycles = 0, 3
+		}, []step{
+			{PhaseWork, 25 * time.Minute},
+			{PhaseWork, 25 * time.Minute}, {PhaseLongBreak, 15 * time.Minute},
+			{PhaseWork, 25 * time.Minute}, {PhaseDone, 0},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := base
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+			s := NewSession(cfg)
+			got := []step{{s.CurrentPhase(), s.PhaseDuration()}}
+			for s.CurrentPhase() != PhaseDone {
+				before := *s
+				phase, d := s.PeekNext()
+				if !reflect.DeepEqual(*s, before) {
+					t.Fatalf("PeekNext at %s changed the session", s.CurrentPhase())
+				}
+				// Zero-length phases are never run, so never next.
+				s.NextPhase()
+				for s.CurrentPhase() != PhaseDone && s.PhaseDuration() == 0 {
+					s.NextPhase()
+				}
+				if next := (step{s.CurrentPhase(), s.PhaseDuration()}); next != (step{phase, d}) {
+					t.Errorf("PeekNext %s %s, then %s %s", phase, d, next.phase, next.d)
+				}
+				got = append(got, step{phase, d})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("phases %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLunchCovers(t *testing.T) {
+	l := Lunch{From: 12 * time.Hour, Until: 13*time.Hour + 30*time.Minute, Duration: time.Hour}
+	for clock, want := range map[string]bool{
+		"11:59": false, "12:00": true, "12:45": true, "13:29": true, "13:30": false, "00:30": false,
+	} {
+		at, err := time.ParseInLocation("2006-01-02 15:04", "2025-01-06 "+clock, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := l.Covers(at); got != want {
+			t.Errorf("Covers(%s) = %v, want %v", clock, got, want)
+		}
+	}
+}
+
+// TestStartLunch starts the session's long breaks at different times of
+// day, with lunch taken outright or only offered.
+func TestStartLunch(t *testing.T) {
+	day := func(h, m int) time.Time { return time.Date(2025, time.January, 6, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		name string
+		auto bool
+		long time.Duration
+		// at is when each long break starts; lunch is the length the long
+		// breaks run, or with an offer, are offered to.
+		at    []time.Time
+		lunch []time.Duration
+		offer []time.Duration
+	}{
+		{"auto in the window", true, 15 * time.Minute, []time.Time{day(12, 10)}, []time.Duration{time.Hour}, []time.Duration{0}},
+		{"offered in the window", false, 15 * time.Minute, []time.Time{day(12, 10)}, []time.Duration{15 * time.Minute}, []time.Duration{45 * time.Minute}},
+		{"before the window", true, 15 * time.Minute, []time.Time{day(11, 59)}, []time.Duration{15 * time.Minute}, []time.Duration{0}},
+		{"after the window", true, 15 * time.Minute, []time.Time{day(13, 30)}, []time.Duration{15 * time.Minute}, []time.Duration{0}},
+		{"long break longer than lunch", true, 90 * time.Minute, []time.Time{day(12, 10)}, []time.Duration{90 * time.Minute}, []time.Duration{0}},
+		{"only the first in the window", true, 15 * time.Minute, []time.Time{day(12, 0), day(13, 0)}, []time.Duration{time.Hour, 15 * time.Minute}, []time.Duration{0, 0}},
+		{"first after the window opens", true, 15 * time.Minute, []time.Time{day(11, 0), day(12, 30)}, []time.Duration{15 * time.Minute, time.Hour}, []time.Duration{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSession(Config{
+				WorkDuration:       25 * time.Minute,
+				ShortBreakDuration: 5 * time.Minute,
+				LongBreakDuration:  tt.long,
+				LongBreakEvery:     1,
+				TotalCycles:        len(tt.at) + 1,
+				Lunch:              Lunch{From: 12 * time.Hour, Until: 13*time.Hour + 30*time.Minute, Duration: time.Hour, Auto: tt.auto},
+			})
+			for i, at := range tt.at {
+				// Nothing but a long break about to start is lunch.
+				s.StartLunch(at)
+				if lunch, offer := s.Lunch(); lunch || offer != 0 {
+					t.Fatalf("work taken for lunch")
+				}
+				s.NextPhase()
+				s.StartLunch(at)
+				if d := s.PhaseDuration(); d != tt.lunch[i] {
+					t.Errorf("long break %d runs %s, want %s", i+1, d, tt.lunch[i])
+				}
+				lunch, offer := s.Lunch()
+				if offer != tt.offer[i] || lunch != (tt.lunch[i] == time.Hour) {
+					t.Errorf("long break %d: lunch %v, offered %s, want %v, %s", i+1, lunch, offer, tt.lunch[i] == time.Hour, tt.offer[i])
+				}
+				if offer > 0 {
+					// Extending by the offer takes it.
+					s.Extend(offer)
+					if lunch, offer := s.Lunch(); !lunch || offer != 0 {
+						t.Errorf("extended by the offer: lunch %v, offered %s", lunch, offer)
+					}
+				}
+				if err := s.CheckInvariants(); err != nil {
+					t.Fatal(err)
+				}
+				s.NextPhase()
+				if lunch, offer := s.Lunch(); lunch || offer != 0 {
+					t.Errorf("lunch carried past its break")
+				}
+			}
+		})
+	}
+}
+
+// TestPlanAtLunch plans sessions from different times of day, checking
+// lunch only changes the long break that would start in its window.
+func TestPlanAtLunch(t *testing.T) {
+	cfg := Config{
+		WorkDuration:       50 * time.Minute,
+		ShortBreakDuration: 10 * time.Minute,
+		LongBreakDuration:  20 * time.Minute,
+		LongBreakEvery:     2,
+		TotalCycles:        4,
+		Lunch:              Lunch{From: 12 * time.Hour, Until: 13*time.Hour + 30*time.Minute, Duration: time.Hour, Auto: true},
+	}
+	// The long break is 1h50m in, and the session 4h10m long, 4h50m with
+	// lunch.
+	for _, tt := range []struct {
+		start string
+		lunch bool
+	}{
+		{"08:00", false},
+		{"10:10", true},
+		{"11:39", true},
+		{"11:41", false},
+	} {
+		start, err := time.Parse("2006-01-02 15:04", "2025-01-06 "+tt.start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := NewSession(cfg)
+		plan, plain := s.PlanAt(start, 0), s.Plan(0)
+		if len(plan) != len(plain) {
+			t.Fatalf("from %s: %d phases, want %d", tt.start, len(plan), len(plain))
+		}
+		for i := range plan {
+			want := plain[i]
+			if want.Phase == PhaseLongBreak && tt.lunch {
+				want.Duration = time.Hour
+			}
+			if i > 3 && tt.lunch {
+				want.Offset += 40 * time.Minute
+			}
+			if plan[i] != want {
+				t.Errorf("from %s: phase %d planned %+v, want %+v", tt.start, i, plan[i], want)
+			}
+		}
+	}
+}
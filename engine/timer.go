@@ -7,101 +7,971 @@ import (
 
 const DefaultTickInterval = 200 * time.Millisecond
 
+// TimerEvent is a tick of the running phase unless Type says otherwise.
+// Session events carry the current position but no phase progress.
+// Transition events count down to the phase in Phase, their Elapsed,
+// Remaining, and Total being the transition's. Snooze events do the same
+// for a snooze, the last one carrying Ended.
 type TimerEvent struct {
-	Phase         Phase
-	Elapsed       time.Duration
-	Remaining     time.Duration
-	Total         time.Duration
-	Fraction      float64
-	PhaseComplete bool
-	CycleNum      int
-	TotalCycles   int
-	PhaseNum      int
-	TotalPhases   int
+	Type             EventType
+	Phase            Phase
+	Elapsed          time.Duration
+	Remaining        time.Duration
+	Total            time.Duration
+	SessionRemaining time.Duration
+	Fraction         float64
+	PhaseComplete    bool
+	Ended            EndReason
+	Paused           bool
+	PausedTotal      time.Duration
+	// PhaseStartedAt is when the phase started on the timer's clock, moved
+	// forward by the time paused, so it plus Elapsed is the event's time
+	// and plus Total is when the phase is due to end. It is only set on
+	// phase ticks.
+	PhaseStartedAt time.Time
+	// Capped marks the interrupted end of a phase cut off by
+	// Config.HardCap.
+	Capped  bool
+	Counted bool
+	// Extra marks a phase spliced in with Inject rather than scheduled.
+	Extra bool
+	// Voided marks the end of a work phase Config.StrictPomodoro voids,
+	// which is not counted. Retry marks the phase run again in its place.
+	Voided bool
+	Retry  bool
+	// Final is the shortened length of the session's last work phase, set
+	// on its events and those leading up to it.
+	Final       time.Duration
+	CycleNum    int
+	TotalCycles int
+	PhaseNum    int
+	TotalPhases int
+	// UntilLongBreak and WorkUntilLongBreak count down to the next long
+	// break in cycles or in work time, whichever cadence the session uses.
+	// The other one is -1.
+	UntilLongBreak     int
+	WorkUntilLongBreak time.Duration
+	// Overdue and Enforced follow Session's: the work done without a long
+	// break once it passes the guard, and whether this long break was forced.
+	Overdue  time.Duration
+	Enforced bool
+	// Snoozes counts the snoozes the current break has been given, through
+	// the snooze and transition after it.
+	Snoozes int
+	// ClockJump is how far the wall clock has just gone back, set on the
+	// one event after it did. Elapsed and the rest carry on regardless.
+	ClockJump time.Duration
+	// SessionWork is the work done in the session so far, this phase's
+	// included, as SessionSummary.Work adds it up. Only phase ticks have it.
+	SessionWork time.Duration
+	// NextPhase and NextDuration are what runs after this phase as things
+	// stand: a pending extra, else the schedule's next phase as
+	// Session.PeekNext has it, or PhaseDone once Stop has been asked for.
+	// Only phase ticks have them.
+	NextPhase    Phase
+	NextDuration time.Duration
+	// Bank is the break time banked with Config.BankBreaks. Extended is
+	// how much the current break has been extended by, FromBank how much
+	// of that the bank paid for. Banked, on the end of a break, is what
+	// it adds to the bank, which Bank does not have yet.
+	Bank     time.Duration
+	Extended time.Duration
+	FromBank time.Duration
+	Banked   time.Duration
+	// Lunch marks a long break stretched to Config.Lunch, or extended to
+	// it. LunchOffer is, on one only offered lunch, how much extending it
+	// would take.
+	Lunch      bool
+	LunchOffer time.Duration
+
+	// Set on EventSessionStarted.
+	Config *Config
+	Plan   []PlannedPhase
+
+	// Set on EventSessionEnded.
+	Summary *SessionSummary
+}
+
+// CycleCompleted is the cycle e completes, if any: a cycle is a work phase
+// and the break after it, so the end of a break completes the one before,
+// and the end of the last work phase completes the last, with no break to
+// follow. Extras, warmups, cooldowns, voided work, and interrupted phases
+// complete none.
+func (e TimerEvent) CycleCompleted() (cycle int, ok bool) {
+	if e.Type != EventTick || e.Extra || e.Voided || e.Ended == "" || e.Ended == EndInterrupted {
+		return 0, false
+	}
+	switch e.Phase {
+	case PhaseShortBreak, PhaseLongBreak:
+		// The work before it is already counted in CycleNum.
+		return e.CycleNum - 1, e.CycleNum > 1
+	case PhaseWork:
+		return e.CycleNum, e.TotalCycles > 0 && e.CycleNum == e.TotalCycles
+	}
+	return 0, false
 }
 
+// EventType tells what a TimerEvent reports.
+type EventType int
+
+const (
+	EventTick EventType = iota
+	EventSessionStarted
+	EventSessionEnded
+	EventTransition
+	EventSnooze
+	// EventAway is a wait for Timer.CheckIn before the work after a break,
+	// with Config.CheckIn. Elapsed is how long it has gone on.
+	EventAway
+
+	// numEventTypes follows the last event type.
+	numEventTypes
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventTick:
+		return "tick"
+	case EventSessionStarted:
+		return "session started"
+	case EventSessionEnded:
+		return "session ended"
+	case EventTransition:
+		return "transition"
+	case EventSnooze:
+		return "snooze"
+	case EventAway:
+		return "away"
+	default:
+		return "Unknown"
+	}
+}
+
+// SessionSummary describes a session once it has stopped. Ended is
+// EndCompleted or EndInterrupted; Stopped marks a completed session cut
+// short by Stop, Abandon, or Config.MaxDuration, or by Config.HardCap,
+// which also sets Capped. Snoozed is the time spent snoozing over the
+// session's Snoozes. Voided counts the work phases Config.StrictPomodoro voided.
+// Bank is the break time left banked with Config.BankBreaks. Away is the
+// time spent waiting for Timer.CheckIn with Config.CheckIn.
+type SessionSummary struct {
+	Ended          EndReason
+	Stopped        bool
+	Capped         bool
+	CyclesComplete int
+	PhasesComplete int
+	Voided         int
+	Work           time.Duration
+	Snoozed        time.Duration
+	Snoozes        int
+	Bank           time.Duration
+	Away           time.Duration
+}
+
+// EndReason says how a phase ended. It is empty on events for a phase that
+// is still running.
+type EndReason string
+
+const (
+	EndCompleted   EndReason = "completed"
+	EndSkipped     EndReason = "skipped"
+	EndInterrupted EndReason = "interrupted"
+)
+
+type control int
+
+const (
+	controlSkip control = iota
+	controlPause
+	controlResume
+	controlStop
+	controlAbandon
+	controlCheckIn
+)
+
+// Timer runs a Session in real time, or on any Clock, reporting it as
+// TimerEvents. Its controls are safe to call from any goroutine while Run
+// is running: each is queued on a channel only Run's goroutine reads,
+// between ticks, so neither the timer's state nor the session's is ever
+// touched by another. A control sent while eight of its kind are waiting is
+// dropped rather than block the caller.
 type Timer struct {
 	clock        Clock
 	tickInterval time.Duration
 	session      *Session
+	controls     chan control
+	extras       chan Extra
+	snoozes      chan time.Duration
+	extends      chan time.Duration
+	queue        []Extra
+	stopping     bool
+	// startPaused carries a pause asked for during a transition over to
+	// the phase after it.
+	startPaused bool
+
+	// snoozeDue is the snooze the last break has been given, or is being
+	// given. afterBreak marks that the phase about to run follows a break.
+	snoozeDue    time.Duration
+	snoozeCount  int
+	afterBreak   bool
+	snoozed      time.Duration
+	snoozesTaken int
+
+	// checkedIn marks that the work about to run after a break has been
+	// checked in for; away adds up the waits for it.
+	checkedIn bool
+	away      time.Duration
+
+	// hardCap fires once Config.HardCap has passed, setting capped.
+	hardCap <-chan time.Time
+	capped  bool
+	// abandoned marks the phase just run as cut off by Abandon.
+	abandoned bool
+
+	// voided marks the phase just run as voided.
+	voided bool
+
+	// work adds up the work phases run so far, as SessionSummary.Work.
+	work time.Duration
 }
 
+// Extra is an unscheduled phase, run ahead of the rest of the schedule.
+type Extra struct {
+	Phase    Phase
+	Duration time.Duration
+}
+
+// phaseRun is one phase as the timer runs it, scheduled or extra.
+type phaseRun struct {
+	phase    Phase
+	duration time.Duration
+	upcoming time.Duration
+	extra    bool
+}
+
+// NewTimer runs cfg on the system clock, ticking every
+// DefaultTickInterval.
 func NewTimer(cfg Config) *Timer {
 	return NewTimerWithClock(cfg, RealClock{}, DefaultTickInterval)
 }
 
+// NewTimerWithClock runs cfg on clock, ticking every tickInterval. With a
+// MockClock a session runs as fast as the clock is advanced.
 func NewTimerWithClock(cfg Config, clock Clock, tickInterval time.Duration) *Timer {
 	return &Timer{
 		clock:        clock,
 		tickInterval: tickInterval,
 		session:      NewSession(cfg),
+		controls:     make(chan control, 8),
+		extras:       make(chan Extra, 8),
+		snoozes:      make(chan time.Duration, 8),
+		extends:      make(chan time.Duration, 8),
 	}
 }
 
+// NewExtraTimer runs nothing but one extra phase.
+func NewExtraTimer(x Extra, clock Clock, tickInterval time.Duration) *Timer {
+	t := NewTimerWithClock(Config{}, clock, tickInterval)
+	t.session.currentPhase = PhaseDone
+	t.Inject(x)
+	return t
+}
+
+// Session is the timer's session. Once Run has started, only its Config,
+// TotalCycles, and TotalPhases, which never change, are safe to read;
+// events carry the rest.
 func (t *Timer) Session() *Session { return t.session }
 
-// Run blocks until session completes or context is cancelled.
+// Skip, Pause, and Resume are safe to call from any goroutine. Time spent
+// paused does not count toward the phase.
+func (t *Timer) Skip()   { t.send(controlSkip) }
+func (t *Timer) Pause()  { t.send(controlPause) }
+func (t *Timer) Resume() { t.send(controlResume) }
+
+// Stop ends the session once the current phase is over, as if it had been
+// the last one planned.
+func (t *Timer) Stop() { t.send(controlStop) }
+
+// Abandon ends the session now rather than once the phase is over, the
+// phase interrupted and not counted, as Config.HardCap does. Between
+// phases it is Stop.
+func (t *Timer) Abandon() { t.send(controlAbandon) }
+
+// CheckIn starts the work a break has ended before, with Config.CheckIn,
+// as Skip does. Otherwise it does nothing.
+func (t *Timer) CheckIn() { t.send(controlCheckIn) }
+
+// Snooze puts off the work after the current break by d, as time counted
+// toward neither. It can be called during the break, the transition after
+// it, or the snooze itself, which it extends, up to Config.MaxSnoozes times
+// per break; otherwise it does nothing.
+func (t *Timer) Snooze(d time.Duration) {
+	select {
+	case t.snoozes <- d:
+	default:
+	}
+}
+
+// Extend lengthens the current break by d, drawing on the time
+// Config.BankBreaks has banked first. Past the bank it extends the break
+// all the same. Outside a scheduled break it does nothing.
+func (t *Timer) Extend(d time.Duration) {
+	select {
+	case t.extends <- d:
+	default:
+	}
+}
+
+// Inject ends the current phase as though skipped and runs x in its place,
+// then carries on with the schedule. Extras count toward neither cycles nor
+// the planned phase totals.
+func (t *Timer) Inject(x Extra) {
+	select {
+	case t.extras <- x:
+	default:
+	}
+}
+
+func (t *Timer) send(c control) {
+	select {
+	case t.controls <- c:
+	default:
+	}
+}
+
+// Run blocks until session completes or context is cancelled. The first
+// event is EventSessionStarted and the last EventSessionEnded, which is sent
+// even after cancellation.
 func (t *Timer) Run(ctx context.Context, events chan<- TimerEvent) error {
 	defer close(events)
 
-	for t.session.CurrentPhase() != PhaseDone {
-		if err := t.runPhase(ctx, events); err != nil {
-			return err
+	cfg := t.session.config
+	started := t.position()
+	started.Type = EventSessionStarted
+	started.Config = &cfg
+	started.Plan = t.session.PlanAt(t.clock.Now(), 0)
+	events <- started
+
+	var err error
+	summary := SessionSummary{Ended: EndCompleted}
+	session := newStopwatch(t.clock)
+	if cfg.HardCap > 0 {
+		t.hardCap = t.clock.After(cfg.HardCap)
+	}
+	ran := false
+	for {
+		run, ok := t.next()
+		if !ok {
+			break
+		}
+
+		if ran && run.duration > 0 {
+			var redo bool
+			redo, err = t.between(ctx, events, run)
+			if err != nil {
+				summary.Ended = EndInterrupted
+				break
+			}
+			if t.stopping {
+				summary.Stopped, summary.Capped = true, t.capped
+				break
+			}
+			if redo {
+				ran = false
+				continue
+			}
+		}
+		ran = ran || run.duration > 0
+
+		var elapsed time.Duration
+		elapsed, err = t.runPhase(ctx, events, run)
+		if run.phase == PhaseWork {
+			t.work += elapsed
+		}
+		if t.voided {
+			summary.Voided++
+		}
+		if err != nil {
+			summary.Ended = EndInterrupted
+			break
+		}
+		if t.capped || t.abandoned {
+			summary.Stopped, summary.Capped = true, t.capped
+			break
+		}
+		switch {
+		case run.extra:
+		case t.voided:
+			t.session.VoidPhase(elapsed)
+		default:
+			t.session.CompletePhase(elapsed)
+		}
+		t.afterBreak = isBreak(run)
+
+		if limit := cfg.MaxDuration; limit > 0 && session.elapsed() >= limit {
+			t.stopping = true
+		}
+		if t.stopping {
+			summary.Stopped = true
+			break
 		}
-		t.session.NextPhase()
 	}
 
-	return nil
+	summary.CyclesComplete = t.session.CyclesComplete()
+	summary.PhasesComplete = t.session.PhasesComplete()
+	summary.Work = t.work
+	summary.Snoozed, summary.Snoozes = t.snoozed, t.snoozesTaken
+	summary.Bank = t.session.Bank()
+	summary.Away = t.away
+	ended := t.position()
+	ended.Type = EventSessionEnded
+	ended.Summary = &summary
+	events <- ended
+
+	return err
 }
 
-func (t *Timer) runPhase(ctx context.Context, events chan<- TimerEvent) error {
-	duration := t.session.PhaseDuration()
-	if duration == 0 {
-		return nil
+// next picks the phase to run: a pending extra if any, else the schedule's.
+func (t *Timer) next() (phaseRun, bool) {
+	for drained := false; !drained; {
+		select {
+		case x := <-t.extras:
+			t.queue = append(t.queue, x)
+		default:
+			drained = true
+		}
+	}
+
+	if len(t.queue) > 0 {
+		x := t.queue[0]
+		t.queue = t.queue[1:]
+		upcoming := t.session.plannedDuration()
+		if upcoming > 0 {
+			upcoming += t.session.config.TransitionDuration
+		}
+		return phaseRun{phase: x.Phase, duration: x.Duration, upcoming: upcoming, extra: true}, true
+	}
+	if t.session.CurrentPhase() == PhaseDone {
+		return phaseRun{}, false
+	}
+	if t.session.config.Lunch.Duration > 0 {
+		// A long break starts once the transition before it is over.
+		t.session.StartLunch(t.clock.Now().Add(t.session.config.TransitionDuration))
+	}
+	return phaseRun{
+		phase:    t.session.CurrentPhase(),
+		duration: t.session.PhaseDuration(),
+		upcoming: t.session.upcomingDuration(),
+	}, true
+}
+
+func (t *Timer) position() TimerEvent {
+	event := TimerEvent{
+		Phase:       t.session.CurrentPhase(),
+		Counted:     t.session.Counted(),
+		CycleNum:    t.session.CyclesComplete() + 1,
+		TotalCycles: t.session.TotalCycles(),
+		PhaseNum:    t.session.PhasesComplete() + 1,
+		TotalPhases: t.session.TotalPhases(),
+
+		UntilLongBreak:     t.session.UntilLongBreak(),
+		WorkUntilLongBreak: t.session.WorkUntilLongBreak(),
+		Overdue:            t.session.Overdue(),
+		Enforced:           t.session.Enforced(),
+		Snoozes:            t.snoozeCount,
+		Retry:              t.session.Retrying(),
+		Final:              t.session.FinalWork(),
+		Bank:               t.session.Bank(),
+	}
+	event.Extended, event.FromBank = t.session.Extension()
+	event.Lunch, event.LunchOffer = t.session.Lunch()
+	return event
+}
+
+// runPhase returns how long the phase actually ran, excluding pauses: its
+// full duration, extensions included, unless it was skipped or interrupted.
+func (t *Timer) runPhase(ctx context.Context, events chan<- TimerEvent, run phaseRun) (time.Duration, error) {
+	if run.duration == 0 {
+		return 0, nil
+	}
+	t.snoozeDue, t.snoozeCount = 0, 0
+	t.checkedIn = false
+	t.voided = false
+
+	watch := newStopwatch(t.clock)
+	ticker := t.clock.NewTicker(t.tickInterval)
+	defer ticker.Stop()
+
+	// pausedAt is the stopwatch's reading when the pause started.
+	var pausedTotal, pausedAt time.Duration
+	paused := t.startPaused
+	t.startPaused = false
+
+	elapsed := func() time.Duration {
+		now := watch.elapsed()
+		if paused {
+			now = pausedAt
+		}
+		return now - pausedTotal
+	}
+	pausedSoFar := func() time.Duration {
+		if paused {
+			return pausedTotal + watch.elapsed() - pausedAt
+		}
+		return pausedTotal
+	}
+	// Only active time completes a phase, so a pause spanning the point
+	// where it would have ended keeps it open until resumed.
+	phaseEvent := func() TimerEvent {
+		event := t.event(elapsed(), run)
+		event.Paused = paused
+		event.PausedTotal = pausedSoFar()
+		event.PhaseStartedAt = watch.start.Add(event.PausedTotal)
+		event.ClockJump = watch.jumped()
+		return event
+	}
+	// The consumer drains events until they are closed, so the interrupted
+	// phase is reported even though ctx is done.
+	interrupted := func() (time.Duration, error) {
+		event := phaseEvent()
+		event.Ended = EndInterrupted
+		t.settle(&event, run)
+		events <- event
+		return event.Elapsed, ctx.Err()
 	}
 
-	start := t.clock.Now()
+	// The last stretch is timed on its own so completion lands on the
+	// deadline rather than on the next tick after it.
+	var deadline <-chan time.Time
+
+	for {
+		event := phaseEvent()
+		t.settle(&event, run)
+		// Set up before emitting, so nothing touches the clock between an
+		// event going out and the wait for the next one.
+		if deadline == nil && !paused && !event.PhaseComplete && event.Remaining <= t.tickInterval {
+			deadline = t.clock.After(event.Remaining)
+		}
+		if err := emit(ctx, events, event); err != nil {
+			return interrupted()
+		}
+		if event.PhaseComplete {
+			return run.duration, nil
+		}
+
+		select {
+		case <-ticker.C():
+		case <-deadline:
+		case c := <-t.controls:
+			switch c {
+			case controlSkip:
+				event := phaseEvent()
+				event.PhaseComplete = true
+				event.Ended = EndSkipped
+				t.settle(&event, run)
+				if err := emit(ctx, events, event); err != nil {
+					return interrupted()
+				}
+				return event.Elapsed, nil
+
+			case controlPause:
+				if !paused {
+					paused, pausedAt = true, watch.elapsed()
+					deadline = nil
+				}
+
+			case controlResume:
+				if paused {
+					pausedTotal += watch.elapsed() - pausedAt
+					paused = false
+				}
+
+			case controlStop:
+				t.stopping = true
+
+			case controlAbandon:
+				t.abandoned, t.stopping = true, true
+				event := phaseEvent()
+				event.Ended = EndInterrupted
+				t.settle(&event, run)
+				if err := emit(ctx, events, event); err != nil {
+					return interrupted()
+				}
+				return event.Elapsed, nil
+			}
+		case d := <-t.snoozes:
+			if isBreak(run) {
+				t.addSnooze(d)
+			}
+		case d := <-t.extends:
+			if isBreak(run) && d > 0 {
+				t.session.Extend(d)
+				run.duration += d
+				deadline = nil
+			}
+		case <-t.hardCap:
+			t.capped, t.stopping = true, true
+			event := phaseEvent()
+			event.Ended = EndInterrupted
+			event.Capped = true
+			t.settle(&event, run)
+			if err := emit(ctx, events, event); err != nil {
+				return interrupted()
+			}
+			return event.Elapsed, nil
+		case x := <-t.extras:
+			t.queue = append(t.queue, x)
+			event := phaseEvent()
+			event.PhaseComplete = true
+			event.Ended = EndSkipped
+			t.settle(&event, run)
+			if err := emit(ctx, events, event); err != nil {
+				return interrupted()
+			}
+			return event.Elapsed, nil
+		case <-ctx.Done():
+			return interrupted()
+		}
+	}
+}
+
+// between runs what comes before run once another phase has: the snooze
+// the break before may have been given, the wait for a check-in, then the
+// transition. Snoozing during the transition ends it, to count down again
+// after the snooze.
+func (t *Timer) between(ctx context.Context, events chan<- TimerEvent, run phaseRun) (redo bool, err error) {
+	for {
+		if t.snoozeDue > 0 {
+			if redo, err = t.snooze(ctx, events, run); err != nil || redo || t.stopping {
+				return redo, err
+			}
+		}
+		if t.session.config.CheckIn && !t.checkedIn && run.phase == PhaseWork && !run.extra && t.afterBreak {
+			if redo, err = t.awaitCheckIn(ctx, events, run); err != nil || redo || t.stopping {
+				return redo, err
+			}
+		}
+		if t.session.config.TransitionDuration <= 0 {
+			return false, nil
+		}
+		if redo, err = t.transition(ctx, events, run); err != nil || redo || t.stopping || t.snoozeDue == 0 {
+			return redo, err
+		}
+	}
+}
+
+// transition counts down Config.TransitionDuration before run starts, on
+// neither phase's clock. Skipping cuts it short and stopping ends the
+// session before run. An extra ends it with redo set, to run next, in place
+// of run if that was an extra too. A pause carries over, so run starts
+// paused.
+func (t *Timer) transition(ctx context.Context, events chan<- TimerEvent, run phaseRun) (redo bool, err error) {
+	duration := t.session.config.TransitionDuration
+	watch := newStopwatch(t.clock)
 	ticker := t.clock.NewTicker(t.tickInterval)
 	defer ticker.Stop()
+	deadline := t.clock.After(duration)
 
 	for {
-		elapsed := t.clock.Now().Sub(start)
-		remaining := duration - elapsed
-		if remaining < 0 {
-			remaining = 0
+		elapsed := watch.elapsed()
+		if elapsed >= duration {
+			return false, nil
 		}
+		event := t.countdown(EventTransition, elapsed, duration, 0, run)
+		event.ClockJump = watch.jumped()
+		if err := emit(ctx, events, event); err != nil {
+			return false, err
+		}
+
+		select {
+		case <-ticker.C():
+		case <-deadline:
+		case c := <-t.controls:
+			switch c {
+			case controlSkip:
+				return false, nil
+			case controlPause:
+				t.startPaused = true
+			case controlResume:
+				t.startPaused = false
+			case controlStop, controlAbandon:
+				t.stopping = true
+				return false, nil
+			case controlCheckIn:
+			}
+		case d := <-t.snoozes:
+			if run.phase == PhaseWork && t.afterBreak && t.addSnooze(d) {
+				return false, nil
+			}
+		case <-t.hardCap:
+			t.capped, t.stopping = true, true
+			return false, nil
+		case x := <-t.extras:
+			t.queue = append(t.queue, x)
+			return true, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+}
 
-		event := TimerEvent{
-			Phase:         t.session.CurrentPhase(),
-			Elapsed:       elapsed,
-			Remaining:     remaining,
-			Total:         duration,
-			Fraction:      float64(elapsed) / float64(duration),
-			PhaseComplete: elapsed >= duration,
-			CycleNum:      t.session.CyclesComplete() + 1,
-			TotalCycles:   t.session.TotalCycles(),
-			PhaseNum:      t.session.PhasesComplete() + 1,
-			TotalPhases:   t.session.TotalPhases(),
+// snooze holds run off for snoozeDue, on neither phase's clock, as a
+// transition does and with the same controls. Snoozing again extends it.
+func (t *Timer) snooze(ctx context.Context, events chan<- TimerEvent, run phaseRun) (redo bool, err error) {
+	watch := newStopwatch(t.clock)
+	ticker := t.clock.NewTicker(t.tickInterval)
+	defer ticker.Stop()
+	deadline := t.clock.After(t.snoozeDue)
+
+	// The last event ends the snooze, after which nothing is due.
+	end := func(reason EndReason) TimerEvent {
+		elapsed := min(watch.elapsed(), t.snoozeDue)
+		event := t.countdown(EventSnooze, elapsed, t.snoozeDue, t.session.config.TransitionDuration, run)
+		event.Ended = reason
+		t.snoozed += elapsed
+		t.snoozeDue = 0
+		return event
+	}
+	finish := func(reason EndReason, redo bool) (bool, error) {
+		if err := emit(ctx, events, end(reason)); err != nil {
+			return false, err
 		}
+		return redo, nil
+	}
 
-		if event.Fraction > 1.0 {
-			event.Fraction = 1.0
+	for {
+		elapsed := watch.elapsed()
+		if elapsed >= t.snoozeDue {
+			return finish(EndCompleted, false)
+		}
+		event := t.countdown(EventSnooze, elapsed, t.snoozeDue, t.session.config.TransitionDuration, run)
+		event.ClockJump = watch.jumped()
+		if err := emit(ctx, events, event); err != nil {
+			events <- end(EndInterrupted)
+			return false, err
 		}
 
 		select {
-		case events <- event:
+		case <-ticker.C():
+		case <-deadline:
+		case c := <-t.controls:
+			switch c {
+			case controlSkip:
+				return finish(EndSkipped, false)
+			case controlPause:
+				t.startPaused = true
+			case controlResume:
+				t.startPaused = false
+			case controlStop, controlAbandon:
+				t.stopping = true
+				return finish(EndSkipped, false)
+			}
+		case d := <-t.snoozes:
+			if t.addSnooze(d) {
+				deadline = t.clock.After(t.snoozeDue - watch.elapsed())
+			}
+		case <-t.hardCap:
+			t.capped, t.stopping = true, true
+			return finish(EndInterrupted, false)
+		case x := <-t.extras:
+			t.queue = append(t.queue, x)
+			return finish(EndSkipped, true)
 		case <-ctx.Done():
-			return ctx.Err()
+			events <- end(EndInterrupted)
+			return false, ctx.Err()
 		}
+	}
+}
 
-		if event.PhaseComplete {
-			return nil
+// awaitCheckIn holds run, the work after a break, until CheckIn or Skip,
+// on neither phase's clock. Pausing carries over to run, stopping ends the
+// session, and an extra ends the wait with redo set, as in a snooze.
+func (t *Timer) awaitCheckIn(ctx context.Context, events chan<- TimerEvent, run phaseRun) (redo bool, err error) {
+	watch := newStopwatch(t.clock)
+	ticker := t.clock.NewTicker(t.tickInterval)
+	defer ticker.Stop()
+
+	event := func() TimerEvent {
+		event := t.countdown(EventAway, watch.elapsed(), 0, t.session.config.TransitionDuration, run)
+		event.ClockJump = watch.jumped()
+		return event
+	}
+	// The last event ends the wait, which stays over while run is held
+	// for a snooze or transition after it.
+	end := func(reason EndReason) TimerEvent {
+		e := event()
+		e.Ended = reason
+		t.away += e.Elapsed
+		t.checkedIn = true
+		return e
+	}
+	finish := func(reason EndReason, redo bool) (bool, error) {
+		if err := emit(ctx, events, end(reason)); err != nil {
+			return false, err
+		}
+		return redo, nil
+	}
+
+	for {
+		if err := emit(ctx, events, event()); err != nil {
+			events <- end(EndInterrupted)
+			return false, err
 		}
 
 		select {
 		case <-ticker.C():
+		case c := <-t.controls:
+			switch c {
+			case controlCheckIn, controlSkip:
+				return finish(EndCompleted, false)
+			case controlPause:
+				t.startPaused = true
+			case controlResume:
+				t.startPaused = false
+			case controlStop, controlAbandon:
+				t.stopping = true
+				return finish(EndSkipped, false)
+			}
+		case <-t.hardCap:
+			t.capped, t.stopping = true, true
+			return finish(EndInterrupted, false)
+		case x := <-t.extras:
+			t.queue = append(t.queue, x)
+			return finish(EndSkipped, true)
 		case <-ctx.Done():
-			return ctx.Err()
+			events <- end(EndInterrupted)
+			return false, ctx.Err()
 		}
 	}
 }
+
+// addSnooze reports whether d was added to the snooze, which it is unless
+// the break has had its Config.MaxSnoozes.
+func (t *Timer) addSnooze(d time.Duration) bool {
+	if d <= 0 || t.snoozeCount >= t.session.config.MaxSnoozes {
+		return false
+	}
+	t.snoozeDue += d
+	t.snoozeCount++
+	t.snoozesTaken++
+	return true
+}
+
+// countdown is an event of a transition, snooze, or wait before run, total
+// long, or 0 for a wait with no end, and elapsed so far, with after still
+// to come between it and run.
+func (t *Timer) countdown(typ EventType, elapsed, total, after time.Duration, run phaseRun) TimerEvent {
+	event := t.position()
+	event.Type = typ
+	event.Phase = run.phase
+	event.Extra = run.extra
+	event.Counted = event.Counted && !run.extra
+	if run.extra {
+		event.Final = 0
+	}
+	event.Elapsed = elapsed
+	event.Total = total
+	if total > 0 {
+		event.Remaining = total - elapsed
+		event.Fraction = float64(elapsed) / float64(total)
+	}
+	event.Paused = t.startPaused
+	if t.session.TotalCycles() > 0 {
+		event.SessionRemaining = event.Remaining + after + run.duration + run.upcoming
+	}
+	return event
+}
+
+// settle fills in e, the end of run, with what it voids or banks.
+func (t *Timer) settle(e *TimerEvent, run phaseRun) {
+	t.void(e, run)
+	if isBreak(run) && e.Ended != "" && e.Ended != EndInterrupted {
+		e.Banked = t.session.Unused(e.Elapsed)
+	}
+}
+
+// void marks e, the end of run, as voided if Config.StrictPomodoro voids
+// it, and notes as much for Run. Past the retries, a phase skipped or
+// paused too long is voided all the same; Session.VoidPhase puts a break
+// before its work runs again.
+func (t *Timer) void(e *TimerEvent, run phaseRun) {
+	c := t.session.config
+	if !c.StrictPomodoro || run.extra || run.phase != PhaseWork || e.Ended == "" {
+		return
+	}
+	if e.Ended == EndCompleted && float64(e.PausedTotal) <= c.StrictMaxPause*float64(e.Total) {
+		return
+	}
+	e.Voided, e.Counted = true, false
+	t.voided = true
+}
+
+// isBreak reports whether run is one of the schedule's breaks, the only
+// phases that can be snoozed.
+func isBreak(run phaseRun) bool {
+	return !run.extra && (run.phase == PhaseShortBreak || run.phase == PhaseLongBreak)
+}
+
+func emit(ctx context.Context, events chan<- TimerEvent, e TimerEvent) error {
+	select {
+	case events <- e:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// peekNext is what runs after run: an extra phase runs ahead of the
+// schedule, which picks up where it was after one.
+func (t *Timer) peekNext(run phaseRun) (Phase, time.Duration) {
+	switch {
+	case t.stopping:
+		return PhaseDone, 0
+	case len(t.queue) > 0:
+		return t.queue[0].Phase, t.queue[0].Duration
+	case run.extra:
+		return t.session.CurrentPhase(), t.session.PhaseDuration()
+	}
+	return t.session.PeekNext()
+}
+
+// run.upcoming is the planned time after the phase, zero for infinite
+// sessions.
+func (t *Timer) event(elapsed time.Duration, run phaseRun) TimerEvent {
+	duration := run.duration
+	elapsed = min(elapsed, duration)
+	remaining := duration - elapsed
+
+	event := t.position()
+	event.Phase = run.phase
+	event.Extra = run.extra
+	event.Counted = event.Counted && !run.extra
+	if run.extra {
+		event.Final = 0
+		event.Lunch, event.LunchOffer = false, 0
+	}
+	event.Elapsed = elapsed
+	event.Remaining = remaining
+	event.Total = duration
+	event.SessionRemaining = remaining + run.upcoming
+	event.Fraction = float64(elapsed) / float64(duration)
+	event.PhaseComplete = elapsed == duration
+	event.SessionWork = t.work
+	if run.phase == PhaseWork {
+		event.SessionWork += elapsed
+	}
+	event.NextPhase, event.NextDuration = t.peekNext(run)
+	if run.phase == PhaseWork && !run.extra && event.WorkUntilLongBreak > 0 {
+		event.WorkUntilLongBreak = max(event.WorkUntilLongBreak-elapsed, 0)
+	}
+
+	if event.PhaseComplete {
+		event.Ended = EndCompleted
+	}
+
+	if t.session.TotalCycles() == 0 {
+		event.SessionRemaining = 0
+	}
+
+	return event
+}
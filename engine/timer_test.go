@@ -0,0 +1,585 @@
+package engine
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestControlsFromManyGoroutines pauses, resumes, skips, snoozes, and
+// extends from several goroutines while a session runs, as the key
+// listener, signal handler, and control socket do. Run with -race.
+func TestControlsFromManyGoroutines(t *testing.T) {
+	clock := NewMockClock(time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC))
+	cfg := Config{
+		WorkDuration:       25 * time.Minute,
+		ShortBreakDuration: 5 * time.Minute,
+		LongBreakDuration:  15 * time.Minute,
+		LongBreakEvery:     2,
+		TotalCycles:        8,
+		TransitionDuration: 5 * time.Second,
+		MaxSnoozes:         2,
+		BankBreaks:         true,
+	}
+	timer := NewTimerWithClock(cfg, clock, time.Second)
+
+	events := make(chan TimerEvent)
+	done := make(chan error, 1)
+	go func() { done <- timer.Run(context.Background(), events) }()
+
+	// Skips come slower than the rest, so the phases run a while.
+	stop := make(chan struct{})
+	var hammering sync.WaitGroup
+	hammer := func(control func(), every time.Duration) {
+		hammering.Add(1)
+		go func() {
+			defer hammering.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-time.After(every):
+					control()
+				}
+			}
+		}()
+	}
+	hammer(timer.Pause, 20*time.Microsecond)
+	hammer(timer.Resume, 20*time.Microsecond)
+	hammer(func() { timer.Snooze(time.Minute) }, 50*time.Microsecond)
+	hammer(func() { timer.Extend(time.Minute) }, 50*time.Microsecond)
+	hammer(timer.Skip, 2*time.Millisecond)
+	hammer(func() { clock.Advance(time.Second) }, 10*time.Microsecond)
+	defer hammering.Wait()
+	defer close(stop)
+
+	var summary *SessionSummary
+	paused := 0
+	deadline := time.After(30 * time.Second)
+	for events != nil {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				break
+			}
+			switch {
+			case e.Type == EventSessionEnded:
+				summary = e.Summary
+			case e.Paused:
+				paused++
+			}
+		case <-deadline:
+			t.Fatal("session still running after 30s")
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if paused == 0 || summary.Snoozes == 0 {
+		t.Errorf("%d paused ticks and %d snoozes, want the controls to have taken", paused, summary.Snoozes)
+	}
+	s := timer.Session()
+	if err := s.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+	if s.CurrentPhase() != PhaseDone || summary.Ended != EndCompleted || summary.Stopped {
+		t.Fatalf("session at %s, ended %+v, want it run to the end", s.CurrentPhase(), summary)
+	}
+	if summary.CyclesComplete != cfg.TotalCycles || summary.PhasesComplete != s.TotalPhases() {
+		t.Fatalf("%d cycles and %d phases complete, want %d and %d",
+			summary.CyclesComplete, summary.PhasesComplete, cfg.TotalCycles, s.TotalPhases())
+	}
+}
+
+// TestStrictVoidsSkipPastRetries skips the first work phase with no
+// retries left: it is still voided, and a break comes before it runs again.
+func TestStrictVoidsSkipPastRetries(t *testing.T) {
+	clock := NewMockClock(time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC))
+	timer := NewTimerWithClock(Config{
+		WorkDuration:       2 * time.Second,
+		ShortBreakDuration: time.Second,
+		TotalCycles:        1,
+		StrictPomodoro:     true,
+	}, clock, time.Second)
+
+	events := make(chan TimerEvent)
+	done := make(chan error, 1)
+	go func() { done <- timer.Run(context.Background(), events) }()
+
+	type end struct {
+		phase   Phase
+		ended   EndReason
+		voided  bool
+		counted bool
+	}
+	var ends []end
+	var summary *SessionSummary
+	skipped := false
+	for e := range events {
+		switch {
+		case e.Type == EventSessionEnded:
+			summary = e.Summary
+		case e.Type != EventTick:
+		case e.Ended != "":
+			ends = append(ends, end{e.Phase, e.Ended, e.Voided, e.Counted})
+		case !skipped:
+			skipped = true
+			timer.Skip()
+		default:
+			if d, ok := clock.UntilNext(); ok {
+				clock.Advance(d)
+			}
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	want := []end{
+		{PhaseWork, EndSkipped, true, false},
+		{PhaseShortBreak, EndCompleted, false, false},
+		{PhaseWork, EndCompleted, false, true},
+	}
+	if !slices.Equal(ends, want) {
+		t.Errorf("phases ended %+v, want %+v", ends, want)
+	}
+	if summary.CyclesComplete != 1 || summary.Voided != 1 {
+		t.Errorf("%d cycles and %d voided, want 1 of each", summary.CyclesComplete, summary.Voided)
+	}
+}
+
+func TestEveryEventTypeHasAName(t *testing.T) {
+	seen := map[string]EventType{}
+	for typ := EventTick; typ < numEventTypes; typ++ {
+		name := typ.String()
+		if name == "Unknown" {
+			t.Errorf("event type %d has no name", typ)
+			continue
+		}
+		if other, ok := seen[name]; ok {
+			t.Errorf("event types %d and %d are both %q", other, typ, name)
+		}
+		seen[name] = typ
+	}
+	// Renderers find the types by name, up to the first without one.
+	if name := numEventTypes.String(); name != "Unknown" {
+		t.Errorf("event type %d past the last named %q", numEventTypes, name)
+	}
+}
+
+// TestCompletionLandsOnDeadline runs phases whose lengths the tick does
+// not divide: each completes exactly at its deadline, not a tick late.
+func TestCompletionLandsOnDeadline(t *testing.T) {
+	clock := NewMockClock(time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC))
+	timer := NewTimerWithClock(Config{
+		WorkDuration:       10100 * time.Millisecond,
+		ShortBreakDuration: 2350 * time.Millisecond,
+		LongBreakDuration:  3 * time.Second,
+		TotalCycles:        2,
+	}, clock, 200*time.Millisecond)
+
+	events := make(chan TimerEvent)
+	done := make(chan error, 1)
+	go func() { done <- timer.Run(context.Background(), events) }()
+
+	var ends []TimerEvent
+	for e := range events {
+		if e.Type != EventTick {
+			continue
+		}
+		if e.Elapsed > e.Total || e.Remaining < 0 {
+			t.Errorf("%s tick at %s of %s, %s left", e.Phase, e.Elapsed, e.Total, e.Remaining)
+		}
+		if e.PhaseComplete {
+			if now := clock.Now(); !now.Equal(e.PhaseStartedAt.Add(e.Total)) {
+				t.Errorf("%s completed at %s, due at %s", e.Phase, now.Format(time.StampMilli), e.PhaseStartedAt.Add(e.Total).Format(time.StampMilli))
+			}
+			ends = append(ends, e)
+			continue
+		}
+		if d, ok := clock.UntilNext(); ok {
+			clock.Advance(d)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Phase{PhaseWork, PhaseShortBreak, PhaseWork}
+	if len(ends) != len(want) {
+		t.Fatalf("%d phases completed, want %d", len(ends), len(want))
+	}
+	for i, e := range ends {
+		if e.Phase != want[i] || e.Elapsed != e.Total || e.Remaining != 0 || e.Ended != EndCompleted || e.Fraction != 1 {
+			t.Errorf("phase %d completed as %s at %s of %s, %s left, ended %q, fraction %g; want %s at its total, ended completed",
+				i+1, e.Phase, e.Elapsed, e.Total, e.Remaining, e.Ended, e.Fraction, want[i])
+		}
+	}
+}
+
+// TestClockJumpBack sets the wall clock back 10 minutes 5 minutes into
+// work, and half a second 20 minutes in: elapsed time keeps going forward,
+// and only the jump is reported, once. MockClock has no monotonic reading,
+// so the stopwatch holds still over the tick each goes back in, the jump
+// showing as 9m59s and work taking 25m1.5s on the clock.
+func TestClockJumpBack(t *testing.T) {
+	clock := NewMockClock(time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC))
+	timer := NewTimerWithClock(Config{
+		WorkDuration:       25 * time.Minute,
+		ShortBreakDuration: 5 * time.Minute,
+		TotalCycles:        1,
+	}, clock, time.Second)
+
+	events := make(chan TimerEvent)
+	done := make(chan error, 1)
+	go func() { done <- timer.Run(context.Background(), events) }()
+
+	var last *TimerEvent
+	var jumps []time.Duration
+	advanced := time.Duration(0)
+	jumped, corrected := false, false
+	for e := range events {
+		if e.ClockJump != 0 {
+			jumps = append(jumps, e.ClockJump)
+		}
+		if e.Type != EventTick {
+			continue
+		}
+		if last != nil && e.Phase == last.Phase && (e.Elapsed < last.Elapsed || e.Remaining > last.Remaining) {
+			t.Errorf("%s went from %s elapsed, %s left, to %s, %s", e.Phase, last.Elapsed, last.Remaining, e.Elapsed, e.Remaining)
+		}
+		if e.Elapsed < 0 || e.Remaining < 0 || e.Fraction < 0 || e.Fraction > 1 {
+			t.Errorf("%s tick at %s elapsed, %s left, fraction %g", e.Phase, e.Elapsed, e.Remaining, e.Fraction)
+		}
+		last = &e
+		if e.PhaseComplete {
+			if e.Phase == PhaseWork && advanced != 25*time.Minute+1500*time.Millisecond {
+				t.Errorf("work completed after %s on the clock, want 25m1.5s", advanced)
+			}
+			continue
+		}
+		switch {
+		case !jumped && e.Elapsed >= 5*time.Minute:
+			clock.Jump(-10 * time.Minute)
+			jumped = true
+		case !corrected && e.Elapsed >= 20*time.Minute:
+			clock.Jump(-500 * time.Millisecond)
+			corrected = true
+		}
+		if d, ok := clock.UntilNext(); ok {
+			clock.Advance(d)
+			advanced += d
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(jumps) != 1 || jumps[0] != 10*time.Minute-time.Second {
+		t.Errorf("clock jumps reported %v, want [9m59s]", jumps)
+	}
+}
+
+// TestCycleCompletedCadence runs four cycles on a MockClock and checks
+// when each completes, by CycleCompleted, and the work done by then: at
+// the end of the break after each work phase, long or short, and at the
+// end of the last work phase, with no break to follow.
+func TestCycleCompletedCadence(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		// at is the minutes into the session each cycle completes.
+		at []int
+	}{
+		{"long break every 2", Config{LongBreakEvery: 2}, []int{30, 70, 100, 125}},
+		{"no long breaks", Config{}, []int{30, 60, 90, 115}},
+		{"warmup and cooldown", Config{WarmupDuration: 5 * time.Minute, CooldownDuration: 5 * time.Minute}, []int{35, 65, 95, 120}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC)
+			clock := NewMockClock(start)
+			cfg := tt.cfg
+			cfg.WorkDuration, cfg.ShortBreakDuration, cfg.LongBreakDuration = 25*time.Minute, 5*time.Minute, 15*time.Minute
+			cfg.TotalCycles = 4
+			timer := NewTimerWithClock(cfg, clock, time.Second)
+
+			events := make(chan TimerEvent)
+			done := make(chan error, 1)
+			go func() { done <- timer.Run(context.Background(), events) }()
+
+			var cycles, at []int
+			for e := range events {
+				if cycle, ok := e.CycleCompleted(); ok {
+					cycles = append(cycles, cycle)
+					at = append(at, int(clock.Since(start).Minutes()))
+					if want := time.Duration(cycle) * cfg.WorkDuration; e.SessionWork != want {
+						t.Errorf("cycle %d completed with %s of work, want %s", cycle, e.SessionWork, want)
+					}
+				}
+				if e.Type == EventTick && e.Ended == "" {
+					if d, ok := clock.UntilNext(); ok {
+						clock.Advance(d)
+					}
+				}
+			}
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			if want := []int{1, 2, 3, 4}; !slices.Equal(cycles, want) {
+				t.Errorf("cycles %v completed, want %v", cycles, want)
+			}
+			if !slices.Equal(at, tt.at) {
+				t.Errorf("cycles completed at minutes %v, want %v", at, tt.at)
+			}
+		})
+	}
+}
+
+// TestTimerBanksBreaks skips the first break 30s in, extends the second by
+// more than that banked, and skips the last a minute in, checking what each
+// break's end and the summary report, banking and not.
+func TestTimerBanksBreaks(t *testing.T) {
+	type end struct {
+		ended                                     EndReason
+		elapsed, extended, fromBank, banked, bank time.Duration
+	}
+	tests := []struct {
+		banking bool
+		ends    []end
+		left    time.Duration
+	}{
+		{true, []end{
+			{EndSkipped, 30 * time.Second, 0, 0, 90 * time.Second, 0},
+			{EndCompleted, 4 * time.Minute, 2 * time.Minute, 90 * time.Second, 0, 0},
+			{EndSkipped, time.Minute, 0, 0, time.Minute, 0},
+		}, time.Minute},
+		{false, []end{
+			{EndSkipped, 30 * time.Second, 0, 0, 0, 0},
+			{EndCompleted, 4 * time.Minute, 2 * time.Minute, 0, 0, 0},
+			{EndSkipped, time.Minute, 0, 0, 0, 0},
+		}, 0},
+	}
+	for _, tt := range tests {
+		clock := NewMockClock(time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC))
+		timer := NewTimerWithClock(Config{
+			WorkDuration:       time.Minute,
+			ShortBreakDuration: 2 * time.Minute,
+			TotalCycles:        4,
+			BankBreaks:         tt.banking,
+		}, clock, time.Second)
+
+		events := make(chan TimerEvent)
+		done := make(chan error, 1)
+		go func() { done <- timer.Run(context.Background(), events) }()
+
+		var ends []end
+		var summary *SessionSummary
+		breaks := 0
+		for e := range events {
+			if e.Type == EventSessionEnded {
+				summary = e.Summary
+			}
+			if e.Type != EventTick || e.Phase != PhaseShortBreak {
+				if e.Type == EventTick && e.Ended == "" {
+					clock.Advance(time.Second)
+				}
+				continue
+			}
+			if e.Ended != "" {
+				ends = append(ends, end{e.Ended, e.Elapsed, e.Extended, e.FromBank, e.Banked, e.Bank})
+				continue
+			}
+			if e.Elapsed == 0 {
+				breaks++
+			}
+			if e.Bank < 0 {
+				t.Errorf("bank %s", e.Bank)
+			}
+			switch {
+			case breaks == 1 && e.Elapsed == 30*time.Second, breaks == 3 && e.Elapsed == time.Minute:
+				timer.Skip()
+				continue
+			case breaks == 2 && e.Elapsed == 10*time.Second:
+				timer.Extend(2 * time.Minute)
+			}
+			clock.Advance(time.Second)
+		}
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+
+		if !slices.Equal(ends, tt.ends) {
+			t.Errorf("banking %v: breaks ended %+v, want %+v", tt.banking, ends, tt.ends)
+		}
+		if summary.Bank != tt.left {
+			t.Errorf("banking %v: %s left in the bank, want %s", tt.banking, summary.Bank, tt.left)
+		}
+	}
+}
+
+// TestNextOnEvents runs a session with an extra break put in and a stop
+// asked for along the way, checking every tick names the phase that does
+// run next.
+func TestNextOnEvents(t *testing.T) {
+	clock := NewMockClock(time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC))
+	timer := NewTimerWithClock(Config{
+		WorkDuration:       time.Minute,
+		ShortBreakDuration: 30 * time.Second,
+		TotalCycles:        3,
+	}, clock, time.Second)
+
+	events := make(chan TimerEvent)
+	done := make(chan error, 1)
+	go func() { done <- timer.Run(context.Background(), events) }()
+
+	type next struct {
+		phase Phase
+		d     time.Duration
+	}
+	var claimed, ran []next
+	var last *next
+	injected, stopped := false, false
+	for e := range events {
+		if e.Type != EventTick {
+			continue
+		}
+		if e.Elapsed == 0 {
+			ran = append(ran, next{e.Phase, e.Total})
+			if last != nil {
+				claimed = append(claimed, *last)
+			}
+		}
+		last = &next{e.NextPhase, e.NextDuration}
+		if e.Ended != "" {
+			continue
+		}
+		switch {
+		case !injected && e.Phase == PhaseWork && e.Elapsed == 10*time.Second:
+			injected = true
+			timer.Inject(Extra{Phase: PhaseLongBreak, Duration: 20 * time.Second})
+			continue
+		case !stopped && e.CycleNum == 2 && e.Phase == PhaseWork && e.Elapsed == 10*time.Second:
+			stopped = true
+			timer.Stop()
+		}
+		clock.Advance(time.Second)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	claimed = append(claimed, *last)
+	ran = append(ran, next{PhaseDone, 0})
+
+	// The extra takes the place of the first work, the schedule going on
+	// from its break, and the stop ends the session after the second.
+	want := []next{
+		{PhaseWork, time.Minute},
+		{PhaseLongBreak, 20 * time.Second},
+		{PhaseShortBreak, 30 * time.Second},
+		{PhaseWork, time.Minute},
+		{PhaseDone, 0},
+	}
+	if !slices.Equal(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if !slices.Equal(claimed, ran[1:]) {
+		t.Errorf("ticks named %v next, but %v ran", claimed, ran[1:])
+	}
+}
+
+// TestCheckIn holds work after each break until a check-in, or a skip,
+// 4m and 2m on, and stops the session in a wait, checking the waits
+// come after breaks only, hold the work back, and add up to Away.
+func TestCheckIn(t *testing.T) {
+	tests := []struct {
+		name    string
+		stop    bool
+		waits   []EndReason
+		cycles  int
+		away    time.Duration
+		elapsed []time.Duration
+	}{
+		{"checked in", false, []EndReason{EndCompleted, EndCompleted}, 3, 6 * time.Minute, []time.Duration{4 * time.Minute, 2 * time.Minute}},
+		{"stopped waiting", true, []EndReason{EndSkipped}, 1, 4 * time.Minute, []time.Duration{4 * time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC)
+			clock := NewMockClock(start)
+			timer := NewTimerWithClock(Config{
+				WorkDuration:       time.Minute,
+				ShortBreakDuration: time.Minute,
+				TotalCycles:        3,
+				CheckIn:            true,
+			}, clock, time.Second)
+
+			events := make(chan TimerEvent)
+			done := make(chan error, 1)
+			go func() { done <- timer.Run(context.Background(), events) }()
+
+			var waits []EndReason
+			var elapsed []time.Duration
+			var summary *SessionSummary
+			var last Phase = PhaseDone
+			for e := range events {
+				switch e.Type {
+				case EventSessionEnded:
+					summary = e.Summary
+				case EventAway:
+					if last != PhaseShortBreak {
+						t.Errorf("waiting for a check-in after %s", last)
+					}
+					if e.Ended != "" {
+						waits = append(waits, e.Ended)
+						elapsed = append(elapsed, e.Elapsed)
+						continue
+					}
+					switch {
+					case len(waits) == 0 && e.Elapsed == 4*time.Minute:
+						if tt.stop {
+							timer.Stop()
+						} else {
+							timer.CheckIn()
+						}
+						continue
+					case len(waits) == 1 && e.Elapsed == 2*time.Minute:
+						timer.Skip()
+						continue
+					}
+					clock.Advance(time.Second)
+				case EventTick:
+					if e.Elapsed == 0 && e.Phase == PhaseWork && len(waits) > 0 {
+						// The work starts as the wait ends, none of it gone.
+						if got, want := e.PhaseStartedAt, clock.Now(); !got.Equal(want) {
+							t.Errorf("work after the wait started %s, want %s", got, want)
+						}
+					}
+					last = e.Phase
+					if e.Ended == "" {
+						clock.Advance(time.Second)
+					}
+				}
+			}
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+
+			if !slices.Equal(waits, tt.waits) || !slices.Equal(elapsed, tt.elapsed) {
+				t.Errorf("waits ended %v after %v, want %v after %v", waits, elapsed, tt.waits, tt.elapsed)
+			}
+			if summary.Away != tt.away || summary.CyclesComplete != tt.cycles {
+				t.Errorf("%s away over %d cycles, want %s over %d", summary.Away, summary.CyclesComplete, tt.away, tt.cycles)
+			}
+			if !tt.stop {
+				// 3m of work, 2m of breaks, and the waits.
+				if got, want := clock.Since(start), 5*time.Minute+tt.away; got != want {
+					t.Errorf("session took %s, want %s", got, want)
+				}
+			}
+		})
+	}
+}
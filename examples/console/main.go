@@ -0,0 +1,72 @@
+// Console runs a pomodoro session with the engine package and prints a
+// line as each phase starts and a countdown while it runs. Ctrl-C ends the
+// session early.
+//
+//	go run ./examples/console -work 25m -break 5m -cycles 4
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+func main() {
+	work := flag.Duration("work", 25*time.Minute, "work phase length")
+	short := flag.Duration("break", 5*time.Minute, "short break length")
+	cycles := flag.Int("cycles", 4, "work phases to run (0 = until interrupted)")
+	flag.Parse()
+
+	cfg := engine.Config{
+		WorkDuration:       *work,
+		ShortBreakDuration: *short,
+		LongBreakDuration:  3 * *short,
+		LongBreakEvery:     4,
+		TotalCycles:        *cycles,
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	timer := engine.NewTimer(cfg)
+	events := make(chan engine.TimerEvent)
+	done := make(chan error, 1)
+	go func() { done <- timer.Run(ctx, events) }()
+
+	// Events must be taken until the channel closes; the timer waits on
+	// each one.
+	running := false
+	for e := range events {
+		switch e.Type {
+		case engine.EventSessionEnded:
+			s := e.Summary
+			fmt.Printf("\nSession %s: %d cycles, %s of work\n", s.Ended, s.CyclesComplete, s.Work.Round(time.Second))
+		case engine.EventTick:
+			switch {
+			case running:
+			case e.Phase == engine.PhaseWork:
+				fmt.Printf("%s (%d/%d)\n", e.Phase, e.CycleNum, e.TotalCycles)
+			default:
+				fmt.Println(e.Phase)
+			}
+			running = e.Ended == ""
+			if running {
+				fmt.Printf("\r  %s left ", e.Remaining.Round(time.Second))
+			} else {
+				fmt.Printf("\r  %-12s\n", e.Ended)
+			}
+		}
+	}
+	if err := <-done; err != nil && ctx.Err() == nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,59 @@
+// Simulated runs a full day's schedule on a MockClock in an instant and
+// prints when each phase would start and end, skipping the second work
+// phase halfway through to show how the schedule absorbs it.
+//
+//	go run ./examples/simulated
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+func main() {
+	start := time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC)
+	clock := engine.NewMockClock(start)
+	cfg := engine.Config{
+		WorkDuration:       50 * time.Minute,
+		ShortBreakDuration: 10 * time.Minute,
+		LongBreakDuration:  30 * time.Minute,
+		LongBreakEvery:     3,
+		TotalCycles:        6,
+	}
+	timer := engine.NewTimerWithClock(cfg, clock, time.Second)
+
+	events := make(chan engine.TimerEvent)
+	done := make(chan error, 1)
+	go func() { done <- timer.Run(context.Background(), events) }()
+
+	for e := range events {
+		switch e.Type {
+		case engine.EventSessionStarted:
+			last := e.Plan[len(e.Plan)-1]
+			fmt.Printf("%d phases planned, ending %s\n", len(e.Plan), start.Add(last.Offset+last.Duration).Format("15:04"))
+		case engine.EventSessionEnded:
+			fmt.Printf("%d cycles by %s\n", e.Summary.CyclesComplete, clock.Now().Format("15:04"))
+		case engine.EventTick:
+			if e.Ended != "" {
+				fmt.Printf("%s-%s  %-11s %s\n", e.PhaseStartedAt.Format("15:04"), clock.Now().Format("15:04"), e.Phase, e.Ended)
+				continue
+			}
+			if e.CycleNum == 2 && e.Phase == engine.PhaseWork && e.Fraction >= 0.5 {
+				timer.Skip()
+				continue
+			}
+			// Only advance once the timer is waiting on the clock, after a
+			// tick of a phase still running, so every run is the same.
+			if d, ok := clock.UntilNext(); ok {
+				clock.Advance(d)
+			}
+		}
+	}
+	if err := <-done; err != nil {
+		log.Fatal(err)
+	}
+}
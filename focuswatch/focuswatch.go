@@ -0,0 +1,87 @@
+// Package focuswatch tells which application is in front, so pomo can
+// notice distractions during work phases.
+package focuswatch
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrUnsupported = errors.New("cannot tell which application is in front on this system")
+
+// Watcher reports the name of the frontmost application, e.g. "Slack".
+type Watcher interface {
+	Frontmost() (string, error)
+}
+
+// Blocklist matches application names against case-insensitive regexps.
+type Blocklist []*regexp.Regexp
+
+func Compile(patterns []string) (Blocklist, error) {
+	list := make(Blocklist, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid app pattern %q: %w", p, err)
+		}
+		list[i] = re
+	}
+	return list, nil
+}
+
+func (b Blocklist) Match(app string) bool {
+	for _, re := range b {
+		if re.MatchString(app) {
+			return true
+		}
+	}
+	return false
+}
+
+// Poller asks a Watcher every interval from a background goroutine, since
+// asking can mean running a helper program, and keeps the latest answer.
+type Poller struct {
+	mu   sync.Mutex
+	app  string
+	err  error
+	stop chan struct{}
+	done chan struct{}
+}
+
+func Poll(w Watcher, interval time.Duration) *Poller {
+	p := &Poller{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			app, err := w.Frontmost()
+			p.mu.Lock()
+			p.app, p.err = strings.TrimSpace(app), err
+			p.mu.Unlock()
+
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return p
+}
+
+// Frontmost is the latest answer, empty until the first one arrives.
+func (p *Poller) Frontmost() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.app, p.err
+}
+
+func (p *Poller) Stop() {
+	close(p.stop)
+	<-p.done
+}
@@ -0,0 +1,20 @@
+package focuswatch
+
+import "os/exec"
+
+type appleScript struct{}
+
+// New asks System Events through osascript, which needs the terminal to be
+// allowed to control it under Privacy & Security > Automation.
+func New() (Watcher, error) {
+	if _, err := exec.LookPath("osascript"); err != nil {
+		return nil, ErrUnsupported
+	}
+	return appleScript{}, nil
+}
+
+func (appleScript) Frontmost() (string, error) {
+	out, err := exec.Command("osascript", "-e",
+		`tell application "System Events" to get name of first application process whose frontmost is true`).Output()
+	return string(out), err
+}
@@ -0,0 +1,99 @@
+package focuswatch
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"regexp"
+)
+
+// New picks sway's IPC under a wlroots compositor that speaks it, and
+// xprop under X11. Other Wayland compositors do not tell clients which
+// window has focus.
+func New() (Watcher, error) {
+	switch {
+	case os.Getenv("SWAYSOCK") != "":
+		if _, err := exec.LookPath("swaymsg"); err == nil {
+			return sway{}, nil
+		}
+	case os.Getenv("DISPLAY") != "" && os.Getenv("WAYLAND_DISPLAY") == "":
+		if _, err := exec.LookPath("xprop"); err == nil {
+			return x11{}, nil
+		}
+	}
+	return nil, ErrUnsupported
+}
+
+type x11 struct{}
+
+var (
+	activeWindow = regexp.MustCompile(`window id # (0x[0-9a-f]+)`)
+	// WM_CLASS holds the instance and class names; the class is the
+	// application's, e.g. "Slack".
+	wmClass = regexp.MustCompile(`WM_CLASS\(STRING\) = "[^"]*", "([^"]*)"`)
+)
+
+func (x11) Frontmost() (string, error) {
+	out, err := exec.Command("xprop", "-root", "_NET_ACTIVE_WINDOW").Output()
+	if err != nil {
+		return "", err
+	}
+	m := activeWindow.FindSubmatch(out)
+	if m == nil || string(m[1]) == "0x0" {
+		return "", nil
+	}
+
+	out, err = exec.Command("xprop", "-id", string(m[1]), "WM_CLASS").Output()
+	if err != nil {
+		return "", err
+	}
+	if m = wmClass.FindSubmatch(out); m == nil {
+		return "", nil
+	}
+	return string(m[1]), nil
+}
+
+type sway struct{}
+
+type swayNode struct {
+	Focused          bool       `json:"focused"`
+	AppID            string     `json:"app_id"`
+	Nodes            []swayNode `json:"nodes"`
+	FloatingNodes    []swayNode `json:"floating_nodes"`
+	WindowProperties struct {
+		Class string `json:"class"`
+	} `json:"window_properties"`
+}
+
+func (sway) Frontmost() (string, error) {
+	out, err := exec.Command("swaymsg", "-t", "get_tree").Output()
+	if err != nil {
+		return "", err
+	}
+	var root swayNode
+	if err := json.Unmarshal(out, &root); err != nil {
+		return "", err
+	}
+	if n := root.focused(); n != nil {
+		if n.AppID != "" {
+			return n.AppID, nil
+		}
+		return n.WindowProperties.Class, nil
+	}
+	return "", errors.New("sway reported no focused window")
+}
+
+func (n *swayNode) focused() *swayNode {
+	if n.Focused {
+		return n
+	}
+	for _, children := range [][]swayNode{n.Nodes, n.FloatingNodes} {
+		for i := range children {
+			if f := children[i].focused(); f != nil {
+				return f
+			}
+		}
+	}
+	return nil
+}
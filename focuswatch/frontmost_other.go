@@ -0,0 +1,7 @@
+//go:build !(linux || darwin)
+
+package focuswatch
+
+func New() (Watcher, error) {
+	return nil, ErrUnsupported
+}
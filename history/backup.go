@@ -0,0 +1,227 @@
+package history
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steenfuentes/pomo/fsutil"
+)
+
+// BackupsKept is how many backups of the file are kept, the oldest being
+// removed as new ones are made.
+const BackupsKept = 10
+
+const backupStamp = "20060102T150405.000"
+
+// Backup is a copy of the file taken before it was rewritten.
+type Backup struct {
+	Path  string
+	Taken time.Time
+}
+
+// BackupDir is where the backups of the file at path are kept, a backups
+// directory beside it.
+func BackupDir(path string) string {
+	return filepath.Join(filepath.Dir(path), "backups")
+}
+
+// Backups lists the file's backups, oldest first.
+func Backups(path string) ([]Backup, error) {
+	entries, err := os.ReadDir(BackupDir(path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, ext := backupAffixes(path)
+	var backups []Backup
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		taken, err := time.ParseInLocation(backupStamp, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext), time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Path: filepath.Join(BackupDir(path), name), Taken: taken})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Taken.Before(backups[j].Taken) })
+	return backups, nil
+}
+
+// FindBackup returns the backup whose file name, or the start of it, is
+// name, or the newest for "latest".
+func FindBackup(path, name string) (Backup, error) {
+	backups, err := Backups(path)
+	if err != nil {
+		return Backup{}, err
+	}
+	if len(backups) == 0 {
+		return Backup{}, errors.New("no backups")
+	}
+	if name == "latest" {
+		return backups[len(backups)-1], nil
+	}
+	var found []Backup
+	for _, b := range backups {
+		if strings.HasPrefix(filepath.Base(b.Path), name) {
+			found = append(found, b)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Backup{}, fmt.Errorf("no backup %q", name)
+	case 1:
+		return found[0], nil
+	default:
+		return Backup{}, fmt.Errorf("%q matches more than one backup", name)
+	}
+}
+
+// Restore replaces the file with the backup b, after backing up the file
+// as it is, so a restore can be undone the same way.
+func Restore(path string, b Backup) error {
+	data, err := os.ReadFile(b.Path)
+	if err != nil {
+		return err
+	}
+	return replace(path, data)
+}
+
+// Prune moves the records that started before the given time out of the
+// file, appending them to a gzipped archive beside it, and returns the
+// archive's path and how many moved. Like undo and edit, it refuses a file
+// with corrupt lines.
+func Prune(path string, before time.Time) (string, int, error) {
+	records, lines, bad, err := scan(path)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(bad) > 0 {
+		return "", 0, &CorruptError{Path: path, Lines: bad}
+	}
+
+	var old, kept []byte
+	n := 0
+	for i, r := range records {
+		if r.Start.Before(before) {
+			old = append(append(old, lines[i]...), '\n')
+			n++
+		} else {
+			kept = append(append(kept, lines[i]...), '\n')
+		}
+	}
+	archive := filepath.Join(filepath.Dir(path), "archive", fmt.Sprintf("history-before-%s.jsonl.gz", before.Format(time.DateOnly)))
+	if n == 0 {
+		return archive, 0, nil
+	}
+
+	// The archive is written and synced before the records leave the file,
+	// so a failure between the two leaves them in both rather than neither.
+	if err := appendGzip(archive, old); err != nil {
+		return "", 0, err
+	}
+	if err := replace(path, kept); err != nil {
+		return "", 0, err
+	}
+	return archive, n, nil
+}
+
+// appendGzip adds data to the archive as a gzip member of its own. Readers
+// such as gunzip read concatenated members as one stream.
+func appendGzip(archive string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(archive), 0o700); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(archive, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// replace backs the file up, then writes data over it. Everything that
+// rewrites history goes through here.
+func replace(path string, data []byte) error {
+	if err := backup(path); err != nil {
+		return fmt.Errorf("backing up %s: %w", path, err)
+	}
+	return fsutil.WriteFileAtomic(path, data, 0o600)
+}
+
+// backup copies the file into BackupDir, if there is a file, and removes
+// all but the newest BackupsKept backups.
+func backup(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	dir := BackupDir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	// Backups are named to the millisecond, so one taken in the same
+	// millisecond as the last, or by a clock set back since, is named just
+	// after it rather than over or before it.
+	taken := time.Now()
+	backups, err := Backups(path)
+	if err != nil {
+		return err
+	}
+	if n := len(backups); n > 0 && !taken.Truncate(time.Millisecond).After(backups[n-1].Taken) {
+		taken = backups[n-1].Taken.Add(time.Millisecond)
+	}
+	prefix, ext := backupAffixes(path)
+	name := filepath.Join(dir, prefix+taken.Format(backupStamp)+ext)
+	if err := fsutil.WriteFileAtomic(name, data, 0o600); err != nil {
+		return err
+	}
+
+	backups, err = Backups(path)
+	if err != nil {
+		return err
+	}
+	for _, b := range backups[:max(len(backups)-BackupsKept, 0)] {
+		os.Remove(b.Path)
+	}
+	return nil
+}
+
+// backupAffixes splits history.jsonl's backup names, e.g.
+// history-20240501T093000.000.jsonl, around the time.
+func backupAffixes(path string) (prefix, ext string) {
+	base := filepath.Base(path)
+	ext = filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-", ext
+}
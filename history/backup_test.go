@@ -0,0 +1,231 @@
+package history
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeRecords writes records 1 to n to path, returning the file's bytes.
+func writeRecords(t *testing.T, path string, n int) []byte {
+	t.Helper()
+	for i := 1; i <= n; i++ {
+		if err := Append(path, testRecord(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// TestRepairRollsBack repairs a file with a corrupt line, decides the
+// repair was wrong, and puts the file back from the backup taken.
+func TestRepairRollsBack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	good := writeRecords(t, path, 3)
+	corrupt := append(slices.Clone(good), []byte("{\"start\": \"not a time\"}\n")...)
+	if err := os.WriteFile(path, corrupt, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := Repair(path)
+	if err != nil || removed != 1 {
+		t.Fatalf("Repair: %d, %v, want 1 line removed", removed, err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, good) {
+		t.Errorf("repaired to\n%s\nwant\n%s", data, good)
+	}
+	backups, err := Backups(path)
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("backups %v, %v, want the one Repair took", backups, err)
+	}
+
+	if err := Restore(path, backups[0]); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, corrupt) {
+		t.Errorf("restored to\n%s\nwant\n%s", data, corrupt)
+	}
+	// The restore backed up the repaired file in turn, so it can be undone.
+	backups, _ = Backups(path)
+	if len(backups) != 2 {
+		t.Fatalf("%d backups, want 2", len(backups))
+	}
+	if data, _ := os.ReadFile(backups[1].Path); !bytes.Equal(data, good) {
+		t.Errorf("restore backed up\n%s\nwant the repaired file", data)
+	}
+}
+
+// TestRestoreCorruptedFile overwrites the file with garbage after an edit
+// and restores the latest backup.
+func TestRestoreCorruptedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	before := writeRecords(t, path, 4)
+	if _, _, err := Prune(path, testRecord(2).Start); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("\x00\x00garbage\n{\"phase\":"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Fatal("garbage read without error")
+	}
+
+	b, err := FindBackup(path, "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(path, b); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, before) {
+		t.Errorf("restored to\n%s\nwant\n%s", data, before)
+	}
+	records, err := Read(path)
+	if err != nil || !slices.Equal(cycles(records), []int{1, 2, 3, 4}) {
+		t.Errorf("read back cycles %v, %v, want 1 to 4", cycles(records), err)
+	}
+}
+
+// TestBackupsKept rewrites the file more times than backups are kept, in
+// quicker succession than the millisecond backups are named to.
+func TestBackupsKept(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	writeRecords(t, path, 1)
+	var contents [][]byte
+	for i := 0; i < BackupsKept+3; i++ {
+		data, _ := os.ReadFile(path)
+		contents = append(contents, data)
+		if err := replace(path, append(data, data[:len(data)/len(contents)]...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != BackupsKept {
+		t.Fatalf("%d backups, want %d", len(backups), BackupsKept)
+	}
+	// The oldest went, and the rest are in order.
+	for i, b := range backups {
+		data, _ := os.ReadFile(b.Path)
+		if want := contents[i+3]; !bytes.Equal(data, want) {
+			t.Errorf("backup %d holds %d bytes, want the %d of rewrite %d", i, len(data), len(want), i+3)
+		}
+	}
+}
+
+func TestBackupNothingYet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := replace(path, []byte("\n")); err != nil {
+		t.Fatal(err)
+	}
+	if backups, err := Backups(path); err != nil || len(backups) != 0 {
+		t.Errorf("backups %v, %v, want none of a file that was not there", backups, err)
+	}
+}
+
+func TestFindBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if _, err := FindBackup(path, "latest"); err == nil || err.Error() != "no backups" {
+		t.Errorf("no backups: %v", err)
+	}
+	dir := BackupDir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		"history-20250106T090000.000.jsonl",
+		"history-20250106T093000.000.jsonl",
+		"history-20250107T090000.000.jsonl",
+		"history-yesterday.jsonl",
+		"notes-20250108T090000.000.jsonl",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name, want, err string
+	}{
+		{name: "latest", want: "history-20250107T090000.000.jsonl"},
+		{name: "history-20250106T09", err: `"history-20250106T09" matches more than one backup`},
+		{name: "history-20250106T0930", want: "history-20250106T093000.000.jsonl"},
+		{name: "history-2024", err: `no backup "history-2024"`},
+		{name: "notes", err: `no backup "notes"`},
+	}
+	for _, tt := range tests {
+		b, err := FindBackup(path, tt.name)
+		switch {
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("%s: %v, want %q", tt.name, err, tt.err)
+		case tt.err == "" && (err != nil || filepath.Base(b.Path) != tt.want):
+			t.Errorf("%s: %s, %v, want %s", tt.name, b.Path, err, tt.want)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	writeRecords(t, path, 5)
+
+	archive, n, err := Prune(path, testRecord(3).Start)
+	if err != nil || n != 2 {
+		t.Fatalf("Prune: %d, %v, want 2 moved", n, err)
+	}
+	if want := filepath.Join(filepath.Dir(path), "archive", "history-before-2025-01-06.jsonl.gz"); archive != want {
+		t.Errorf("archived to %s, want %s", archive, want)
+	}
+	// Again, to the same archive: nothing more to move.
+	if _, n, err := Prune(path, testRecord(3).Start); err != nil || n != 0 {
+		t.Errorf("again: %d, %v, want none moved", n, err)
+	}
+	if _, n, err := Prune(path, testRecord(4).Start); err != nil || n != 1 {
+		t.Errorf("one more: %d, %v, want 1 moved", n, err)
+	}
+
+	records, err := Read(path)
+	if err != nil || !slices.Equal(cycles(records), []int{4, 5}) {
+		t.Errorf("left cycles %v, %v, want 4 and 5", cycles(records), err)
+	}
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unpacked := filepath.Join(t.TempDir(), "archived.jsonl")
+	os.WriteFile(unpacked, data, 0o600)
+	if archived, err := Read(unpacked); err != nil || !slices.Equal(cycles(archived), []int{1, 2, 3}) {
+		t.Errorf("archived cycles %v, %v, want 1 to 3", cycles(archived), err)
+	}
+}
+
+func TestPruneRefusesCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	data := append(writeRecords(t, path, 2), "oops\n"...)
+	os.WriteFile(path, data, 0o600)
+	_, _, err := Prune(path, testRecord(2).Start)
+	if _, ok := err.(*CorruptError); !ok {
+		t.Errorf("got %v, want a CorruptError", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, data) {
+		t.Error("file changed")
+	}
+}
@@ -0,0 +1,75 @@
+package history
+
+import "time"
+
+// DebtDay is a day of Debt: its focus time, and what is owed after it.
+// Excluded marks a day of a week with no records at all, taken as time
+// away and owing nothing.
+type DebtDay struct {
+	Day      time.Time
+	Focus    time.Duration
+	Owed     time.Duration
+	Excluded bool
+}
+
+// Debt is the focus time owed against a daily goal, day by day. Owed is
+// negative when ahead of it.
+type Debt struct {
+	Days []DebtDay
+	Owed time.Duration
+}
+
+// FocusDebt adds up goal less the day's focus time for each day from from
+// up to, not including, to, both midnights, leaving out work phases
+// planned shorter than minWork as Summarize does. A week, Monday to
+// Sunday, without a single record is left out unless strict.
+func FocusDebt(records []Record, from, to time.Time, goal, minWork time.Duration, strict bool) Debt {
+	var debt Debt
+	active := make(map[time.Time]bool)
+	for _, r := range records {
+		active[WeekOf(r.Start.In(from.Location()))] = true
+	}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		d := DebtDay{Day: day, Excluded: !strict && !active[WeekOf(day)]}
+		if !d.Excluded {
+			d.Focus = Summarize(On(records, day), minWork).Focus
+			debt.Owed += goal - d.Focus
+		}
+		d.Owed = debt.Owed
+		debt.Days = append(debt.Days, d)
+	}
+	return debt
+}
+
+// WeekDebt is the focus debt of now's week so far against goal a day:
+// what Monday to yesterday fell short of it, as FocusDebt has it, with
+// today's focus time, and owed, the debt less anything today has gone past
+// goal.
+func WeekDebt(records []Record, now time.Time, goal, minWork time.Duration, strict bool) (debt Debt, today, owed time.Duration) {
+	y, m, d := now.Date()
+	debt = FocusDebt(records, WeekOf(now), time.Date(y, m, d, 0, 0, 0, 0, now.Location()), goal, minWork, strict)
+	today = Summarize(On(records, now), minWork).Focus
+	return debt, today, debt.Owed - max(today-goal, 0)
+}
+
+// DaysLeft is the days left in now's week, today included.
+func DaysLeft(now time.Time) int {
+	return 7 - (int(now.Weekday())+6)%7
+}
+
+// WeekOf is the Monday midnight starting t's week.
+func WeekOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	sinceMonday := (int(t.Weekday()) + 6) % 7
+	return time.Date(y, m, d-sinceMonday, 0, 0, 0, 0, t.Location())
+}
+
+// CatchUp is how many extra work phases, work long, a day would pay off
+// owed over days, rounded up. It is only ever a suggestion.
+func CatchUp(owed, work time.Duration, days int) int {
+	if owed <= 0 || work <= 0 || days <= 0 {
+		return 0
+	}
+	perDay := (owed + time.Duration(days) - 1) / time.Duration(days)
+	return int((perDay + work - 1) / work)
+}
@@ -0,0 +1,121 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// focused is a work phase planned at 25 minutes that started at hour on
+// the day of January 2025 and focused for actual.
+func focused(day, hour int, actual time.Duration) Record {
+	start := time.Date(2025, time.January, day, hour, 0, 0, 0, time.UTC)
+	return Record{
+		Start:     start,
+		End:       start.Add(actual),
+		Phase:     engine.PhaseWork,
+		PlannedMS: (25 * time.Minute).Milliseconds(),
+		ActualMS:  actual.Milliseconds(),
+		Ended:     engine.EndCompleted,
+	}
+}
+
+// TestWeekDebt works out the debt of the week of Monday 13 January 2025,
+// the week before it gone without a record, against 2h a day.
+func TestWeekDebt(t *testing.T) {
+	const goal = 2 * time.Hour
+	at := func(day int) time.Time { return time.Date(2025, time.January, day, 10, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name    string
+		now     time.Time
+		records []Record
+		strict  bool
+		minWork time.Duration
+		// debt is what Monday to yesterday owe, and owed that less today's
+		// time past the goal.
+		debt, today, owed time.Duration
+		days              int
+		excluded          int
+	}{
+		{"monday, nothing yet", at(13), nil, false, 0, 0, 0, 0, 0, 0},
+		{"monday, strict", at(13), nil, true, 0, 0, 0, 0, 0, 0},
+		{"day missed", at(15), []Record{focused(13, 9, 2*time.Hour)}, false, 0, 2 * time.Hour, 0, 2 * time.Hour, 2, 0},
+		{"day short", at(15), []Record{focused(13, 9, 2*time.Hour), focused(14, 9, 20*time.Minute)}, false, 0,
+			100 * time.Minute, 0, 100 * time.Minute, 2, 0},
+		{"today pays some off", at(15), []Record{focused(13, 9, 2*time.Hour), focused(14, 9, 20*time.Minute), focused(15, 8, 3*time.Hour)}, false, 0,
+			100 * time.Minute, 3 * time.Hour, 40 * time.Minute, 2, 0},
+		{"today short adds nothing yet", at(15), []Record{focused(13, 9, time.Hour), focused(14, 9, 2*time.Hour), focused(15, 8, 30*time.Minute)}, false, 0,
+			time.Hour, 30 * time.Minute, time.Hour, 2, 0},
+		{"ahead", at(15), []Record{focused(13, 9, 3*time.Hour), focused(14, 9, 3*time.Hour)}, false, 0,
+			-2 * time.Hour, 0, -2 * time.Hour, 2, 0},
+		{"ahead and more today", at(14), []Record{focused(13, 9, 3*time.Hour), focused(14, 8, 3*time.Hour)}, false, 0,
+			-time.Hour, 3 * time.Hour, -2 * time.Hour, 1, 0},
+		{"short phases do not count", at(14), []Record{focused(13, 9, 2*time.Hour)}, false, 30 * time.Minute,
+			2 * time.Hour, 0, 2 * time.Hour, 1, 0},
+		{"sunday, every day missed but one", at(19), []Record{focused(13, 9, 2*time.Hour)}, false, 0, 10 * time.Hour, 0, 10 * time.Hour, 6, 0},
+
+		// A week without a record is time away, unless strict.
+		{"week away", at(15), []Record{focused(8, 9, 2*time.Hour)}, false, 0, 0, 0, 0, 2, 2},
+		{"week away, strict", at(15), []Record{focused(8, 9, 2*time.Hour)}, true, 0, 4 * time.Hour, 0, 4 * time.Hour, 2, 0},
+		{"back today", at(15), []Record{focused(15, 8, time.Hour)}, false, 0, 4 * time.Hour, time.Hour, 4 * time.Hour, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			debt, today, owed := WeekDebt(tt.records, tt.now, goal, tt.minWork, tt.strict)
+			if debt.Owed != tt.debt || today != tt.today || owed != tt.owed {
+				t.Errorf("debt %s, today %s, owed %s, want %s, %s, %s", debt.Owed, today, owed, tt.debt, tt.today, tt.owed)
+			}
+			if len(debt.Days) != tt.days {
+				t.Fatalf("%d days, want %d", len(debt.Days), tt.days)
+			}
+			excluded := 0
+			for i, d := range debt.Days {
+				if want := WeekOf(tt.now).AddDate(0, 0, i); !d.Day.Equal(want) {
+					t.Errorf("day %d is %s, want %s", i, d.Day, want)
+				}
+				if d.Excluded {
+					excluded++
+				}
+			}
+			if excluded != tt.excluded {
+				t.Errorf("%d days excluded, want %d", excluded, tt.excluded)
+			}
+		})
+	}
+}
+
+func TestDaysLeft(t *testing.T) {
+	for day, want := range map[int]int{13: 7, 14: 6, 15: 5, 16: 4, 17: 3, 18: 2, 19: 1} {
+		now := time.Date(2025, time.January, day, 23, 59, 0, 0, time.UTC)
+		if got := DaysLeft(now); got != want {
+			t.Errorf("DaysLeft(%s) = %d, want %d", now.Weekday(), got, want)
+		}
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	const work = 25 * time.Minute
+	tests := []struct {
+		owed time.Duration
+		work time.Duration
+		days int
+		want int
+	}{
+		{0, work, 5, 0},
+		{-time.Hour, work, 5, 0},
+		{time.Minute, work, 5, 1},
+		{50 * time.Minute, work, 1, 2},
+		{51 * time.Minute, work, 1, 3},
+		{100 * time.Minute, work, 1, 4},
+		{100 * time.Minute, work, 5, 1},
+		{100 * time.Minute, work, 2, 2},
+		{time.Hour, 0, 5, 0},
+		{time.Hour, work, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := CatchUp(tt.owed, tt.work, tt.days); got != tt.want {
+			t.Errorf("CatchUp(%s, %s, %d) = %d, want %d", tt.owed, tt.work, tt.days, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IDLength is how many hex digits of the hash an ID has.
+const IDLength = 7
+
+// ErrNotFound is returned for an ID no record has.
+var ErrNotFound = errors.New("no such record")
+
+// ID names r by a hash of its start and phase, which editing never
+// changes, e.g. "3f9a0c1".
+func (r Record) ID() string {
+	sum := sha256.Sum256([]byte(r.identity()))
+	return hex.EncodeToString(sum[:])[:IDLength]
+}
+
+// identity is what ID hashes. Short IDs can collide in a long history, so
+// telling whether two records are the same record compares these instead.
+func (r Record) identity() string {
+	return r.Start.UTC().Format(time.RFC3339Nano) + " " + r.Phase.String()
+}
+
+// Find returns the index of the record id names, or of the last record for
+// "last". A prefix of an ID will do if no other record shares it.
+func Find(records []Record, id string) (int, error) {
+	if id == "last" {
+		if len(records) == 0 {
+			return 0, ErrNotFound
+		}
+		return len(records) - 1, nil
+	}
+	found := -1
+	for i, r := range records {
+		if id == "" || !strings.HasPrefix(r.ID(), id) {
+			continue
+		}
+		if found >= 0 {
+			return 0, fmt.Errorf("%q matches more than one record", id)
+		}
+		found = i
+	}
+	if found < 0 {
+		return 0, fmt.Errorf("%w %q", ErrNotFound, id)
+	}
+	return found, nil
+}
+
+// Delete removes the record id names, as Find finds it, and returns it.
+func Delete(path, id string) (Record, error) {
+	var deleted Record
+	err := rewrite(path, id, func(r *Record) bool {
+		deleted = *r
+		return false
+	})
+	return deleted, err
+}
+
+// Update applies change to the record id names, as Find finds it, and
+// returns it as changed. change should leave the start and phase alone,
+// which the ID comes from.
+func Update(path, id string, change func(*Record)) (Record, error) {
+	var updated Record
+	err := rewrite(path, id, func(r *Record) bool {
+		change(r)
+		updated = *r
+		return true
+	})
+	return updated, err
+}
+
+// rewrite replaces the file with one in which the record id names is
+// passed through edit, and dropped unless edit keeps it. Every other line
+// is written back as it was. A file with corrupt lines has to be repaired
+// first, rather than lose them silently. The file as it was is kept among
+// its Backups.
+func rewrite(path, id string, edit func(*Record) bool) error {
+	records, lines, bad, err := scan(path)
+	if err != nil {
+		return err
+	}
+	if len(bad) > 0 {
+		return &CorruptError{Path: path, Lines: bad}
+	}
+	i, err := Find(records, id)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	for j, line := range lines {
+		if j == i {
+			r := records[i]
+			if !edit(&r) {
+				continue
+			}
+			if line, err = json.Marshal(r); err != nil {
+				return err
+			}
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+	return replace(path, data)
+}
@@ -0,0 +1,135 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Export writes every record in the file to w as it reads them, one JSON
+// object per line, and returns how many it wrote. Records come out as Read
+// returns them, older ones brought up to date. Lines that do not parse are
+// skipped and reported with a *CorruptError, as Read does.
+func Export(w io.Writer, path string) (int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(w)
+	n := 0
+	var bad []int
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r legacyRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			bad = append(bad, line)
+			continue
+		}
+		if err := enc.Encode(r.backfill()); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return n, err
+	}
+	if len(bad) > 0 {
+		return n, &CorruptError{Path: path, Lines: bad}
+	}
+	return n, nil
+}
+
+// Decompress returns r uncompressed if it starts like gzip, and as it is
+// otherwise. Concatenated gzip members, as Prune's archives have, read as
+// one stream.
+func Decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return br, nil
+	}
+	return gzip.NewReader(br)
+}
+
+// Import adds the records read from r, as Export writes them and gzipped
+// or not, to the file, keeping it in order of start. Records the file
+// already has, by start and phase, are left out. It returns how many were added and how
+// many left out. Like undo and edit, it refuses a file with corrupt lines,
+// and the file as it was is kept among its Backups.
+func Import(path string, r io.Reader) (added, dup int, err error) {
+	records, lines, bad, err := scan(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(bad) > 0 {
+		return 0, 0, &CorruptError{Path: path, Lines: bad}
+	}
+	have := make(map[string]bool, len(records))
+	for _, rec := range records {
+		have[rec.identity()] = true
+	}
+
+	in, err := Decompress(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	dec := json.NewDecoder(in)
+	for {
+		var l legacyRecord
+		if err := dec.Decode(&l); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, 0, err
+		}
+		rec := l.backfill()
+		if have[rec.identity()] {
+			dup++
+			continue
+		}
+		have[rec.identity()] = true
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return 0, 0, err
+		}
+		records, lines = append(records, rec), append(lines, line)
+		added++
+	}
+	if added == 0 {
+		return 0, dup, nil
+	}
+
+	order := make([]int, len(records))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return records[order[i]].Start.Before(records[order[j]].Start)
+	})
+	var data []byte
+	for _, i := range order {
+		data = append(append(data, lines[i]...), '\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return 0, 0, err
+	}
+	if err := replace(path, data); err != nil {
+		return 0, 0, err
+	}
+	return added, dup, nil
+}
@@ -0,0 +1,148 @@
+package history
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// writeSynthetic writes n records to path as a long history would have
+// them: every phase, every way of ending, and the odd label, tag and note.
+func writeSynthetic(t *testing.T, path string, n int) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	phases := []engine.Phase{engine.PhaseWork, engine.PhaseShortBreak, engine.PhaseWork, engine.PhaseLongBreak}
+	ends := []engine.EndReason{engine.EndCompleted, engine.EndCompleted, engine.EndSkipped, engine.EndInterrupted}
+	at := testStart
+	for i := 0; i < n; i++ {
+		r := Record{
+			Start:     at,
+			Phase:     phases[i%len(phases)],
+			PlannedMS: (25 * time.Minute).Milliseconds(),
+			ActualMS:  (time.Duration(i%1500) * time.Second).Milliseconds(),
+			PausedMS:  int64(i%7) * 1000,
+			Pauses:    i % 3,
+			Cycle:     i/4 + 1,
+			Ended:     ends[i%len(ends)],
+		}
+		if i%5 == 0 {
+			r.Label = "write tests"
+		}
+		if i%11 == 0 {
+			r.Tags, r.Note = []string{"deep"}, "went well"
+		}
+		r.End = at.Add(r.Actual() + r.Paused())
+		if err := enc.Encode(r); err != nil {
+			t.Fatal(err)
+		}
+		at = r.End.Add(time.Second)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestExportImportRoundTrip exports a large history gzipped into a pipe
+// that import reads from as it is written, so neither end holds it all,
+// and checks the history import builds is the one exported.
+func TestExportImportRoundTrip(t *testing.T) {
+	const n = 50000
+	dir := t.TempDir()
+	from, to := filepath.Join(dir, "from.jsonl"), filepath.Join(dir, "to.jsonl")
+	writeSynthetic(t, from, n)
+
+	pr, pw := io.Pipe()
+	exported := make(chan int, 1)
+	go func() {
+		zw := gzip.NewWriter(pw)
+		count, err := Export(zw, from)
+		if err == nil {
+			err = zw.Close()
+		}
+		exported <- count
+		pw.CloseWithError(err)
+	}()
+	added, dup, err := Import(to, pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := <-exported; count != n || added != n || dup != 0 {
+		t.Errorf("exported %d, imported %d with %d already there, want %d, %d and 0", count, added, dup, n, n)
+	}
+
+	want, err := Read(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Error("imported history differs from the exported one")
+	}
+
+	// The same export again adds nothing.
+	f, err := os.Open(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if added, dup, err := Import(to, f); err != nil || added != 0 || dup != n {
+		t.Errorf("importing again added %d with %d already there (%v), want 0 and %d", added, dup, err, n)
+	}
+}
+
+// TestImportMerges imports half a history into the other half, with a
+// record in common, and checks the two end up in order of start.
+func TestImportMerges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.jsonl")
+	for _, n := range []int{1, 3, 4} {
+		if err := Append(path, testRecord(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other := filepath.Join(dir, "other.jsonl")
+	for _, n := range []int{2, 4, 5} {
+		if err := Append(other, testRecord(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Open(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	added, dup, err := Import(path, f)
+	if err != nil || added != 2 || dup != 1 {
+		t.Fatalf("added %d with %d already there (%v), want 2 and 1", added, dup, err)
+	}
+	records, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cycles(records); !reflect.DeepEqual(got, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("cycles %v after import, want 1 to 5 in order", got)
+	}
+	if backups, err := Backups(path); err != nil || len(backups) != 1 {
+		t.Errorf("%d backups (%v), want the history as it was", len(backups), err)
+	}
+}
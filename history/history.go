@@ -0,0 +1,286 @@
+// Package history keeps a log of every phase pomo has run, one JSON record
+// per line, for the log and stats commands.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/paths"
+)
+
+type Record struct {
+	Start     time.Time        `json:"start"`
+	End       time.Time        `json:"end"`
+	Phase     engine.Phase     `json:"phase"`
+	PlannedMS int64            `json:"planned_ms"`
+	ActualMS  int64            `json:"actual_ms"`
+	PausedMS  int64            `json:"paused_ms"`
+	Pauses    int              `json:"pauses"`
+	Cycle     int              `json:"cycle"`
+	Ended     engine.EndReason `json:"ended_reason"`
+	Label     string           `json:"label,omitempty"`
+	Extra     bool             `json:"extra,omitempty"`
+	Enforced  bool             `json:"enforced,omitempty"`
+	// Tags and Note are only ever set afterwards, by pomo history edit.
+	Tags []string `json:"tags,omitempty"`
+	Note string   `json:"note,omitempty"`
+	// Times a blocked app stayed in front during the phase.
+	Distractions int `json:"distractions,omitempty"`
+	// How long snoozing the break before put the phase off.
+	SnoozedMS int64 `json:"snoozed_ms,omitempty"`
+	// Suspicious marks a phase the session's hard cap cut off, most likely
+	// because pomo was left running.
+	Suspicious bool `json:"suspicious,omitempty"`
+	// With --activity-score, the minutes of the phase sampled for input
+	// and how many of them had some.
+	ActivitySamples int `json:"activity_samples,omitempty"`
+	ActiveSamples   int `json:"active_samples,omitempty"`
+	// Voided marks a work phase strict pomodoro voided, which does not
+	// count however it ended.
+	Voided bool `json:"voided,omitempty"`
+	// AwayMS is the start of the phase that went by with no one there,
+	// under --idle-pause or --idle-stop, left out of ActualMS.
+	AwayMS int64 `json:"away_ms,omitempty"`
+	// With --bank-breaks, what a break added to the bank by ending early,
+	// how long it was extended past PlannedMS and how much of that the
+	// bank paid for, and the bank left once the phase was over.
+	BankedMS   int64 `json:"banked_ms,omitempty"`
+	ExtendedMS int64 `json:"extended_ms,omitempty"`
+	FromBankMS int64 `json:"from_bank_ms,omitempty"`
+	BankMS     int64 `json:"bank_ms,omitempty"`
+	// Project is what the session was started for: --project, else the
+	// git repository the directory it started in is in. Dir is that
+	// directory. Both are left out with --no-record-dir, but for
+	// --project.
+	Project string `json:"project,omitempty"`
+	Dir     string `json:"dir,omitempty"`
+	// Lunch marks a long break stretched, or extended, to lunch as
+	// --lunch has it.
+	Lunch bool `json:"lunch,omitempty"`
+	// CheckInMS is how long, with --checkin, the phase waited after the
+	// break before it for someone to come back and check in.
+	CheckInMS int64 `json:"check_in_ms,omitempty"`
+	// Rating, from 1 to MaxRating, is how focused the session was by
+	// its own account, and Retro a line on how it went. Both are set
+	// after the phase, on the session's last work phase, by pomo start
+	// --review or pomo review.
+	Rating int    `json:"rating,omitempty"`
+	Retro  string `json:"retro,omitempty"`
+}
+
+// ProjectName is what pomo stats --by-project counts r toward: its
+// project, else the directory it was started in, else "".
+func (r Record) ProjectName() string {
+	if r.Project != "" {
+		return r.Project
+	}
+	return r.Dir
+}
+
+// legacyRecord has the fields of records written before ended_reason.
+type legacyRecord struct {
+	Record
+	Skipped     bool `json:"skipped"`
+	Interrupted bool `json:"interrupted"`
+}
+
+// backfill fills in what older records lack: without an end reason a phase
+// is taken to have completed as planned.
+func (l legacyRecord) backfill() Record {
+	r := l.Record
+	if r.Ended == "" {
+		switch {
+		case l.Interrupted:
+			r.Ended = engine.EndInterrupted
+		case l.Skipped:
+			r.Ended = engine.EndSkipped
+		default:
+			r.Ended = engine.EndCompleted
+		}
+	}
+	if r.Ended == engine.EndCompleted {
+		if r.ActualMS == 0 {
+			r.ActualMS = r.PlannedMS
+		}
+		if r.PlannedMS == 0 {
+			r.PlannedMS = r.ActualMS
+		}
+	}
+	return r
+}
+
+func (r Record) Planned() time.Duration { return time.Duration(r.PlannedMS) * time.Millisecond }
+func (r Record) Actual() time.Duration  { return time.Duration(r.ActualMS) * time.Millisecond }
+func (r Record) Paused() time.Duration  { return time.Duration(r.PausedMS) * time.Millisecond }
+func (r Record) Snoozed() time.Duration { return time.Duration(r.SnoozedMS) * time.Millisecond }
+func (r Record) Away() time.Duration    { return time.Duration(r.AwayMS) * time.Millisecond }
+
+// CheckInWait is how long r waited for a check-in before it started.
+func (r Record) CheckInWait() time.Duration { return time.Duration(r.CheckInMS) * time.Millisecond }
+
+// Activity is the fraction of sampled minutes that had input, if any were
+// sampled.
+func (r Record) Activity() (float64, bool) {
+	return activity(r.ActiveSamples, r.ActivitySamples)
+}
+
+func activity(active, sampled int) (float64, bool) {
+	if sampled == 0 {
+		return 0, false
+	}
+	return float64(active) / float64(sampled), true
+}
+
+// Dir is where history is kept, as paths.History has it.
+func Dir() (string, error) {
+	return paths.History()
+}
+
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// Append adds r as one line in a single write and syncs it to disk. If an
+// earlier write was cut short, the new record starts on a fresh line so
+// only the partial one is lost.
+func Append(path string, r Record) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			data = append([]byte{'\n'}, data...)
+		}
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// CorruptError lists lines Read skipped because they did not parse, as
+// left behind by a write cut short.
+type CorruptError struct {
+	Path  string
+	Lines []int
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("%s: skipped %d corrupt record(s) at line(s) %s", e.Path, len(e.Lines), joinInts(e.Lines))
+}
+
+// Read returns every record in the file, oldest first. A missing file is an
+// empty history. Lines that do not parse are skipped and reported with a
+// *CorruptError alongside the records that did.
+func Read(path string) ([]Record, error) {
+	records, _, bad, err := scan(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(bad) > 0 {
+		return records, &CorruptError{Path: path, Lines: bad}
+	}
+	return records, nil
+}
+
+// Repair rewrites the file without its corrupt lines, returning how many
+// were dropped. The file as it was is kept among its Backups.
+func Repair(path string) (int, error) {
+	_, good, bad, err := scan(path)
+	if err != nil || len(bad) == 0 {
+		return 0, err
+	}
+
+	var data []byte
+	for _, line := range good {
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+	if err := replace(path, data); err != nil {
+		return 0, err
+	}
+	return len(bad), nil
+}
+
+// scan parses path, returning the records, the raw lines they came from,
+// and the numbers of lines that failed to parse.
+func scan(path string) ([]Record, [][]byte, []int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	var good [][]byte
+	var bad []int
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r legacyRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			bad = append(bad, line)
+			continue
+		}
+		records = append(records, r.backfill())
+		good = append(good, append([]byte(nil), scanner.Bytes()...))
+	}
+	return records, good, bad, scanner.Err()
+}
+
+func joinInts(ns []int) string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ", ")
+}
+
+// On returns the records that started on day's local date.
+func On(records []Record, day time.Time) []Record {
+	y, m, d := day.Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, day.Location())
+	to := from.AddDate(0, 0, 1)
+
+	var out []Record
+	for _, r := range records {
+		if !r.Start.Before(from) && r.Start.Before(to) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
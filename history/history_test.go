@@ -0,0 +1,124 @@
+package history
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+var testStart = time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+// testRecord is the work phase of cycle n, 25 minutes after the last.
+func testRecord(n int) Record {
+	start := testStart.Add(time.Duration(n-1) * 25 * time.Minute)
+	return Record{
+		Start:     start,
+		End:       start.Add(25 * time.Minute),
+		Phase:     engine.PhaseWork,
+		PlannedMS: (25 * time.Minute).Milliseconds(),
+		ActualMS:  (25 * time.Minute).Milliseconds(),
+		Cycle:     n,
+		Ended:     engine.EndCompleted,
+		Label:     "write tests",
+	}
+}
+
+func cycles(records []Record) []int {
+	var out []int
+	for _, r := range records {
+		out = append(out, r.Cycle)
+	}
+	return out
+}
+
+// writeCut appends records 1 to 3 and cuts the file off cut bytes into the
+// last one's line, as a write cut short would leave it, returning how long
+// that line is, newline and all.
+func writeCut(t *testing.T, path string, cut int) int {
+	t.Helper()
+	os.Remove(path)
+	for n := 1; n <= 3; n++ {
+		if err := Append(path, testRecord(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := bytes.LastIndexByte(data[:len(data)-1], '\n') + 1
+	if err := os.WriteFile(path, data[:last+cut], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return len(data) - last
+}
+
+func TestReadCutAtEveryByte(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	length := writeCut(t, path, 0)
+	for cut := 0; cut <= length; cut++ {
+		writeCut(t, path, cut)
+
+		// Cut before its first byte or after its last, the record is
+		// whole; anywhere between, it is skipped and reported.
+		whole := cut == 0 || cut >= length-1
+		want := []int{1, 2}
+		if cut >= length-1 {
+			want = []int{1, 2, 3}
+		}
+
+		records, err := Read(path)
+		if !slices.Equal(cycles(records), want) {
+			t.Fatalf("cut at %d: read cycles %v, want %v", cut, cycles(records), want)
+		}
+		var corrupt *CorruptError
+		switch {
+		case whole && err != nil:
+			t.Fatalf("cut at %d: %v", cut, err)
+		case !whole && (!errors.As(err, &corrupt) || !slices.Equal(corrupt.Lines, []int{3})):
+			t.Fatalf("cut at %d: err = %v, want line 3 reported corrupt", cut, err)
+		}
+
+		dropped, err := Repair(path)
+		if err != nil {
+			t.Fatalf("cut at %d: repair: %v", cut, err)
+		}
+		wantDropped := 1
+		if whole {
+			wantDropped = 0
+		}
+		if dropped != wantDropped {
+			t.Fatalf("cut at %d: repair dropped %d, want %d", cut, dropped, wantDropped)
+		}
+		if records, err := Read(path); err != nil || !slices.Equal(cycles(records), want) {
+			t.Fatalf("cut at %d: after repair read %v, %v, want %v", cut, cycles(records), err, want)
+		}
+	}
+}
+
+func TestAppendAfterCut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	length := writeCut(t, path, 0)
+	for cut := 1; cut < length-1; cut++ {
+		writeCut(t, path, cut)
+		if err := Append(path, testRecord(4)); err != nil {
+			t.Fatal(err)
+		}
+
+		// Only the cut record is lost; the next starts on a line of its own.
+		records, err := Read(path)
+		var corrupt *CorruptError
+		if !errors.As(err, &corrupt) || !slices.Equal(corrupt.Lines, []int{3}) {
+			t.Fatalf("cut at %d: err = %v, want line 3 reported corrupt", cut, err)
+		}
+		if got := cycles(records); !slices.Equal(got, []int{1, 2, 4}) {
+			t.Fatalf("cut at %d: read cycles %v, want [1 2 4]", cut, got)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package history
+
+import (
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// Recorder appends a Record for every phase that ends, including the one
+// cut short when the session stops.
+type Recorder struct {
+	path  string
+	clock engine.Clock
+	label string
+	// project and dir are where the session was started.
+	project string
+	dir     string
+
+	open    bool
+	current Record
+	paused  bool
+	// snoozed is the snooze before the next phase, recorded with it.
+	snoozed time.Duration
+	// waited is the wait for a check-in before the next phase, likewise.
+	waited time.Duration
+	// away is how much of the current phase no one was there for.
+	away time.Duration
+	err  error
+}
+
+func NewRecorder(path string, clock engine.Clock, label string) *Recorder {
+	return &Recorder{path: path, clock: clock, label: label}
+}
+
+func (r *Recorder) Handle(e engine.TimerEvent) {
+	now := r.clock.Now()
+
+	switch e.Type {
+	case engine.EventSessionStarted, engine.EventTransition:
+		return
+	case engine.EventSnooze:
+		if e.Ended != "" {
+			r.snoozed += e.Elapsed
+		}
+		return
+	case engine.EventAway:
+		if e.Ended != "" {
+			r.waited += e.Elapsed
+		}
+		return
+	case engine.EventSessionEnded:
+		if r.open {
+			r.current.Ended = engine.EndInterrupted
+			r.current.End = now
+			r.write()
+		}
+		return
+	}
+
+	if !r.open {
+		cycle := e.CycleNum
+		if e.Phase != engine.PhaseWork {
+			cycle--
+		}
+		r.open = true
+		r.paused = false
+		r.away = 0
+		r.current = Record{
+			Start:     e.PhaseStartedAt.Add(-e.PausedTotal),
+			Phase:     e.Phase,
+			PlannedMS: e.Total.Milliseconds(),
+			Cycle:     cycle,
+			Label:     r.label,
+			Project:   r.project,
+			Dir:       r.dir,
+			Extra:     e.Extra,
+			Enforced:  e.Enforced,
+			SnoozedMS: r.snoozed.Milliseconds(),
+			CheckInMS: r.waited.Milliseconds(),
+		}
+		r.snoozed, r.waited = 0, 0
+	}
+
+	if e.Paused && !r.paused {
+		r.current.Pauses++
+	}
+	r.paused = e.Paused
+	r.current.ActualMS = max(e.Elapsed-r.away, 0).Milliseconds()
+	r.current.AwayMS = r.away.Milliseconds()
+	r.current.PausedMS = e.PausedTotal.Milliseconds()
+	r.current.End = e.PhaseStartedAt.Add(e.Elapsed)
+	r.current.ExtendedMS = e.Extended.Milliseconds()
+	r.current.FromBankMS = e.FromBank.Milliseconds()
+	r.current.BankedMS = e.Banked.Milliseconds()
+	r.current.BankMS = (e.Bank + e.Banked).Milliseconds()
+	r.current.Lunch = e.Lunch
+
+	if e.Ended != "" {
+		r.current.Ended = e.Ended
+		r.current.Suspicious = e.Capped
+		r.current.Voided = e.Voided
+		r.write()
+	}
+}
+
+// Place records project and dir, what the session was started for and
+// where, with every phase.
+func (r *Recorder) Place(project, dir string) {
+	r.project, r.dir = project, dir
+}
+
+// Distracted counts a distraction against the phase being recorded.
+func (r *Recorder) Distracted() {
+	if r.open {
+		r.current.Distractions++
+	}
+}
+
+// Activity adds sampled minutes, active of them with input, to the phase
+// being recorded.
+func (r *Recorder) Activity(sampled, active int) {
+	if r.open {
+		r.current.ActivitySamples += sampled
+		r.current.ActiveSamples += active
+	}
+}
+
+// Away leaves the first d of the phase being recorded out of its actual
+// time, as gone by with no one there.
+func (r *Recorder) Away(d time.Duration) {
+	if r.open {
+		r.away = d
+	}
+}
+
+func (r *Recorder) write() {
+	r.open = false
+	if r.err != nil {
+		return
+	}
+	r.err = Append(r.path, r.current)
+}
+
+func (r *Recorder) Close() error {
+	return r.err
+}
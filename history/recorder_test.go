@@ -0,0 +1,135 @@
+package history
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// record runs cfg on a mock clock through a Recorder, calling act on every
+// event of a phase, or wait between phases, still running, and returns
+// what was recorded. act returns whether it ended what was running, which
+// leaves the clock where it is.
+func record(t *testing.T, cfg engine.Config, act func(*engine.Timer, engine.TimerEvent) bool) []Record {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	clock := engine.NewMockClock(testStart)
+	timer := engine.NewTimerWithClock(cfg, clock, time.Second)
+	rec := NewRecorder(path, clock, "")
+
+	events := make(chan engine.TimerEvent)
+	done := make(chan error, 1)
+	go func() { done <- timer.Run(context.Background(), events) }()
+	for e := range events {
+		rec.Handle(e)
+		if e.Ended != "" || e.Type == engine.EventSessionStarted || e.Type == engine.EventSessionEnded {
+			continue
+		}
+		if act == nil || !act(timer, e) {
+			clock.Advance(time.Second)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	records, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+// TestRecordBank skips the first break 30s in and extends the second by
+// more than that, checking what each break's record has of the bank.
+func TestRecordBank(t *testing.T) {
+	breaks := 0
+	records := record(t, engine.Config{
+		WorkDuration:       time.Minute,
+		ShortBreakDuration: 2 * time.Minute,
+		TotalCycles:        3,
+		BankBreaks:         true,
+	}, func(timer *engine.Timer, e engine.TimerEvent) bool {
+		if e.Type != engine.EventTick || e.Phase != engine.PhaseShortBreak {
+			return false
+		}
+		if e.Elapsed == 0 {
+			breaks++
+		}
+		switch {
+		case breaks == 1 && e.Elapsed == 30*time.Second:
+			timer.Skip()
+			return true
+		case breaks == 2 && e.Elapsed == 10*time.Second:
+			timer.Extend(2 * time.Minute)
+		}
+		return false
+	})
+
+	type bank struct{ banked, extended, fromBank, bank, actual int64 }
+	var got []bank
+	for _, r := range records {
+		if r.Phase == engine.PhaseShortBreak {
+			got = append(got, bank{r.BankedMS, r.ExtendedMS, r.FromBankMS, r.BankMS, r.ActualMS})
+		} else if r.BankedMS != 0 || r.FromBankMS != 0 || r.ExtendedMS != 0 {
+			t.Errorf("%s recorded bank %+v", r.Phase, r)
+		}
+	}
+	want := []bank{
+		{90000, 0, 0, 90000, 30000},
+		{0, 120000, 90000, 0, 240000},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("%d breaks recorded, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("break %d recorded %+v, want %+v", i+1, got[i], want[i])
+		}
+	}
+}
+
+// TestRecordCheckIn waits 4m to check in after the first break and 2m
+// after the second, each wait recorded with the work it held.
+func TestRecordCheckIn(t *testing.T) {
+	waits := 0
+	records := record(t, engine.Config{
+		WorkDuration:       time.Minute,
+		ShortBreakDuration: time.Minute,
+		TotalCycles:        3,
+		CheckIn:            true,
+	}, func(timer *engine.Timer, e engine.TimerEvent) bool {
+		if e.Type != engine.EventAway {
+			return false
+		}
+		if e.Elapsed == 0 {
+			waits++
+		}
+		if e.Elapsed == time.Duration(6-2*waits)*time.Minute {
+			timer.CheckIn()
+			return true
+		}
+		return false
+	})
+
+	var got []time.Duration
+	for _, r := range records {
+		got = append(got, r.CheckInWait())
+		if r.Phase == engine.PhaseWork && r.Actual() != time.Minute {
+			t.Errorf("work %d recorded %s, want the minute it ran, not the wait", r.Cycle, r.Actual())
+		}
+	}
+	want := []time.Duration{0, 0, 4 * time.Minute, 0, 2 * time.Minute}
+	if !slices.Equal(got, want) {
+		t.Errorf("waits recorded %v, want %v, with the work after each break", got, want)
+	}
+	if s := Summarize(records, 0); s.CheckInWait != 6*time.Minute || s.Focus != 3*time.Minute {
+		t.Errorf("summarized %s waiting and %s focused, want 6m and 3m", s.CheckInWait, s.Focus)
+	}
+}
@@ -0,0 +1,147 @@
+package history
+
+import (
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// MaxRating is the top of the focus rating scale, which starts at 1.
+const MaxRating = 5
+
+// Review is a session's focus rating, and the line on how it went if one
+// was given, as pomo start --review or pomo review recorded them.
+type Review struct {
+	Rating int
+	Retro  string
+	// Start and Length span the phases the review covers.
+	Start  time.Time
+	Length time.Duration
+}
+
+// Reviews returns the reviews in records, oldest first. Each covers the
+// phases of its session since the one reviewed before it, and any after it
+// that nothing reviewed, such as the break following the last work phase.
+func Reviews(records []Record) []Review {
+	var out []Review
+	for _, session := range sessions(records) {
+		from, last := 0, -1
+		for i, r := range session {
+			if r.Rating == 0 {
+				continue
+			}
+			out = append(out, Review{Rating: r.Rating, Retro: r.Retro, Start: session[from].Start, Length: r.End.Sub(session[from].Start)})
+			from, last = i+1, len(out)-1
+		}
+		if last >= 0 && from < len(session) {
+			out[last].Length = session[len(session)-1].End.Sub(out[last].Start)
+		}
+	}
+	return out
+}
+
+// ReviewTarget returns the index of the record that holds the review of
+// the session records[i] is in: the first reviewed record from i on, else
+// the session's last work phase, else its last phase.
+func ReviewTarget(records []Record, i int) int {
+	start, end := i, i+1
+	for start > 0 && records[start].Start.Sub(records[start-1].End) <= sessionGap {
+		start--
+	}
+	for end < len(records) && records[end].Start.Sub(records[end-1].End) <= sessionGap {
+		end++
+	}
+	for j := i; j < end; j++ {
+		if records[j].Rating > 0 {
+			return j
+		}
+	}
+	for j := end - 1; j >= start; j-- {
+		if records[j].Phase == engine.PhaseWork {
+			return j
+		}
+	}
+	return end - 1
+}
+
+// RatingBucket is the mean rating of the reviews that fell in a bucket.
+type RatingBucket struct {
+	Name    string
+	Reviews int
+	Mean    float64
+}
+
+// ReviewSummary is the mean rating of reviews, and the mean within simple
+// buckets of session length and of the part of the day sessions started
+// in, leaving out buckets no review fell in.
+type ReviewSummary struct {
+	Reviews  int
+	Mean     float64
+	ByLength []RatingBucket
+	ByPart   []RatingBucket
+}
+
+// lengthBuckets are the upper bounds of the ByLength buckets, the last
+// open-ended.
+var lengthBuckets = []struct {
+	name  string
+	under time.Duration
+}{
+	{"under 1h", time.Hour},
+	{"1-2h", 2 * time.Hour},
+	{"2-4h", 4 * time.Hour},
+	{"4h+", 0},
+}
+
+var dayParts = []string{"morning", "afternoon", "evening", "night"}
+
+// SummarizeReviews sorts reviews into parts of the day in loc.
+func SummarizeReviews(reviews []Review, loc *time.Location) ReviewSummary {
+	s := ReviewSummary{Reviews: len(reviews)}
+	if len(reviews) == 0 {
+		return s
+	}
+
+	var total int
+	byLength := make([]RatingBucket, len(lengthBuckets))
+	byPart := make([]RatingBucket, len(dayParts))
+	for i, b := range lengthBuckets {
+		byLength[i].Name = b.name
+	}
+	for i, p := range dayParts {
+		byPart[i].Name = p
+	}
+	add := func(b *RatingBucket, rating int) {
+		b.Mean = (b.Mean*float64(b.Reviews) + float64(rating)) / float64(b.Reviews+1)
+		b.Reviews++
+	}
+	for _, r := range reviews {
+		total += r.Rating
+		for i, b := range lengthBuckets {
+			if b.under == 0 || r.Length < b.under {
+				add(&byLength[i], r.Rating)
+				break
+			}
+		}
+		part := SlotOf(r.Start.In(loc)).Part
+		for i, p := range dayParts {
+			if p == part {
+				add(&byPart[i], r.Rating)
+			}
+		}
+	}
+	s.Mean = float64(total) / float64(len(reviews))
+	s.ByLength = nonEmpty(byLength)
+	s.ByPart = nonEmpty(byPart)
+	return s
+}
+
+func nonEmpty(buckets []RatingBucket) []RatingBucket {
+	var out []RatingBucket
+	for _, b := range buckets {
+		if b.Reviews > 0 {
+			out = append(out, b)
+		}
+	}
+	return out
+}
@@ -0,0 +1,162 @@
+package history
+
+import (
+	"sort"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// Summary covers work phases only; breaks and cooldowns are not focus time.
+type Summary struct {
+	Focus     time.Duration
+	Work      int
+	Completed int
+	// Deviation is the mean of actual minus planned duration, negative when
+	// phases end early.
+	Deviation    time.Duration
+	Distractions int
+	// Snoozed is how long breaks were snoozed past, putting work off.
+	Snoozed time.Duration
+	// CheckInWait is how long work waited after breaks for a check-in.
+	CheckInWait time.Duration
+	// Voided work phases count toward Work but not Completed, their time
+	// toward VoidedTime rather than Focus.
+	Voided     int
+	VoidedTime time.Duration
+	// ActivitySamples and ActiveSamples add up those of the phases
+	// with an activity score.
+	ActivitySamples int
+	ActiveSamples   int
+	// Short work phases were left out of everything above.
+	Short    int
+	Excluded time.Duration
+}
+
+// Summarize leaves out work phases planned shorter than minWork, such as
+// test runs. The threshold applies when reading, so changing it reclassifies
+// old records too.
+func Summarize(records []Record, minWork time.Duration) Summary {
+	var s Summary
+	var deviation time.Duration
+	for _, r := range records {
+		if r.Phase != engine.PhaseWork {
+			continue
+		}
+		if r.Planned() < minWork {
+			s.Short++
+			s.Excluded += r.Actual()
+			continue
+		}
+		s.Work++
+		s.Distractions += r.Distractions
+		s.Snoozed += r.Snoozed()
+		s.CheckInWait += r.CheckInWait()
+		s.ActivitySamples += r.ActivitySamples
+		s.ActiveSamples += r.ActiveSamples
+		deviation += r.Actual() - r.Planned()
+		if r.Voided {
+			s.Voided++
+			s.VoidedTime += r.Actual()
+			continue
+		}
+		s.Focus += r.Actual()
+		if r.Ended == engine.EndCompleted {
+			s.Completed++
+		}
+	}
+	if s.Work > 0 {
+		s.Deviation = deviation / time.Duration(s.Work)
+	}
+	return s
+}
+
+// CompletionRate is the fraction of work phases that ran as planned.
+func (s Summary) CompletionRate() float64 {
+	if s.Work == 0 {
+		return 0
+	}
+	return float64(s.Completed) / float64(s.Work)
+}
+
+// Activity is the fraction of every sampled minute that had input, so
+// longer phases weigh more.
+func (s Summary) Activity() (float64, bool) {
+	return activity(s.ActiveSamples, s.ActivitySamples)
+}
+
+// Since returns the records that started at or after t.
+func Since(records []Record, t time.Time) []Record {
+	var out []Record
+	for _, r := range records {
+		if !r.Start.Before(t) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// ProjectSummary is the Summary of a project's records, by
+// Record.ProjectName.
+type ProjectSummary struct {
+	Name string
+	Summary
+}
+
+// ByProject summarizes records by project, most focus time first. Records
+// with no project, from before pomo recorded one or with --no-record-dir,
+// come last under "".
+func ByProject(records []Record, minWork time.Duration) []ProjectSummary {
+	grouped := make(map[string][]Record)
+	for _, r := range records {
+		name := r.ProjectName()
+		grouped[name] = append(grouped[name], r)
+	}
+	out := make([]ProjectSummary, 0, len(grouped))
+	for name, rs := range grouped {
+		out = append(out, ProjectSummary{name, Summarize(rs, minWork)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if (a.Name == "") != (b.Name == "") {
+			return b.Name == ""
+		}
+		if a.Focus != b.Focus {
+			return a.Focus > b.Focus
+		}
+		return a.Name < b.Name
+	})
+	return out
+}
+
+// Carry is what earlier sessions leave toward the next long break.
+type Carry struct {
+	Cycles int
+	Work   time.Duration
+	// End is when the latest of those sessions ended.
+	End time.Time
+}
+
+// CarryOver walks back through sessions that followed each other with gaps
+// of at most within, the latest ending within before now, counting the work
+// phases done since the last long break or cooldown. As in a session,
+// interrupted and extra work phases do not count.
+func CarryOver(records []Record, now time.Time, within time.Duration) Carry {
+	var c Carry
+	next := now
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		if next.Sub(r.End) > within || r.Phase == engine.PhaseLongBreak || r.Phase == engine.PhaseCooldown {
+			break
+		}
+		if c.End.IsZero() {
+			c.End = r.End
+		}
+		if r.Phase == engine.PhaseWork && !r.Extra && r.Ended != engine.EndInterrupted {
+			c.Cycles++
+			c.Work += r.Actual()
+		}
+		next = r.Start
+	}
+	return c
+}
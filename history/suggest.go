@@ -0,0 +1,137 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// Suggest only trusts history reaching back at least this far.
+const MinSuggestHistory = 14 * 24 * time.Hour
+
+// sessionGap splits history into sessions, as pomo log marks gaps.
+const sessionGap = 30 * time.Minute
+
+// minSuggestSessions is how many past sessions a suggestion rests on.
+const minSuggestSessions = 2
+
+var ErrShortHistory = errors.New("less than two weeks of history")
+
+// Suggestion is a session plan drawn from sessions that started at the same
+// part of the same weekday as now.
+type Suggestion struct {
+	Slot     Slot
+	Sessions int
+	// Cycles is the mean number of work phases completed per session.
+	Cycles int
+	// Work and Break are the most common planned lengths, zero if no phase
+	// of the kind was found.
+	Work  time.Duration
+	Break time.Duration
+}
+
+// Slot is a part of a weekday: morning, afternoon, evening, or night.
+type Slot struct {
+	Weekday time.Weekday
+	Part    string
+}
+
+func SlotOf(t time.Time) Slot {
+	part := "night"
+	switch h := t.Hour(); {
+	case h >= 5 && h < 12:
+		part = "morning"
+	case h >= 12 && h < 17:
+		part = "afternoon"
+	case h >= 17 && h < 22:
+		part = "evening"
+	}
+	return Slot{Weekday: t.Weekday(), Part: part}
+}
+
+// String reads like "Friday afternoons".
+func (s Slot) String() string {
+	return fmt.Sprintf("%s %ss", s.Weekday, s.Part)
+}
+
+// Suggest looks at the sessions in records that started in now's slot, in
+// now's time zone. Work phases planned shorter than minWork count for
+// nothing, as in Summarize.
+func Suggest(records []Record, now time.Time, minWork time.Duration) (Suggestion, error) {
+	slot := SlotOf(now)
+	s := Suggestion{Slot: slot}
+	if len(records) == 0 || now.Sub(records[0].Start) < MinSuggestHistory {
+		return s, ErrShortHistory
+	}
+
+	var cycles int
+	var work, breaks []time.Duration
+	for _, session := range sessions(records) {
+		if SlotOf(session[0].Start.In(now.Location())) != slot {
+			continue
+		}
+		counted := false
+		for _, r := range session {
+			switch {
+			case r.Extra:
+			case r.Phase == engine.PhaseWork && r.Planned() >= minWork:
+				counted = true
+				if r.Ended == engine.EndCompleted && !r.Voided {
+					cycles++
+					work = append(work, r.Planned())
+				}
+			case r.Phase == engine.PhaseShortBreak:
+				breaks = append(breaks, r.Planned())
+			}
+		}
+		if counted {
+			s.Sessions++
+		}
+	}
+	if s.Sessions < minSuggestSessions {
+		return s, fmt.Errorf("fewer than %d sessions on %s", minSuggestSessions, slot)
+	}
+
+	s.Cycles = max((cycles+s.Sessions/2)/s.Sessions, 1)
+	s.Work = mostCommon(work)
+	s.Break = mostCommon(breaks)
+	return s, nil
+}
+
+// sessions splits records, which are in order, wherever one starts more
+// than sessionGap after the previous ended.
+func sessions(records []Record) [][]Record {
+	var out [][]Record
+	start := 0
+	for i := 1; i <= len(records); i++ {
+		if i == len(records) || records[i].Start.Sub(records[i-1].End) > sessionGap {
+			out = append(out, records[start:i])
+			start = i
+		}
+	}
+	return out
+}
+
+// mostCommon picks the most frequent duration, the longer on a tie.
+func mostCommon(ds []time.Duration) time.Duration {
+	counts := make(map[time.Duration]int)
+	for _, d := range ds {
+		counts[d]++
+	}
+	keys := make([]time.Duration, 0, len(counts))
+	for d := range counts {
+		keys = append(keys, d)
+	}
+	slices.Sort(keys)
+
+	var best time.Duration
+	for _, d := range keys {
+		if counts[d] >= counts[best] {
+			best = d
+		}
+	}
+	return best
+}
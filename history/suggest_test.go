@@ -0,0 +1,103 @@
+package history
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// session is work phases of the given lengths from start, each followed
+// but the last by a break of brk, all completed.
+func session(start time.Time, brk time.Duration, work ...time.Duration) []Record {
+	var out []Record
+	add := func(phase engine.Phase, d time.Duration) {
+		out = append(out, Record{Start: start, End: start.Add(d), Phase: phase, PlannedMS: d.Milliseconds(), ActualMS: d.Milliseconds(), Ended: engine.EndCompleted})
+		start = start.Add(d)
+	}
+	for i, d := range work {
+		add(engine.PhaseWork, d)
+		if i < len(work)-1 {
+			add(engine.PhaseShortBreak, brk)
+		}
+	}
+	return out
+}
+
+func TestSuggest(t *testing.T) {
+	m := time.Minute
+	// Monday mornings, with a Tuesday morning between.
+	now := time.Date(2025, 1, 20, 10, 0, 0, 0, time.UTC)
+	first := session(time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC), 5*m, 25*m, 25*m, 25*m)
+	second := session(time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC), 5*m, 25*m, 25*m)
+	tuesday := session(time.Date(2025, 1, 14, 9, 0, 0, 0, time.UTC), 10*m, 50*m, 50*m, 50*m, 50*m)
+	join := func(sessions ...[]Record) []Record {
+		var out []Record
+		for _, s := range sessions {
+			out = append(out, s...)
+		}
+		return out
+	}
+	voided := join(first, second)
+	voided[len(voided)-1].Voided = true
+	extra := join(first, second)
+	extra = append(extra, Record{Start: extra[len(extra)-1].End, End: extra[len(extra)-1].End.Add(50 * m), Phase: engine.PhaseWork, PlannedMS: (50 * m).Milliseconds(), Ended: engine.EndCompleted, Extra: true})
+
+	tests := []struct {
+		name    string
+		records []Record
+		now     time.Time
+		minWork time.Duration
+		want    Suggestion
+		err     bool
+	}{
+		{"two sessions", join(first, second, tuesday), now, 0, Suggestion{Sessions: 2, Cycles: 3, Work: 25 * m, Break: 5 * m}, false},
+		{"other slot", join(first, second, tuesday), now.Add(4 * time.Hour), 0, Suggestion{}, true},
+		{"one session", join(second, tuesday), now.Add(7 * 24 * time.Hour), 0, Suggestion{}, true},
+		{"short history", join(second, tuesday), now, 0, Suggestion{}, true},
+		{"no history", nil, now, 0, Suggestion{}, true},
+		{"voided work", voided, now, 0, Suggestion{Sessions: 2, Cycles: 2, Work: 25 * m, Break: 5 * m}, false},
+		{"extra work", extra, now, 0, Suggestion{Sessions: 2, Cycles: 3, Work: 25 * m, Break: 5 * m}, false},
+		{"work under the minimum", join(first, second), now, 30 * m, Suggestion{}, true},
+		{"ties, the longer", join(first, session(time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC), 10*m, 50*m, 50*m, 50*m)), now, 0,
+			Suggestion{Sessions: 2, Cycles: 3, Work: 50 * m, Break: 10 * m}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Suggest(tt.records, tt.now, tt.minWork)
+			if tt.err {
+				if err == nil {
+					t.Errorf("suggested %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.want.Slot = SlotOf(tt.now)
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestShortHistory(t *testing.T) {
+	records := session(time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC), 5*time.Minute, 25*time.Minute)
+	if _, err := Suggest(records, time.Date(2025, 1, 20, 10, 0, 0, 0, time.UTC), 0); !errors.Is(err, ErrShortHistory) {
+		t.Errorf("got %v, want ErrShortHistory", err)
+	}
+}
+
+func TestSlotOf(t *testing.T) {
+	for hour, part := range map[int]string{0: "night", 4: "night", 5: "morning", 11: "morning", 12: "afternoon", 16: "afternoon", 17: "evening", 21: "evening", 22: "night"} {
+		got := SlotOf(time.Date(2025, 1, 10, hour, 30, 0, 0, time.UTC))
+		if want := (Slot{time.Friday, part}); got != want {
+			t.Errorf("%02d:30 is %v, want %v", hour, got, want)
+		}
+	}
+	if got := (Slot{time.Friday, "afternoon"}).String(); got != "Friday afternoons" {
+		t.Errorf("slot reads %q", got)
+	}
+}
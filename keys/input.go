@@ -0,0 +1,144 @@
+package keys
+
+import "time"
+
+const (
+	// RepeatWindow is how soon the same key again counts as the same press,
+	// held down or mashed.
+	RepeatWindow = 300 * time.Millisecond
+	// burstKeys is how many keys one read can bring before they are taken
+	// for a paste the terminal did not bracket. Typing, even mashing, comes
+	// a key or two per read.
+	burstKeys = 4
+)
+
+// Bracketed paste: the terminal wraps pasted text in these, once asked to
+// with enablePaste.
+const (
+	enablePaste  = "\x1b[?2004h"
+	disablePaste = "\x1b[?2004l"
+	pasteEnd     = "\x1b[201~"
+)
+
+type decodeState int
+
+const (
+	ground decodeState = iota
+	escape             // after ESC
+	csi                // after ESC [, until a final byte
+	ss3                // after ESC O, for one byte
+	paste              // between the paste brackets
+)
+
+// Decoder turns what is read from the terminal into the keys meant as
+// commands. It drops pasted text, whether bracketed or arriving in a burst,
+// escape sequences such as arrow keys, control characters other than
+// Enter, and the same key pressed again within RepeatWindow.
+type Decoder struct {
+	now func() time.Time
+
+	state decodeState
+	// params holds a CSI sequence's parameters, to spot the paste start.
+	params []byte
+	// matched is how much of pasteEnd has been seen inside a paste.
+	matched int
+	// quietUntil drops keys after a burst, for the tail of a paste that
+	// came in a read of its own.
+	quietUntil time.Time
+	last       byte
+	lastAt     time.Time
+}
+
+// NewDecoder returns a Decoder that reads the time from now, or the wall
+// clock if now is nil.
+func NewDecoder(now func() time.Time) *Decoder {
+	if now == nil {
+		now = time.Now
+	}
+	return &Decoder{now: now}
+}
+
+// Feed decodes one read's worth of input and returns the keys in it, if
+// any.
+func (d *Decoder) Feed(chunk []byte) []byte {
+	now := d.now()
+	var keys []byte
+	for _, b := range chunk {
+		if k, ok := d.step(b); ok {
+			keys = append(keys, k)
+		}
+	}
+	// A lone ESC at the end of a read is the Escape key, not the start of a
+	// sequence, which terminals send in one go.
+	if d.state == escape {
+		d.state = ground
+	}
+
+	if len(keys) > burstKeys {
+		d.quietUntil = now.Add(RepeatWindow)
+		return nil
+	}
+	if now.Before(d.quietUntil) {
+		return nil
+	}
+
+	var out []byte
+	for _, k := range keys {
+		if k == d.last && now.Sub(d.lastAt) < RepeatWindow {
+			d.lastAt = now
+			continue
+		}
+		d.last, d.lastAt = k, now
+		out = append(out, k)
+	}
+	return out
+}
+
+// step advances past b, returning it if it is a key.
+func (d *Decoder) step(b byte) (byte, bool) {
+	switch d.state {
+	case escape:
+		switch b {
+		case '[':
+			d.state, d.params = csi, d.params[:0]
+		case 'O':
+			d.state = ss3
+		default:
+			// Alt with a key.
+			d.state = ground
+		}
+	case csi:
+		if b >= 0x40 && b <= 0x7e {
+			d.state = ground
+			if b == '~' && string(d.params) == "200" {
+				d.state, d.matched = paste, 0
+			}
+		} else {
+			d.params = append(d.params, b)
+		}
+	case ss3:
+		d.state = ground
+	case paste:
+		switch {
+		case b == pasteEnd[d.matched]:
+			d.matched++
+			if d.matched == len(pasteEnd) {
+				d.state = ground
+			}
+		case b == pasteEnd[0]:
+			d.matched = 1
+		default:
+			d.matched = 0
+		}
+	default:
+		switch {
+		case b == 0x1b:
+			d.state = escape
+		case b == '\r' || b == '\n':
+			return '\n', true
+		case b >= 0x20 && b < 0x7f:
+			return b, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,74 @@
+package keys
+
+import (
+	"testing"
+	"time"
+)
+
+// read is one read from the terminal, at a time from the first.
+type read struct {
+	at    time.Duration
+	chunk string
+}
+
+func TestDecoder(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name  string
+		reads []read
+		want  string
+	}{
+		{"keys", []read{{0, "p"}, {100 * ms, "s"}, {200 * ms, "e"}}, "pse"},
+		{"enter", []read{{0, "\r"}, {time.Second, "\n"}}, "\n\n"},
+		{"control characters", []read{{0, "\x03\t\x7f\x00"}}, ""},
+
+		// Key mashing comes out as one press of each key.
+		{"same key in one read", []read{{0, "pp"}}, "p"},
+		{"same key again soon", []read{{0, "s"}, {299 * ms, "s"}}, "s"},
+		{"same key again later", []read{{0, "s"}, {300 * ms, "s"}}, "ss"},
+		{"key held down", []read{{0, "p"}, {200 * ms, "p"}, {400 * ms, "p"}, {600 * ms, "p"}, {800 * ms, "p"}}, "p"},
+		{"held, then let go", []read{{0, "p"}, {200 * ms, "p"}, {400 * ms, "p"}, {800 * ms, "p"}}, "pp"},
+		{"mashing", []read{{0, "ss"}, {50 * ms, "s"}, {90 * ms, "ss"}, {150 * ms, "s"}}, "s"},
+		{"other key between", []read{{0, "p"}, {50 * ms, "s"}, {100 * ms, "p"}}, "psp"},
+
+		// A burst too big to be typing is a paste, whatever it holds, and
+		// so is the rest of it arriving soon after.
+		{"four keys", []read{{0, "psex"}}, "psex"},
+		{"five keys", []read{{0, "psexq"}}, ""},
+		{"burst and its tail", []read{{0, "skip skip skip"}, {10 * ms, "skip"}, {299 * ms, "p"}}, ""},
+		{"after a burst", []read{{0, "skip skip skip"}, {300 * ms, "p"}}, "p"},
+		{"newlines in a burst", []read{{0, "p\ns\np\ns\n"}}, ""},
+
+		// Bracketed pastes are dropped, however they are read.
+		{"paste", []read{{0, "\x1b[200~p\x1b[201~"}}, ""},
+		{"paste, then a key", []read{{0, "\x1b[200~ssss\x1b[201~"}, {time.Second, "p"}}, "p"},
+		{"key after a paste in its read", []read{{0, "\x1b[200~sss\x1b[201~p"}}, "p"},
+		{"paste over reads", []read{{0, "\x1b[200~pause"}, {time.Second, " skip"}, {2 * time.Second, " extend\x1b[201~"}, {3 * time.Second, "s"}}, "s"},
+		{"paste end split", []read{{0, "\x1b[200~ppp\x1b[2"}, {time.Second, "01~"}, {2 * time.Second, "s"}}, "s"},
+		{"paste start split", []read{{0, "\x1b["}, {time.Second, "200~p"}, {2 * time.Second, "s\x1b[201~"}}, ""},
+		{"escapes in a paste", []read{{0, "\x1b[200~\x1b[A\x1b[20p\x1b\x1b[201s\x1b[201~e"}}, "e"},
+		{"unfinished paste", []read{{0, "\x1b[200~p"}, {time.Hour, "s"}}, ""},
+
+		// Escape sequences are not keys, nor what follows ESC.
+		{"arrows", []read{{0, "\x1b[A"}, {time.Second, "\x1b[1;5C"}}, ""},
+		{"function key", []read{{0, "\x1bOP"}}, ""},
+		{"alt", []read{{0, "\x1bp"}}, ""},
+		{"escape alone", []read{{0, "\x1b"}, {time.Second, "p"}}, "p"},
+		{"other bracket", []read{{0, "\x1b[201~p"}}, "p"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+			var now time.Time
+			d := NewDecoder(func() time.Time { return now })
+			var got []byte
+			for _, r := range tt.reads {
+				now = start.Add(r.at)
+				got = append(got, d.Feed([]byte(r.chunk))...)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Package keys reads single keypresses from the terminal while the progress
+// bars are rendering.
+package keys
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+var ErrNotTerminal = errors.New("not a terminal")
+
+// Listener switches a terminal to cbreak mode: input is delivered per key
+// without echo, while output processing and signal keys like Ctrl-C keep
+// working as usual.
+type Listener struct {
+	restore func() error
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Listen calls handle from a background goroutine for every key read from f
+// until Stop is called, as a Decoder passes them on: pastes, escape
+// sequences, and repeats never reach it. The terminal is asked to bracket
+// pastes for as long as it listens.
+func Listen(f *os.File, handle func(byte)) (*Listener, error) {
+	restore, err := makeCbreak(int(f.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	f.WriteString(enablePaste)
+
+	l := &Listener{
+		restore: func() error {
+			f.WriteString(disablePaste)
+			return restore()
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go l.read(f, handle)
+	return l, nil
+}
+
+func (l *Listener) read(f *os.File, handle func(byte)) {
+	defer close(l.done)
+
+	d := NewDecoder(nil)
+	buf := make([]byte, 64)
+	for {
+		select {
+		case <-l.stop:
+			return
+		default:
+		}
+
+		// The terminal is configured to return from read after a short
+		// timeout with no input, surfacing as io.EOF, so stop is polled.
+		n, err := f.Read(buf)
+		if n > 0 {
+			for _, k := range d.Feed(buf[:n]) {
+				handle(k)
+			}
+		}
+		if err != nil && err != io.EOF {
+			return
+		}
+	}
+}
+
+// Restore puts the terminal back in its previous mode without waiting for
+// the reader, for cleaning up on the way out of a crash. Stop is still
+// needed to end the reader.
+func (l *Listener) Restore() error {
+	return l.restore()
+}
+
+// Stop waits for the reader to exit and restores the terminal's previous
+// mode, so stdin can be read normally afterwards.
+func (l *Listener) Stop() error {
+	close(l.stop)
+	<-l.done
+	return l.restore()
+}
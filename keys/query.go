@@ -0,0 +1,48 @@
+package keys
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"time"
+)
+
+var ErrNoAnswer = errors.New("terminal did not report a background color")
+
+// QueryBackground asks the terminal for its background color with OSC 11 and
+// returns the reported color spec, e.g. "rgb:ffff/ffff/ffff". The query is
+// followed by a primary device attributes request, which every terminal
+// answers, so one that ignores OSC 11 is detected without waiting out the
+// timeout and no late reply is left in the input stream.
+func QueryBackground(in, out *os.File, timeout time.Duration) (string, error) {
+	restore, err := makeCbreak(int(in.Fd()))
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	if _, err := out.WriteString("\x1b]11;?\x07\x1b[c"); err != nil {
+		return "", err
+	}
+
+	var resp []byte
+	buf := make([]byte, 64)
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		n, _ := in.Read(buf)
+		resp = append(resp, buf[:n]...)
+		if bytes.Contains(resp, []byte("\x1b[?")) && bytes.HasSuffix(resp, []byte("c")) {
+			break
+		}
+	}
+
+	start := bytes.Index(resp, []byte("\x1b]11;"))
+	if start < 0 {
+		return "", ErrNoAnswer
+	}
+	spec := resp[start+len("\x1b]11;"):]
+	if end := bytes.IndexAny(spec, "\x07\x1b"); end >= 0 {
+		spec = spec[:end]
+	}
+	return string(spec), nil
+}
@@ -0,0 +1,7 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package keys
+
+func makeCbreak(fd int) (func() error, error) {
+	return nil, ErrNotTerminal
+}
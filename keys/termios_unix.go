@@ -0,0 +1,24 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package keys
+
+import "golang.org/x/sys/unix"
+
+func makeCbreak(fd int) (func() error, error) {
+	old, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil {
+		return nil, ErrNotTerminal
+	}
+
+	t := *old
+	t.Lflag &^= unix.ICANON | unix.ECHO
+	t.Cc[unix.VMIN] = 0
+	t.Cc[unix.VTIME] = 1
+	if err := unix.IoctlSetTermios(fd, ioctlWriteTermios, &t); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return unix.IoctlSetTermios(fd, ioctlWriteTermios, old)
+	}, nil
+}
@@ -0,0 +1,110 @@
+// Package logfile is the diagnostics log pomo start writes: a file that
+// rotates once it grows past a size, keeping a few old ones as path.1,
+// path.2, and so on, newest first.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	DefaultMaxSize = 1 << 20
+	DefaultKeep    = 3
+)
+
+// File is an io.Writer safe for concurrent use. A write that would take
+// the file past maxSize rotates it first, so a file only goes over when a
+// single write does.
+type File struct {
+	path    string
+	maxSize int64
+	keep    int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// Open appends to path, creating it and its directory if need be.
+func Open(path string, maxSize int64, keep int) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	l := &File{path: path, maxSize: maxSize, keep: keep}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *File) open() error {
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.f, l.size = f, info.Size()
+	return nil
+}
+
+func (l *File) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.f == nil {
+		return 0, os.ErrClosed
+	}
+	if l.size > 0 && l.size+int64(len(p)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := l.f.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// rotate shifts path.N to path.N+1, dropping whatever falls past keep,
+// and starts path afresh.
+func (l *File) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	l.f = nil
+
+	os.Remove(Rotated(l.path, l.keep))
+	for i := l.keep - 1; i >= 1; i-- {
+		os.Rename(Rotated(l.path, i), Rotated(l.path, i+1))
+	}
+	if l.keep > 0 {
+		if err := os.Rename(l.path, Rotated(l.path, 1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(l.path); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+// Rotated is the name of the nth newest rotated file.
+func Rotated(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+func (l *File) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
@@ -0,0 +1,163 @@
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func write(t *testing.T, l *File, s string) {
+	t.Helper()
+	if n, err := l.Write([]byte(s)); err != nil || n != len(s) {
+		t.Fatalf("write %q: %d, %v", s, n, err)
+	}
+}
+
+// contents is each of path, path.1, path.2, ... up to path.n, "-" for one
+// missing.
+func contents(t *testing.T, path string, n int) []string {
+	t.Helper()
+	var out []string
+	for i := 0; i <= n; i++ {
+		name := path
+		if i > 0 {
+			name = Rotated(path, i)
+		}
+		data, err := os.ReadFile(name)
+		switch {
+		case os.IsNotExist(err):
+			out = append(out, "-")
+		case err != nil:
+			t.Fatal(err)
+		default:
+			out = append(out, string(data))
+		}
+	}
+	return out
+}
+
+func TestRotatesAtSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log", "pomo.log")
+	l, err := Open(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	steps := []struct {
+		write string
+		want  []string
+	}{
+		{"aaaa\n", []string{"aaaa\n", "-", "-", "-"}},
+		// Exactly at the size does not rotate.
+		{"bbbb\n", []string{"aaaa\nbbbb\n", "-", "-", "-"}},
+		{"c\n", []string{"c\n", "aaaa\nbbbb\n", "-", "-"}},
+		{"dddddddd\n", []string{"dddddddd\n", "c\n", "aaaa\nbbbb\n", "-"}},
+		// Only keep old files are kept.
+		{"e\n", []string{"e\n", "dddddddd\n", "c\n", "-"}},
+		// A write bigger than the size goes in a file of its own.
+		{"ffffffffffff\n", []string{"ffffffffffff\n", "e\n", "dddddddd\n", "-"}},
+		{"g\n", []string{"g\n", "ffffffffffff\n", "e\n", "-"}},
+	}
+	for _, s := range steps {
+		write(t, l, s.write)
+		if got := contents(t, path, 3); strings.Join(got, "|") != strings.Join(s.want, "|") {
+			t.Fatalf("after %q: files %q, want %q", s.write, got, s.want)
+		}
+	}
+}
+
+func TestKeepNone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pomo.log")
+	l, err := Open(path, 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	write(t, l, "abc\n")
+	write(t, l, "de\n")
+	if got := contents(t, path, 1); strings.Join(got, "|") != "de\n|-" {
+		t.Errorf("files %q, want only the new one", got)
+	}
+}
+
+func TestReopenCountsExistingSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pomo.log")
+	l, err := Open(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, l, "aaaaaaa\n")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = Open(path, 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	write(t, l, "bbb\n")
+	if got := contents(t, path, 1); strings.Join(got, "|") != "bbb\n|aaaaaaa\n" {
+		t.Errorf("files %q, want the earlier run's rotated", got)
+	}
+}
+
+func TestWritesAfterRotationLand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pomo.log")
+	l, err := Open(path, 64, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			write(t, l, fmt.Sprintf("line %02d\n", i))
+		}()
+	}
+	wg.Wait()
+
+	// Eight lines fill a file, so the twenty are in files of 4, 8, and 8
+	// lines, the newest in the file reopened by the last rotation.
+	var lines []int
+	for i, data := range contents(t, path, 2) {
+		if !strings.HasSuffix(data, "\n") {
+			t.Fatalf("file %d is %q", i, data)
+		}
+		lines = append(lines, strings.Count(data, "\n"))
+	}
+	if fmt.Sprint(lines) != "[4 8 8]" {
+		t.Errorf("files of %v lines, want [4 8 8]", lines)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != l.size {
+		t.Errorf("file is %d bytes, but counted as %d", info.Size(), l.size)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "pomo.log"), 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	if _, err := l.Write([]byte("x\n")); err != os.ErrClosed {
+		t.Errorf("write after close: %v, want os.ErrClosed", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+}
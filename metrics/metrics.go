@@ -0,0 +1,161 @@
+// Package metrics publishes the session as Prometheus metrics in the text
+// exposition format, written to a file for node_exporter's textfile
+// collector rather than served, so nothing listens on a port.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/fsutil"
+	"github.com/steenfuentes/pomo/notify"
+)
+
+// WriteEvery is the longest a TextfileWriter leaves the file alone while a
+// phase runs. Phase boundaries are written as they happen.
+const WriteEvery = 15 * time.Second
+
+// phases are those the phase gauge has a series for, every one of them in
+// every snapshot, so a query never sees a series come and go.
+var phases = []engine.Phase{
+	engine.PhaseWork,
+	engine.PhaseShortBreak,
+	engine.PhaseLongBreak,
+	engine.PhaseCooldown,
+	engine.PhaseWarmup,
+}
+
+// Snapshot is the metric set as of one event.
+type Snapshot struct {
+	Phase     engine.Phase
+	Remaining time.Duration
+	Paused    bool
+	// Completed counts the phases of each kind completed in the session,
+	// extras included, voided work not.
+	Completed map[engine.Phase]int
+	// Work is the work done in the session so far.
+	Work time.Duration
+	// Notifications are each notification channel's counts, if any.
+	Notifications []notify.Stats
+}
+
+// WriteTo writes s in the text exposition format.
+func (s Snapshot) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("pomo_session_running", "gauge", "Whether a pomo session is running.")
+	b.WriteString("pomo_session_running 1\n")
+	metric("pomo_phase", "gauge", "The phase the session is in, 1 for it and 0 for the rest.")
+	for _, p := range phases {
+		fmt.Fprintf(&b, "pomo_phase{phase=%s} %d\n", quote(label(p)), bit(p == s.Phase))
+	}
+	metric("pomo_phase_remaining_seconds", "gauge", "Time left in the current phase.")
+	fmt.Fprintf(&b, "pomo_phase_remaining_seconds %g\n", s.Remaining.Round(time.Millisecond).Seconds())
+	metric("pomo_paused", "gauge", "Whether the current phase is paused.")
+	fmt.Fprintf(&b, "pomo_paused %d\n", bit(s.Paused))
+	metric("pomo_phases_completed_total", "counter", "Phases completed in the session, by kind.")
+	for _, p := range phases {
+		fmt.Fprintf(&b, "pomo_phases_completed_total{phase=%s} %d\n", quote(label(p)), s.Completed[p])
+	}
+	metric("pomo_work_seconds_total", "counter", "Work done in the session.")
+	fmt.Fprintf(&b, "pomo_work_seconds_total %g\n", s.Work.Round(time.Millisecond).Seconds())
+	if len(s.Notifications) > 0 {
+		metric("pomo_notifications_total", "counter", "Notifications by channel and what became of them.")
+		for _, n := range s.Notifications {
+			for _, c := range []struct {
+				result string
+				n      int64
+			}{{"sent", n.Sent}, {"retried", n.Retried}, {"dropped", n.Dropped}, {"failed", n.Failed}} {
+				fmt.Fprintf(&b, "pomo_notifications_total{channel=%s,result=%s} %d\n", quote(n.Channel), quote(c.result), c.n)
+			}
+		}
+	}
+	return b.WriteTo(w)
+}
+
+// label is p as a label value, e.g. "short_break".
+func label(p engine.Phase) string {
+	return strings.ReplaceAll(strings.ToLower(p.String()), " ", "_")
+}
+
+// quote is v as a quoted label value, escaped as the format has it, which
+// is not as Go quotes strings.
+func quote(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func bit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// TextfileWriter keeps a .prom file for the textfile collector in step
+// with the session: on every phase's start and end, and every WriteEvery
+// in between. Each write is atomic, as the collector requires, and the
+// file is removed once the session is over.
+type TextfileWriter struct {
+	path          string
+	notifications func() []notify.Stats
+	current       Snapshot
+	written       time.Time
+	// started marks a phase under way, whose first tick has been written.
+	started bool
+	err     error
+}
+
+// NewTextfileWriter takes each write's notification counts from
+// notifications, if not nil.
+func NewTextfileWriter(path string, notifications func() []notify.Stats) *TextfileWriter {
+	return &TextfileWriter{path: path, notifications: notifications, current: Snapshot{Completed: map[engine.Phase]int{}}}
+}
+
+func (w *TextfileWriter) Handle(e engine.TimerEvent) {
+	if w.err != nil || e.Type != engine.EventTick {
+		return
+	}
+
+	s := &w.current
+	s.Phase, s.Remaining, s.Paused = e.Phase, e.Remaining, e.Paused
+	s.Work = e.SessionWork
+	boundary := !w.started || e.Ended != ""
+	w.started = e.Ended == ""
+	if e.Ended == engine.EndCompleted && !e.Voided {
+		s.Completed[e.Phase]++
+	}
+
+	now := time.Now()
+	if !boundary && now.Sub(w.written) < WriteEvery {
+		return
+	}
+	if w.notifications != nil {
+		s.Notifications = w.notifications()
+	}
+	var b bytes.Buffer
+	s.WriteTo(&b)
+	if err := fsutil.WriteFileAtomic(w.path, b.Bytes(), 0o644); err != nil {
+		w.err = fmt.Errorf("writing %s: %w", w.path, err)
+		return
+	}
+	w.written = now
+}
+
+// Close removes the file, so the collector stops reporting the session,
+// and reports the first write error, if any.
+func (w *TextfileWriter) Close() error {
+	if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) && w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
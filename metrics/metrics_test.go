@@ -0,0 +1,211 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/notify"
+)
+
+var (
+	nameRE   = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelRE  = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*)="((?:[^"\\\n]|\\[\\"n])*)"`)
+	sampleRE = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{.*\})? (\S+)$`)
+	helpRE   = regexp.MustCompile(`^# HELP (\S+) ((?:[^\\\n]|\\[\\n])*)$`)
+	typeRE   = regexp.MustCompile(`^# TYPE (\S+) (counter|gauge|histogram|summary|untyped)$`)
+)
+
+// parseExposition checks text against the text exposition format as
+// strictly as Prometheus reads it, returning each series' value by its
+// name and labels, the label values unescaped, as in
+// `pomo_phase{phase="work"}`.
+func parseExposition(text string) (map[string]float64, error) {
+	if !strings.HasSuffix(text, "\n") {
+		return nil, fmt.Errorf("no newline at the end")
+	}
+	samples := map[string]float64{}
+	typed := map[string]bool{}
+	helped := map[string]bool{}
+	done := map[string]bool{}
+	family := ""
+	enter := func(name string) error {
+		if name == family {
+			return nil
+		}
+		if done[name] {
+			return fmt.Errorf("%s split into two groups", name)
+		}
+		done[family] = true
+		family = name
+		return nil
+	}
+
+	lines := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; lines.Scan(); n++ {
+		line := lines.Text()
+		wrap := func(err error) error { return fmt.Errorf("line %d %q: %w", n, line, err) }
+		switch {
+		case strings.HasPrefix(line, "# HELP "):
+			m := helpRE.FindStringSubmatch(line)
+			if m == nil || !nameRE.MatchString(m[1]) {
+				return nil, wrap(fmt.Errorf("bad HELP"))
+			}
+			if helped[m[1]] {
+				return nil, wrap(fmt.Errorf("second HELP"))
+			}
+			helped[m[1]] = true
+			if err := enter(m[1]); err != nil {
+				return nil, wrap(err)
+			}
+		case strings.HasPrefix(line, "# TYPE "):
+			m := typeRE.FindStringSubmatch(line)
+			if m == nil || !nameRE.MatchString(m[1]) {
+				return nil, wrap(fmt.Errorf("bad TYPE"))
+			}
+			if typed[m[1]] || hasSeries(samples, m[1]) {
+				return nil, wrap(fmt.Errorf("TYPE after its samples, or twice"))
+			}
+			typed[m[1]] = true
+			if err := enter(m[1]); err != nil {
+				return nil, wrap(err)
+			}
+		case strings.HasPrefix(line, "#"), line == "":
+			return nil, wrap(fmt.Errorf("stray comment or blank line"))
+		default:
+			m := sampleRE.FindStringSubmatch(line)
+			if m == nil {
+				return nil, wrap(fmt.Errorf("bad sample"))
+			}
+			if err := enter(m[1]); err != nil {
+				return nil, wrap(err)
+			}
+			if !typed[m[1]] {
+				return nil, wrap(fmt.Errorf("sample without a TYPE"))
+			}
+			labels, err := parseLabels(m[2])
+			if err != nil {
+				return nil, wrap(err)
+			}
+			value, err := strconv.ParseFloat(m[3], 64)
+			if err != nil {
+				return nil, wrap(err)
+			}
+			series := m[1] + labels
+			if _, ok := samples[series]; ok {
+				return nil, wrap(fmt.Errorf("duplicate series"))
+			}
+			samples[series] = value
+		}
+	}
+	return samples, lines.Err()
+}
+
+// parseLabels checks a sample's {...}, returning it with its values
+// unescaped.
+func parseLabels(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	rest := strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}")
+	seen := map[string]bool{}
+	var out []string
+	for rest != "" {
+		m := labelRE.FindStringSubmatch(rest)
+		if m == nil {
+			return "", fmt.Errorf("bad labels at %q", rest)
+		}
+		if seen[m[1]] {
+			return "", fmt.Errorf("label %s twice", m[1])
+		}
+		seen[m[1]] = true
+		value := strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n").Replace(m[2])
+		out = append(out, m[1]+"="+strconv.Quote(value))
+		rest = rest[len(m[0]):]
+		if rest != "" {
+			if rest[0] != ',' || len(rest) == 1 {
+				return "", fmt.Errorf("bad labels at %q", rest)
+			}
+			rest = rest[1:]
+		}
+	}
+	return "{" + strings.Join(out, ",") + "}", nil
+}
+
+func hasSeries(samples map[string]float64, name string) bool {
+	for series := range samples {
+		if series == name || strings.HasPrefix(series, name+"{") {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSnapshotExposition(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Snapshot
+		want map[string]float64
+	}{
+		{
+			name: "starting",
+			s:    Snapshot{Phase: engine.PhaseWork, Remaining: 25 * time.Minute},
+			want: map[string]float64{
+				`pomo_session_running`:                            1,
+				`pomo_phase{phase="work"}`:                        1,
+				`pomo_phase{phase="short_break"}`:                 0,
+				`pomo_phase_remaining_seconds`:                    1500,
+				`pomo_phases_completed_total{phase="long_break"}`: 0,
+				`pomo_work_seconds_total`:                         0,
+			},
+		},
+		{
+			name: "under way",
+			s: Snapshot{
+				Phase:     engine.PhaseShortBreak,
+				Remaining: 4*time.Minute + 30500*time.Millisecond,
+				Paused:    true,
+				Completed: map[engine.Phase]int{engine.PhaseWork: 2, engine.PhaseShortBreak: 1},
+				Work:      50*time.Minute + 250*time.Millisecond,
+				Notifications: []notify.Stats{
+					{Channel: "desktop", Sent: 3},
+					// Names from the config can hold anything.
+					{Channel: "hook \"ci\"\\nightly\n\tbüro", Sent: 2, Retried: 1, Dropped: 4, Failed: 1},
+				},
+			},
+			want: map[string]float64{
+				`pomo_phase{phase="short_break"}`:                             1,
+				`pomo_phase{phase="work"}`:                                    0,
+				`pomo_phase_remaining_seconds`:                                270.5,
+				`pomo_paused`:                                                 1,
+				`pomo_phases_completed_total{phase="work"}`:                   2,
+				`pomo_work_seconds_total`:                                     3000.25,
+				`pomo_notifications_total{channel="desktop",result="sent"}`:   3,
+				`pomo_notifications_total{channel="desktop",result="failed"}`: 0,
+				`pomo_notifications_total{channel="hook \"ci\"\\nightly\n\tbüro",result="dropped"}`: 4,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if _, err := tt.s.WriteTo(&b); err != nil {
+				t.Fatal(err)
+			}
+			samples, err := parseExposition(b.String())
+			if err != nil {
+				t.Fatalf("%v\n%s", err, b.String())
+			}
+			for series, want := range tt.want {
+				if got, ok := samples[series]; !ok || got != want {
+					t.Errorf("%s = %g (present %t), want %g\n%s", series, got, ok, want, b.String())
+				}
+			}
+		})
+	}
+}
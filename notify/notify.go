@@ -0,0 +1,311 @@
+// Package notify delivers notifications off the timer's goroutine. Each
+// channel gets its own queue and worker, is rate limited, drops quick repeats,
+// and retries failed deliveries with backoff.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+type Kind string
+
+const (
+	// KindWorkDone follows each scheduled work phase that runs its
+	// course, and none that was skipped, cut short, or voided.
+	KindWorkDone Kind = "work-done"
+	// KindInterrupted follows a session that was interrupted.
+	KindInterrupted Kind = "interrupted"
+	// KindCapped follows a session stopped by its hard cap.
+	KindCapped Kind = "capped"
+	// KindMilestone follows every so many pomodoros in a day, as [rewards]
+	// in the config file sets.
+	KindMilestone Kind = "milestone"
+	// KindAwayPaused and KindAwayStopped follow a phase paused, then the
+	// session stopped, because no one was there, as --idle-pause and
+	// --idle-stop set.
+	KindAwayPaused  Kind = "away-paused"
+	KindAwayStopped Kind = "away-stopped"
+	// KindWarmupDone follows the warmup, as work begins.
+	KindWarmupDone Kind = "warmup-done"
+	// KindCheckIn follows a break with --checkin, as the work after it
+	// waits for someone to check in, and repeats while it waits.
+	KindCheckIn Kind = "check-in"
+)
+
+// Message is one notification. Repeats are told apart by the whole
+// message, so Text should not carry what changes every time.
+type Message struct {
+	Kind Kind
+	Text string
+}
+
+// Notifier is a channel notifications go out on, e.g. heartbeat pings.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, m Message) error
+}
+
+const (
+	DefaultEvery   = 2 * time.Second
+	DefaultDedupe  = time.Minute
+	DefaultBackoff = time.Second
+	DefaultQueue   = 32
+)
+
+// Limits shape a channel's deliveries. Zero values of Every, Dedupe, and
+// Retries turn off rate limiting, deduplication, and retries.
+type Limits struct {
+	// Every is the least time between deliveries; later messages wait.
+	Every time.Duration
+	// Dedupe drops a message identical to the last one queued this recently.
+	Dedupe time.Duration
+	// Retries are attempts after the first, Backoff apart at first and
+	// doubling each time.
+	Retries int
+	Backoff time.Duration
+	// Timeout bounds each attempt; zero leaves it to the notifier.
+	Timeout time.Duration
+	// Queue is how many messages can wait before new ones are dropped.
+	Queue int
+}
+
+// Stats counts one channel's messages. Dropped covers repeats, messages
+// that found the queue full, and those still waiting at shutdown; Failed
+// those that ran out of retries.
+type Stats struct {
+	Channel string
+	Sent    int64
+	Retried int64
+	Dropped int64
+	Failed  int64
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%s %d sent, %d retried, %d dropped, %d failed", s.Channel, s.Sent, s.Retried, s.Dropped, s.Failed)
+}
+
+// Dispatcher is a subscriber that turns timer events into messages for
+// every registered channel. It outlives each session's subscribers, so it
+// is shut down with Shutdown rather than Close.
+type Dispatcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	closed   bool
+	channels []*channel
+}
+
+func NewDispatcher() *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{ctx: ctx, cancel: cancel}
+}
+
+// Register starts a worker for n. Channels registered after Shutdown are
+// ignored.
+func (d *Dispatcher) Register(n Notifier, limits Limits) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	c := &channel{
+		notifier: n,
+		limits:   limits,
+		queue:    make(chan Message, max(limits.Queue, 1)),
+		done:     make(chan struct{}),
+	}
+	d.channels = append(d.channels, c)
+	go c.work(d.ctx)
+}
+
+func (d *Dispatcher) Handle(e engine.TimerEvent) {
+	switch {
+	case e.Type == engine.EventTick && e.Phase == engine.PhaseWork && e.Ended == engine.EndCompleted && !e.Voided && !e.Extra:
+		d.Send(Message{Kind: KindWorkDone, Text: "Work phase over"})
+	case e.Type == engine.EventTick && e.Ended == engine.EndCompleted && e.Phase == engine.PhaseWarmup:
+		d.Send(Message{Kind: KindWarmupDone, Text: "Warmup over — work begins"})
+	case e.Type == engine.EventSessionEnded && e.Summary.Ended == engine.EndInterrupted:
+		d.Send(Message{Kind: KindInterrupted, Text: "Session interrupted"})
+	case e.Type == engine.EventSessionEnded && e.Summary.Capped:
+		d.Send(Message{Kind: KindCapped, Text: "Session stopped at its hard cap"})
+	}
+}
+
+// Send queues m on every channel without blocking.
+func (d *Dispatcher) Send(m Message) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	now := time.Now()
+	for _, c := range d.channels {
+		c.enqueue(m, now)
+	}
+}
+
+// Stats reports every channel's counts so far, in registration order.
+func (d *Dispatcher) Stats() []Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := make([]Stats, len(d.channels))
+	for i, c := range d.channels {
+		stats[i] = c.stats()
+	}
+	return stats
+}
+
+// Shutdown waits up to timeout for queued and in-flight deliveries, then
+// gives up on the rest. It returns every delivery error seen.
+func (d *Dispatcher) Shutdown(timeout time.Duration) error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	channels := d.channels
+	for _, c := range channels {
+		close(c.queue)
+	}
+	d.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for _, c := range channels {
+		select {
+		case <-c.done:
+		case <-timer.C:
+			d.cancel()
+			<-c.done
+		}
+	}
+	d.cancel()
+
+	var errs []error
+	for _, c := range channels {
+		errs = append(errs, c.errs...)
+		if n := c.abandoned.Load(); n > 0 {
+			errs = append(errs, fmt.Errorf("%s: gave up on %d pending after %s", c.notifier.Name(), n, timeout))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type channel struct {
+	notifier Notifier
+	limits   Limits
+	queue    chan Message
+	done     chan struct{}
+
+	// Guarded by the dispatcher's mu.
+	lastQueued   Message
+	lastQueuedAt time.Time
+
+	sent, retried, dropped, failed atomic.Int64
+	// abandoned counts the dropped messages Shutdown gave up on.
+	abandoned atomic.Int64
+	// Written by the worker, read once it is done.
+	errs []error
+}
+
+func (c *channel) enqueue(m Message, now time.Time) {
+	name := c.notifier.Name()
+	if c.limits.Dedupe > 0 && m == c.lastQueued && now.Sub(c.lastQueuedAt) < c.limits.Dedupe {
+		c.dropped.Add(1)
+		slog.Debug("notification dropped as a repeat", "channel", name, "kind", m.Kind)
+		return
+	}
+	select {
+	case c.queue <- m:
+		c.lastQueued, c.lastQueuedAt = m, now
+	default:
+		c.dropped.Add(1)
+		slog.Warn("notification dropped, queue full", "channel", name, "kind", m.Kind)
+	}
+}
+
+func (c *channel) work(ctx context.Context) {
+	defer close(c.done)
+
+	var lastSent time.Time
+	for m := range c.queue {
+		if ctx.Err() != nil || (!lastSent.IsZero() && !sleep(ctx, c.limits.Every-time.Since(lastSent))) {
+			c.dropped.Add(1)
+			c.abandoned.Add(1)
+			slog.Warn("notification abandoned at shutdown", "channel", c.notifier.Name(), "kind", m.Kind)
+			continue
+		}
+
+		err := c.deliver(ctx, m)
+		lastSent = time.Now()
+		if err != nil {
+			c.failed.Add(1)
+			c.errs = append(c.errs, fmt.Errorf("%s: %w", c.notifier.Name(), err))
+			slog.Warn("notification failed", "channel", c.notifier.Name(), "kind", m.Kind, "err", err)
+			continue
+		}
+		c.sent.Add(1)
+		slog.Debug("notification sent", "channel", c.notifier.Name(), "kind", m.Kind)
+	}
+}
+
+func (c *channel) deliver(ctx context.Context, m Message) error {
+	var err error
+	for attempt := 0; attempt <= c.limits.Retries; attempt++ {
+		if attempt > 0 {
+			if !sleep(ctx, c.limits.Backoff<<(attempt-1)) {
+				return err
+			}
+			c.retried.Add(1)
+			slog.Debug("notification retried", "channel", c.notifier.Name(), "kind", m.Kind, "attempt", attempt, "err", err)
+		}
+		if err = c.attempt(ctx, m); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (c *channel) attempt(ctx context.Context, m Message) error {
+	if c.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.limits.Timeout)
+		defer cancel()
+	}
+	return c.notifier.Notify(ctx, m)
+}
+
+func (c *channel) stats() Stats {
+	return Stats{
+		Channel: c.notifier.Name(),
+		Sent:    c.sent.Load(),
+		Retried: c.retried.Load(),
+		Dropped: c.dropped.Load(),
+		Failed:  c.failed.Load(),
+	}
+}
+
+// sleep waits d unless ctx is done first, reporting whether it waited.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
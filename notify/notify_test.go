@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+type recorder struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (r *recorder) Name() string { return "recorder" }
+
+func (r *recorder) Notify(ctx context.Context, m Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, m)
+	return nil
+}
+
+func TestWorkDoneOnlyForCompletedWork(t *testing.T) {
+	ended := func(reason engine.EndReason) engine.TimerEvent {
+		return engine.TimerEvent{Type: engine.EventTick, Phase: engine.PhaseWork, PhaseComplete: true, Ended: reason, Counted: true}
+	}
+	voided := ended(engine.EndSkipped)
+	voided.Voided, voided.Counted = true, false
+	extra := ended(engine.EndCompleted)
+	extra.Extra, extra.Counted = true, false
+	strictCompleted := ended(engine.EndCompleted)
+	strictCompleted.Voided = true
+
+	for _, tc := range []struct {
+		name  string
+		event engine.TimerEvent
+		want  int
+	}{
+		{"completed", ended(engine.EndCompleted), 1},
+		{"skipped", ended(engine.EndSkipped), 0},
+		{"interrupted", ended(engine.EndInterrupted), 0},
+		{"voided skip", voided, 0},
+		{"voided pauses", strictCompleted, 0},
+		{"extra", extra, 0},
+		{"running", engine.TimerEvent{Type: engine.EventTick, Phase: engine.PhaseWork}, 0},
+		{"break", engine.TimerEvent{Type: engine.EventTick, Phase: engine.PhaseShortBreak, PhaseComplete: true, Ended: engine.EndCompleted}, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &recorder{}
+			d := NewDispatcher()
+			d.Register(r, Limits{Queue: DefaultQueue})
+			d.Handle(tc.event)
+			if err := d.Shutdown(time.Second); err != nil {
+				t.Fatal(err)
+			}
+			n := 0
+			for _, m := range r.sent {
+				if m.Kind == KindWorkDone {
+					n++
+				}
+			}
+			if n != tc.want {
+				t.Errorf("%d work-done messages, want %d", n, tc.want)
+			}
+		})
+	}
+}
+
+// TestWarmupDoneOnce runs whole sessions through a dispatcher and checks
+// the warmup's end is announced once, ahead of the first work phase's, and
+// not at all when skipped or not asked for.
+func TestWarmupDoneOnce(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		warmup time.Duration
+		skip   bool
+		want   int
+	}{
+		{"warmup", 3 * time.Minute, false, 1},
+		{"skipped", 3 * time.Minute, true, 0},
+		{"none", 0, false, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := engine.NewMockClock(time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC))
+			timer := engine.NewTimerWithClock(engine.Config{
+				WorkDuration:       25 * time.Minute,
+				ShortBreakDuration: 5 * time.Minute,
+				TotalCycles:        3,
+				WarmupDuration:     tc.warmup,
+			}, clock, time.Second)
+			r := &recorder{}
+			d := NewDispatcher()
+			d.Register(r, Limits{Queue: DefaultQueue})
+
+			events := make(chan engine.TimerEvent)
+			done := make(chan error, 1)
+			go func() { done <- timer.Run(context.Background(), events) }()
+			skipped := false
+			for e := range events {
+				d.Handle(e)
+				if e.Type != engine.EventTick || e.Ended != "" {
+					continue
+				}
+				if tc.skip && !skipped && e.Phase == engine.PhaseWarmup {
+					skipped = true
+					timer.Skip()
+					continue
+				}
+				if next, ok := clock.UntilNext(); ok {
+					clock.Advance(next)
+				}
+			}
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Shutdown(time.Second); err != nil {
+				t.Fatal(err)
+			}
+
+			n, first := 0, -1
+			for i, m := range r.sent {
+				switch m.Kind {
+				case KindWarmupDone:
+					n++
+					if m.Text != "Warmup over — work begins" {
+						t.Errorf("warmup over as %q", m.Text)
+					}
+					if first >= 0 {
+						t.Errorf("warmup over announced after work, at %d", i)
+					}
+				case KindWorkDone:
+					if first < 0 {
+						first = i
+					}
+				}
+			}
+			if n != tc.want {
+				t.Errorf("%d warmup-done messages, want %d", n, tc.want)
+			}
+			if first < 0 {
+				t.Error("no work phase announced")
+			}
+		})
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	for _, tc := range []struct {
+		dedupe        time.Duration
+		sent, dropped int64
+	}{
+		{0, 2, 0},
+		{DefaultDedupe, 1, 1},
+	} {
+		r := &recorder{}
+		d := NewDispatcher()
+		d.Register(r, Limits{Dedupe: tc.dedupe, Queue: DefaultQueue})
+		done := engine.TimerEvent{Type: engine.EventTick, Phase: engine.PhaseWork, PhaseComplete: true, Ended: engine.EndCompleted, Counted: true}
+		d.Handle(done)
+		d.Handle(done)
+		if err := d.Shutdown(time.Second); err != nil {
+			t.Fatal(err)
+		}
+		if s := d.Stats()[0]; s.Sent != tc.sent || s.Dropped != tc.dropped {
+			t.Errorf("dedupe %s: %s, want %d sent, %d dropped", tc.dedupe, s, tc.sent, tc.dropped)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package overlay writes the live timer to text files for streaming
+// software such as OBS to display.
+package overlay
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/fsutil"
+)
+
+const DefaultFormat = "{phase} {remaining}"
+
+// FileWriter keeps a file's contents in sync with the session. Each update
+// is written to a temp file and renamed over the target, so readers polling
+// the file never see a partial write.
+type FileWriter struct {
+	path   string
+	format *Format
+	label  string
+	last   string
+	err    error
+}
+
+func NewFileWriter(path string, format *Format, label string) *FileWriter {
+	return &FileWriter{path: path, format: format, label: label}
+}
+
+func (w *FileWriter) Handle(e engine.TimerEvent) {
+	if w.err != nil || e.Type != engine.EventTick {
+		return
+	}
+
+	text, err := w.format.Execute(FieldsOf(e, w.label, time.Now()))
+	if err != nil {
+		w.err = fmt.Errorf("writing %s: %w", w.path, err)
+		return
+	}
+	if text == w.last {
+		return
+	}
+
+	if err := fsutil.WriteFileAtomic(w.path, []byte(text), 0o644); err != nil {
+		w.err = fmt.Errorf("writing %s: %w", w.path, err)
+		return
+	}
+	w.last = text
+}
+
+// Close removes the file and reports the first write error, if any.
+func (w *FileWriter) Close() error {
+	if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) && w.err == nil {
+		w.err = err
+	}
+	return w.err
+}
+
+// untilLong is the number of work phases left before the next long break,
+// or the work time left as e.g. "35m" when long breaks follow accumulated
+// work, and empty without long breaks.
+func untilLong(e engine.TimerEvent) string {
+	switch {
+	case e.UntilLongBreak >= 0:
+		return fmt.Sprint(e.UntilLongBreak)
+	case e.WorkUntilLongBreak >= 0:
+		return fmt.Sprintf("%dm", int64((e.WorkUntilLongBreak+time.Minute-1)/time.Minute))
+	default:
+		return ""
+	}
+}
+
+func Icon(phase engine.Phase) string {
+	switch phase {
+	case engine.PhaseWork:
+		return "🍅"
+	case engine.PhaseShortBreak:
+		return "☕"
+	case engine.PhaseLongBreak:
+		return "🌴"
+	case engine.PhaseCooldown:
+		return "🧊"
+	case engine.PhaseWarmup:
+		return "📝"
+	default:
+		return "⏱"
+	}
+}
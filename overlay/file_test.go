@@ -0,0 +1,22 @@
+package overlay
+
+import (
+	"testing"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+func TestEveryPhaseHasAnIcon(t *testing.T) {
+	unknown := Icon(engine.PhaseDone + 1)
+	seen := map[string]engine.Phase{}
+	for phase := engine.PhaseWork; phase < engine.PhaseDone; phase++ {
+		icon := Icon(phase)
+		if icon == unknown {
+			t.Errorf("%s has no icon of its own", phase)
+		}
+		if other, ok := seen[icon]; ok {
+			t.Errorf("%s has %s's icon", phase, other)
+		}
+		seen[icon] = phase
+	}
+}
@@ -0,0 +1,126 @@
+package overlay
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+// Fields are what a format can show of the session.
+type Fields struct {
+	Phase     string
+	PhaseIcon string
+	// Elapsed, Remaining, and Total read like "12:34", or "1:02:34" from an
+	// hour.
+	Elapsed          string
+	Remaining        string
+	Total            string
+	ElapsedSeconds   int64
+	RemainingSeconds int64
+	TotalSeconds     int64
+	// Minutes is the time remaining, rounded up to whole minutes.
+	Minutes     int64
+	Percent     float64
+	Cycle       int
+	TotalCycles int
+	// UntilLong is the work phases, or work time, left before the next long
+	// break, as in {until_long}.
+	UntilLong string
+	Label     string
+	// StartedAt follows TimerEvent's PhaseStartedAt.
+	StartedAt time.Time
+	EndsAt    time.Time
+	// State is "running" or "paused".
+	State string
+}
+
+func FieldsOf(e engine.TimerEvent, label string, now time.Time) Fields {
+	cycle := e.CycleNum
+	if e.Phase != engine.PhaseWork {
+		cycle--
+	}
+	state := "running"
+	if e.Paused {
+		state = "paused"
+	}
+
+	return Fields{
+		Phase:            e.Phase.String(),
+		PhaseIcon:        Icon(e.Phase),
+		Elapsed:          format.DurationClock(e.Elapsed),
+		Remaining:        format.DurationClock(e.Remaining),
+		Total:            format.DurationClock(e.Total),
+		ElapsedSeconds:   int64(e.Elapsed / time.Second),
+		RemainingSeconds: int64(e.Remaining / time.Second),
+		TotalSeconds:     int64(e.Total / time.Second),
+		Minutes:          int64((e.Remaining + time.Minute - 1) / time.Minute),
+		Percent:          e.Fraction * 100,
+		Cycle:            cycle,
+		TotalCycles:      e.TotalCycles,
+		UntilLong:        untilLong(e),
+		Label:            label,
+		StartedAt:        e.PhaseStartedAt,
+		EndsAt:           now.Add(e.Remaining),
+		State:            state,
+	}
+}
+
+// Format is a parsed format: a Go template over Fields when it contains
+// "{{", e.g. `{{.Phase}} {{printf "%.0f" .Percent}}%`, and otherwise text
+// with the shorthands {phase}, {icon}, {remaining}, {minutes}, {elapsed},
+// {total}, {percent}, {cycle}, {cycles}, {until_long}, and {label}.
+type Format struct {
+	text string
+	tmpl *template.Template
+}
+
+// Parse reports a template's mistakes, including unknown fields, with
+// where in format they are.
+func Parse(format string) (*Format, error) {
+	f := &Format{text: format}
+	if !strings.Contains(format, "{{") {
+		return f, nil
+	}
+
+	tmpl, err := template.New("format").Option("missingkey=error").Parse(format)
+	if err != nil {
+		return nil, err
+	}
+	f.tmpl = tmpl
+	if _, err := f.Execute(Fields{}); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *Format) Execute(fields Fields) (string, error) {
+	if f.tmpl == nil {
+		return expand(f.text, fields), nil
+	}
+	var b strings.Builder
+	if err := f.tmpl.Execute(&b, fields); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func expand(format string, f Fields) string {
+	r := strings.NewReplacer(
+		"{phase}", f.Phase,
+		"{icon}", f.PhaseIcon,
+		"{remaining}", f.Remaining,
+		"{minutes}", fmt.Sprint(f.Minutes),
+		"{elapsed}", f.Elapsed,
+		"{total}", f.Total,
+		"{percent}", fmt.Sprintf("%.0f", f.Percent),
+		"{cycle}", fmt.Sprint(f.Cycle),
+		"{cycles}", fmt.Sprint(f.TotalCycles),
+		"{until_long}", f.UntilLong,
+		"{label}", f.Label,
+	)
+	return r.Replace(format)
+}
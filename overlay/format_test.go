@@ -0,0 +1,196 @@
+package overlay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+var testNow = time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+// workEvent is 10m30s into the second of four 25m work phases, with two
+// more before the long break.
+func workEvent() engine.TimerEvent {
+	return engine.TimerEvent{
+		Type:               engine.EventTick,
+		Phase:              engine.PhaseWork,
+		Elapsed:            10*time.Minute + 30*time.Second,
+		Remaining:          14*time.Minute + 30*time.Second,
+		Total:              25 * time.Minute,
+		Fraction:           0.42,
+		CycleNum:           2,
+		TotalCycles:        4,
+		UntilLongBreak:     2,
+		WorkUntilLongBreak: -1,
+		PhaseStartedAt:     testNow.Add(-10*time.Minute - 30*time.Second),
+	}
+}
+
+func TestShorthands(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"{phase}", "Work"},
+		{"{icon}", "🍅"},
+		{"{remaining}", "14:30"},
+		{"{minutes}", "15"},
+		{"{elapsed}", "10:30"},
+		{"{total}", "25:00"},
+		{"{percent}", "42"},
+		{"{cycle}", "2"},
+		{"{cycles}", "4"},
+		{"{until_long}", "2"},
+		{"{label}", "essay"},
+		{"{phase} {remaining} ({cycle}/{cycles})", "Work 14:30 (2/4)"},
+		// Anything else is left as it is.
+		{"{nope} {Phase} {phase", "{nope} {Phase} {phase"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		f, err := Parse(tt.format)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.format, err)
+			continue
+		}
+		got, err := f.Execute(FieldsOf(workEvent(), "essay", testNow))
+		if err != nil {
+			t.Errorf("%q: %v", tt.format, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"{{.Phase}} {{.PhaseIcon}} {{.State}}", "Work 🍅 running"},
+		{"{{.ElapsedSeconds}}/{{.TotalSeconds}} {{.RemainingSeconds}}", "630/1500 870"},
+		{`{{printf "%.1f" .Percent}}% of {{.Total}}`, "42.0% of 25:00"},
+		{`{{.StartedAt.Format "15:04:05"}}-{{.EndsAt.Format "15:04:05"}}`, "08:49:30-09:14:30"},
+		{"{{.Cycle}}/{{.TotalCycles}} {{.UntilLong}}", "2/4 2"},
+		{"[{{.Label}}]", "[essay]"},
+		// Shorthands mean nothing in a template.
+		{"{{.Phase}} {remaining}", "Work {remaining}"},
+	}
+	for _, tt := range tests {
+		f, err := Parse(tt.format)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.format, err)
+			continue
+		}
+		got, err := f.Execute(FieldsOf(workEvent(), "essay", testNow))
+		if err != nil {
+			t.Errorf("%q: %v", tt.format, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q = %q, want %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejectsUnknownFields(t *testing.T) {
+	for _, format := range []string{
+		"{{.Nope}}",
+		"{{phase}}",
+		"{{.phase}}",
+		"{{.Phase}",
+		"{{.Phase.Nope}}",
+		"{{template \"x\"}}",
+	} {
+		if _, err := Parse(format); err == nil {
+			t.Errorf("Parse(%q) took it", format)
+		}
+	}
+}
+
+func TestEmptyLabel(t *testing.T) {
+	for format, want := range map[string]string{
+		"{phase}{label}":         "Work",
+		"[{label}]":              "[]",
+		"{{.Phase}}{{.Label}}":   "Work",
+		"{{if .Label}}x{{end}}!": "!",
+	} {
+		f, err := Parse(format)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", format, err)
+		}
+		if got, err := f.Execute(FieldsOf(workEvent(), "", testNow)); err != nil || got != want {
+			t.Errorf("%q with no label = %q, %v, want %q", format, got, err, want)
+		}
+	}
+}
+
+func TestFieldsOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		event func(*engine.TimerEvent)
+		check func(Fields) bool
+		want  string
+	}{
+		{
+			name:  "a break counts in the cycle before it",
+			event: func(e *engine.TimerEvent) { e.Phase = engine.PhaseShortBreak },
+			check: func(f Fields) bool { return f.Cycle == 1 && f.Phase == "Short Break" && f.PhaseIcon == "☕" },
+			want:  "cycle 1 of a short break",
+		},
+		{
+			name:  "paused",
+			event: func(e *engine.TimerEvent) { e.Paused = true },
+			check: func(f Fields) bool { return f.State == "paused" },
+			want:  "paused",
+		},
+		{
+			name:  "minutes round up",
+			event: func(e *engine.TimerEvent) { e.Remaining = time.Minute + time.Second },
+			check: func(f Fields) bool { return f.Minutes == 2 },
+			want:  "2 minutes",
+		},
+		{
+			name:  "the last seconds are a minute",
+			event: func(e *engine.TimerEvent) { e.Remaining = time.Second },
+			check: func(f Fields) bool { return f.Minutes == 1 },
+			want:  "1 minute",
+		},
+		{
+			name:  "an hour and more",
+			event: func(e *engine.TimerEvent) { e.Total, e.Remaining = 90*time.Minute, 62*time.Minute+34*time.Second },
+			check: func(f Fields) bool { return f.Total == "1:30:00" && f.Remaining == "1:02:34" },
+			want:  "1:30:00 and 1:02:34",
+		},
+		{
+			name: "work left before a long break",
+			event: func(e *engine.TimerEvent) {
+				e.UntilLongBreak, e.WorkUntilLongBreak = -1, 34*time.Minute+time.Second
+			},
+			check: func(f Fields) bool { return f.UntilLong == "35m" },
+			want:  "35m",
+		},
+		{
+			name:  "no long breaks",
+			event: func(e *engine.TimerEvent) { e.UntilLongBreak = -1 },
+			check: func(f Fields) bool { return f.UntilLong == "" },
+			want:  "no until_long",
+		},
+		{
+			name:  "ends from now",
+			event: func(e *engine.TimerEvent) {},
+			check: func(f Fields) bool { return f.EndsAt.Equal(testNow.Add(14*time.Minute + 30*time.Second)) },
+			want:  "09:14:30",
+		},
+	}
+	for _, tt := range tests {
+		e := workEvent()
+		tt.event(&e)
+		if f := FieldsOf(e, "", testNow); !tt.check(f) {
+			t.Errorf("%s: %+v, want %s", tt.name, f, tt.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package paths decides where pomo keeps its files. By default that is
+// the XDG directories, each kind in its own. A data directory, from
+// --data-dir, POMO_DATA_DIR, or a pomo.portable marker next to the
+// executable, in that order, moves all of them under one directory
+// instead, so pomo can run from a USB stick on machines it cannot write
+// to otherwise.
+package paths
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// EnvDataDir names the data directory, like the --data-dir flag.
+const EnvDataDir = "POMO_DATA_DIR"
+
+// PortableMarker is the file whose presence next to the executable puts
+// pomo in portable mode, keeping everything in a data directory beside it.
+const PortableMarker = "pomo.portable"
+
+var flagDir string
+
+// SetDataDir sets the data directory given with --data-dir, which takes
+// precedence over the rest, before anything asks for a path. An empty dir
+// leaves it to them.
+func SetDataDir(dir string) { flagDir = dir }
+
+// DataDir is the one directory everything is kept under, and what picked
+// it: "--data-dir", EnvDataDir, or PortableMarker. It is empty when none
+// did, and the XDG directories are used.
+func DataDir() (dir, source string, err error) {
+	dir, source = flagDir, "--data-dir"
+	if dir == "" {
+		dir, source = os.Getenv(EnvDataDir), EnvDataDir
+	}
+	if dir == "" {
+		if dir, err = portableDir(); err != nil || dir == "" {
+			return "", "", err
+		}
+		return dir, PortableMarker, nil
+	}
+	// Made absolute, so the socket is the same from any working directory.
+	if dir, err = filepath.Abs(dir); err != nil {
+		return "", "", err
+	}
+	return dir, source, nil
+}
+
+// portableDir is the data directory beside the executable, if a
+// PortableMarker is there too.
+func portableDir() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		// Nowhere to look for a marker, so not portable.
+		return "", nil
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	dir := filepath.Dir(exe)
+	if _, err := os.Stat(filepath.Join(dir, PortableMarker)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	return filepath.Join(dir, "data"), nil
+}
+
+// Config is where the config file is looked for: config in the data
+// directory, else $XDG_CONFIG_HOME/pomo, defaulting to ~/.config/pomo.
+func Config() (string, error) {
+	return dir("config", "XDG_CONFIG_HOME", ".config")
+}
+
+// History is where history, its backups, and its archive are kept:
+// history in the data directory, else $XDG_DATA_HOME/pomo, defaulting to
+// ~/.local/share/pomo.
+func History() (string, error) {
+	return dir("history", "XDG_DATA_HOME", ".local", "share")
+}
+
+// State is where the running session's state, the log, and the like are
+// kept: state in the data directory, else $XDG_STATE_HOME/pomo,
+// defaulting to ~/.local/state/pomo.
+func State() (string, error) {
+	return dir("state", "XDG_STATE_HOME", ".local", "state")
+}
+
+// Runtime is where the control socket goes: the state directory when
+// there is a data directory, else $XDG_RUNTIME_DIR, falling back to the
+// state directory.
+func Runtime() (string, error) {
+	root, _, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	if root == "" {
+		if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+			return dir, nil
+		}
+	}
+	return State()
+}
+
+// dir is name in the data directory if there is one, else pomo in the
+// directory env names, defaulting to home under the home directory.
+func dir(name, env string, home ...string) (string, error) {
+	root, _, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	if root != "" {
+		return filepath.Join(root, name), nil
+	}
+	if dir := os.Getenv(env); dir != "" {
+		return filepath.Join(dir, "pomo"), nil
+	}
+
+	base, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append(append([]string{base}, home...), "pomo")...), nil
+}
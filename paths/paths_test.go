@@ -0,0 +1,144 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPrecedence sets each way of choosing directories in turn, over all
+// those below it, and checks the highest picks every directory.
+func TestPrecedence(t *testing.T) {
+	home := t.TempDir()
+	tests := []struct {
+		name    string
+		flag    string
+		env     string
+		xdg     bool
+		source  string
+		config  string
+		history string
+		state   string
+		runtime string
+	}{
+		{
+			name:   "home",
+			config: home + "/.config/pomo", history: home + "/.local/share/pomo",
+			state: home + "/.local/state/pomo", runtime: home + "/.local/state/pomo",
+		},
+		{
+			name: "xdg", xdg: true,
+			config: home + "/xdg-config/pomo", history: home + "/xdg-data/pomo",
+			state: home + "/xdg-state/pomo", runtime: home + "/xdg-runtime",
+		},
+		{
+			name: "env over xdg", env: home + "/env", xdg: true, source: EnvDataDir,
+			config: home + "/env/config", history: home + "/env/history",
+			state: home + "/env/state", runtime: home + "/env/state",
+		},
+		{
+			name: "flag over env and xdg", flag: home + "/flag", env: home + "/env", xdg: true, source: "--data-dir",
+			config: home + "/flag/config", history: home + "/flag/history",
+			state: home + "/flag/state", runtime: home + "/flag/state",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", home)
+			xdg := map[string]string{
+				"XDG_CONFIG_HOME": "/xdg-config",
+				"XDG_DATA_HOME":   "/xdg-data",
+				"XDG_STATE_HOME":  "/xdg-state",
+				"XDG_RUNTIME_DIR": "/xdg-runtime",
+			}
+			for name, dir := range xdg {
+				if tt.xdg {
+					t.Setenv(name, home+dir)
+				} else {
+					t.Setenv(name, "")
+				}
+			}
+			t.Setenv(EnvDataDir, tt.env)
+			SetDataDir(tt.flag)
+			defer SetDataDir("")
+
+			_, source, err := DataDir()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if source != tt.source {
+				t.Errorf("data directory from %q, want %q", source, tt.source)
+			}
+			for _, d := range []struct {
+				name string
+				get  func() (string, error)
+				want string
+			}{
+				{"config", Config, tt.config},
+				{"history", History, tt.history},
+				{"state", State, tt.state},
+				{"runtime", Runtime, tt.runtime},
+			} {
+				got, err := d.get()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != d.want {
+					t.Errorf("%s directory %s, want %s", d.name, got, d.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRelativeDataDir(t *testing.T) {
+	SetDataDir("usb/pomo")
+	defer SetDataDir("")
+	dir, _, err := DataDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(wd, "usb", "pomo"); dir != want {
+		t.Errorf("--data-dir usb/pomo is %s, want %s", dir, want)
+	}
+}
+
+// TestPortable puts a marker next to the test binary, which stands in for
+// pomo's, and checks it is used below POMO_DATA_DIR but above XDG.
+func TestPortable(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skipf("no executable: %v", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	marker := filepath.Join(filepath.Dir(exe), PortableMarker)
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		t.Skipf("cannot write beside the test binary: %v", err)
+	}
+	defer os.Remove(marker)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv(EnvDataDir, "")
+
+	dir, source, err := DataDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(filepath.Dir(exe), "data"); dir != want || source != PortableMarker {
+		t.Errorf("data directory %s from %q, want %s from the marker", dir, source, want)
+	}
+	if state, err := State(); err != nil || state != filepath.Join(dir, "state") {
+		t.Errorf("state directory %s (%v), want it in %s", state, err, dir)
+	}
+
+	env := t.TempDir()
+	t.Setenv(EnvDataDir, env)
+	if dir, source, _ := DataDir(); dir != env || source != EnvDataDir {
+		t.Errorf("with %s set, data directory %s from %q, want %s", EnvDataDir, dir, source, env)
+	}
+}
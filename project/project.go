@@ -0,0 +1,141 @@
+// Package project works out what a session is being worked on from the
+// directory pomo starts in: the name of the git repository it is in, going
+// by its remote, without running git.
+package project
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Detect is the name of the git repository dir is in: the last part of
+// its origin remote's URL, or of its first remote's without an origin,
+// less any ".git", else the name of the repository's top directory. It is
+// "" outside a repository.
+func Detect(dir string) string {
+	top, gitDir, ok := findRepo(dir)
+	if !ok {
+		return ""
+	}
+	if url := remoteURL(filepath.Join(gitDir, "config")); url != "" {
+		if name := repoName(url); name != "" {
+			return name
+		}
+	}
+	return filepath.Base(top)
+}
+
+// findRepo walks up from dir to the repository's top directory and its
+// git directory, which a .git file, as in a worktree or submodule, points
+// elsewhere. A worktree's config is its main repository's.
+func findRepo(dir string) (top, gitDir string, ok bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", false
+	}
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return dir, dotGit, true
+			}
+			if gitDir, ok := readGitFile(dotGit); ok {
+				return dir, commonDir(gitDir), true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
+// readGitFile follows a .git file's "gitdir: <path>" line.
+func readGitFile(name string) (string, bool) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", false
+	}
+	dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", false
+	}
+	dir = strings.TrimSpace(dir)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(filepath.Dir(name), dir)
+	}
+	return dir, true
+}
+
+// commonDir is where a worktree's git directory keeps what it shares with
+// the main one, config included, or gitDir itself.
+func commonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	dir := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+	return dir
+}
+
+// remoteURL is the origin remote's URL in the git config file at name, or
+// the first remote's if there is no origin.
+func remoteURL(name string) string {
+	f, err := os.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var remote, first, origin string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			remote = ""
+			// [remote "origin"]
+			if rest, ok := strings.CutPrefix(line, "[remote "); ok {
+				remote = strings.Trim(strings.TrimSuffix(rest, "]"), `" `)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if remote == "" || !ok || strings.TrimSpace(key) != "url" {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if first == "" {
+			first = value
+		}
+		if remote == "origin" && origin == "" {
+			origin = value
+		}
+	}
+	if origin != "" {
+		return origin
+	}
+	return first
+}
+
+// repoName is the last part of a remote's URL less any ".git", as in
+// "git@github.com:steenfuentes/pomo.git" or
+// "https://github.com/steenfuentes/pomo".
+func repoName(url string) string {
+	url = strings.TrimRight(url, "/")
+	// scp-like addresses put a colon before the path.
+	if i := strings.LastIndexAny(url, ":/"); i >= 0 && url[i] == ':' {
+		url = url[i+1:]
+	}
+	name := strings.TrimSuffix(path.Base(filepath.ToSlash(url)), ".git")
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
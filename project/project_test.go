@@ -0,0 +1,101 @@
+package project
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// git runs git in dir, failing the test if it fails.
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_GLOBAL="+os.DevNull, "GIT_CONFIG_SYSTEM="+os.DevNull,
+		"GIT_AUTHOR_NAME=pomo", "GIT_AUTHOR_EMAIL=pomo@example.com",
+		"GIT_COMMITTER_NAME=pomo", "GIT_COMMITTER_EMAIL=pomo@example.com")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// repo is a new repository named name in a temporary directory, with the
+// remotes given as name, URL pairs, in that order.
+func repo(t *testing.T, name string, remotes ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := filepath.Join(t.TempDir(), name)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "init", "-q")
+	for i := 0; i+1 < len(remotes); i += 2 {
+		git(t, dir, "remote", "add", remotes[i], remotes[i+1])
+	}
+	return dir
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		remotes []string
+		want    string
+	}{
+		{"no remote", nil, "checkout"},
+		{"scp origin", []string{"origin", "git@github.com:steenfuentes/pomo.git"}, "pomo"},
+		{"https origin", []string{"origin", "https://github.com/steenfuentes/pomo"}, "pomo"},
+		{"trailing slash", []string{"origin", "https://git.example.com/team/tools/"}, "tools"},
+		{"ssh url", []string{"origin", "ssh://git@example.com:2222/team/api.git"}, "api"},
+		{"origin not first", []string{"fork", "git@github.com:me/pomo-fork.git", "origin", "git@github.com:steenfuentes/pomo.git"}, "pomo"},
+		{"first without origin", []string{"upstream", "https://example.com/a/first.git", "mine", "https://example.com/b/second.git"}, "first"},
+		{"local path", []string{"origin", "/srv/git/notes.git"}, "notes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := repo(t, "checkout", tt.remotes...)
+			if got := Detect(dir); got != tt.want {
+				t.Errorf("Detect = %q, want %q", got, tt.want)
+			}
+			sub := filepath.Join(dir, "cmd", "pomo")
+			if err := os.MkdirAll(sub, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if got := Detect(sub); got != tt.want {
+				t.Errorf("Detect in a subdirectory = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectOutsideRepository(t *testing.T) {
+	if got := Detect(t.TempDir()); got != "" {
+		t.Errorf("Detect = %q outside a repository", got)
+	}
+}
+
+// TestDetectWorktree checks a worktree, whose .git is a file, goes by its
+// main repository's remote rather than its own directory's name.
+func TestDetectWorktree(t *testing.T) {
+	dir := repo(t, "main", "origin", "git@github.com:steenfuentes/pomo.git")
+	git(t, dir, "commit", "-q", "--allow-empty", "-m", "start")
+	tree := filepath.Join(t.TempDir(), "feature")
+	git(t, dir, "worktree", "add", "-q", tree)
+	if info, err := os.Stat(filepath.Join(tree, ".git")); err != nil || info.IsDir() {
+		t.Fatalf("worktree .git is not a file: %v", err)
+	}
+	if got := Detect(tree); got != "pomo" {
+		t.Errorf("Detect in a worktree = %q, want pomo", got)
+	}
+
+	plain := repo(t, "plain")
+	git(t, plain, "commit", "-q", "--allow-empty", "-m", "start")
+	tree = filepath.Join(t.TempDir(), "feature")
+	git(t, plain, "worktree", "add", "-q", tree)
+	if got := Detect(tree); got != "feature" {
+		t.Errorf("Detect in a worktree without remotes = %q, want feature, its own top directory", got)
+	}
+}
@@ -0,0 +1,326 @@
+// Package provider runs the integrations named in the config file, shell
+// commands for each point in a session, e.g. to change the wallpaper or the
+// lights with the phase.
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/overlay"
+)
+
+type Event string
+
+const (
+	EventWork  Event = "on_work"
+	EventBreak Event = "on_break"
+	EventDone  Event = "on_done"
+)
+
+var Events = []Event{EventWork, EventBreak, EventDone}
+
+// queueLen is how many commands can wait behind a running one before more
+// are dropped.
+const queueLen = 8
+
+// Invocation is a command as it runs, with e's values filled in: the
+// command line and the variables added to pomo's environment.
+type Invocation struct {
+	Command string
+	Env     []string
+}
+
+// Result is how one command went.
+type Result struct {
+	Provider   string
+	Event      Event
+	Invocation Invocation
+	// DryRun marks a result from Preview, which ran nothing.
+	DryRun bool
+	// ExitCode is -1 when the command did not run to an exit, e.g. when it
+	// timed out.
+	ExitCode int
+	Duration time.Duration
+	Output   []byte
+	Err      error
+}
+
+func (r Result) String() string {
+	if r.DryRun {
+		if r.Err != nil {
+			return fmt.Sprintf("provider %s: %s: %v", r.Provider, r.Event, r.Err)
+		}
+		return fmt.Sprintf("provider %s: %s would run: %s", r.Provider, r.Event, r.Invocation.Command)
+	}
+	if r.Err != nil {
+		return fmt.Sprintf("provider %s: %s failed after %s: %v", r.Provider, r.Event, r.Duration.Round(time.Millisecond), r.Err)
+	}
+	return fmt.Sprintf("provider %s: %s exited 0 in %s", r.Provider, r.Event, r.Duration.Round(time.Millisecond))
+}
+
+// Command returns p's command for ev, empty if it has none.
+func Command(p config.Provider, ev Event) string {
+	switch ev {
+	case EventWork:
+		return p.OnWork
+	case EventBreak:
+		return p.OnBreak
+	case EventDone:
+		return p.OnDone
+	default:
+		return ""
+	}
+}
+
+// Check reports a command whose format does not parse.
+func Check(p config.Provider) error {
+	for _, ev := range Events {
+		if _, err := overlay.Parse(Command(p, ev)); err != nil {
+			return fmt.Errorf("provider %q: %s: %w", p.Name, ev, err)
+		}
+	}
+	return nil
+}
+
+// Prepare fills e's values, as of now, into p's command for ev, as a format
+// like --write-format's, and into POMO_* variables. It is what Run runs.
+// Each value goes into the command shell-quoted, as one word, so a label
+// cannot run as part of it.
+func Prepare(p config.Provider, ev Event, e engine.TimerEvent, label string, now time.Time) (Invocation, error) {
+	command, err := expand(p, ev, e, label, now)
+	if err != nil {
+		return Invocation{}, err
+	}
+	return Invocation{Command: command, Env: environ(p, ev, e, label)}, nil
+}
+
+// Preview is Run without running anything: the result only has the
+// invocation, or why it could not be prepared.
+func Preview(p config.Provider, ev Event, e engine.TimerEvent, label string, now time.Time) Result {
+	res := Result{Provider: p.Name, Event: ev, DryRun: true, ExitCode: -1}
+	res.Invocation, res.Err = Prepare(p, ev, e, label, now)
+	return res
+}
+
+// Run runs p's command for ev as Prepare has it, timing it on clock. It
+// returns once the command exits or its timeout passes.
+func Run(p config.Provider, ev Event, e engine.TimerEvent, label string, clock engine.Clock) Result {
+	res := Result{Provider: p.Name, Event: ev, ExitCode: -1}
+	start := clock.Now()
+	inv, err := Prepare(p, ev, e, label, start)
+	res.Invocation = inv
+	if err != nil || inv.Command == "" {
+		res.Err = err
+		return res
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+
+	cmd := Shell(ctx, inv.Command)
+	cmd.Env = append(os.Environ(), inv.Env...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	// Whatever the command started keeps the pipes open, so stop waiting
+	// for output soon after it is killed.
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	res.Duration = clock.Since(start)
+	res.Output = out.Bytes()
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		res.Err = fmt.Errorf("timed out after %s", p.Timeout)
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitCode()
+		res.Err = fmt.Errorf("exit status %d", res.ExitCode)
+	case err != nil:
+		res.Err = err
+	default:
+		res.ExitCode = 0
+	}
+	return res
+}
+
+func expand(p config.Provider, ev Event, e engine.TimerEvent, label string, now time.Time) (string, error) {
+	format, err := overlay.Parse(Command(p, ev))
+	if err != nil {
+		return "", err
+	}
+	return format.Execute(quoted(overlay.FieldsOf(e, label, now)))
+}
+
+// quoted is fields with every string quoted for the shell. The rest are
+// numbers and times, which a format can only print as such.
+func quoted(f overlay.Fields) overlay.Fields {
+	for _, s := range []*string{&f.Phase, &f.PhaseIcon, &f.Elapsed, &f.Remaining, &f.Total, &f.UntilLong, &f.Label, &f.State} {
+		*s = quote(*s)
+	}
+	return f
+}
+
+// quote makes s one word for Shell's shell: single-quoted for sh, and
+// double-quoted for cmd, which leaves its operators inert but for %.
+func quote(s string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Shell runs command in the platform's shell, sh or cmd.
+func Shell(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+func environ(p config.Provider, ev Event, e engine.TimerEvent, label string) []string {
+	cycle := e.CycleNum
+	if e.Phase != engine.PhaseWork {
+		cycle--
+	}
+	env := []string{
+		"POMO_PROVIDER=" + p.Name,
+		"POMO_EVENT=" + string(ev),
+		"POMO_PHASE=" + e.Phase.String(),
+		fmt.Sprintf("POMO_DURATION_SECONDS=%d", int64(e.Total/time.Second)),
+		fmt.Sprintf("POMO_CYCLE=%d", cycle),
+		fmt.Sprintf("POMO_CYCLES=%d", e.TotalCycles),
+		"POMO_LABEL=" + label,
+	}
+	if !e.PhaseStartedAt.IsZero() {
+		env = append(env,
+			"POMO_STARTED_AT="+e.PhaseStartedAt.Format(time.RFC3339),
+			"POMO_ENDED_AT="+e.PhaseStartedAt.Add(e.Total).Format(time.RFC3339))
+	}
+	for _, k := range slices.Sorted(maps.Keys(p.Env)) {
+		env = append(env, k+"="+p.Env[k])
+	}
+	return env
+}
+
+type job struct {
+	ev Event
+	e  engine.TimerEvent
+}
+
+// Runner is a subscriber that runs a provider's commands as phases start
+// and as the session ends. They run one at a time, in order, off the
+// timer's goroutine.
+type Runner struct {
+	provider config.Provider
+	label    string
+	clock    engine.Clock
+	dryRun   bool
+	report   func(Result)
+	queue    chan job
+	done     chan struct{}
+
+	started      bool
+	lastComplete bool
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewRunner calls report with each result, failed or not, as its command
+// finishes. With dryRun, commands are only previewed, at the moment they
+// would have run. Commands see the time, and are timed, on clock.
+func NewRunner(p config.Provider, label string, clock engine.Clock, dryRun bool, report func(Result)) *Runner {
+	r := &Runner{
+		provider: p,
+		label:    label,
+		clock:    clock,
+		dryRun:   dryRun,
+		report:   report,
+		queue:    make(chan job, queueLen),
+		done:     make(chan struct{}),
+	}
+	go r.work()
+	return r
+}
+
+func (r *Runner) Handle(e engine.TimerEvent) {
+	switch e.Type {
+	case engine.EventSessionEnded:
+		r.enqueue(EventDone, e)
+		return
+	case engine.EventTick:
+	default:
+		return
+	}
+
+	// Every phase ends on a complete event, and consecutive phases can be
+	// of the same kind once extras are spliced in.
+	if !r.started || r.lastComplete {
+		switch e.Phase {
+		case engine.PhaseWork:
+			r.enqueue(EventWork, e)
+		case engine.PhaseShortBreak, engine.PhaseLongBreak, engine.PhaseCooldown:
+			r.enqueue(EventBreak, e)
+		}
+	}
+	r.started = true
+	r.lastComplete = e.PhaseComplete
+}
+
+func (r *Runner) enqueue(ev Event, e engine.TimerEvent) {
+	if Command(r.provider, ev) == "" {
+		return
+	}
+	select {
+	case r.queue <- job{ev, e}:
+	default:
+		r.fail(fmt.Errorf("provider %s: %s: queue full, dropped", r.provider.Name, ev))
+	}
+}
+
+func (r *Runner) work() {
+	defer close(r.done)
+	for j := range r.queue {
+		var res Result
+		if r.dryRun {
+			res = Preview(r.provider, j.ev, j.e, r.label, r.clock.Now())
+		} else {
+			res = Run(r.provider, j.ev, j.e, r.label, r.clock)
+		}
+		if r.report != nil {
+			r.report(res)
+		}
+	}
+}
+
+func (r *Runner) fail(err error) {
+	r.mu.Lock()
+	r.errs = append(r.errs, err)
+	r.mu.Unlock()
+}
+
+// Close waits for queued commands, each bounded by its timeout, and reports
+// any that were dropped.
+func (r *Runner) Close() error {
+	close(r.queue)
+	<-r.done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return errors.Join(r.errs...)
+}
@@ -0,0 +1,119 @@
+package provider
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/config"
+	"github.com/steenfuentes/pomo/engine"
+)
+
+var testNow = time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+func workEvent() engine.TimerEvent {
+	return engine.TimerEvent{
+		Type:               engine.EventTick,
+		Phase:              engine.PhaseWork,
+		Total:              25 * time.Minute,
+		Remaining:          25 * time.Minute,
+		CycleNum:           1,
+		TotalCycles:        4,
+		UntilLongBreak:     -1,
+		WorkUntilLongBreak: -1,
+		PhaseStartedAt:     testNow,
+	}
+}
+
+// hostileLabels would each run touch on the marker file, or break the
+// command, if pasted into it as they are.
+func hostileLabels(marker string) []string {
+	return []string{
+		`x"; touch ` + marker + `; "`,
+		`x'; touch ` + marker + `; '`,
+		`$(touch ` + marker + `)`,
+		"`touch " + marker + "`",
+		`a; touch ` + marker,
+		`it's $HOME & co | cat > ` + marker,
+		"two\nlines; touch " + marker,
+		`back\slash '' "" $`,
+		``,
+	}
+}
+
+func TestRunKeepsLabelOneWord(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh quoting")
+	}
+	marker := filepath.Join(t.TempDir(), "ran")
+	for _, command := range []string{`printf '[%s]' {label}`, `printf '[%s]' {{.Label}}`} {
+		for _, label := range hostileLabels(marker) {
+			p := config.Provider{Name: "test", OnWork: command, Timeout: 5 * time.Second}
+			res := Run(p, EventWork, workEvent(), label, engine.NewMockClock(testNow))
+			if res.Err != nil {
+				t.Errorf("%s with label %q: %v: %s", command, label, res.Err, res.Output)
+				continue
+			}
+			if got, want := string(res.Output), "["+label+"]"; got != want {
+				t.Errorf("%s with label %q printed %q, want %q", command, label, got, want)
+			}
+			if _, err := os.Stat(marker); err == nil {
+				t.Fatalf("%s with label %q ran the label", command, label)
+			}
+		}
+	}
+}
+
+func TestPrepareQuotesEveryField(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh quoting")
+	}
+	p := config.Provider{Name: "test", OnWork: "echo {phase} {remaining} {minutes} {cycle}/{cycles} {until_long} {label}"}
+	inv, err := Prepare(p, EventWork, workEvent(), "deep work", testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "echo 'Work' '25:00' 25 1/4 '' 'deep work'"; inv.Command != want {
+		t.Errorf("command %q, want %q", inv.Command, want)
+	}
+	if !strings.Contains(strings.Join(inv.Env, "\n"), "POMO_LABEL=deep work\n") {
+		t.Errorf("environment lacks the label as it is: %q", inv.Env)
+	}
+}
+
+func TestPrepareTakesTimeFromClock(t *testing.T) {
+	p := config.Provider{Name: "test", OnWork: `at {{.EndsAt.Format "15:04"}}`}
+	e := workEvent()
+	e.Remaining = 10 * time.Minute
+	for range 2 {
+		res := Preview(p, EventWork, e, "", testNow)
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		if want := "at 09:10"; res.Invocation.Command != want {
+			t.Errorf("command %q, want %q", res.Invocation.Command, want)
+		}
+	}
+}
+
+func TestRunnerPreviewsOnItsClock(t *testing.T) {
+	clock := engine.NewMockClock(testNow)
+	var results []Result
+	p := config.Provider{Name: "test", OnWork: `{{.EndsAt.Format "15:04"}} {{.Label}}`}
+	r := NewRunner(p, "it's", clock, true, func(res Result) { results = append(results, res) })
+	e := workEvent()
+	e.Remaining = 5 * time.Minute
+	r.Handle(e)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("%d results, want 1", len(results))
+	}
+	if got, want := results[0].Invocation.Command, "09:05 "+quote("it's"); got != want {
+		t.Errorf("command %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+// Package quiet decides when pomo should keep the noise down, from windows
+// of wall-clock time that may differ by weekday.
+package quiet
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Hours holds at most one window per weekday. A window runs from its start
+// on that day to its end, on the next day if the end is not later.
+type Hours struct {
+	days [7]*window
+}
+
+// window bounds are minutes after midnight.
+type window struct {
+	start, end int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Parse reads specs like "22:00-07:00", which applies to every day, and
+// "sat=00:00-09:00" or "fri=off", which override one weekday whatever
+// their order.
+func Parse(specs []string) (Hours, error) {
+	var h Hours
+	var overrides []string
+	for _, spec := range specs {
+		if strings.Contains(spec, "=") {
+			overrides = append(overrides, spec)
+			continue
+		}
+		w, err := parseWindow(spec)
+		if err != nil {
+			return h, err
+		}
+		for d := range h.days {
+			h.days[d] = w
+		}
+	}
+
+	for _, spec := range overrides {
+		name, value, _ := strings.Cut(spec, "=")
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return h, fmt.Errorf("invalid quiet hours day %q (want mon, tue, ... sun)", name)
+		}
+		if value == "off" {
+			h.days[day] = nil
+			continue
+		}
+		w, err := parseWindow(value)
+		if err != nil {
+			return h, err
+		}
+		h.days[day] = w
+	}
+	return h, nil
+}
+
+func parseWindow(s string) (*window, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid quiet hours %q (want HH:MM-HH:MM)", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours %q: %w", s, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiet hours %q: %w", s, err)
+	}
+	return &window{start: start, end: end}, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains reports whether t falls in a quiet window. Bounds are wall-clock
+// times in t's location, so windows keep their local hours across DST
+// changes; a bound that does not exist that day moves forward with the
+// clock.
+func (h Hours) Contains(t time.Time) bool {
+	y, m, d := t.Date()
+	// A window that started yesterday may still be running.
+	for _, back := range []int{0, 1} {
+		day := time.Date(y, m, d-back, 0, 0, 0, 0, t.Location())
+		w := h.days[day.Weekday()]
+		if w == nil {
+			continue
+		}
+
+		start := wallClock(y, m, d-back, w.start, t.Location())
+		end := wallClock(y, m, d-back, w.end, t.Location())
+		if w.end <= w.start {
+			end = wallClock(y, m, d-back+1, w.end, t.Location())
+		}
+		if !t.Before(start) && t.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// wallClock is minute minutes after midnight by the clock on the given day
+// in loc. time.Date may take a time the clock skips back by the jump, e.g.
+// 02:30 to 01:30 when clocks go forward at 02:00; it is moved forward to
+// 03:30 instead.
+func wallClock(y int, m time.Month, d, minute int, loc *time.Location) time.Time {
+	t := time.Date(y, m, d, 0, minute, 0, 0, loc)
+	want := time.Date(y, m, d, 0, minute, 0, 0, time.UTC)
+	got := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	if got.Before(want) {
+		t = t.Add(want.Sub(got))
+	}
+	return t
+}
@@ -0,0 +1,104 @@
+package quiet
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestContains(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, ny)
+	}
+	// utc gives times of day that happen twice, as the clocks go back.
+	utc := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, time.UTC).In(ny)
+	}
+
+	tests := []struct {
+		name  string
+		specs []string
+		t     time.Time
+		want  bool
+	}{
+		// Friday 9 January to Saturday 10 January 2026.
+		{"before the window", []string{"22:00-07:00"}, at(1, 9, 21, 59), false},
+		{"at its start", []string{"22:00-07:00"}, at(1, 9, 22, 0), true},
+		{"before midnight", []string{"22:00-07:00"}, at(1, 9, 23, 59), true},
+		{"at midnight", []string{"22:00-07:00"}, at(1, 10, 0, 0), true},
+		{"after midnight", []string{"22:00-07:00"}, at(1, 10, 6, 59), true},
+		{"at its end", []string{"22:00-07:00"}, at(1, 10, 7, 0), false},
+		{"midday", []string{"22:00-07:00"}, at(1, 10, 12, 0), false},
+		{"same day window", []string{"12:00-13:00"}, at(1, 10, 12, 30), true},
+		{"after a same day window", []string{"12:00-13:00"}, at(1, 10, 13, 0), false},
+		{"whole day", []string{"00:00-00:00"}, at(1, 10, 15, 0), true},
+		{"none", nil, at(1, 10, 3, 0), false},
+
+		// Saturday off keeps Friday's window running into Saturday
+		// morning, but ends Saturday's before it starts.
+		{"off day, yesterday's window", []string{"22:00-07:00", "sat=off"}, at(1, 10, 6, 0), true},
+		{"off day, its own window", []string{"22:00-07:00", "sat=off"}, at(1, 10, 23, 0), false},
+		{"after an off day", []string{"22:00-07:00", "sat=off"}, at(1, 11, 6, 0), false},
+		{"override given first", []string{"sat=off", "22:00-07:00"}, at(1, 10, 23, 0), false},
+		{"override day, its own window", []string{"22:00-07:00", "sat=00:00-09:00"}, at(1, 10, 8, 0), true},
+		{"override day, after its window", []string{"22:00-07:00", "sat=00:00-09:00"}, at(1, 10, 23, 0), false},
+		{"override day, both windows", []string{"22:00-07:00", "sat=00:00-09:00"}, at(1, 10, 6, 0), true},
+		{"day before the override", []string{"22:00-07:00", "sat=00:00-09:00"}, at(1, 9, 23, 0), true},
+		{"override on its own", []string{"SAT=22:00-07:00"}, at(1, 11, 6, 0), true},
+		{"day after an override on its own", []string{"SAT=22:00-07:00"}, at(1, 11, 23, 0), false},
+
+		// Clocks go forward at 02:00 on Sunday 8 March 2026, so the night
+		// is an hour shorter but still ends at 07:00.
+		{"spring forward, evening before", []string{"22:00-07:00"}, at(3, 7, 23, 0), true},
+		{"spring forward, after the change", []string{"22:00-07:00"}, at(3, 8, 3, 0), true},
+		{"spring forward, before its end", []string{"22:00-07:00"}, at(3, 8, 6, 59), true},
+		{"spring forward, at its end", []string{"22:00-07:00"}, at(3, 8, 7, 0), false},
+		// 02:30 does not exist that day, and moves forward to 03:30.
+		{"spring forward, before a missing end", []string{"01:30-02:30"}, at(3, 8, 1, 45), true},
+		{"spring forward, past a missing end", []string{"01:30-02:30"}, at(3, 8, 3, 15), true},
+		{"spring forward, at a missing end", []string{"01:30-02:30"}, at(3, 8, 3, 30), false},
+		{"spring forward, missing start", []string{"02:30-04:00"}, at(3, 8, 3, 15), false},
+		{"spring forward, after a missing start", []string{"02:30-04:00"}, at(3, 8, 3, 30), true},
+
+		// Clocks go back at 02:00 on Sunday 1 November 2026, so 01:00 to
+		// 02:00 happens twice and the night is an hour longer.
+		{"fall back, evening before", []string{"22:00-07:00"}, at(10, 31, 23, 0), true},
+		{"fall back, first 01:30", []string{"22:00-07:00"}, utc(11, 1, 5, 30), true},
+		{"fall back, second 01:30", []string{"22:00-07:00"}, utc(11, 1, 6, 30), true},
+		{"fall back, before its end", []string{"22:00-07:00"}, at(11, 1, 6, 59), true},
+		{"fall back, at its end", []string{"22:00-07:00"}, at(11, 1, 7, 0), false},
+		{"fall back, window over the change, first 01:30", []string{"01:00-03:00"}, utc(11, 1, 5, 30), true},
+		{"fall back, window over the change, second 01:30", []string{"01:00-03:00"}, utc(11, 1, 6, 30), true},
+		{"fall back, window over the change, at its end", []string{"01:00-03:00"}, at(11, 1, 3, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := Parse(tt.specs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := h.Contains(tt.t); got != tt.want {
+				t.Errorf("%v contains %s = %v, want %v", tt.specs, tt.t.Format(time.RFC3339), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, specs := range [][]string{
+		{"22:00"},
+		{"22:00-7"},
+		{"25:00-07:00"},
+		{"sun=22:00"},
+		{"someday=22:00-07:00"},
+		{"fri=on"},
+	} {
+		if _, err := Parse(specs); err == nil {
+			t.Errorf("Parse(%q) succeeded", specs)
+		}
+	}
+}
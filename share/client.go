@@ -0,0 +1,118 @@
+package share
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = 10 * time.Second
+)
+
+// Follower reads a shared session's stream, reconnecting whenever it
+// breaks, and delivers each message exactly once.
+type Follower struct {
+	base    string
+	http    *http.Client
+	boot    string
+	lastSeq uint64
+}
+
+// NewFollower follows the server at base, e.g. "http://host:7657".
+func NewFollower(base string) *Follower {
+	return &Follower{base: strings.TrimRight(base, "/"), http: &http.Client{}}
+}
+
+// Run delivers messages until ctx is done. It fails if the first connection
+// does; after that, lost is called with the error when the stream breaks
+// and with nil once it is back, not for each failed attempt between.
+func (f *Follower) Run(ctx context.Context, deliver func(Message), lost func(error)) error {
+	err := f.stream(ctx, deliver, func() {})
+	if f.boot == "" && err != nil && ctx.Err() == nil {
+		return err
+	}
+
+	backoff := minBackoff
+	up := true
+	for ctx.Err() == nil {
+		if up {
+			lost(err)
+			up = false
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil
+		}
+		backoff = min(backoff*2, maxBackoff)
+		err = f.stream(ctx, deliver, func() {
+			backoff, up = minBackoff, true
+			lost(nil)
+		})
+	}
+	return nil
+}
+
+// stream reads one connection's messages, calling connected once it is up.
+func (f *Follower) stream(ctx context.Context, deliver func(Message), connected func()) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/events?boot=%s&after=%d", f.base, url.QueryEscape(f.boot), f.lastSeq), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", f.base, resp.Status)
+	}
+	if boot := resp.Header.Get(bootHeader); boot != f.boot {
+		f.boot, f.lastSeq = boot, 0
+	}
+	connected()
+
+	dec := json.NewDecoder(bufio.NewReader(resp.Body))
+	for {
+		var m Message
+		if err := dec.Decode(&m); err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("the session's host closed the connection")
+			}
+			return err
+		}
+		if m.Seq <= f.lastSeq {
+			continue
+		}
+		f.lastSeq = m.Seq
+		deliver(m)
+	}
+}
+
+// Send asks the host to run a control command, e.g. "skip", and returns
+// its reply.
+func Send(base, command string) (string, error) {
+	resp, err := http.Post(strings.TrimRight(base, "/")+"/control", "text/plain", strings.NewReader(command))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	reply := strings.TrimSpace(string(body))
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(reply)
+	}
+	if msg, ok := strings.CutPrefix(reply, "error "); ok {
+		return "", errors.New(msg)
+	}
+	return strings.TrimPrefix(reply, "ok "), nil
+}
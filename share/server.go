@@ -0,0 +1,263 @@
+// Package share mirrors a running session over HTTP, so a pair on another
+// machine can follow it with pomo join and, if allowed, pause or skip it.
+package share
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/state"
+)
+
+const bootHeader = "Pomo-Boot"
+
+// clientBuffer is how many messages a follower can lag behind before it is
+// cut off, to catch up on reconnecting.
+const clientBuffer = 64
+
+// remoteCommands are the control commands a follower may send.
+var remoteCommands = map[string]bool{"pause": true, "resume": true, "toggle-pause": true, "skip": true, "snooze": true}
+
+// Message is one event of the stream. Seq increases through the life of the
+// server, across sessions, so a follower that reconnects can ask for what
+// it missed. Config is set on the session's start.
+type Message struct {
+	Seq    uint64             `json:"seq"`
+	Event  engine.TimerEvent  `json:"event"`
+	Config *engine.ConfigJSON `json:"config,omitempty"`
+}
+
+// Server is a subscriber that streams events to followers as JSON lines
+// from GET /events, replies with the state file's JSON on GET /status and
+// the session's color on GET /color, and takes control commands on POST
+// /control when control is set.
+type Server struct {
+	control func(command string) (string, error)
+	palette state.Palette
+	label   string
+	profile string
+	srv     *http.Server
+	// boot tells followers a restarted server's Seq apart.
+	boot string
+
+	mu  sync.Mutex
+	seq uint64
+	// backlog holds the current session's start and every phase end since,
+	// followed by the latest tick if newer.
+	backlog   []Message
+	latest    *Message
+	followers map[chan Message]struct{}
+}
+
+// Listen serves on addr until Shutdown, publishing label and the profile
+// named with each session's settings, and colors from palette. A nil
+// control refuses commands.
+func Listen(addr, label, profile string, palette state.Palette, control func(command string) (string, error)) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		control:   control,
+		palette:   palette,
+		label:     label,
+		profile:   profile,
+		boot:      strconv.FormatInt(time.Now().UnixNano(), 36),
+		followers: make(map[chan Message]struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /events", s.events)
+	mux.HandleFunc("GET /status", s.status)
+	mux.HandleFunc("GET /color", s.color)
+	mux.HandleFunc("POST /control", s.command)
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go s.srv.Serve(ln)
+	return s, nil
+}
+
+func (s *Server) Handle(e engine.TimerEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	m := Message{Seq: s.seq, Event: e}
+	switch {
+	case e.Type == engine.EventSessionStarted:
+		if e.Config != nil {
+			c := e.Config.JSON(s.label, s.profile)
+			m.Config = &c
+		}
+		s.backlog = []Message{m}
+		s.latest = nil
+	case e.Type == engine.EventSessionEnded || e.PhaseComplete || e.Ended != "":
+		s.backlog = append(s.backlog, m)
+		s.latest = nil
+	case e.Type == engine.EventTransition || e.Type == engine.EventSnooze || e.Type == engine.EventAway:
+		// Only worth seeing live, and not a state of the session.
+	default:
+		s.latest = &m
+	}
+
+	for ch := range s.followers {
+		select {
+		case ch <- m:
+		default:
+			// Too far behind: closing makes it reconnect and catch up.
+			delete(s.followers, ch)
+			close(ch)
+		}
+	}
+}
+
+// events streams the backlog after the seq given as ?after=, then events as
+// they happen. The seq only counts if ?boot= matches this server's.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	var after uint64
+	if r.URL.Query().Get("boot") == s.boot {
+		after, _ = strconv.ParseUint(r.URL.Query().Get("after"), 10, 64)
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ch := make(chan Message, clientBuffer)
+	s.mu.Lock()
+	var missed []Message
+	for _, m := range s.backlog {
+		if m.Seq > after {
+			missed = append(missed, m)
+		}
+	}
+	if s.latest != nil && s.latest.Seq > after {
+		missed = append(missed, *s.latest)
+	}
+	s.followers[ch] = struct{}{}
+	s.mu.Unlock()
+	slog.Info("follower connected", "remote", r.RemoteAddr, "missed", len(missed))
+	defer slog.Info("follower disconnected", "remote", r.RemoteAddr)
+	defer s.unfollow(ch)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set(bootHeader, s.boot)
+	enc := json.NewEncoder(w)
+	for _, m := range missed {
+		if enc.Encode(m) != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case m, ok := <-ch:
+			if !ok || enc.Encode(m) != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// status replies with the session as the state file has it, or 404 when
+// none is running.
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var last *Message
+	if s.latest != nil {
+		last = s.latest
+	} else if len(s.backlog) > 0 {
+		last = &s.backlog[len(s.backlog)-1]
+	}
+	var st state.State
+	if last != nil {
+		st = state.FromEvent(last.Event, time.Now())
+		st.Label = s.label
+		st.Config = s.backlog[0].Config
+	}
+	s.mu.Unlock()
+
+	if last == nil || last.Event.Type == engine.EventSessionEnded {
+		http.Error(w, state.ErrNotRunning.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(st)
+}
+
+// color replies with the session's color as "#rrggbb", the palette's idle
+// color when none is running.
+func (s *Server) color(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var last *engine.TimerEvent
+	if s.latest != nil {
+		last = &s.latest.Event
+	} else if len(s.backlog) > 0 {
+		last = &s.backlog[len(s.backlog)-1].Event
+	}
+	c := s.palette.Color(last)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, c.Hex())
+}
+
+func (s *Server) unfollow(ch chan Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.followers[ch]; ok {
+		delete(s.followers, ch)
+		close(ch)
+	}
+}
+
+// command takes one command as the request body and replies like the
+// control socket, "ok <reply>" or "error <message>".
+func (s *Server) command(w http.ResponseWriter, r *http.Request) {
+	if s.control == nil {
+		http.Error(w, "remote control is not allowed (start the session with --share-control)", http.StatusForbidden)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 256))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(string(body))
+	slog.Info("remote command", "remote", r.RemoteAddr, "command", name)
+	if !remoteCommands[name] {
+		http.Error(w, fmt.Sprintf("%q cannot be sent remotely", name), http.StatusForbidden)
+		return
+	}
+
+	reply, err := s.control(name)
+	if err != nil {
+		fmt.Fprintf(w, "error %s\n", err)
+		return
+	}
+	fmt.Fprintf(w, "ok %s\n", reply)
+}
+
+// Shutdown disconnects followers and stops serving. It is not Close, so the
+// server outlives each session's subscribers.
+func (s *Server) Shutdown() error {
+	err := s.srv.Close()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,231 @@
+// Package sound plays what the [sounds] table sets for each cue: the
+// terminal bell, nothing, or a sound file through whichever player the
+// system has.
+package sound
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// Cue is a moment a sound can mark, named as in the [sounds] table.
+type Cue string
+
+const (
+	WorkStart      Cue = "work_start"
+	BreakStart     Cue = "break_start"
+	LongBreakStart Cue = "long_break_start"
+	SessionDone    Cue = "session_done"
+	Warning        Cue = "warning"
+)
+
+// Cues lists every cue, in the order a session reaches them.
+var Cues = []Cue{WorkStart, Warning, BreakStart, LongBreakStart, SessionDone}
+
+// PhaseStart is the cue for phase starting. A cooldown is a break.
+func PhaseStart(phase engine.Phase) Cue {
+	switch phase {
+	case engine.PhaseWork:
+		return WorkStart
+	case engine.PhaseLongBreak:
+		return LongBreakStart
+	default:
+		return BreakStart
+	}
+}
+
+const (
+	Bell = "bell"
+	None = "none"
+)
+
+// playTimeout bounds each file played, so a stuck player is not left
+// running.
+const playTimeout = 30 * time.Second
+
+// Player plays a sound file at a volume from 0 to 1.
+type Player interface {
+	Play(path string, volume float64) error
+}
+
+// Set is what each cue plays. A nil Set rings the bell for everything.
+type Set struct {
+	cues   map[Cue]string
+	volume float64
+	player Player
+	wg     sync.WaitGroup
+}
+
+// New checks cues, as config.Sounds has them, so a missing or unreadable
+// file is an error before the session starts. Paths may start with ~/. player plays the files; if
+// nil, the first of paplay, afplay, and aplay found is used.
+func New(cues map[string]string, volume float64, player Player) (*Set, error) {
+	s := &Set{cues: make(map[Cue]string), volume: volume, player: player}
+	var errs []error
+	files := false
+	for key, v := range cues {
+		if rest, ok := strings.CutPrefix(v, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			v = filepath.Join(home, rest)
+		}
+		s.cues[Cue(key)] = v
+		if v == Bell || v == None {
+			continue
+		}
+		files = true
+		if err := check(v); err != nil {
+			errs = append(errs, fmt.Errorf("sounds.%s: %w", key, err))
+		}
+	}
+	if files && s.player == nil {
+		p, err := FindPlayer()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		s.player = p
+	}
+	return s, errors.Join(errs...)
+}
+
+// check makes sure path is a file that can be read, and is not empty.
+func check(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() || info.Size() == 0 {
+		return fmt.Errorf("%s: not a sound file", path)
+	}
+	_, err = f.Read(make([]byte, 1))
+	return err
+}
+
+// Configured reports whether the config sets what c plays, as opposed to
+// it ringing the bell by default.
+func (s *Set) Configured(c Cue) bool {
+	if s == nil {
+		return false
+	}
+	_, ok := s.cues[c]
+	return ok
+}
+
+// Ring sounds c, reporting whether that means ringing the terminal bell,
+// which is left to the caller. A file plays in the background.
+func (s *Set) Ring(c Cue) (bell bool) {
+	if s == nil {
+		return true
+	}
+	switch v, ok := s.cues[c]; {
+	case !ok || v == Bell:
+		return true
+	case v == None:
+		return false
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if _, err := s.Play(c); err != nil {
+			slog.Warn("sound failed", "cue", c, "err", err)
+		}
+	}()
+	return false
+}
+
+// Play is Ring waiting for the file to finish, with whatever kept it from
+// playing.
+func (s *Set) Play(c Cue) (bell bool, err error) {
+	if s == nil {
+		return true, nil
+	}
+	switch v, ok := s.cues[c]; {
+	case !ok || v == Bell:
+		return true, nil
+	case v == None:
+		return false, nil
+	default:
+		if err := s.player.Play(v, s.volume); err != nil {
+			return false, fmt.Errorf("%s: %w", v, err)
+		}
+		return false, nil
+	}
+}
+
+// Sound is what c plays: Bell, None, or a file's path.
+func (s *Set) Sound(c Cue) string {
+	if s == nil {
+		return Bell
+	}
+	if v, ok := s.cues[c]; ok {
+		return v
+	}
+	return Bell
+}
+
+// Wait waits for the files playing to finish.
+func (s *Set) Wait() {
+	if s != nil {
+		s.wg.Wait()
+	}
+}
+
+// commandPlayer plays files with a command line player.
+type commandPlayer struct {
+	name string
+	args func(path string, volume float64) []string
+}
+
+// players are tried in order. paplay takes volume out of 65536 and afplay
+// as a multiplier; aplay has no volume.
+var players = []commandPlayer{
+	{"paplay", func(path string, volume float64) []string {
+		return []string{"--volume=" + strconv.Itoa(int(volume*65536)), path}
+	}},
+	{"afplay", func(path string, volume float64) []string {
+		return []string{"-v", strconv.FormatFloat(volume, 'f', 2, 64), path}
+	}},
+	{"aplay", func(path string, volume float64) []string {
+		return []string{"-q", path}
+	}},
+}
+
+// FindPlayer returns a Player for the first of paplay, afplay, and aplay on
+// PATH.
+func FindPlayer() (Player, error) {
+	var names []string
+	for _, p := range players {
+		if _, err := exec.LookPath(p.name); err == nil {
+			return p, nil
+		}
+		names = append(names, p.name)
+	}
+	return nil, fmt.Errorf("no sound player found (want %s)", strings.Join(names, ", "))
+}
+
+func (p commandPlayer) Play(path string, volume float64) error {
+	cmd := exec.Command(p.name, p.args(path, volume)...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	timer := time.AfterFunc(playTimeout, func() { cmd.Process.Kill() })
+	defer timer.Stop()
+	return cmd.Wait()
+}
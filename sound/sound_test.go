@@ -0,0 +1,176 @@
+package sound
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// fakePlayer records what it was asked to play, failing for fail.
+type fakePlayer struct {
+	mu     sync.Mutex
+	played []string
+	volume []float64
+	fail   string
+}
+
+func (f *fakePlayer) Play(path string, volume float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if path == f.fail {
+		return errors.New("player crashed")
+	}
+	f.played = append(f.played, filepath.Base(path))
+	f.volume = append(f.volume, volume)
+	return nil
+}
+
+// soundFile writes a file that passes for a sound into dir.
+func soundFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("RIFF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSet(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	soundFile(t, dir, "done.wav")
+	player := &fakePlayer{}
+	s, err := New(map[string]string{
+		"work_start":  soundFile(t, dir, "work.wav"),
+		"break_start": Bell,
+		"warning":     None,
+		// From home.
+		"session_done": "~/done.wav",
+	}, 0.4, player)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		cue        Cue
+		configured bool
+		bell       bool
+		sound      string
+		played     string
+	}{
+		{WorkStart, true, false, filepath.Join(dir, "work.wav"), "work.wav"},
+		{BreakStart, true, true, Bell, ""},
+		{Warning, true, false, None, ""},
+		{SessionDone, true, false, filepath.Join(dir, "done.wav"), "done.wav"},
+		// Not in the table, the bell.
+		{LongBreakStart, false, true, Bell, ""},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.cue), func(t *testing.T) {
+			player.played = nil
+			if got := s.Configured(tt.cue); got != tt.configured {
+				t.Errorf("configured %v, want %v", got, tt.configured)
+			}
+			if got := s.Sound(tt.cue); got != tt.sound {
+				t.Errorf("sound %q, want %q", got, tt.sound)
+			}
+			bell, err := s.Play(tt.cue)
+			if bell != tt.bell || err != nil {
+				t.Errorf("Play: bell %v, %v, want %v", bell, err, tt.bell)
+			}
+			if bell := s.Ring(tt.cue); bell != tt.bell {
+				t.Errorf("Ring: bell %v, want %v", bell, tt.bell)
+			}
+			s.Wait()
+			var want []string
+			if tt.played != "" {
+				want = []string{tt.played, tt.played}
+			}
+			if !slices.Equal(player.played, want) {
+				t.Errorf("played %v, want %v", player.played, want)
+			}
+		})
+	}
+	for _, v := range player.volume {
+		if v != 0.4 {
+			t.Errorf("played at volume %g, want 0.4", v)
+		}
+	}
+}
+
+func TestNilSetRingsBell(t *testing.T) {
+	var s *Set
+	for _, c := range Cues {
+		if !s.Ring(c) || s.Configured(c) || s.Sound(c) != Bell {
+			t.Errorf("%s: want the bell, unconfigured", c)
+		}
+		if bell, err := s.Play(c); !bell || err != nil {
+			t.Errorf("%s: Play %v, %v, want the bell", c, bell, err)
+		}
+	}
+	s.Wait()
+}
+
+func TestPlayFails(t *testing.T) {
+	dir := t.TempDir()
+	path := soundFile(t, dir, "work.wav")
+	s, err := New(map[string]string{"work_start": path}, 1, &fakePlayer{fail: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Play(WorkStart); err == nil || err.Error() != path+": player crashed" {
+		t.Errorf("got %v, want the file and the player's error", err)
+	}
+	// Rung, the failure is only logged.
+	if bell := s.Ring(WorkStart); bell {
+		t.Error("rang the bell for a file that failed")
+	}
+	s.Wait()
+}
+
+// TestNewChecksFiles has every bad file reported before a session starts.
+func TestNewChecksFiles(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.wav")
+	os.WriteFile(empty, nil, 0o644)
+	_, err := New(map[string]string{
+		"work_start":  filepath.Join(dir, "typo.wav"),
+		"break_start": empty,
+		"warning":     dir,
+		"bell":        Bell,
+	}, 1, &fakePlayer{})
+	if err == nil {
+		t.Fatal("no error")
+	}
+	for _, want := range []string{
+		"sounds.work_start: open " + filepath.Join(dir, "typo.wav") + ": no such file or directory",
+		"sounds.break_start: " + empty + ": not a sound file",
+		"sounds.warning: ",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q leaves out %q", err, want)
+		}
+	}
+	if n := strings.Count(err.Error(), "\n") + 1; n != 3 {
+		t.Errorf("%d errors, want 3:\n%s", n, err)
+	}
+}
+
+func TestPhaseStart(t *testing.T) {
+	for phase, want := range map[engine.Phase]Cue{
+		engine.PhaseWork:       WorkStart,
+		engine.PhaseShortBreak: BreakStart,
+		engine.PhaseLongBreak:  LongBreakStart,
+		engine.PhaseCooldown:   BreakStart,
+	} {
+		if got := PhaseStart(phase); got != want {
+			t.Errorf("%s: %s, want %s", phase, got, want)
+		}
+	}
+}
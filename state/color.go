@@ -0,0 +1,82 @@
+package state
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// RGB is a color for lights to show the session by.
+type RGB struct{ R, G, B uint8 }
+
+// ParseRGB reads a color as "#rrggbb".
+func ParseRGB(s string) (RGB, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return RGB{}, fmt.Errorf("invalid color %q (want #rrggbb)", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return RGB{}, fmt.Errorf("invalid color %q (want #rrggbb)", s)
+	}
+	return RGB{uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+func (c RGB) Hex() string { return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B) }
+
+func (c RGB) scale(f float64) RGB {
+	return RGB{uint8(float64(c.R)*f + 0.5), uint8(float64(c.G)*f + 0.5), uint8(float64(c.B)*f + 0.5)}
+}
+
+// Palette maps what a session is doing to a color, for home automation to
+// bind lights to.
+type Palette struct {
+	Work, Short, Long, Cooldown, Paused, Idle RGB
+	// Fade dims a running phase's color as it goes, from full at its start
+	// to MinBrightness at its end, in steps of a tenth.
+	Fade          bool
+	MinBrightness float64
+}
+
+var DefaultPalette = Palette{
+	Work:          RGB{255, 0, 0},
+	Short:         RGB{0, 255, 0},
+	Long:          RGB{0, 0, 255},
+	Cooldown:      RGB{128, 0, 255},
+	Paused:        RGB{255, 191, 0},
+	Idle:          RGB{0, 0, 0},
+	MinBrightness: 0.2,
+}
+
+// Color is the color for e, or Idle for nil, when no session is running.
+// Transitions and snoozes show the phase they lead to.
+func (p Palette) Color(e *engine.TimerEvent) RGB {
+	if e == nil || e.Type == engine.EventSessionEnded {
+		return p.Idle
+	}
+	if e.Paused {
+		return p.Paused
+	}
+	var c RGB
+	switch e.Phase {
+	case engine.PhaseWork:
+		c = p.Work
+	case engine.PhaseShortBreak:
+		c = p.Short
+	case engine.PhaseLongBreak:
+		c = p.Long
+	case engine.PhaseCooldown:
+		c = p.Cooldown
+	case engine.PhaseWarmup:
+		// Warmup is neither work nor a break, and the light stays as it
+		// was between sessions.
+		return p.Idle
+	default:
+		return p.Idle
+	}
+	if !p.Fade || e.Type != engine.EventTick {
+		return c
+	}
+	step := float64(int(e.Fraction*10)) / 10
+	return c.scale(1 - step*(1-p.MinBrightness))
+}
@@ -0,0 +1,37 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+func TestEveryPhaseHasAColor(t *testing.T) {
+	p := DefaultPalette
+	want := map[engine.Phase]RGB{
+		engine.PhaseWork:       p.Work,
+		engine.PhaseShortBreak: p.Short,
+		engine.PhaseLongBreak:  p.Long,
+		engine.PhaseCooldown:   p.Cooldown,
+		// Warmup leaves the lights as they were.
+		engine.PhaseWarmup: p.Idle,
+	}
+	for phase := engine.PhaseWork; phase < engine.PhaseDone; phase++ {
+		c, ok := want[phase]
+		if !ok {
+			t.Errorf("no color expected for %s", phase)
+			continue
+		}
+		e := engine.TimerEvent{Type: engine.EventTick, Phase: phase}
+		if got := p.Color(&e); got != c {
+			t.Errorf("%s: %s, want %s", phase, got.Hex(), c.Hex())
+		}
+		e.Paused = true
+		if got := p.Color(&e); got != p.Paused {
+			t.Errorf("%s paused: %s, want %s", phase, got.Hex(), p.Paused.Hex())
+		}
+	}
+	if got := p.Color(nil); got != p.Idle {
+		t.Errorf("no session: %s, want %s", got.Hex(), p.Idle.Hex())
+	}
+}
@@ -0,0 +1,8 @@
+package state
+
+import _ "embed"
+
+// Schema is the JSON schema of the state file at Version.
+//
+//go:embed schema.json
+var Schema []byte
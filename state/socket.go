@@ -0,0 +1,113 @@
+package state
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrInUse = errors.New("another pomo session owns the control socket")
+
+// Handler executes one control command, returning a short reply.
+type Handler func(command string) (string, error)
+
+// Server answers one newline-terminated command per connection with
+// "ok <reply>" or "error <message>".
+type Server struct {
+	ln   net.Listener
+	path string
+}
+
+func Listen(path string, h Handler) (*Server, error) {
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return nil, ErrInUse
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	os.Remove(path)
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+
+	s := &Server{ln: ln, path: path}
+	go s.serve(h)
+	return s, nil
+}
+
+func (s *Server) serve(h Handler) {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go handle(conn, h)
+	}
+}
+
+func handle(conn net.Conn, h Handler) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return
+	}
+
+	reply, err := h(strings.TrimSpace(line))
+	if err != nil {
+		fmt.Fprintf(conn, "error %s\n", err)
+		return
+	}
+	fmt.Fprintf(conn, "ok %s\n", reply)
+}
+
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	os.Remove(s.path)
+	return err
+}
+
+// Send delivers a command to the running session and returns its reply.
+// Without a session to take it, it fails with ErrNotRunning, whether no
+// one is listening or the listener is between sessions.
+func Send(path, command string) (string, error) {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return "", ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := fmt.Fprintf(conn, "%s\n", command); err != nil {
+		return "", err
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSpace(line)
+
+	if reply, ok := strings.CutPrefix(line, "ok"); ok {
+		return strings.TrimSpace(reply), nil
+	}
+	msg := strings.TrimSpace(strings.TrimPrefix(line, "error"))
+	if msg == ErrNotRunning.Error() {
+		return "", ErrNotRunning
+	}
+	return "", errors.New(msg)
+}
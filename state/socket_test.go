@@ -0,0 +1,45 @@
+package state
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestSendNotRunning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pomo.sock")
+	if _, err := Send(path, "pause"); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Send with no one listening: %v, want ErrNotRunning", err)
+	}
+
+	// Between sessions the listener is up but has no session to control.
+	s, err := Listen(path, func(string) (string, error) { return "", ErrNotRunning })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := Send(path, "pause"); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Send between sessions: %v, want ErrNotRunning", err)
+	}
+}
+
+func TestSendReply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pomo.sock")
+	s, err := Listen(path, func(command string) (string, error) {
+		if command == "pause" {
+			return "paused", nil
+		}
+		return "", errors.New("unknown command " + command)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if reply, err := Send(path, "pause"); err != nil || reply != "paused" {
+		t.Errorf("Send pause: %q, %v", reply, err)
+	}
+	if _, err := Send(path, "dance"); err == nil || err.Error() != "unknown command dance" || errors.Is(err, ErrNotRunning) {
+		t.Errorf("Send dance: %v, want the session's error", err)
+	}
+}
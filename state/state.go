@@ -0,0 +1,247 @@
+// Package state publishes the running session to a file and a control
+// socket so other pomo commands and scripts can observe and steer it.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/paths"
+)
+
+var ErrNotRunning = errors.New("no pomo session is running")
+
+// Version is the state file's schema version, printed by pomo state
+// schema. Fields are only ever added within a version, which readers
+// ignore until they know them; anything else takes a new version, which
+// older readers refuse rather than misread.
+const Version = 1
+
+// VersionError is Read's error for a state file written under a newer
+// Version than this pomo knows.
+type VersionError struct {
+	Version int
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("state file is version %d, newer than the %d this pomo reads — upgrade pomo", e.Version, Version)
+}
+
+// RefreshEvery is the longest a Writer leaves the state file alone, paused
+// or between phases, so that one much older was most likely left behind by
+// a session that died.
+const RefreshEvery = time.Minute
+
+// StaleError is ReadFresh's error for a state file left behind. It counts
+// as ErrNotRunning.
+type StaleError struct {
+	UpdatedAt time.Time
+	Age       time.Duration
+}
+
+func (e *StaleError) Error() string {
+	return "stale — last update " + approxAge(e.Age) + " ago"
+}
+
+func (e *StaleError) Is(target error) bool { return target == ErrNotRunning }
+
+// approxAge renders an age in its largest unit, e.g. "2d", "5h", or "12m".
+func approxAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
+type State struct {
+	// Version is 0 in files written before versions, which read as
+	// version 1.
+	Version     int    `json:"version"`
+	PID         int    `json:"pid"`
+	Phase       string `json:"phase"`
+	Paused      bool   `json:"paused"`
+	PausedMS    int64  `json:"paused_ms"`
+	ElapsedMS   int64  `json:"elapsed_ms"`
+	RemainingMS int64  `json:"remaining_ms"`
+	TotalMS     int64  `json:"total_ms"`
+	Cycle       int    `json:"cycle"`
+	TotalCycles int    `json:"total_cycles"`
+	// UntilLong and UntilLongMS follow TimerEvent's countdowns to the next
+	// long break.
+	UntilLong   int    `json:"until_long"`
+	UntilLongMS int64  `json:"until_long_ms"`
+	Label       string `json:"label,omitempty"`
+	// StartedAt and EndedAt follow TimerEvent's PhaseStartedAt: EndedAt is
+	// when the phase is due to end, if it is not paused again.
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Config is the session's settings, once it has started.
+	Config *engine.ConfigJSON `json:"config,omitempty"`
+}
+
+func FromEvent(e engine.TimerEvent, now time.Time) State {
+	cycle := e.CycleNum
+	if e.Phase != engine.PhaseWork {
+		cycle--
+	}
+
+	return State{
+		Version:     Version,
+		PID:         os.Getpid(),
+		Phase:       e.Phase.String(),
+		Paused:      e.Paused,
+		PausedMS:    e.PausedTotal.Milliseconds(),
+		ElapsedMS:   e.Elapsed.Milliseconds(),
+		RemainingMS: e.Remaining.Milliseconds(),
+		TotalMS:     e.Total.Milliseconds(),
+		Cycle:       cycle,
+		TotalCycles: e.TotalCycles,
+		UntilLong:   e.UntilLongBreak,
+		UntilLongMS: e.WorkUntilLongBreak.Milliseconds(),
+		StartedAt:   e.PhaseStartedAt,
+		EndedAt:     e.PhaseStartedAt.Add(e.Total),
+		UpdatedAt:   now,
+	}
+}
+
+// RemainingAt extrapolates the remaining time from the last update, since
+// the file is only rewritten about once per second.
+func (s State) RemainingAt(now time.Time) time.Duration {
+	remaining := time.Duration(s.RemainingMS) * time.Millisecond
+	if !s.Paused {
+		remaining -= now.Sub(s.UpdatedAt)
+	}
+	return max(remaining, 0)
+}
+
+// EventAt rebuilds the fields of a TimerEvent the file records, as of now.
+func (s State) EventAt(now time.Time) engine.TimerEvent {
+	phase, _ := engine.ParsePhase(s.Phase)
+	remaining := s.RemainingAt(now)
+	total := time.Duration(s.TotalMS) * time.Millisecond
+	elapsed := max(total-remaining, 0)
+
+	cycle := s.Cycle
+	if phase != engine.PhaseWork {
+		cycle++
+	}
+
+	e := engine.TimerEvent{
+		Phase:       phase,
+		Elapsed:     elapsed,
+		Remaining:   remaining,
+		Total:       total,
+		Paused:      s.Paused,
+		PausedTotal: time.Duration(s.PausedMS) * time.Millisecond,
+		CycleNum:    cycle,
+		TotalCycles: s.TotalCycles,
+
+		UntilLongBreak:     s.UntilLong,
+		WorkUntilLongBreak: time.Duration(s.UntilLongMS) * time.Millisecond,
+		PhaseStartedAt:     s.StartedAt,
+	}
+	if s.Paused && !s.StartedAt.IsZero() {
+		e.PhaseStartedAt = s.StartedAt.Add(now.Sub(s.UpdatedAt))
+	}
+	if phase == engine.PhaseWork && e.WorkUntilLongBreak > 0 {
+		e.WorkUntilLongBreak = max(e.WorkUntilLongBreak-(elapsed-time.Duration(s.ElapsedMS)*time.Millisecond), 0)
+	}
+	if total > 0 {
+		e.Fraction = float64(elapsed) / float64(total)
+	}
+	return e
+}
+
+// Dir is where state and the log are kept, as paths.State has it.
+func Dir() (string, error) {
+	return paths.State()
+}
+
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "state.json"), nil
+}
+
+// LogPath is the diagnostics log, including what happened while nobody
+// could see the terminal.
+func LogPath() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "log", "pomo.log"), nil
+}
+
+// SocketPath is in paths.Runtime.
+func SocketPath() (string, error) {
+	dir, err := paths.Runtime()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pomo.sock"), nil
+}
+
+func Read(path string) (State, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return State{}, ErrNotRunning
+	}
+	if err != nil {
+		return State{}, err
+	}
+
+	// The version comes first, as a newer one may not fit State at all.
+	var v struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return State{}, err
+	}
+	if v.Version > Version {
+		return State{}, &VersionError{Version: v.Version}
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return State{}, err
+	}
+	return s, nil
+}
+
+// ReadFresh is Read for a file modified within staleAfter of now, which a
+// Writer sees to at least every RefreshEvery. An older one is removed, so
+// only the first reader to find it reports a StaleError. Zero staleAfter
+// takes any file as current.
+func ReadFresh(path string, staleAfter time.Duration, now time.Time) (State, error) {
+	s, err := Read(path)
+	if err != nil || staleAfter <= 0 {
+		return s, err
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return State{}, ErrNotRunning
+	}
+	if err != nil {
+		return State{}, err
+	}
+	if age := now.Sub(info.ModTime()); age > staleAfter {
+		os.Remove(path)
+		return State{}, &StaleError{UpdatedAt: info.ModTime(), Age: age}
+	}
+	return s, nil
+}
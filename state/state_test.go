@@ -0,0 +1,242 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// writeState has a Writer publish a work phase to path, as a session
+// would, and returns when the file was written.
+func writeState(t *testing.T, path string) time.Time {
+	t.Helper()
+	w, err := NewWriter(path, "write tests", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Handle(engine.TimerEvent{Type: engine.EventTick, Phase: engine.PhaseWork, Total: 25 * time.Minute, Remaining: 20 * time.Minute, CycleNum: 1})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.ModTime()
+}
+
+func TestReadFresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	written := writeState(t, path)
+
+	s, err := ReadFresh(path, 10*time.Minute, written.Add(10*time.Minute))
+	if err != nil {
+		t.Fatalf("fresh file: %v", err)
+	}
+	if s.Phase != "Work" || s.Label != "write tests" {
+		t.Errorf("read %+v", s)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("fresh file removed: %v", err)
+	}
+}
+
+func TestReadFreshBackDated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	writeState(t, path)
+	now := time.Now()
+	// Left behind two days ago by a session that died.
+	old := now.Add(-49 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ReadFresh(path, 10*time.Minute, now)
+	var stale *StaleError
+	if !errors.As(err, &stale) {
+		t.Fatalf("back-dated file: %v, want a StaleError", err)
+	}
+	if !errors.Is(err, ErrNotRunning) {
+		t.Error("StaleError does not count as ErrNotRunning")
+	}
+	if got, want := err.Error(), "stale — last update 2d ago"; got != want {
+		t.Errorf("error %q, want %q", got, want)
+	}
+	if !stale.UpdatedAt.Equal(old) || stale.Age != 49*time.Hour {
+		t.Errorf("stale since %s, %s ago, want %s, 49h ago", stale.UpdatedAt, stale.Age, old)
+	}
+
+	// It is cleaned up, so the next reader finds nothing running.
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("stale file still there: %v", err)
+	}
+	if _, err := ReadFresh(path, 10*time.Minute, now); err != ErrNotRunning {
+		t.Errorf("second read: %v, want ErrNotRunning", err)
+	}
+}
+
+func TestReadFreshThreshold(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	writeState(t, path)
+	now := time.Now()
+	old := now.Add(-10 * time.Minute)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// Exactly at the threshold is still fresh, and zero takes any age.
+	if _, err := ReadFresh(path, 10*time.Minute, now); err != nil {
+		t.Errorf("at the threshold: %v", err)
+	}
+	if _, err := ReadFresh(path, 0, now.Add(100*time.Hour)); err != nil {
+		t.Errorf("no threshold: %v", err)
+	}
+	if _, err := ReadFresh(path, 10*time.Minute, now.Add(time.Second)); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("past the threshold: %v, want stale", err)
+	}
+}
+
+func TestApproxAge(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		5 * time.Second:            "5s",
+		59 * time.Second:           "59s",
+		time.Minute:                "1m",
+		59 * time.Minute:           "59m",
+		time.Hour:                  "1h",
+		23*time.Hour + time.Minute: "23h",
+		24 * time.Hour:             "1d",
+		100 * time.Hour:            "4d",
+	} {
+		if got := approxAge(d); got != want {
+			t.Errorf("approxAge(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+// TestReadVersions reads a state file as written by each version of pomo,
+// each paused 10m30s into the second of four 25m work phases: from before
+// versions, with only the first fields and then with all of them; at
+// version 1; and at version 1 with fields added since.
+func TestReadVersions(t *testing.T) {
+	updated := time.Date(2025, 1, 6, 9, 40, 30, 0, time.UTC)
+	started := time.Date(2025, 1, 6, 9, 29, 15, 0, time.UTC)
+	want := engine.TimerEvent{
+		Phase:              engine.PhaseWork,
+		Elapsed:            10*time.Minute + 30*time.Second,
+		Remaining:          14*time.Minute + 30*time.Second,
+		Total:              25 * time.Minute,
+		Paused:             true,
+		PausedTotal:        45 * time.Second,
+		CycleNum:           2,
+		TotalCycles:        4,
+		UntilLongBreak:     2,
+		WorkUntilLongBreak: 39*time.Minute + 30*time.Second,
+		PhaseStartedAt:     started,
+		Fraction:           0.42,
+	}
+	config := &engine.ConfigJSON{WorkMS: 1500000, ShortBreakMS: 300000, LongBreakMS: 900000, LongBreakEvery: 4, TotalCycles: 4, Label: "essay", Profile: "writing"}
+	tests := []struct {
+		file    string
+		version int
+		label   string
+		config  *engine.ConfigJSON
+		// first leaves out what the first state files did not have.
+		first bool
+	}{
+		{"v0-first.json", 0, "", nil, true},
+		{"v0.json", 0, "essay", nil, false},
+		{"v1.json", 1, "essay", config, false},
+		{"v1-added.json", 1, "essay", config, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			s, err := Read(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Version != tt.version || s.PID != 4242 || s.Label != tt.label || !s.UpdatedAt.Equal(updated) {
+				t.Errorf("version %d, pid %d, label %q, updated %s", s.Version, s.PID, s.Label, s.UpdatedAt)
+			}
+			if (s.Config == nil) != (tt.config == nil) || s.Config != nil && *s.Config != *tt.config {
+				t.Errorf("config %+v, want %+v", s.Config, tt.config)
+			}
+
+			want := want
+			if tt.first {
+				want.PausedTotal, want.UntilLongBreak, want.WorkUntilLongBreak, want.PhaseStartedAt = 0, 0, 0, time.Time{}
+			}
+			// An hour after the update, paused, nothing has moved on but
+			// the phase's start.
+			e := s.EventAt(updated.Add(time.Hour))
+			if !want.PhaseStartedAt.IsZero() {
+				want.PhaseStartedAt = want.PhaseStartedAt.Add(time.Hour)
+			}
+			if !reflect.DeepEqual(e, want) {
+				t.Errorf("read as\n%+v\nwant\n%+v", e, want)
+			}
+		})
+	}
+}
+
+func TestReadRefusesNewerVersion(t *testing.T) {
+	_, err := Read(filepath.Join("testdata", "v2.json"))
+	var v *VersionError
+	if !errors.As(err, &v) || v.Version != 2 {
+		t.Fatalf("got %v, want a VersionError for version 2", err)
+	}
+	if want := "state file is version 2, newer than the 1 this pomo reads — upgrade pomo"; err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+}
+
+// TestWriterWritesVersion checks what a Writer writes reads back at the
+// current Version.
+func TestWriterWritesVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	writeState(t, path)
+	s, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != Version {
+		t.Errorf("written at version %d, want %d", s.Version, Version)
+	}
+}
+
+// TestSchemaDocumentsState checks the schema is for Version, names every
+// field State writes, and lists every phase a session runs.
+func TestSchemaDocumentsState(t *testing.T) {
+	var schema struct {
+		Properties map[string]struct {
+			Const *int     `json:"const"`
+			Enum  []string `json:"enum"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(Schema, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if v := schema.Properties["version"].Const; v == nil || *v != Version {
+		t.Errorf("schema is for version %v, want %d", v, Version)
+	}
+	fields := reflect.TypeFor[State]()
+	for i := range fields.NumField() {
+		name, _, _ := strings.Cut(fields.Field(i).Tag.Get("json"), ",")
+		if _, ok := schema.Properties[name]; !ok {
+			t.Errorf("schema leaves out %s", name)
+		}
+	}
+	enum := schema.Properties["phase"].Enum
+	for p := engine.PhaseWork; p < engine.PhaseDone; p++ {
+		if !slices.Contains(enum, p.String()) {
+			t.Errorf("schema phases %q leave out %s", enum, p)
+		}
+	}
+}
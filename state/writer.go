@@ -0,0 +1,96 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/fsutil"
+)
+
+// Writer keeps the state file current while a session runs and removes it
+// when the session ends.
+type Writer struct {
+	path    string
+	label   string
+	profile string
+	config  *engine.ConfigJSON
+	last    State
+	err     error
+}
+
+// NewWriter publishes label and the profile named with the session's
+// settings.
+func NewWriter(path, label, profile string) (*Writer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	return &Writer{path: path, label: label, profile: profile}, nil
+}
+
+func (w *Writer) Handle(e engine.TimerEvent) {
+	switch e.Type {
+	case engine.EventSessionStarted:
+		if e.Config != nil {
+			c := e.Config.JSON(w.label, w.profile)
+			w.config = &c
+		}
+		return
+	case engine.EventTransition, engine.EventSnooze, engine.EventAway:
+		// Nothing is running, but the file still has to look alive.
+		if now := time.Now(); w.last.PID != 0 && now.Sub(w.last.UpdatedAt) >= RefreshEvery {
+			s := w.last
+			s.RemainingMS, s.UpdatedAt = 0, now
+			w.write(s)
+		}
+		return
+	case engine.EventSessionEnded:
+		w.remove()
+		return
+	}
+
+	s := FromEvent(e, time.Now())
+	s.Label = w.label
+	s.Config = w.config
+	if w.changed(s) {
+		w.write(s)
+	}
+}
+
+func (w *Writer) write(s State) {
+	if w.err != nil {
+		return
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		w.err = err
+		return
+	}
+	if err := fsutil.WriteFileAtomic(w.path, data, 0o600); err != nil {
+		w.err = err
+		return
+	}
+	w.last = s
+}
+
+// changed limits rewrites to once per displayed second, plus any phase or
+// pause change, and at least every RefreshEvery.
+func (w *Writer) changed(s State) bool {
+	return s.Phase != w.last.Phase ||
+		s.Cycle != w.last.Cycle ||
+		s.Paused != w.last.Paused ||
+		s.RemainingMS/1000 != w.last.RemainingMS/1000 ||
+		s.UpdatedAt.Sub(w.last.UpdatedAt) >= RefreshEvery
+}
+
+func (w *Writer) remove() {
+	if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) && w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *Writer) Close() error {
+	return w.err
+}
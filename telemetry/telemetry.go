@@ -0,0 +1,165 @@
+// Package telemetry sends the daily usage ping a [telemetry] table can opt
+// into: a random install ID, pomo's version, and how many pomodoros were
+// completed since the last ping, nothing more. Callers hold a Reporter, so
+// with telemetry off nothing here runs at all.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"github.com/steenfuentes/pomo/fsutil"
+)
+
+// Every is how often a ping is due.
+const Every = 24 * time.Hour
+
+// Timeout bounds each ping.
+const Timeout = 10 * time.Second
+
+// Payload is all a ping sends.
+type Payload struct {
+	InstallID string `json:"install_id"`
+	Version   string `json:"version"`
+	Pomodoros int    `json:"pomodoros_completed"`
+}
+
+// State is what the state file keeps between pings. The install ID is
+// made up the first time one is due, and forgotten by Forget.
+type State struct {
+	InstallID string    `json:"install_id"`
+	LastSent  time.Time `json:"last_sent"`
+}
+
+// Reporter sends pings. New returns one that never does when telemetry is
+// off.
+type Reporter interface {
+	// Next is the exact body the ping due at now would send, counting
+	// pomodoros with count from the last ping on, or nil if none is due.
+	Next(now time.Time, count func(since time.Time) int) ([]byte, error)
+	// Send posts body, as Next made it, and records it sent at now.
+	Send(ctx context.Context, now time.Time, body []byte) error
+}
+
+// New returns the Reporter for the config's settings, keeping its state in
+// statePath.
+func New(enabled bool, endpoint, statePath string) Reporter {
+	if !enabled {
+		return off{}
+	}
+	return &pinger{http: &http.Client{Timeout: Timeout}, endpoint: endpoint, path: statePath}
+}
+
+type off struct{}
+
+func (off) Next(time.Time, func(time.Time) int) ([]byte, error) { return nil, nil }
+func (off) Send(context.Context, time.Time, []byte) error       { return nil }
+
+type pinger struct {
+	http     *http.Client
+	endpoint string
+	path     string
+}
+
+func (p *pinger) Next(now time.Time, count func(since time.Time) int) ([]byte, error) {
+	s, err := ReadState(p.path)
+	if err != nil {
+		return nil, err
+	}
+	if !s.LastSent.IsZero() && now.Sub(s.LastSent) < Every {
+		return nil, nil
+	}
+	if s.InstallID == "" {
+		id := make([]byte, 16)
+		rand.Read(id)
+		s.InstallID = hex.EncodeToString(id)
+		if err := writeState(p.path, s); err != nil {
+			return nil, err
+		}
+	}
+	since := s.LastSent
+	if since.IsZero() {
+		since = now.Add(-Every)
+	}
+	return json.Marshal(Payload{InstallID: s.InstallID, Version: Version(), Pomodoros: count(since)})
+}
+
+func (p *pinger) Send(ctx context.Context, now time.Time, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s: %s", p.endpoint, resp.Status)
+	}
+
+	s, err := ReadState(p.path)
+	if err != nil {
+		return err
+	}
+	s.LastSent = now
+	return writeState(p.path, s)
+}
+
+// Version is pomo's module version, or "(devel)" for a build from a
+// checkout.
+func Version() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// ReadState reads the state file. A missing file is an empty state.
+func ReadState(path string) (State, error) {
+	var s State
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+func writeState(path string, s State) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(path, append(data, '\n'), 0o600)
+}
+
+// Forget removes the state file, install ID and all, so pings sent after
+// telemetry is turned back on cannot be tied to earlier ones.
+func Forget(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
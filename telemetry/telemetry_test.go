@@ -0,0 +1,134 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+// forbidden is an endpoint that fails the test if anything reaches it.
+func forbidden(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("telemetry off, but got %s %s", r.Method, r.URL)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestOffSendsNothing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "telemetry.json")
+	r := New(false, forbidden(t), path)
+
+	body, err := r.Next(testNow, func(time.Time) int {
+		t.Error("telemetry off, but history was counted")
+		return 0
+	})
+	if body != nil || err != nil {
+		t.Errorf("Next = %q, %v, want nothing due", body, err)
+	}
+	if err := r.Send(context.Background(), testNow, []byte(`{}`)); err != nil {
+		t.Errorf("Send: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("state written with telemetry off: %v", err)
+	}
+}
+
+func TestPing(t *testing.T) {
+	var got [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		got = append(got, body)
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "telemetry.json")
+	r := New(true, srv.URL, path)
+
+	var counted []time.Time
+	count := func(since time.Time) int {
+		counted = append(counted, since)
+		return 3
+	}
+	body, err := r.Next(testNow, count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if want := []string{"install_id", "pomodoros_completed", "version"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("payload sends %v, want only %v", keys, want)
+	}
+	if fields["pomodoros_completed"] != 3.0 || len(fields["install_id"].(string)) != 32 {
+		t.Errorf("payload %s, want 3 pomodoros and a 32-digit install ID", body)
+	}
+	if err := r.Send(context.Background(), testNow, body); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || string(got[0]) != string(body) {
+		t.Fatalf("server got %q, want exactly %q", got, body)
+	}
+
+	// Not due again for a day, then counted from the last ping.
+	if body, err := r.Next(testNow.Add(Every-time.Second), count); body != nil || err != nil {
+		t.Errorf("Next within a day = %q, %v, want nothing due", body, err)
+	}
+	again, err := r.Next(testNow.Add(Every), count)
+	if err != nil || again == nil {
+		t.Fatalf("Next a day on = %q, %v, want a ping due", again, err)
+	}
+	if want := []time.Time{testNow.Add(-Every), testNow}; !reflect.DeepEqual(counted, want) {
+		t.Errorf("counted since %v, want %v", counted, want)
+	}
+	var p Payload
+	if err := json.Unmarshal(again, &p); err != nil || p.InstallID != fields["install_id"] {
+		t.Errorf("second ping %s, want the same install ID", again)
+	}
+
+	if err := Forget(path); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := ReadState(path); err != nil || s != (State{}) {
+		t.Errorf("state after Forget %+v, %v, want none", s, err)
+	}
+}
+
+func TestSendFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "telemetry.json")
+	r := New(true, srv.URL, path)
+	body, err := r.Next(testNow, func(time.Time) int { return 0 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Send(context.Background(), testNow, body); err == nil {
+		t.Error("Send succeeded against a 503")
+	}
+	// Not recorded as sent, so it is due again.
+	if body, err := r.Next(testNow.Add(time.Minute), func(time.Time) int { return 0 }); body == nil || err != nil {
+		t.Errorf("Next after a failed ping = %q, %v, want it due again", body, err)
+	}
+}
@@ -0,0 +1,184 @@
+// Package tracing exports each session as an OpenTelemetry trace: a span
+// for the session with a child span per phase, sent over OTLP/HTTP.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer is a subscriber that turns timer events into spans. Span times
+// come from the session's clock rather than when events are handled.
+type Tracer struct {
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+	clock    engine.Clock
+	label    string
+	timeout  time.Duration
+	setup    chan error
+	// exportErr is the latest error the SDK reported while exporting.
+	exportErr atomic.Pointer[error]
+
+	session     context.Context
+	sessionSpan trace.Span
+	phaseSpan   trace.Span
+	phase       engine.TimerEvent
+}
+
+// New starts exporting to endpoint, a host:port or URL; empty means the
+// OTEL_EXPORTER_OTLP_* variables or localhost:4318. The exporter is set up
+// in the background, so spans can start at once: they are only sent when
+// they end, by which time it is ready, or was given up on after timeout.
+// timeout also bounds the flush in Shutdown.
+func New(endpoint string, clock engine.Clock, label string, timeout time.Duration) *Tracer {
+	res, _ := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName("pomo")))
+	provider := sdktrace.NewTracerProvider(sdktrace.WithResource(res))
+	setup := make(chan error, 1)
+
+	go func() {
+		var opts []otlptracehttp.Option
+		switch {
+		case strings.HasPrefix(endpoint, "http://"), strings.HasPrefix(endpoint, "https://"):
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		case endpoint != "":
+			opts = append(opts, otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure())
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err == nil {
+			provider.RegisterSpanProcessor(sdktrace.NewBatchSpanProcessor(exporter))
+		}
+		setup <- err
+	}()
+
+	t := newTracer(provider, setup, clock, label, timeout)
+	// The SDK logs export errors by default, which would land on top of
+	// the progress bars.
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		t.exportErr.Store(&err)
+	}))
+	return t
+}
+
+// newTracer traces to provider once setup reports it ready.
+func newTracer(provider *sdktrace.TracerProvider, setup chan error, clock engine.Clock, label string, timeout time.Duration) *Tracer {
+	return &Tracer{
+		provider: provider,
+		tracer:   provider.Tracer("github.com/steenfuentes/pomo"),
+		clock:    clock,
+		label:    label,
+		timeout:  timeout,
+		setup:    setup,
+		session:  context.Background(),
+	}
+}
+
+func (t *Tracer) Handle(e engine.TimerEvent) {
+	now := t.clock.Now()
+
+	switch e.Type {
+	case engine.EventSessionStarted:
+		attrs := []attribute.KeyValue{attribute.Int("pomo.cycles", e.TotalCycles)}
+		if t.label != "" {
+			attrs = append(attrs, attribute.String("pomo.label", t.label))
+		}
+		t.session, t.sessionSpan = t.tracer.Start(context.Background(), "session",
+			trace.WithTimestamp(now), trace.WithAttributes(attrs...))
+		return
+
+	case engine.EventSessionEnded:
+		if t.phaseSpan != nil {
+			t.phase.Ended = engine.EndInterrupted
+			t.endPhase(now)
+		}
+		if t.sessionSpan != nil {
+			s := e.Summary
+			t.sessionSpan.SetAttributes(
+				attribute.String("pomo.ended", string(s.Ended)),
+				attribute.Bool("pomo.stopped", s.Stopped),
+				attribute.Int("pomo.cycles_complete", s.CyclesComplete),
+				attribute.Float64("pomo.work_seconds", s.Work.Seconds()),
+			)
+			if s.Ended == engine.EndInterrupted {
+				t.sessionSpan.SetStatus(codes.Error, "interrupted")
+			}
+			t.sessionSpan.End(trace.WithTimestamp(now))
+			t.sessionSpan = nil
+		}
+		return
+
+	case engine.EventTransition, engine.EventSnooze, engine.EventAway:
+		return
+	}
+
+	if t.phaseSpan == nil {
+		_, t.phaseSpan = t.tracer.Start(t.session, e.Phase.String(),
+			trace.WithTimestamp(e.PhaseStartedAt.Add(-e.PausedTotal)))
+	}
+	t.phase = e
+	if e.Ended != "" {
+		t.endPhase(now)
+	}
+}
+
+func (t *Tracer) endPhase(now time.Time) {
+	e := t.phase
+	cycle := e.CycleNum
+	if e.Phase != engine.PhaseWork {
+		cycle--
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("pomo.phase", e.Phase.String()),
+		attribute.Float64("pomo.planned_seconds", e.Total.Seconds()),
+		attribute.Float64("pomo.actual_seconds", e.Elapsed.Seconds()),
+		attribute.Float64("pomo.paused_seconds", e.PausedTotal.Seconds()),
+		attribute.String("pomo.ended", string(e.Ended)),
+		attribute.Int("pomo.cycle", cycle),
+	}
+	if e.Extra {
+		attrs = append(attrs, attribute.Bool("pomo.extra", true))
+	}
+	if t.label != "" {
+		attrs = append(attrs, attribute.String("pomo.label", t.label))
+	}
+	t.phaseSpan.SetAttributes(attrs...)
+	t.phaseSpan.End(trace.WithTimestamp(now))
+	t.phaseSpan = nil
+}
+
+// Shutdown sends what is left, giving up after the timeout passed to New.
+// It is not Close, so the tracer outlives each session's subscribers and
+// restarted sessions keep exporting.
+func (t *Tracer) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+
+	select {
+	case err := <-t.setup:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return errors.New("exporter setup timed out")
+	}
+	err := t.provider.Shutdown(ctx)
+	if exportErr := t.exportErr.Load(); exportErr != nil && err == nil {
+		err = *exportErr
+	}
+	return err
+}
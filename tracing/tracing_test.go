@@ -0,0 +1,149 @@
+package tracing
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var testStart = time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+// recorder is a Tracer whose spans end up in memory.
+func recorder(t *testing.T, clock engine.Clock, label string) (*Tracer, *tracetest.SpanRecorder) {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	setup := make(chan error, 1)
+	setup <- nil
+	tr := newTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)), setup, clock, label, time.Second)
+	t.Cleanup(func() {
+		if err := tr.Shutdown(); err != nil {
+			t.Error(err)
+		}
+	})
+	return tr, rec
+}
+
+// phase sends a phase's start and end as the timer would, on clock,
+// paused for paused of it.
+func phase(tr *Tracer, clock *engine.MockClock, p engine.Phase, cycle int, total, elapsed, paused time.Duration, ended engine.EndReason) {
+	started := clock.Now()
+	e := engine.TimerEvent{Type: engine.EventTick, Phase: p, Total: total, CycleNum: cycle, TotalCycles: 2, PhaseStartedAt: started}
+	tr.Handle(e)
+	clock.Advance(elapsed + paused)
+	e.Elapsed, e.PausedTotal, e.Ended = elapsed, paused, ended
+	e.PhaseStartedAt = started.Add(paused)
+	e.PhaseComplete = ended != engine.EndInterrupted
+	tr.Handle(e)
+}
+
+func TestSessionSpans(t *testing.T) {
+	clock := engine.NewMockClock(testStart)
+	tr, rec := recorder(t, clock, "writing")
+
+	tr.Handle(engine.TimerEvent{Type: engine.EventSessionStarted, TotalCycles: 2})
+	phase(tr, clock, engine.PhaseWork, 1, 25*time.Minute, 25*time.Minute, 2*time.Minute, engine.EndCompleted)
+	tr.Handle(engine.TimerEvent{Type: engine.EventTransition, Phase: engine.PhaseShortBreak})
+	clock.Advance(10 * time.Second)
+	phase(tr, clock, engine.PhaseShortBreak, 2, 5*time.Minute, time.Minute, 0, engine.EndSkipped)
+	phase(tr, clock, engine.PhaseWork, 2, 25*time.Minute, 25*time.Minute, 0, engine.EndCompleted)
+	summary := engine.SessionSummary{Ended: engine.EndCompleted, CyclesComplete: 2, Work: 50 * time.Minute}
+	tr.Handle(engine.TimerEvent{Type: engine.EventSessionEnded, Summary: &summary})
+
+	spans := rec.Ended()
+	var names []string
+	for _, s := range spans {
+		names = append(names, s.Name())
+	}
+	if want := []string{"Work", "Short Break", "Work", "session"}; !slices.Equal(names, want) {
+		t.Fatalf("spans %q, want %q", names, want)
+	}
+	session := spans[3]
+	for _, s := range spans[:3] {
+		if s.Parent().SpanID() != session.SpanContext().SpanID() || s.SpanContext().TraceID() != session.SpanContext().TraceID() {
+			t.Errorf("%s span is not the session's child", s.Name())
+		}
+	}
+
+	type want struct {
+		start, end time.Duration
+		attrs      []attribute.KeyValue
+	}
+	for i, w := range []want{
+		{0, 27 * time.Minute, []attribute.KeyValue{
+			attribute.String("pomo.phase", "Work"),
+			attribute.Float64("pomo.planned_seconds", 1500),
+			attribute.Float64("pomo.actual_seconds", 1500),
+			attribute.Float64("pomo.paused_seconds", 120),
+			attribute.String("pomo.ended", "completed"),
+			attribute.Int("pomo.cycle", 1),
+			attribute.String("pomo.label", "writing"),
+		}},
+		{27*time.Minute + 10*time.Second, 28*time.Minute + 10*time.Second, []attribute.KeyValue{
+			attribute.String("pomo.phase", "Short Break"),
+			attribute.Float64("pomo.planned_seconds", 300),
+			attribute.Float64("pomo.actual_seconds", 60),
+			attribute.Float64("pomo.paused_seconds", 0),
+			attribute.String("pomo.ended", "skipped"),
+			attribute.Int("pomo.cycle", 1),
+			attribute.String("pomo.label", "writing"),
+		}},
+		{0, 53*time.Minute + 10*time.Second, []attribute.KeyValue{
+			attribute.Int("pomo.cycles", 2),
+			attribute.String("pomo.label", "writing"),
+			attribute.String("pomo.ended", "completed"),
+			attribute.Bool("pomo.stopped", false),
+			attribute.Int("pomo.cycles_complete", 2),
+			attribute.Float64("pomo.work_seconds", 3000),
+		}},
+	} {
+		s := spans[i]
+		if i == 2 {
+			s = session
+		}
+		if got := s.StartTime().Sub(testStart); got != w.start {
+			t.Errorf("%s span starts at %s, want %s", s.Name(), got, w.start)
+		}
+		if got := s.EndTime().Sub(testStart); got != w.end {
+			t.Errorf("%s span ends at %s, want %s", s.Name(), got, w.end)
+		}
+		if got := s.Attributes(); !slices.Equal(got, w.attrs) {
+			t.Errorf("%s span attributes\n%v\nwant\n%v", s.Name(), got, w.attrs)
+		}
+	}
+	if got := session.Status().Code; got != codes.Unset {
+		t.Errorf("completed session status %v", got)
+	}
+}
+
+func TestInterruptedSessionSpans(t *testing.T) {
+	clock := engine.NewMockClock(testStart)
+	tr, rec := recorder(t, clock, "")
+
+	tr.Handle(engine.TimerEvent{Type: engine.EventSessionStarted, TotalCycles: 2})
+	tr.Handle(engine.TimerEvent{Type: engine.EventTick, Phase: engine.PhaseWork, Total: 25 * time.Minute, CycleNum: 1, PhaseStartedAt: testStart})
+	clock.Advance(10 * time.Minute)
+	tr.Handle(engine.TimerEvent{Type: engine.EventTick, Phase: engine.PhaseWork, Total: 25 * time.Minute, Elapsed: 10 * time.Minute, CycleNum: 1, PhaseStartedAt: testStart})
+	summary := engine.SessionSummary{Ended: engine.EndInterrupted, Work: 10 * time.Minute}
+	tr.Handle(engine.TimerEvent{Type: engine.EventSessionEnded, Summary: &summary})
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans, want the work phase and the session", len(spans))
+	}
+	work, session := spans[0], spans[1]
+	if !slices.Contains(work.Attributes(), attribute.String("pomo.ended", "interrupted")) {
+		t.Errorf("work span attributes %v, want it ended interrupted", work.Attributes())
+	}
+	if got := work.EndTime().Sub(testStart); got != 10*time.Minute {
+		t.Errorf("work span ends at %s, want 10m", got)
+	}
+	if got := session.Status().Code; got != codes.Error {
+		t.Errorf("interrupted session status %v, want an error", got)
+	}
+}
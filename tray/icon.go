@@ -0,0 +1,107 @@
+// Package tray draws the icons pomo tray shows in the system tray.
+package tray
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+const (
+	iconSize = 64
+	// Each digit is drawn from a 3x5 grid of dots this many pixels wide.
+	dotSize = 6
+)
+
+var (
+	pausedColor = color.RGBA{0x9e, 0x9e, 0x9e, 0xff}
+	textColor   = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+// PhaseColor matches the terminal colors of the default theme.
+func PhaseColor(phase engine.Phase) color.RGBA {
+	switch phase {
+	case engine.PhaseWork:
+		return color.RGBA{0xe5, 0x39, 0x35, 0xff}
+	case engine.PhaseShortBreak:
+		return color.RGBA{0x00, 0xac, 0xc1, 0xff}
+	case engine.PhaseLongBreak:
+		return color.RGBA{0x43, 0xa0, 0x47, 0xff}
+	case engine.PhaseCooldown:
+		return color.RGBA{0x8e, 0x24, 0xaa, 0xff}
+	case engine.PhaseWarmup:
+		return color.RGBA{0xb0, 0xbe, 0xc5, 0xff}
+	default:
+		return pausedColor
+	}
+}
+
+// digits holds 0-9 as rows of a 3x5 grid, most significant bit leftmost.
+var digits = [10][5]uint8{
+	{7, 5, 5, 5, 7},
+	{2, 6, 2, 2, 7},
+	{7, 1, 7, 4, 7},
+	{7, 1, 3, 1, 7},
+	{5, 5, 7, 1, 1},
+	{7, 4, 7, 1, 7},
+	{7, 4, 7, 5, 7},
+	{7, 1, 1, 2, 2},
+	{7, 5, 7, 5, 7},
+	{7, 5, 7, 1, 7},
+}
+
+// Icon is a PNG of a circle in the phase's color, gray while paused, with
+// the minutes left written across it. Minutes are capped at 99.
+func Icon(phase engine.Phase, minutes int, paused bool) []byte {
+	fill := PhaseColor(phase)
+	if paused {
+		fill = pausedColor
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, iconSize, iconSize))
+	r := iconSize / 2
+	for y := range iconSize {
+		for x := range iconSize {
+			dx, dy := x-r, y-r
+			if dx*dx+dy*dy < r*r {
+				img.SetRGBA(x, y, fill)
+			}
+		}
+	}
+
+	minutes = min(max(minutes, 0), 99)
+	var text []int
+	if minutes >= 10 {
+		text = append(text, minutes/10)
+	}
+	text = append(text, minutes%10)
+
+	width := len(text)*3*dotSize + (len(text)-1)*dotSize
+	left := (iconSize - width) / 2
+	top := (iconSize - 5*dotSize) / 2
+	for i, d := range text {
+		x0 := left + i*4*dotSize
+		for row, bits := range digits[d] {
+			for col := range 3 {
+				if bits&(4>>col) != 0 {
+					dot(img, x0+col*dotSize, top+row*dotSize)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func dot(img *image.RGBA, x, y int) {
+	for dy := range dotSize {
+		for dx := range dotSize {
+			img.SetRGBA(x+dx, y+dy, textColor)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package tray
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/steenfuentes/pomo/engine"
+)
+
+func TestEveryPhaseHasAColor(t *testing.T) {
+	seen := map[color.RGBA]engine.Phase{}
+	for phase := engine.PhaseWork; phase < engine.PhaseDone; phase++ {
+		c := PhaseColor(phase)
+		if c == pausedColor {
+			t.Errorf("%s drawn as paused", phase)
+		}
+		if other, ok := seen[c]; ok {
+			t.Errorf("%s has %s's color", phase, other)
+		}
+		seen[c] = phase
+		if len(Icon(phase, 12, false)) == 0 {
+			t.Errorf("%s has no icon", phase)
+		}
+	}
+}
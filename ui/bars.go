@@ -0,0 +1,418 @@
+package ui
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/ui/format"
+	"github.com/vbauerster/mpb/v8"
+	"github.com/vbauerster/mpb/v8/decor"
+)
+
+// barSet is what Progress draws with. Progress decides what each bar shows
+// and when; mpbBars is the only thing that knows how it is rendered.
+type barSet interface {
+	// Write prints above the bars.
+	io.Writer
+	addPhase(spec phaseSpec) bar
+	addOverall(total int64, unit string, remaining *atomic.Int64) bar
+	addTally(cycles, focused *atomic.Int64) bar
+	// addHeader shows a line above every other bar.
+	addHeader(text func() string) bar
+	// addTransition shows a countdown to the phase named next.
+	addTransition(next string, remaining *atomic.Int64) bar
+	// addSnooze counts down a snooze before the phase named next.
+	addSnooze(next string, remaining *atomic.Int64) bar
+	// addAway counts up the wait for a check-in before the phase named
+	// next.
+	addAway(next string, waited *atomic.Int64) bar
+	// addCompact draws the compact line for what view holds.
+	addCompact(view *atomic.Pointer[compactView], layout Layout) bar
+	// width is the width drawn to, or 0 if unknown.
+	width() int
+	// frame draws the bars now when stepping, and is a no-op otherwise.
+	frame()
+	// err explains why rendering stopped.
+	err() error
+	shutdown()
+	wait()
+}
+
+type bar interface {
+	setCurrent(n int64)
+	increment()
+	// complete fills the bar and removes it once drawn.
+	complete()
+	// abort freezes the bar where it is.
+	abort()
+	// drop removes a bar that has not completed.
+	drop()
+	running() bool
+}
+
+// phaseSpec is a phase bar. Once compact is set it shows short in place of
+// name and leaves out the rest.
+type phaseSpec struct {
+	total   int64
+	style   mpb.BarFillerBuilder
+	name    string
+	short   string
+	compact *atomic.Bool
+	paused  *atomic.Int64
+	note    *atomic.Pointer[string]
+	// ended is how the phase ended, shown once its bar is frozen.
+	ended *atomic.Pointer[engine.EndReason]
+	// next, if set, is a line shown dim under the bar while it runs.
+	next *atomic.Pointer[string]
+}
+
+type mpbBars struct {
+	container *mpb.Progress
+	debug     renderLog
+	output    io.Writer
+	style     format.Style
+
+	// maxWidth caps the lines drawn, if set, at a terminal termWidth wide.
+	maxWidth  int
+	termWidth atomic.Int64
+
+	// Set when stepping.
+	refresh chan any
+	drawn   chan struct{}
+}
+
+const (
+	barWidth = 50
+	// Line width when stepping, whatever the terminal's.
+	frameWidth = 120
+	// How long frame waits on a renderer that may have stopped.
+	frameTimeout = time.Second
+
+	// The header and the overall bar stay on top, even when added again
+	// after phase bars.
+	headerPriority  = -2
+	overallPriority = -1
+)
+
+// newBars is what NewProgress draws with; tests record the operations
+// instead.
+var newBars = func(output io.Writer, stepping bool, maxWidth int, style format.Style) barSet {
+	return newMPBBars(output, stepping, maxWidth, style)
+}
+
+func newMPBBars(output io.Writer, stepping bool, maxWidth int, style format.Style) *mpbBars {
+	b := &mpbBars{output: output, maxWidth: maxWidth, style: style}
+	opts := []mpb.ContainerOption{
+		mpb.WithWidth(barWidth),
+		mpb.WithRefreshRate(50 * time.Millisecond),
+		mpb.WithDebugOutput(&b.debug),
+	}
+	if stepping {
+		if output == nil {
+			output = os.Stdout
+		}
+		// mpb writes each frame in one call. Behind the wrapper it no longer
+		// sees a terminal, so lines are frameWidth wide.
+		b.refresh = make(chan any)
+		b.drawn = make(chan struct{}, 1)
+		output = frameWriter{output, b.drawn}
+		opts = append(opts, mpb.WithManualRefresh(b.refresh), mpb.WithWidth(frameWidth))
+	}
+	if output != nil {
+		opts = append(opts, mpb.WithOutput(output))
+	}
+	b.container = mpb.New(opts...)
+	return b
+}
+
+func (b *mpbBars) frame() {
+	if b.refresh == nil {
+		return
+	}
+	select {
+	case b.refresh <- nil:
+	case <-time.After(frameTimeout):
+		return
+	}
+	select {
+	case <-b.drawn:
+	case <-time.After(frameTimeout):
+	}
+}
+
+type frameWriter struct {
+	w     io.Writer
+	drawn chan<- struct{}
+}
+
+func (f frameWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	select {
+	case f.drawn <- struct{}{}:
+	default:
+	}
+	return n, err
+}
+
+func (b *mpbBars) Write(p []byte) (int, error) { return b.container.Write(p) }
+func (b *mpbBars) err() error                  { return b.debug.err() }
+func (b *mpbBars) shutdown()                   { b.container.Shutdown() }
+func (b *mpbBars) wait()                       { b.container.Wait() }
+
+func (b *mpbBars) width() int {
+	w := terminalWidth(b.output)
+	b.termWidth.Store(int64(w))
+	if b.maxWidth > 0 && (w == 0 || w > b.maxWidth) {
+		return b.maxWidth
+	}
+	return w
+}
+
+// filler guards style and keeps it within maxWidth.
+func (b *mpbBars) filler(style mpb.BarFillerBuilder) guardedFiller {
+	return guardedFiller{style, b}
+}
+
+func (b *mpbBars) addPhase(spec phaseSpec) bar {
+	var next mpb.BarOption
+	if spec.next != nil {
+		next = mpb.BarExtender(mpb.BarFillerFunc(func(w io.Writer, s decor.Statistics) error {
+			defer RestoreOnPanic()
+			line := *spec.next.Load()
+			if line == "" || s.Completed || s.Aborted || spec.compact.Load() {
+				return nil
+			}
+			_, err := io.WriteString(w, dimColor.Sprint("  "+line)+"\n")
+			return err
+		}), false)
+	}
+	return &mpbBar{total: spec.total, Bar: b.container.New(spec.total,
+		b.filler(spec.style),
+		mpb.BarWidth(barWidth),
+		mpb.PrependDecorators(
+			decor.Any(func(decor.Statistics) string {
+				defer RestoreOnPanic()
+				if spec.compact.Load() {
+					return spec.short
+				}
+				return spec.name
+			}, decor.WCSyncSpaceR),
+		),
+		mpb.AppendDecorators(
+			decor.Any(func(s decor.Statistics) string {
+				defer RestoreOnPanic()
+				elapsed := time.Duration(s.Current) * time.Millisecond
+				total := time.Duration(s.Total) * time.Millisecond
+				return dimColor.Sprintf(" %s/%s", b.style.Duration(elapsed), b.style.Duration(total))
+			}, decor.WCSyncSpace),
+			decor.Any(func(decor.Statistics) string {
+				defer RestoreOnPanic()
+				paused := time.Duration(spec.paused.Load())
+				if paused < time.Second || spec.compact.Load() {
+					return ""
+				}
+				return dimColor.Sprintf(" (paused %s)", format.DurationPrecise(paused))
+			}),
+			decor.Meta(decor.Any(func(decor.Statistics) string {
+				defer RestoreOnPanic()
+				if note := *spec.note.Load(); note != "" && !spec.compact.Load() {
+					return " (" + note + ")"
+				}
+				return ""
+			}), func(s string) string {
+				return dimColor.Sprint(s)
+			}),
+			decor.Any(func(s decor.Statistics) string {
+				defer RestoreOnPanic()
+				if !s.Aborted {
+					return ""
+				}
+				return dimColor.Sprintf(" %s", *spec.ended.Load())
+			}),
+		),
+		mpb.BarFillerClearOnComplete(),
+		next,
+	)}
+}
+
+func (b *mpbBars) addOverall(total int64, unit string, remaining *atomic.Int64) bar {
+	return &mpbBar{total: total, Bar: b.container.New(total,
+		b.filler(mpb.BarStyle().Lbound("[").Filler("=").Tip(">").Padding("-").Rbound("]")),
+		mpb.BarWidth(barWidth),
+		mpb.BarPriority(overallPriority),
+		mpb.PrependDecorators(
+			decor.Name(overallColor.Sprint("  Total "), decor.WCSyncSpaceR),
+		),
+		mpb.AppendDecorators(
+			decor.Meta(decor.CountersNoUnit(" %d/%d"+unit, decor.WCSyncSpace), func(s string) string {
+				defer RestoreOnPanic()
+				return dimColor.Sprint(s)
+			}),
+			decor.Any(func(decor.Statistics) string {
+				defer RestoreOnPanic()
+				remaining := time.Duration(remaining.Load())
+				if remaining <= 0 {
+					return ""
+				}
+				return dimColor.Sprintf(" ~%s left", format.DurationHuman(remaining))
+			}),
+		),
+		mpb.BarFillerClearOnComplete(),
+	)}
+}
+
+func (b *mpbBars) addTally(cycles, focused *atomic.Int64) bar {
+	return &mpbBar{total: 1, Bar: b.container.New(1,
+		mpb.NopStyle(),
+		mpb.BarPriority(overallPriority),
+		mpb.PrependDecorators(
+			decor.Any(func(decor.Statistics) string {
+				defer RestoreOnPanic()
+				n := int(cycles.Load())
+				return overallColor.Sprintf("  %d %s", n, plural(n, "cycle")) +
+					dimColor.Sprintf(", %s focused today", format.DurationHuman(time.Duration(focused.Load())))
+			}),
+		),
+	)}
+}
+
+func (b *mpbBars) addHeader(text func() string) bar {
+	return &mpbBar{total: 1, Bar: b.container.New(1,
+		mpb.NopStyle(),
+		mpb.BarPriority(headerPriority),
+		mpb.PrependDecorators(
+			decor.Any(func(decor.Statistics) string {
+				defer RestoreOnPanic()
+				return dimColor.Sprint("Today: ") + text()
+			}),
+		),
+	)}
+}
+
+func (b *mpbBars) addTransition(next string, remaining *atomic.Int64) bar {
+	return &mpbBar{total: 1, Bar: b.container.New(1,
+		mpb.NopStyle(),
+		mpb.PrependDecorators(
+			decor.Any(func(decor.Statistics) string {
+				defer RestoreOnPanic()
+				seconds := (time.Duration(remaining.Load()) + time.Second - 1) / time.Second
+				return dimColor.Sprint("  Next: ") + next + dimColor.Sprintf(" in %ds", seconds)
+			}),
+		),
+		mpb.BarRemoveOnComplete(),
+	)}
+}
+
+func (b *mpbBars) addSnooze(next string, remaining *atomic.Int64) bar {
+	return &mpbBar{total: 1, Bar: b.container.New(1,
+		mpb.NopStyle(),
+		mpb.PrependDecorators(
+			decor.Any(func(decor.Statistics) string {
+				defer RestoreOnPanic()
+				left := (time.Duration(remaining.Load()) + time.Second - 1).Truncate(time.Second)
+				return warningColor.Sprintf("  Snoozed +%s", b.style.Duration(left)) + dimColor.Sprint(" before ") + next
+			}),
+		),
+		mpb.BarRemoveOnComplete(),
+	)}
+}
+
+func (b *mpbBars) addAway(next string, waited *atomic.Int64) bar {
+	return &mpbBar{total: 1, Bar: b.container.New(1,
+		mpb.NopStyle(),
+		mpb.PrependDecorators(
+			decor.Any(func(decor.Statistics) string {
+				defer RestoreOnPanic()
+				away := time.Duration(waited.Load()).Truncate(time.Second)
+				return warningColor.Sprintf("  Away %s", b.style.Duration(away)) + dimColor.Sprint(" — press any key or run pomo checkin to start ") + next
+			}),
+		),
+		mpb.BarRemoveOnComplete(),
+	)}
+}
+
+func (b *mpbBars) addCompact(view *atomic.Pointer[compactView], layout Layout) bar {
+	return &mpbBar{total: 1, Bar: b.container.New(1,
+		mpb.NopStyle(),
+		mpb.PrependDecorators(
+			decor.Any(func(s decor.Statistics) string {
+				defer RestoreOnPanic()
+				v := view.Load()
+				if v == nil {
+					return ""
+				}
+				width := s.AvailableWidth
+				if layout.MaxWidth > 0 {
+					width = min(width, layout.MaxWidth)
+				}
+				return compactLine(*v, layout.BarWidth, width, layout.ASCII, b.style)
+			}),
+		),
+	)}
+}
+
+// guardedFiller restores the terminal if drawing panics. Fillers and
+// decorators run on mpb's render goroutines, out of reach of any recover
+// further up. It also narrows the bar by however far bars' maxWidth is
+// under the terminal's.
+type guardedFiller struct {
+	mpb.BarFillerBuilder
+	bars *mpbBars
+}
+
+func (g guardedFiller) Build() mpb.BarFiller {
+	f := g.BarFillerBuilder.Build()
+	return mpb.BarFillerFunc(func(w io.Writer, st decor.Statistics) error {
+		defer RestoreOnPanic()
+		if g.bars.maxWidth > 0 {
+			if over := int(g.bars.termWidth.Load()) - g.bars.maxWidth; over > 0 {
+				st.AvailableWidth = max(st.AvailableWidth-over, 0)
+			}
+		}
+		return f.Fill(w, st)
+	})
+}
+
+type mpbBar struct {
+	*mpb.Bar
+	total int64
+}
+
+func (b *mpbBar) setCurrent(n int64) { b.SetCurrent(n) }
+func (b *mpbBar) increment()         { b.Increment() }
+func (b *mpbBar) abort()             { b.Abort(false) }
+func (b *mpbBar) drop()              { b.Abort(true) }
+func (b *mpbBar) running() bool      { return b.IsRunning() }
+
+func (b *mpbBar) complete() {
+	b.SetCurrent(b.total)
+	b.EnableTriggerComplete()
+}
+
+// renderLog keeps what mpb reports on its debug output, which is where a
+// failed render ends up once the container shuts down.
+type renderLog struct {
+	mu  sync.Mutex
+	buf strings.Builder
+}
+
+func (l *renderLog) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(b)
+}
+
+func (l *renderLog) err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if msg := strings.TrimSpace(l.buf.String()); msg != "" {
+		return errors.New(msg)
+	}
+	return errors.New("output stopped accepting writes")
+}
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxBlocks is the most cells Blocks draws; larger counts are scaled down.
+const maxBlocks = 24
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// UnicodeLocale reports whether the locale's character set is UTF-8, going
+// by LC_ALL, LC_CTYPE, and LANG in that order, as the C library does.
+func UnicodeLocale() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			v = strings.ToLower(v)
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return false
+}
+
+// Blocks renders done of goal as a row of cells, e.g. "▇▇▇▁▁▁▁▁ 3/8", or
+// just the digits with ascii. A goal of 0 shows the count alone.
+func Blocks(done, goal int, ascii bool) string {
+	count := strconv.Itoa(done)
+	if goal > 0 {
+		count = fmt.Sprintf("%d/%d", done, goal)
+	}
+	cells := max(done, goal)
+	if ascii || cells == 0 {
+		return count
+	}
+	filled := done
+	if cells > maxBlocks {
+		filled, cells = done*maxBlocks/cells, maxBlocks
+	}
+	s := workColor.Sprint(strings.Repeat("▇", filled))
+	if filled < cells {
+		s += dimColor.Sprint(strings.Repeat("▁", cells-filled))
+	}
+	return s + " " + count
+}
+
+// Sparkline renders values as one block each, scaled to the largest, e.g.
+// "▁▃▇█▂", or as the digits separated by spaces with ascii.
+func Sparkline(values []int, ascii bool) string {
+	if ascii {
+		digits := make([]string, len(values))
+		for i, v := range values {
+			digits[i] = strconv.Itoa(v)
+		}
+		return strings.Join(digits, " ")
+	}
+	top := 0
+	for _, v := range values {
+		top = max(top, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if top > 0 {
+			level = v * (len(sparks) - 1) / top
+		}
+		b.WriteRune(sparks[level])
+	}
+	return b.String()
+}
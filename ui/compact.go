@@ -0,0 +1,206 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+const (
+	// CompactBelow is the width under which the compact line is drawn by
+	// default.
+	CompactBelow = 60
+	// DefaultCompactBar is the compact line's bar width, brackets included.
+	DefaultCompactBar = 10
+	// MinCompactBar is the narrowest bar the compact line draws before
+	// leaving it out.
+	MinCompactBar = 5
+)
+
+// Layout picks between the full layout, a bar per phase under the overall
+// bar, and a compact single line for narrow panes: icon, phase, bar, time
+// left, and cycle, e.g. "▶ W [===>-----] 12:34 2/4".
+type Layout struct {
+	// Compact always draws the compact line. Otherwise it is drawn while the
+	// width is under Below, if set, and the layout follows the terminal as
+	// it is resized.
+	Compact bool
+	Below   int
+	// BarWidth is the compact line's bar, brackets included.
+	BarWidth int
+	// MaxWidth caps the width drawn to, whatever the terminal's, if set.
+	MaxWidth int
+	// ASCII draws the compact line's icons as plain characters.
+	ASCII bool
+}
+
+// DefaultLayout switches to the compact line below CompactBelow columns.
+func DefaultLayout() Layout {
+	return Layout{Below: CompactBelow, BarWidth: DefaultCompactBar}
+}
+
+// WithLayout replaces DefaultLayout.
+func WithLayout(l Layout) Option {
+	return func(p *Progress) {
+		p.layout = l
+	}
+}
+
+// compactAt reports whether the layout is compact at width, which is 0 if
+// unknown.
+func (l Layout) compactAt(width int) bool {
+	return l.Compact || width > 0 && width < l.Below
+}
+
+// compactView is what the compact line shows as of the last update.
+// Countdown marks a transition or snooze before phase, whose elapsed and
+// total it carries. Away marks a wait for a check-in before it, elapsed
+// long so far.
+type compactView struct {
+	phase     engine.Phase
+	elapsed   time.Duration
+	total     time.Duration
+	paused    bool
+	countdown bool
+	away      bool
+	cycles    string
+}
+
+// compactLine renders v in at most width columns, or as is for a width of
+// 0, with the time left in style. What does not fit goes in turn: the bar,
+// shrinking first, the cycle, then the icon.
+func compactLine(v compactView, barWidth, width int, ascii bool, style format.Style) string {
+	icon := compactIcon(v, ascii)
+	name := phaseAbbrev(v.phase)
+	left := style.Duration(v.total - v.elapsed)
+	if v.away {
+		left = "+" + style.Duration(v.elapsed)
+	}
+	cycles := v.cycles
+
+	size := func(bar int) int {
+		n := utf8.RuneCountInString(name) + 1 + utf8.RuneCountInString(left)
+		if icon != "" {
+			n += utf8.RuneCountInString(icon) + 1
+		}
+		if cycles != "" {
+			n += 1 + utf8.RuneCountInString(cycles)
+		}
+		if bar > 0 {
+			n += bar + 1
+		}
+		return n
+	}
+	bar := barWidth
+	if width > 0 {
+		if bar = min(bar, width-size(0)-1); bar < MinCompactBar {
+			bar = 0
+		}
+		if size(0) > width {
+			cycles = ""
+		}
+		if size(0) > width {
+			icon = ""
+		}
+	}
+
+	c := PhaseColor(v.phase)
+	var parts []string
+	if icon != "" {
+		parts = append(parts, c.Sprint(icon))
+	}
+	parts = append(parts, c.Sprint(name))
+	if bar > 0 {
+		var fraction float64
+		if v.total > 0 {
+			fraction = float64(v.elapsed) / float64(v.total)
+		}
+		parts = append(parts, miniBar(bar, fraction, c.Sprint))
+	}
+	parts = append(parts, left)
+	if cycles != "" {
+		parts = append(parts, dimColor.Sprint(cycles))
+	}
+	return strings.Join(parts, " ")
+}
+
+// compactIcon tells running, paused, counting down to the next phase, and
+// waiting for a check-in apart.
+func compactIcon(v compactView, ascii bool) string {
+	switch {
+	case v.away && ascii:
+		return "?"
+	case v.away:
+		return "…"
+	case v.countdown && ascii:
+		return ">>"
+	case v.countdown:
+		return "»"
+	case v.paused && ascii:
+		return "="
+	case v.paused:
+		return "‖"
+	case ascii:
+		return ">"
+	default:
+		return "▶"
+	}
+}
+
+// miniBar draws fraction of a bar width wide, brackets included, in the
+// style of the full layout's.
+func miniBar(width int, fraction float64, paint func(...any) string) string {
+	inner := width - 2
+	filled := min(max(int(fraction*float64(inner)), 0), inner)
+	fill := strings.Repeat("=", filled)
+	if filled > 0 && filled < inner {
+		fill = fill[:filled-1] + ">"
+	}
+	return "[" + paint(fill) + dimColor.Sprint(strings.Repeat("-", inner-filled)) + "]"
+}
+
+// phaseAbbrev is phase's name for the compact line.
+func phaseAbbrev(phase engine.Phase) string {
+	switch phase {
+	case engine.PhaseWork:
+		return "W"
+	case engine.PhaseShortBreak:
+		return "SB"
+	case engine.PhaseLongBreak:
+		return "LB"
+	case engine.PhaseCooldown:
+		return "CD"
+	case engine.PhaseWarmup:
+		return "WU"
+	default:
+		return phase.String()
+	}
+}
+
+// compactCycles is the cycle e's phase belongs to for the compact line,
+// e.g. "2/4", "#2" in an infinite session, or "" for an extra, warmup, or
+// cooldown.
+func compactCycles(e engine.TimerEvent) string {
+	switch {
+	case e.Extra || e.Phase == engine.PhaseCooldown || e.Phase == engine.PhaseWarmup:
+		return ""
+	case e.TotalCycles > 0:
+		return fmt.Sprintf("%d/%d", phaseCycle(e), e.TotalCycles)
+	default:
+		return fmt.Sprintf("#%d", phaseCycle(e))
+	}
+}
+
+// compactName is a finished phase's line once the layout turns compact,
+// e.g. "W 2/4".
+func compactName(e engine.TimerEvent) string {
+	name := PhaseColor(e.Phase).Sprint(phaseAbbrev(e.Phase))
+	if cycles := compactCycles(e); cycles != "" {
+		name += " " + dimColor.Sprint(cycles)
+	}
+	return name
+}
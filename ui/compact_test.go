@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+// TestCompactLineWidths draws 12:34 left of the second of four work phases
+// at the widths of a 60-column pane down to one too narrow for anything
+// but the phase and the time left, which are kept however narrow.
+func TestCompactLineWidths(t *testing.T) {
+	noColor(t)
+	v := compactView{phase: engine.PhaseWork, elapsed: 12*time.Minute + 26*time.Second, total: 25 * time.Minute, cycles: "2/4"}
+	tests := []struct {
+		width int
+		want  string
+	}{
+		{0, "▶ W [==>-----] 12:34 2/4"},
+		{60, "▶ W [==>-----] 12:34 2/4"},
+		{40, "▶ W [==>-----] 12:34 2/4"},
+		{24, "▶ W [==>-----] 12:34 2/4"},
+		// The bar shrinks to fit, then goes below MinCompactBar.
+		{23, "▶ W [==>----] 12:34 2/4"},
+		{20, "▶ W [>---] 12:34 2/4"},
+		{19, "▶ W [>--] 12:34 2/4"},
+		{18, "▶ W 12:34 2/4"},
+		{13, "▶ W 12:34 2/4"},
+		// Then the cycle, and then the icon.
+		{12, "▶ W 12:34"},
+		{9, "▶ W 12:34"},
+		{8, "W 12:34"},
+		{7, "W 12:34"},
+		{1, "W 12:34"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.width), func(t *testing.T) {
+			if got := compactLine(v, DefaultCompactBar, tt.width, false, format.StyleClock); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompactLine(t *testing.T) {
+	noColor(t)
+	tests := []struct {
+		name  string
+		v     compactView
+		bar   int
+		ascii bool
+		style format.Style
+		want  string
+	}{
+		{"short break", compactView{phase: engine.PhaseShortBreak, elapsed: time.Minute, total: 5 * time.Minute, cycles: "2/4"}, 10, false, format.StyleClock, "▶ SB [>-------] 04:00 2/4"},
+		{"long break, wide bar", compactView{phase: engine.PhaseLongBreak, total: 15 * time.Minute, cycles: "#3"}, 14, false, format.StyleClock, "▶ LB [------------] 15:00 #3"},
+		{"done", compactView{phase: engine.PhaseWork, elapsed: time.Minute, total: time.Minute, cycles: "4/4"}, 10, false, format.StyleClock, "▶ W [========] 00:00 4/4"},
+		{"paused", compactView{phase: engine.PhaseWork, elapsed: 10 * time.Minute, total: 20 * time.Minute, paused: true, cycles: "1/4"}, 10, false, format.StyleClock, "‖ W [===>----] 10:00 1/4"},
+		{"paused ascii", compactView{phase: engine.PhaseWork, elapsed: 10 * time.Minute, total: 20 * time.Minute, paused: true, cycles: "1/4"}, 10, true, format.StyleClock, "= W [===>----] 10:00 1/4"},
+		{"countdown", compactView{phase: engine.PhaseShortBreak, elapsed: 2 * time.Second, total: 5 * time.Second, countdown: true}, 10, false, format.StyleClock, "» SB [==>-----] 00:03"},
+		{"countdown ascii", compactView{phase: engine.PhaseShortBreak, elapsed: 2 * time.Second, total: 5 * time.Second, countdown: true}, 10, true, format.StyleClock, ">> SB [==>-----] 00:03"},
+		{"away", compactView{phase: engine.PhaseWork, elapsed: 90 * time.Second, away: true, cycles: "2/4"}, 10, false, format.StyleClock, "… W [--------] +01:30 2/4"},
+		{"away ascii", compactView{phase: engine.PhaseWork, elapsed: 90 * time.Second, away: true, cycles: "2/4"}, 10, true, format.StyleClock, "? W [--------] +01:30 2/4"},
+		{"warmup", compactView{phase: engine.PhaseWarmup, elapsed: 30 * time.Second, total: 2 * time.Minute}, 10, true, format.StyleHuman, "> WU [=>------] 2m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compactLine(tt.v, tt.bar, 0, tt.ascii, tt.style); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayoutCompactAt(t *testing.T) {
+	tests := []struct {
+		layout Layout
+		width  int
+		want   bool
+	}{
+		{DefaultLayout(), 0, false},
+		{DefaultLayout(), 40, true},
+		{DefaultLayout(), CompactBelow - 1, true},
+		{DefaultLayout(), CompactBelow, false},
+		{DefaultLayout(), 120, false},
+		{Layout{Compact: true}, 0, true},
+		{Layout{Compact: true}, 120, true},
+		{Layout{}, 40, false},
+	}
+	for _, tt := range tests {
+		if got := tt.layout.compactAt(tt.width); got != tt.want {
+			t.Errorf("%+v at %d columns: compact %v, want %v", tt.layout, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestCompactCycles(t *testing.T) {
+	tests := []struct {
+		e    engine.TimerEvent
+		want string
+	}{
+		{engine.TimerEvent{Phase: engine.PhaseWork, CycleNum: 2, TotalCycles: 4}, "2/4"},
+		{engine.TimerEvent{Phase: engine.PhaseWork, CycleNum: 5}, "#5"},
+		{engine.TimerEvent{Phase: engine.PhaseWork, CycleNum: 2, TotalCycles: 4, Extra: true}, ""},
+		{engine.TimerEvent{Phase: engine.PhaseWarmup, TotalCycles: 4}, ""},
+		{engine.TimerEvent{Phase: engine.PhaseCooldown, CycleNum: 4, TotalCycles: 4}, ""},
+	}
+	for _, tt := range tests {
+		if got := compactCycles(tt.e); got != tt.want {
+			t.Errorf("%s, cycle %d of %d, extra %v: %q, want %q", tt.e.Phase, tt.e.CycleNum, tt.e.TotalCycles, tt.e.Extra, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+// PhaseName is e's phase as the bars name it, colored, e.g. "Work (2/4)" or
+// "Short Break (extra)", for drawing a session elsewhere, as ui/teapomo
+// does.
+func PhaseName(e engine.TimerEvent) string {
+	return formatPhaseName(e)
+}
+
+// Bar draws fraction of a bar width wide, brackets included, in phase's
+// color, e.g. "[===>-----]". PhaseDone draws it in the overall bar's.
+func Bar(width int, fraction float64, phase engine.Phase) string {
+	return miniBar(width, fraction, PhaseColor(phase).Sprint)
+}
+
+// CompactLine is e as the compact layout draws it, e.g. "▶ W [===>-----]
+// 12:34 2/4", in at most width columns, or as is for a width of 0.
+func CompactLine(e engine.TimerEvent, width int, ascii bool, style format.Style) string {
+	v := compactView{
+		phase:     e.Phase,
+		elapsed:   e.Elapsed,
+		total:     e.Total,
+		paused:    e.Paused,
+		countdown: e.Type == engine.EventTransition || e.Type == engine.EventSnooze,
+		away:      e.Type == engine.EventAway,
+		cycles:    compactCycles(e),
+	}
+	return compactLine(v, DefaultCompactBar, width, ascii, style)
+}
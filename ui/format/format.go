@@ -0,0 +1,99 @@
+// Package format renders durations and fractions the same way everywhere
+// pomo shows them: the live bars, the session summary, status lines, and
+// stats.
+package format
+
+import (
+	"fmt"
+	"time"
+)
+
+// DurationClock renders d to the second as a clock, "mm:ss" under an hour
+// and "h:mm:ss" from one up, e.g. "07:05" or "1:30:00". Hours carry on past
+// a day, and a negative d is rendered as zero.
+func DurationClock(d time.Duration) string {
+	d = max(d, 0).Round(time.Second)
+	h, m, s := d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%02d:%02d", m, s)
+}
+
+// DurationHuman renders d for reading rather than counting down: to the
+// minute once it is one, e.g. "1h 30m", "2h", or "45m", and to the second
+// under that, e.g. "40s". A negative d is rendered as zero.
+func DurationHuman(d time.Duration) string {
+	d = max(d, 0)
+	if d.Round(time.Second) < time.Minute {
+		return fmt.Sprintf("%ds", d.Round(time.Second)/time.Second)
+	}
+	d = d.Round(time.Minute)
+	h, m := d/time.Hour, d%time.Hour/time.Minute
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%dm", m)
+	case m == 0:
+		return fmt.Sprintf("%dh", h)
+	default:
+		return fmt.Sprintf("%dh %dm", h, m)
+	}
+}
+
+// DurationPrecise renders short stretches like pauses to the second, e.g.
+// "45s" or "3m12s", and longer ones as "1h05m12s". A negative d is
+// rendered as zero.
+func DurationPrecise(d time.Duration) string {
+	d = max(d, 0).Round(time.Second)
+	h, m, s := d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second
+	switch {
+	case h > 0:
+		return fmt.Sprintf("%dh%02dm%02ds", h, m, s)
+	case m > 0:
+		return fmt.Sprintf("%dm%02ds", m, s)
+	default:
+		return fmt.Sprintf("%ds", s)
+	}
+}
+
+// Percent renders a fraction as a whole percentage, e.g. "84%".
+func Percent(f float64) string {
+	return fmt.Sprintf("%.0f%%", f*100)
+}
+
+// Style is how the live bars show time.
+type Style int
+
+const (
+	// StyleClock counts like a clock, e.g. "12:34".
+	StyleClock Style = iota
+	// StyleHuman reads like DurationHuman, e.g. "12m".
+	StyleHuman
+)
+
+// ParseStyle takes "clock" or "human".
+func ParseStyle(s string) (Style, error) {
+	switch s {
+	case "clock":
+		return StyleClock, nil
+	case "human":
+		return StyleHuman, nil
+	default:
+		return 0, fmt.Errorf("unknown time style %q (want clock or human)", s)
+	}
+}
+
+func (s Style) String() string {
+	if s == StyleHuman {
+		return "human"
+	}
+	return "clock"
+}
+
+// Duration renders d in the style.
+func (s Style) Duration(d time.Duration) string {
+	if s == StyleHuman {
+		return DurationHuman(d)
+	}
+	return DurationClock(d)
+}
@@ -0,0 +1,151 @@
+package format
+
+import (
+	"testing"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+func TestDurationClock(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "00:00"},
+		{-time.Second, "00:00"},
+		{-2 * time.Hour, "00:00"},
+		{time.Nanosecond, "00:00"},
+		{499 * time.Millisecond, "00:00"},
+		{500 * time.Millisecond, "00:01"},
+		{999 * time.Millisecond, "00:01"},
+		{time.Second, "00:01"},
+		{7*time.Minute + 5*time.Second, "07:05"},
+		{59*time.Minute + 59*time.Second, "59:59"},
+		{59*time.Minute + 59*time.Second + 499*time.Millisecond, "59:59"},
+		{59*time.Minute + 59*time.Second + 500*time.Millisecond, "1:00:00"},
+		{time.Hour, "1:00:00"},
+		{90 * time.Minute, "1:30:00"},
+		{10*time.Hour + 5*time.Second, "10:00:05"},
+		{day - time.Second, "23:59:59"},
+		{day, "24:00:00"},
+		{day + 90*time.Minute, "25:30:00"},
+		{100*day + time.Second, "2400:00:01"},
+	}
+	for _, tt := range tests {
+		if got := DurationClock(tt.d); got != tt.want {
+			t.Errorf("DurationClock(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestDurationHuman(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{-time.Minute, "0s"},
+		{time.Nanosecond, "0s"},
+		{499 * time.Millisecond, "0s"},
+		{500 * time.Millisecond, "1s"},
+		{40 * time.Second, "40s"},
+		{59*time.Second + 499*time.Millisecond, "59s"},
+		// From a minute, to the nearest minute.
+		{59*time.Second + 500*time.Millisecond, "1m"},
+		{time.Minute, "1m"},
+		{89 * time.Second, "1m"},
+		{90 * time.Second, "2m"},
+		{45 * time.Minute, "45m"},
+		{59*time.Minute + 29*time.Second, "59m"},
+		{59*time.Minute + 30*time.Second, "1h"},
+		{time.Hour, "1h"},
+		{90 * time.Minute, "1h 30m"},
+		{2 * time.Hour, "2h"},
+		{day - 31*time.Second, "23h 59m"},
+		{day, "24h"},
+		{day + 90*time.Minute, "25h 30m"},
+		{100 * day, "2400h"},
+	}
+	for _, tt := range tests {
+		if got := DurationHuman(tt.d); got != tt.want {
+			t.Errorf("DurationHuman(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestDurationPrecise(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{-time.Second, "0s"},
+		{499 * time.Millisecond, "0s"},
+		{500 * time.Millisecond, "1s"},
+		{45 * time.Second, "45s"},
+		{time.Minute, "1m00s"},
+		{3*time.Minute + 12*time.Second, "3m12s"},
+		{59*time.Minute + 59*time.Second + 500*time.Millisecond, "1h00m00s"},
+		{time.Hour + 5*time.Minute + 12*time.Second, "1h05m12s"},
+		{day + time.Second, "24h00m01s"},
+	}
+	for _, tt := range tests {
+		if got := DurationPrecise(tt.d); got != tt.want {
+			t.Errorf("DurationPrecise(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		f    float64
+		want string
+	}{
+		{0, "0%"},
+		{0.004, "0%"},
+		{0.006, "1%"},
+		{0.5, "50%"},
+		{0.844, "84%"},
+		{0.846, "85%"},
+		{0.999, "100%"},
+		{1, "100%"},
+		{1.5, "150%"},
+		{-0.25, "-25%"},
+	}
+	for _, tt := range tests {
+		if got := Percent(tt.f); got != tt.want {
+			t.Errorf("Percent(%g) = %q, want %q", tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestStyle(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Style
+		d    string
+	}{
+		{"clock", StyleClock, "1:30:00"},
+		{"human", StyleHuman, "1h 30m"},
+	}
+	for _, tt := range tests {
+		s, err := ParseStyle(tt.in)
+		if err != nil || s != tt.want {
+			t.Errorf("ParseStyle(%q) = %v, %v, want %v", tt.in, s, err, tt.want)
+		}
+		if s.String() != tt.in {
+			t.Errorf("%v.String() = %q, want %q", s, s.String(), tt.in)
+		}
+		if got := s.Duration(90 * time.Minute); got != tt.d {
+			t.Errorf("%s: 90m as %q, want %q", s, got, tt.d)
+		}
+	}
+	for _, in := range []string{"", "Clock", "HUMAN", "digital", "clock "} {
+		if s, err := ParseStyle(in); err == nil {
+			t.Errorf("ParseStyle(%q) = %v, want an error", in, s)
+		} else if want := `unknown time style "` + in + `" (want clock or human)`; err.Error() != want {
+			t.Errorf("ParseStyle(%q): %q, want %q", in, err, want)
+		}
+	}
+}
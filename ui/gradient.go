@@ -0,0 +1,140 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/vbauerster/mpb/v8"
+	"github.com/vbauerster/mpb/v8/decor"
+)
+
+type RGB struct{ R, G, B uint8 }
+
+type ColorStop struct {
+	At    float64
+	Color RGB
+}
+
+// Gradient maps a fraction in [0, 1] onto colors interpolated between stops,
+// which must be ordered by At.
+type Gradient struct {
+	Stops []ColorStop
+}
+
+var (
+	gradientGreen  = RGB{0x2e, 0xcc, 0x40}
+	gradientYellow = RGB{0xff, 0xdc, 0x00}
+	gradientRed    = RGB{0xff, 0x41, 0x36}
+)
+
+// TrafficLight runs green to yellow at yellowAt and yellow to red at redAt.
+func TrafficLight(yellowAt, redAt float64) Gradient {
+	return Gradient{Stops: []ColorStop{
+		{At: 0, Color: gradientGreen},
+		{At: yellowAt, Color: gradientYellow},
+		{At: redAt, Color: gradientRed},
+	}}
+}
+
+func (g Gradient) At(t float64) RGB {
+	if len(g.Stops) == 0 {
+		return RGB{}
+	}
+	if t <= g.Stops[0].At {
+		return g.Stops[0].Color
+	}
+
+	for i := 1; i < len(g.Stops); i++ {
+		lo, hi := g.Stops[i-1], g.Stops[i]
+		if t > hi.At {
+			continue
+		}
+		span := hi.At - lo.At
+		if span <= 0 {
+			return hi.Color
+		}
+		return lerpRGB(lo.Color, hi.Color, (t-lo.At)/span)
+	}
+
+	return g.Stops[len(g.Stops)-1].Color
+}
+
+func lerpRGB(a, b RGB, t float64) RGB {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*t + 0.5)
+	}
+	return RGB{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B)}
+}
+
+type colorProfile int
+
+const (
+	profileBasic colorProfile = iota
+	profile256
+	profileTrueColor
+)
+
+// detectColorProfile reports profileBasic when gradients should not be used,
+// including under NO_COLOR and when output is not a terminal.
+func detectColorProfile() colorProfile {
+	if color.NoColor {
+		return profileBasic
+	}
+
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return profileTrueColor
+	}
+
+	if strings.Contains(os.Getenv("TERM"), "256color") {
+		return profile256
+	}
+
+	return profileBasic
+}
+
+func (p colorProfile) foreground(c RGB, s string) string {
+	switch p {
+	case profileTrueColor:
+		return fmt.Sprintf("\x1b[38;2;%d;%d;%dm%s\x1b[0m", c.R, c.G, c.B, s)
+	case profile256:
+		return fmt.Sprintf("\x1b[38;5;%dm%s\x1b[0m", ansi256(c), s)
+	default:
+		return s
+	}
+}
+
+// ansi256 picks the nearest entry of the 6x6x6 color cube.
+func ansi256(c RGB) int {
+	level := func(v uint8) int { return (int(v)*5 + 127) / 255 }
+	return 16 + 36*level(c.R) + 6*level(c.G) + level(c.B)
+}
+
+type gradientStyle struct {
+	style    mpb.BarStyleComposer
+	gradient Gradient
+	profile  colorProfile
+	reverse  bool
+}
+
+func (g gradientStyle) Build() mpb.BarFiller {
+	var fraction float64
+
+	inner := g.style.FillerMeta(func(s string) string {
+		t := fraction
+		if g.reverse {
+			t = 1 - t
+		}
+		return g.profile.foreground(g.gradient.At(t), s)
+	}).Build()
+
+	return mpb.BarFillerFunc(func(w io.Writer, st decor.Statistics) error {
+		if st.Total > 0 {
+			fraction = float64(st.Current) / float64(st.Total)
+		}
+		return inner.Fill(w, st)
+	})
+}
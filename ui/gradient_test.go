@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestGradientAt(t *testing.T) {
+	g := TrafficLight(0.5, 0.8)
+	tests := []struct {
+		at   float64
+		want RGB
+	}{
+		{-1, gradientGreen},
+		{0, gradientGreen},
+		{0.25, RGB{0x97, 0xd4, 0x20}},
+		{0.5, gradientYellow},
+		{0.65, RGB{0xff, 0x8f, 0x1b}},
+		{0.8, gradientRed},
+		{1, gradientRed},
+		{2, gradientRed},
+	}
+	for _, tt := range tests {
+		if got := g.At(tt.at); got != tt.want {
+			t.Errorf("At(%g) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestGradientEdges(t *testing.T) {
+	if got := (Gradient{}).At(0.5); got != (RGB{}) {
+		t.Errorf("no stops: %v, want black", got)
+	}
+	one := Gradient{Stops: []ColorStop{{At: 0.3, Color: gradientYellow}}}
+	for _, at := range []float64{0, 0.3, 1} {
+		if got := one.At(at); got != gradientYellow {
+			t.Errorf("one stop, At(%g) = %v, want it", at, got)
+		}
+	}
+	// Stops at the same place switch color there, with nothing to divide
+	// by between them.
+	step := TrafficLight(0.5, 0.5)
+	if got := step.At(0.5); got != gradientYellow {
+		t.Errorf("At the step = %v, want yellow", got)
+	}
+	if got := step.At(0.5000001); got != gradientRed {
+		t.Errorf("past the step = %v, want red", got)
+	}
+}
+
+func TestLerpRGB(t *testing.T) {
+	black, white := RGB{}, RGB{255, 255, 255}
+	for _, tt := range []struct {
+		t    float64
+		want RGB
+	}{
+		{0, black},
+		{1, white},
+		{0.5, RGB{128, 128, 128}},
+		{0.1, RGB{26, 26, 26}},
+	} {
+		if got := lerpRGB(black, white, tt.t); got != tt.want {
+			t.Errorf("lerpRGB(black, white, %g) = %v, want %v", tt.t, got, tt.want)
+		}
+		if got := lerpRGB(white, black, 1-tt.t); got != tt.want {
+			t.Errorf("lerpRGB(white, black, %g) = %v, want %v", 1-tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestANSI256(t *testing.T) {
+	for c, want := range map[RGB]int{
+		{0, 0, 0}:       16,
+		{255, 255, 255}: 231,
+		{255, 0, 0}:     196,
+		gradientGreen:   77,
+		gradientYellow:  220,
+	} {
+		if got := ansi256(c); got != want {
+			t.Errorf("ansi256(%v) = %d, want %d", c, got, want)
+		}
+	}
+}
+
+func TestColorProfile(t *testing.T) {
+	tests := []struct {
+		noColor   bool
+		colorterm string
+		term      string
+		want      colorProfile
+	}{
+		{false, "truecolor", "xterm", profileTrueColor},
+		{false, "24bit", "", profileTrueColor},
+		{false, "", "xterm-256color", profile256},
+		{false, "", "xterm", profileBasic},
+		{false, "", "", profileBasic},
+		{true, "truecolor", "xterm-256color", profileBasic},
+	}
+	for _, tt := range tests {
+		saved := color.NoColor
+		color.NoColor = tt.noColor
+		t.Setenv("COLORTERM", tt.colorterm)
+		t.Setenv("TERM", tt.term)
+		got := detectColorProfile()
+		color.NoColor = saved
+		if got != tt.want {
+			t.Errorf("NoColor %t, COLORTERM %q, TERM %q: profile %d, want %d", tt.noColor, tt.colorterm, tt.term, got, tt.want)
+		}
+	}
+
+	c := RGB{1, 2, 3}
+	for p, want := range map[colorProfile]string{
+		profileTrueColor: "\x1b[38;2;1;2;3m=\x1b[0m",
+		profile256:       "\x1b[38;5;16m=\x1b[0m",
+		profileBasic:     "=",
+	} {
+		if got := p.foreground(c, "="); got != want {
+			t.Errorf("profile %d: %q, want %q", p, got, want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// LinkMode is when to emit terminal hyperlinks.
+type LinkMode int
+
+const (
+	LinksAuto LinkMode = iota
+	LinksAlways
+	LinksNever
+)
+
+// ParseLinkMode takes "auto", "always", or "never".
+func ParseLinkMode(s string) (LinkMode, error) {
+	switch s {
+	case "auto":
+		return LinksAuto, nil
+	case "always":
+		return LinksAlways, nil
+	case "never":
+		return LinksNever, nil
+	default:
+		return 0, fmt.Errorf("unknown hyperlink mode %q (want auto, always, or never)", s)
+	}
+}
+
+// Links reports whether hyperlinks should be written to output under m.
+// Under LinksAuto that takes a terminal that is known to support them,
+// going by what it sets in the environment, since a terminal that does not
+// may print the escape as is. A nil output means standard output.
+func (m LinkMode) Links(output io.Writer) bool {
+	switch m {
+	case LinksAlways:
+		return true
+	case LinksNever:
+		return false
+	}
+	if output == nil {
+		output = os.Stdout
+	}
+	if f, ok := output.(*os.File); !ok || !isatty.IsTerminal(f.Fd()) {
+		return false
+	}
+	return hyperlinkTerminal(os.Getenv)
+}
+
+// hyperlinkTerminal is whether the environment getenv reads is that of a
+// terminal known to support OSC 8 hyperlinks. FORCE_HYPERLINK, as other
+// tools read it, settles it either way.
+func hyperlinkTerminal(getenv func(string) string) bool {
+	if v := getenv("FORCE_HYPERLINK"); v != "" {
+		return v != "0"
+	}
+	term := getenv("TERM")
+	if term == "dumb" {
+		return false
+	}
+	// Multiplexers pass the escape on only when told to, so not by default.
+	if getenv("TMUX") != "" || strings.HasPrefix(term, "screen") {
+		return false
+	}
+	switch getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "rio":
+		return true
+	}
+	for _, name := range []string{"KITTY_WINDOW_ID", "WT_SESSION", "KONSOLE_VERSION", "DOMTERM"} {
+		if getenv(name) != "" {
+			return true
+		}
+	}
+	// GNOME Terminal and the rest built on VTE, from 0.50.
+	if v, err := strconv.Atoi(getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+	for _, name := range []string{"kitty", "alacritty", "foot", "wezterm", "ghostty"} {
+		if strings.Contains(term, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hyperlink wraps text in an OSC 8 escape linking it to url, which a
+// terminal that supports them shows as text and opens as url. Control
+// characters are dropped from url, as they would end the escape early.
+func Hyperlink(url, text string) string {
+	url = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, url)
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// ExpandLink fills in a link template's {id} with id.
+func ExpandLink(template, id string) string {
+	return strings.ReplaceAll(template, "{id}", id)
+}
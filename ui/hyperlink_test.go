@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/history"
+)
+
+func TestHyperlink(t *testing.T) {
+	tests := []struct {
+		url, text, want string
+	}{
+		{"pomo://history/1234567", "1234567", "\x1b]8;;pomo://history/1234567\x1b\\1234567\x1b]8;;\x1b\\"},
+		{"https://notes.example/s?id=1&x=2", "note", "\x1b]8;;https://notes.example/s?id=1&x=2\x1b\\note\x1b]8;;\x1b\\"},
+		{"", "text", "\x1b]8;;\x1b\\text\x1b]8;;\x1b\\"},
+		// Control characters would end the escape early.
+		{"pomo://a\x1b\\b\x07c\nd\x7fe", "t", "\x1b]8;;pomo://a\\bcde\x1b\\t\x1b]8;;\x1b\\"},
+		{"pomo://ünï", "ünï", "\x1b]8;;pomo://ünï\x1b\\ünï\x1b]8;;\x1b\\"},
+	}
+	for _, tt := range tests {
+		if got := Hyperlink(tt.url, tt.text); got != tt.want {
+			t.Errorf("Hyperlink(%q, %q) = %q, want %q", tt.url, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExpandLink(t *testing.T) {
+	if got := ExpandLink("notes://s/{id}?back={id}", "0042"); got != "notes://s/0042?back=0042" {
+		t.Errorf("expanded to %q", got)
+	}
+	if got := ExpandLink("pomo://history", "0042"); got != "pomo://history" {
+		t.Errorf("template without {id} expanded to %q", got)
+	}
+}
+
+func TestLinkMode(t *testing.T) {
+	t.Setenv("FORCE_HYPERLINK", "1")
+	for s, want := range map[string]bool{"always": true, "never": false, "auto": false} {
+		m, err := ParseLinkMode(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Not a terminal, so auto never links, whatever the environment.
+		if got := m.Links(&bytes.Buffer{}); got != want {
+			t.Errorf("%s links to a buffer: %v, want %v", s, got, want)
+		}
+	}
+	if _, err := ParseLinkMode("yes"); err == nil || err.Error() != `unknown hyperlink mode "yes" (want auto, always, or never)` {
+		t.Errorf("ParseLinkMode(yes): %v", err)
+	}
+}
+
+func TestHyperlinkTerminal(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		want bool
+	}{
+		{nil, false},
+		{map[string]string{"TERM": "xterm-256color"}, false},
+		{map[string]string{"TERM_PROGRAM": "iTerm.app"}, true},
+		{map[string]string{"TERM_PROGRAM": "Apple_Terminal"}, false},
+		{map[string]string{"TERM_PROGRAM": "vscode"}, true},
+		{map[string]string{"KITTY_WINDOW_ID": "1"}, true},
+		{map[string]string{"WT_SESSION": "x"}, true},
+		{map[string]string{"VTE_VERSION": "5000"}, true},
+		{map[string]string{"VTE_VERSION": "4602"}, false},
+		{map[string]string{"TERM": "xterm-kitty"}, true},
+		{map[string]string{"TERM": "alacritty"}, true},
+		{map[string]string{"TERM": "dumb", "TERM_PROGRAM": "iTerm.app"}, false},
+		{map[string]string{"TMUX": "/tmp/tmux", "TERM_PROGRAM": "iTerm.app"}, false},
+		{map[string]string{"TERM": "screen-256color", "KITTY_WINDOW_ID": "1"}, false},
+		{map[string]string{"FORCE_HYPERLINK": "1", "TERM": "dumb"}, true},
+		{map[string]string{"FORCE_HYPERLINK": "0", "TERM_PROGRAM": "iTerm.app"}, false},
+	}
+	for _, tt := range tests {
+		getenv := func(name string) string { return tt.env[name] }
+		if got := hyperlinkTerminal(getenv); got != tt.want {
+			t.Errorf("%v: %v, want %v", tt.env, got, tt.want)
+		}
+	}
+}
+
+// TestTimelineLinks prints a record with a link template and without, the
+// plain fallback showing the ID alone.
+func TestTimelineLinks(t *testing.T) {
+	noColor(t)
+	start := time.Date(2025, time.January, 6, 9, 0, 0, 0, time.Local)
+	r := history.Record{
+		Start: start, End: start.Add(25 * time.Minute), Phase: engine.PhaseWork,
+		PlannedMS: 1500000, ActualMS: 1500000, Ended: engine.EndCompleted,
+	}
+	id := r.ID()
+
+	var linked, plain bytes.Buffer
+	PrintTimeline(&linked, []history.Record{r}, 0, true, "pomo://history/{id}")
+	PrintTimeline(&plain, []history.Record{r}, 0, true, "")
+
+	link := "\x1b]8;;pomo://history/" + id + "\x1b\\" + id + "\x1b]8;;\x1b\\ "
+	if !strings.HasPrefix(linked.String(), link) {
+		t.Errorf("linked row %q, want it to start %q", linked.String(), link)
+	}
+	if !strings.HasPrefix(plain.String(), id+" ") || strings.Contains(plain.String(), "\x1b") {
+		t.Errorf("plain row %q, want it to start with the ID and no escapes", plain.String())
+	}
+	if strings.TrimPrefix(linked.String(), link) != strings.TrimPrefix(plain.String(), id+" ") {
+		t.Errorf("rows differ past the ID:\n%q\n%q", linked.String(), plain.String())
+	}
+}
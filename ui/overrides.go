@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Override is a setting given in more than one place: the value that won
+// and where it came from, then the values it overrode, highest first.
+type Override struct {
+	Key    string
+	Value  string
+	Source string
+	Beaten []Beaten
+}
+
+// Beaten is a value an Override took precedence over.
+type Beaten struct {
+	Source string
+	Value  string
+}
+
+var struckColor = color.New(color.CrossedOut)
+
+// PrintOverrides writes a line per override, e.g.
+// "  pomodoro: 30 (flag, profile classic=25)", the overridden values dim
+// and struck through.
+func PrintOverrides(w io.Writer, overrides []Override) {
+	for _, o := range overrides {
+		notes := []string{o.Source}
+		for _, b := range o.Beaten {
+			notes = append(notes, dimColor.Sprint(b.Source+"="+struckColor.Sprint(b.Value)))
+		}
+		fmt.Fprintf(w, "  %s: %s (%s)\n", o.Key, overallColor.Sprint(o.Value), strings.Join(notes, ", "))
+	}
+}
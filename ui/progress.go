@@ -3,150 +3,872 @@ package ui
 import (
 	"fmt"
 	"io"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/sound"
+	"github.com/steenfuentes/pomo/ui/format"
 	"github.com/vbauerster/mpb/v8"
-	"github.com/vbauerster/mpb/v8/decor"
 )
 
 var (
-	workColor      = color.New(color.FgRed)
-	shortColor     = color.New(color.FgCyan)
-	longColor      = color.New(color.FgGreen)
-	overallColor   = color.New(color.FgWhite)
-	dimColor       = color.New(color.Faint)
+	workColor     = DarkTheme.Work
+	shortColor    = DarkTheme.Short
+	longColor     = DarkTheme.Long
+	cooldownColor = DarkTheme.Cooldown
+	warningColor  = DarkTheme.Warning
+	overallColor  = DarkTheme.Overall
+	dimColor      = DarkTheme.Dim
 )
 
 type Progress struct {
-	container   *mpb.Progress
-	phaseBar    *mpb.Bar
-	overallBar  *mpb.Bar
+	bars        barSet
+	phaseBar    bar
+	overallBar  bar
 	showOverall bool
-	totalPhases int
 	phaseTotal  int64
-	lastPhase   engine.Phase
+	// lastComplete is whether the last event ended its phase, and ranOut
+	// whether it ended it by running to its end rather than being skipped.
+	lastComplete bool
+	ranOut       bool
+
+	// The overall bar counts up to overallTotal of what counts says.
+	counts       OverallCounts
+	overallTotal int
+	overallDone  int
+
+	// nextUp shows what comes after the phase under its bar.
+	nextUp bool
+
+	// banking is Config.BankBreaks. extended and fromBank are the current
+	// break's extension as last shown.
+	banking  bool
+	extended time.Duration
+	fromBank time.Duration
+
+	gradient *Gradient
+	profile  colorProfile
+	warnings map[engine.Phase]time.Duration
+	warned   bool
+	quiet    func() bool
+	sounds   *sound.Set
+
+	// Infinite sessions show a tally of cycles and focus time instead of
+	// the overall bar.
+	focusBase time.Duration
+	workDone  time.Duration
+
+	// The header counts completed work phases toward the day's goal.
+	header    bar
+	today     *Today
+	todayDone atomic.Int64
+
+	// Written by Update, read by decorators while rendering.
+	sessionRemaining atomic.Int64
+	phasePaused      *atomic.Int64
+	phaseNote        *atomic.Pointer[string]
+	phaseEnded       *atomic.Pointer[engine.EndReason]
+	phaseNext        *atomic.Pointer[string]
+	cyclesDone       atomic.Int64
+	focused          atomic.Int64
+
+	// transitionBar counts down a transition or, with snoozing set, a
+	// snooze. With awaiting set, it counts up the wait for a check-in in
+	// transitionLeft instead.
+	transitionBar  bar
+	transitionLeft *atomic.Int64
+	transitionNext string
+	counting       bool
+	snoozing       bool
+	awaiting       bool
+
+	// The layout turns compact and back as the width calls for it. The
+	// compact line stands in for every bar, drawing what view holds, and
+	// the phase bars left from before show as in spec.short.
+	layout     Layout
+	compact    atomic.Bool
+	compactBar bar
+	view       atomic.Pointer[compactView]
+	spec       phaseSpec
+
+	// style is how the phase bars and the compact line show time.
+	style format.Style
+
+	detached atomic.Bool
+	failed   chan error
+	stepping bool
+}
+
+type Option func(*Progress)
+
+// WithStepping draws a frame after each update, and only then, waiting for
+// it to be written. The same events then always produce the same output.
+func WithStepping() Option {
+	return func(p *Progress) {
+		p.stepping = true
+	}
+}
+
+// WithGradient shifts the phase bar color along g as the phase progresses,
+// reversed for breaks. It has no effect on terminals without 256-color or
+// truecolor support.
+func WithGradient(g Gradient) Option {
+	return func(p *Progress) {
+		if p.profile = detectColorProfile(); p.profile != profileBasic {
+			p.gradient = &g
+		}
+	}
+}
+
+// WithTimeStyle shows the phase bars' times in style rather than as a
+// clock.
+func WithTimeStyle(style format.Style) Option {
+	return func(p *Progress) {
+		p.style = style
+	}
+}
+
+// OverallCounts is what the overall bar of a finite session counts.
+type OverallCounts int
+
+const (
+	// CountPhases counts every phase the session plans, breaks included,
+	// however each ended.
+	CountPhases OverallCounts = iota
+	// CountWork counts work phases completed, as pomodoros toward the
+	// session's cycles; a skipped or interrupted one does not count.
+	CountWork
+)
+
+// ParseOverallCounts takes "phases" or "work".
+func ParseOverallCounts(s string) (OverallCounts, error) {
+	switch s {
+	case "phases":
+		return CountPhases, nil
+	case "work":
+		return CountWork, nil
+	default:
+		return 0, fmt.Errorf("unknown overall count %q (want phases or work)", s)
+	}
+}
+
+func (c OverallCounts) String() string {
+	if c == CountWork {
+		return "work"
+	}
+	return "phases"
+}
+
+// Total is what the overall bar counts up to for a session of phases
+// phases and cycles cycles, as NewProgress takes it.
+func (c OverallCounts) Total(phases, cycles int) int {
+	if c == CountWork {
+		return cycles
+	}
+	return phases
+}
+
+// WithOverallCounts has the overall bar count c, up to the total
+// NewProgress is given, which c.Total works out.
+func WithOverallCounts(c OverallCounts) Option {
+	return func(p *Progress) {
+		p.counts = c
+	}
+}
+
+// WithNextUp shows a dim line under the phase bar saying what comes
+// next, like "next: Short Break (10m) → Work 3/4".
+func WithNextUp() Option {
+	return func(p *Progress) {
+		p.nextUp = true
+	}
 }
 
-func NewProgress(totalPhases int, output io.Writer) *Progress {
-	opts := []mpb.ContainerOption{
-		mpb.WithWidth(50),
-		mpb.WithRefreshRate(50 * time.Millisecond),
+// WithFocusedToday counts focus time from earlier sessions today into the
+// tally shown for infinite sessions.
+func WithFocusedToday(d time.Duration) Option {
+	return func(p *Progress) {
+		p.focusBase = d
 	}
-	if output != nil {
-		opts = append(opts, mpb.WithOutput(output))
+}
+
+// Today is what the header above the bars starts from: pomodoros already
+// completed today, the day's goal, and the shortest work phase that counts.
+type Today struct {
+	Done    int
+	Goal    int
+	MinWork time.Duration
+	ASCII   bool
+}
+
+// WithToday shows a header like "Today: ▇▇▇▁▁▁▁▁ 3/8" above the bars,
+// counting each work phase completed in the session.
+func WithToday(t Today) Option {
+	return func(p *Progress) {
+		p.today = &t
 	}
+}
 
+// NewProgress draws a session whose overall bar counts up to total, or
+// that shows a tally in its place for a total of 0, as infinite sessions
+// have.
+func NewProgress(total int, output io.Writer, options ...Option) *Progress {
 	p := &Progress{
-		container:   mpb.New(opts...),
-		showOverall: totalPhases > 0,
-		totalPhases: totalPhases,
-		lastPhase:   engine.Phase(-1),
+		showOverall:  total > 0,
+		overallTotal: total,
+		layout:       DefaultLayout(),
+		failed:       make(chan error, 1),
+	}
+	for _, opt := range options {
+		opt(p)
 	}
 
-	if p.showOverall {
-		p.overallBar = p.container.New(int64(totalPhases),
-			mpb.BarStyle().Lbound("[").Filler("=").Tip(">").Padding("-").Rbound("]"),
-			mpb.PrependDecorators(
-				decor.Name(overallColor.Sprint("  Total "), decor.WCSyncSpaceR),
-			),
-			mpb.AppendDecorators(
-				decor.CountersNoUnit(dimColor.Sprint(" %d/%d"), decor.WCSyncSpace),
-			),
-			mpb.BarFillerClearOnComplete(),
-		)
+	p.bars = newBars(output, p.stepping, p.layout.MaxWidth, p.style)
+	GuardTerminal(output)
+	p.focused.Store(int64(p.focusBase))
+	if t := p.today; t != nil {
+		p.todayDone.Store(int64(t.Done))
+	}
+	if p.layout.compactAt(p.bars.width()) {
+		p.compact.Store(true)
+		p.compactBar = p.bars.addCompact(&p.view, p.layout)
+	} else {
+		p.addSummary()
 	}
 
 	return p
 }
 
+// addSummary adds the bars that go above the phase bars: the header and
+// the overall bar, or the tally in its place.
+func (p *Progress) addSummary() {
+	if t := p.today; t != nil {
+		p.header = p.bars.addHeader(func() string {
+			return Blocks(int(p.todayDone.Load()), t.Goal, t.ASCII)
+		})
+	}
+	if p.showOverall {
+		unit := ""
+		if p.counts == CountWork {
+			unit = " pomodoros"
+		}
+		p.overallBar = p.bars.addOverall(int64(p.overallTotal), unit, &p.sessionRemaining)
+		p.overallBar.setCurrent(int64(p.overallDone))
+	} else {
+		p.overallBar = p.bars.addTally(&p.cyclesDone, &p.focused)
+	}
+}
+
+// fitLayout turns the layout compact or back when the width calls for it,
+// e.g. as the terminal is resized, dropping the bars of the one for those
+// of the other.
+func (p *Progress) fitLayout() {
+	compact := p.layout.compactAt(p.bars.width())
+	if compact == p.compact.Load() {
+		return
+	}
+	p.compact.Store(compact)
+
+	if compact {
+		for _, b := range []bar{p.header, p.overallBar, p.transitionBar} {
+			if b != nil {
+				b.drop()
+			}
+		}
+		p.header, p.overallBar, p.transitionBar = nil, nil, nil
+		switch {
+		case p.phaseBar == nil:
+		case p.ranOut:
+			p.phaseBar.complete()
+		default:
+			p.phaseBar.drop()
+		}
+		p.phaseBar = nil
+		p.compactBar = p.bars.addCompact(&p.view, p.layout)
+		return
+	}
+
+	p.compactBar.drop()
+	p.compactBar = nil
+	p.addSummary()
+	if p.phaseTotal > 0 && !p.lastComplete {
+		p.phaseBar = p.bars.addPhase(p.spec)
+	}
+	if p.counting {
+		p.addCountdown()
+	}
+}
+
 func (p *Progress) Update(e engine.TimerEvent) {
-	if p.phaseBar == nil || e.Phase != p.lastPhase {
-		if p.phaseBar != nil {
-			p.phaseBar.SetCurrent(p.phaseTotal)
-			p.phaseBar.EnableTriggerComplete()
+	if p.detached.Load() {
+		return
+	}
+	if e.ClockJump > 0 {
+		p.Logf("%s", warningColor.Sprintf("System clock went back %s, the timer carries on regardless", format.DurationPrecise(e.ClockJump)))
+	}
+	switch e.Type {
+	case engine.EventTransition, engine.EventSnooze, engine.EventAway:
+		p.fitLayout()
+		p.countdown(e)
+		return
+	case engine.EventTick:
+		p.fitLayout()
+	case engine.EventSessionStarted:
+		p.banking = e.Config.BankBreaks
+		return
+	case engine.EventSessionEnded:
+		// The session only sounds its end when the config says how.
+		if e.Summary.Ended != engine.EndInterrupted && p.sounds.Configured(sound.SessionDone) {
+			if bell := p.ring(sound.SessionDone); bell != "" {
+				io.WriteString(p.bars, bell)
+			}
+		}
+		return
+	default:
+		return
+	}
+
+	// mpb cancels every bar when a write to the terminal fails, so a
+	// running phase bar that has stopped means nothing is being drawn.
+	live := p.phaseBar
+	if p.compactBar != nil {
+		live = p.compactBar
+	}
+	if live != nil && !p.lastComplete && !live.running() {
+		p.Detach()
+		p.failed <- p.bars.err()
+		return
+	}
+
+	p.sessionRemaining.Store(int64(e.SessionRemaining))
+	// Every phase ends on a complete event, and consecutive phases can be
+	// of the same kind once extras are spliced in.
+	if p.phaseTotal == 0 || p.lastComplete {
+		// Without a countdown before it, where its cue usually sounds, a
+		// phase sounds its start if the config sets a cue for it.
+		if cue := sound.PhaseStart(e.Phase); !p.counting && p.sounds.Configured(cue) {
+			if bell := p.ring(cue); bell != "" {
+				io.WriteString(p.bars, bell)
+			}
+		}
+		p.endTransition()
+		p.startPhase(e)
+		p.noteOverdue(e)
+		p.noteLunch(e)
+	}
+
+	if e.Extended > p.extended {
+		p.extend(e)
+	}
+
+	if before := p.warnings[e.Phase]; !p.warned && before > 0 && e.Total > before && e.Remaining <= before && e.Ended == "" {
+		p.warned = true
+		p.Logf("%s%s ends in %s", p.ring(sound.Warning), e.Phase, format.DurationPrecise(e.Remaining))
+	}
+
+	if e.Phase == engine.PhaseWarmup && e.Ended == engine.EndCompleted {
+		p.Logf("Warmup over — work begins")
+	}
+
+	p.lastComplete = e.PhaseComplete
+	if e.Ended != "" {
+		p.phaseEnded.Store(&e.Ended)
+	}
+	p.ranOut = e.PhaseComplete && e.Ended == engine.EndCompleted
+	p.phasePaused.Store(int64(e.PausedTotal))
+	if note := phaseNote(e, p.banking); note != *p.phaseNote.Load() {
+		p.phaseNote.Store(&note)
+	}
+	if p.phaseNext != nil {
+		if next := p.nextLine(e); next != *p.phaseNext.Load() {
+			p.phaseNext.Store(&next)
 		}
+	}
+	if p.phaseBar != nil {
+		p.phaseBar.setCurrent(min(int64(e.Elapsed/time.Millisecond), p.phaseTotal))
+	}
+	p.view.Store(&compactView{phase: e.Phase, elapsed: e.Elapsed, total: e.Total, paused: e.Paused, cycles: compactCycles(e)})
 
-		p.lastPhase = e.Phase
-		p.phaseTotal = int64(e.Total / time.Millisecond)
+	if p.showOverall && p.countsOverall(e) {
+		if p.overallBar != nil {
+			p.overallBar.increment()
+		}
+		p.overallDone++
+	}
+	if !p.showOverall {
+		p.tally(e)
+	}
+	if p.today != nil && e.Phase == engine.PhaseWork && e.Ended == engine.EndCompleted && !e.Voided && e.Total >= p.today.MinWork {
+		p.todayDone.Add(1)
+	}
+	p.bars.frame()
+}
 
-		p.phaseBar = p.container.New(p.phaseTotal,
-			barStyleForPhase(e.Phase),
-			mpb.PrependDecorators(
-				decor.Name(formatPhaseName(e), decor.WCSyncSpaceR),
-			),
-			mpb.AppendDecorators(
-				decor.Any(func(s decor.Statistics) string {
-					elapsed := time.Duration(s.Current) * time.Millisecond
-					total := time.Duration(s.Total) * time.Millisecond
-					return dimColor.Sprintf(" %s/%s", formatDuration(elapsed), formatDuration(total))
-				}, decor.WCSyncSpace),
-			),
-			mpb.BarFillerClearOnComplete(),
-		)
+// countsOverall reports whether e, ending a phase, moves the overall bar
+// on.
+func (p *Progress) countsOverall(e engine.TimerEvent) bool {
+	if !e.PhaseComplete || !e.Counted {
+		return false
 	}
+	if p.counts == CountWork {
+		return e.Phase == engine.PhaseWork && e.Ended == engine.EndCompleted && !e.Voided
+	}
+	return true
+}
 
-	elapsed := int64(e.Elapsed / time.Millisecond)
-	p.phaseBar.SetCurrent(elapsed)
+func (p *Progress) tally(e engine.TimerEvent) {
+	cycles := e.CycleNum - 1
+	focused := p.focusBase + p.workDone
+	if e.Phase == engine.PhaseWork {
+		focused += e.Elapsed
+		if e.Ended != "" {
+			p.workDone += e.Elapsed
+			if !e.Extra {
+				cycles++
+			}
+		}
+	}
+	p.cyclesDone.Store(int64(cycles))
+	p.focused.Store(int64(focused))
+}
 
-	if e.PhaseComplete && p.showOverall && p.overallBar != nil {
-		p.overallBar.Increment()
+// countdown shows the transition, snooze, or wait for a check-in before
+// e's phase below the finished one, chiming as a transition starts.
+func (p *Progress) countdown(e engine.TimerEvent) {
+	p.sessionRemaining.Store(int64(e.SessionRemaining))
+	snooze, away := e.Type == engine.EventSnooze, e.Type == engine.EventAway
+	left := e.Remaining
+	if away {
+		left = e.Elapsed
 	}
+	if p.counting && (p.snoozing != snooze || p.awaiting != away || e.Ended != "") {
+		p.endTransition()
+	}
+	if e.Ended != "" {
+		p.bars.frame()
+		return
+	}
+	if !p.counting {
+		p.counting = true
+		p.transitionLeft = new(atomic.Int64)
+		p.transitionLeft.Store(int64(left))
+		p.transitionNext = formatPhaseName(e)
+		p.snoozing, p.awaiting = snooze, away
+		if !p.compact.Load() {
+			p.addCountdown()
+		}
+		if !snooze && !away {
+			if bell := p.ring(sound.PhaseStart(e.Phase)); bell != "" {
+				io.WriteString(p.bars, bell)
+			}
+		}
+	}
+	p.transitionLeft.Store(int64(left))
+	p.view.Store(&compactView{phase: e.Phase, elapsed: e.Elapsed, total: e.Total, countdown: !away, away: away, cycles: compactCycles(e)})
+	p.bars.frame()
 }
 
-func (p *Progress) Wait() {
+// addCountdown adds the bar for the transition, snooze, or wait under way.
+func (p *Progress) addCountdown() {
+	if p.awaiting {
+		p.transitionBar = p.bars.addAway(p.transitionNext, p.transitionLeft)
+	} else if p.snoozing {
+		p.transitionBar = p.bars.addSnooze(p.transitionNext, p.transitionLeft)
+	} else {
+		p.transitionBar = p.bars.addTransition(p.transitionNext, p.transitionLeft)
+	}
+}
+
+func (p *Progress) endTransition() {
+	if p.transitionBar != nil {
+		p.transitionBar.complete()
+		p.transitionBar = nil
+	}
+	p.counting = false
+}
+
+// bell rings the terminal bell, outside quiet hours.
+func (p *Progress) bell() string {
+	if p.quiet != nil && p.quiet() {
+		return ""
+	}
+	return "\a"
+}
+
+// ring sounds cue, outside quiet hours, returning the bell if that is what
+// it plays.
+func (p *Progress) ring(cue sound.Cue) string {
+	if p.quiet != nil && p.quiet() {
+		return ""
+	}
+	if p.sounds.Ring(cue) {
+		return "\a"
+	}
+	return ""
+}
+
+// noteOverdue speaks up as a break starts after too long without a long
+// one, or as the guard forces a long break.
+func (p *Progress) noteOverdue(e engine.TimerEvent) {
+	switch {
+	case e.Enforced:
+		p.Logf("%s%s", p.bell(), warningColor.Sprintf("Long break enforced after %s focused", format.DurationHuman(e.Overdue)))
+	case e.Overdue > 0 && e.Phase == engine.PhaseShortBreak && !e.Extra:
+		p.Logf("%s%s", p.bell(), warningColor.Sprintf("%s focused without a long break, time to take one", format.DurationHuman(e.Overdue)))
+	}
+}
+
+// noteLunch offers to make a long break that starts at lunch time lunch,
+// or says it has been.
+func (p *Progress) noteLunch(e engine.TimerEvent) {
+	switch {
+	case e.Lunch:
+		p.Logf("Lunch time: this long break runs %s", format.DurationHuman(e.Total))
+	case e.LunchOffer > 0:
+		// As pomo extend takes it, rounded up to cover the offer.
+		offer := (e.LunchOffer + time.Minute - 1).Truncate(time.Minute)
+		p.Logf("%s", warningColor.Sprintf("Lunch time: pomo extend %s stretches this break to lunch", strings.TrimSuffix(offer.String(), "0s")))
+	}
+}
+
+// Nudge is Logf in the warning color, with the bell outside quiet hours.
+func (p *Progress) Nudge(format string, args ...any) {
+	p.Logf("%s%s", p.bell(), warningColor.Sprintf(format, args...))
+}
+
+// Celebrate prints text between bursts of confetti in the phase colors,
+// with the bell outside quiet hours.
+func (p *Progress) Celebrate(text string) {
+	p.Logf("%s%s %s %s", p.bell(), confetti(0), text, confetti(1))
+}
+
+func confetti(offset int) string {
+	colors := []*color.Color{workColor, shortColor, longColor, warningColor, cooldownColor}
+	var b strings.Builder
+	for i, c := range "*.+o*.+" {
+		b.WriteString(colors[(i+offset)%len(colors)].Sprint(string(c)))
+	}
+	return b.String()
+}
+
+// startPhase retires the previous bar, full if its phase ran out and frozen
+// where it stopped otherwise, and opens one for e's phase. The new bar's
+// decorators read per-bar state that is set before it is added, so its
+// first frame never shows the previous phase's values.
+func (p *Progress) startPhase(e engine.TimerEvent) {
+	switch {
+	case p.phaseBar == nil:
+	case p.ranOut:
+		p.phaseBar.complete()
+	default:
+		p.phaseBar.abort()
+	}
+
+	p.warned = false
+	p.extended, p.fromBank = 0, 0
+	// A zero-length phase still gets a bar, one that completes at once.
+	p.phaseTotal = max(int64(e.Total/time.Millisecond), 1)
+
+	paused := new(atomic.Int64)
+	paused.Store(int64(e.PausedTotal))
+	p.phasePaused = paused
+	note := new(atomic.Pointer[string])
+	text := phaseNote(e, p.banking)
+	note.Store(&text)
+	p.phaseNote = note
+	// A bar frozen before its phase has an end was interrupted.
+	ended := new(atomic.Pointer[engine.EndReason])
+	interrupted := engine.EndInterrupted
+	ended.Store(&interrupted)
+	p.phaseEnded = ended
+	p.phaseNext = nil
+	if p.nextUp {
+		next := p.nextLine(e)
+		p.phaseNext = new(atomic.Pointer[string])
+		p.phaseNext.Store(&next)
+	}
+
+	p.spec = phaseSpec{
+		total:   p.phaseTotal,
+		style:   p.warningStyle(e.Phase, e.Total),
+		name:    formatPhaseName(e),
+		short:   compactName(e),
+		compact: &p.compact,
+		paused:  paused,
+		note:    note,
+		ended:   ended,
+		next:    p.phaseNext,
+	}
+	p.phaseBar = nil
+	if !p.compact.Load() {
+		p.phaseBar = p.bars.addPhase(p.spec)
+	}
+}
+
+// extend redraws the phase bar to an extended break's new total, mpb
+// holding a bar to the total it was added with, and says where the time
+// came from.
+func (p *Progress) extend(e engine.TimerEvent) {
+	added, paid := e.Extended-p.extended, e.FromBank-p.fromBank
+	p.extended, p.fromBank = e.Extended, e.FromBank
+	p.phaseTotal = max(int64(e.Total/time.Millisecond), 1)
+	p.spec.total = p.phaseTotal
 	if p.phaseBar != nil {
-		p.phaseBar.SetCurrent(p.phaseTotal)
-		p.phaseBar.EnableTriggerComplete()
+		p.phaseBar.drop()
+		p.phaseBar = p.bars.addPhase(p.spec)
+	}
+
+	switch {
+	case !p.banking:
+		p.Logf("Break extended %s", format.DurationHuman(added))
+	case paid == added:
+		p.Logf("Break extended %s from the bank", format.DurationHuman(added))
+	case paid > 0:
+		p.Logf("%s", warningColor.Sprintf("Break extended %s, %s from the bank and %s past it", format.DurationHuman(added), format.DurationHuman(paid), format.DurationHuman(added-paid)))
+	default:
+		p.Logf("%s", warningColor.Sprintf("Break extended %s past the bank", format.DurationHuman(added)))
+	}
+}
+
+// Logf prints a line above the bars without disturbing them.
+func (p *Progress) Logf(format string, args ...any) {
+	if p.detached.Load() {
+		return
+	}
+	fmt.Fprintf(p.bars, format+"\n", args...)
+}
+
+// Failed delivers the renderer's error if writing to the output stops
+// working, e.g. after the terminal is closed. Progress is detached by then.
+func (p *Progress) Failed() <-chan error {
+	return p.failed
+}
+
+// Detach stops rendering for good. Later calls are no-ops, so the session
+// can keep feeding events to a Progress nobody can see.
+func (p *Progress) Detach() {
+	if p.detached.Swap(true) {
+		return
+	}
+	p.bars.shutdown()
+}
+
+// Abort freezes the bars where the session was interrupted, marking an
+// unfinished phase rather than filling it.
+func (p *Progress) Abort() {
+	if p.detached.Load() {
+		return
+	}
+	p.endTransition()
+	p.settlePhase()
+	// Nothing is left of a session that is over, however much was planned.
+	p.sessionRemaining.Store(0)
+	if p.overallBar != nil {
+		p.overallBar.abort()
+	}
+	if p.header != nil {
+		p.header.abort()
+	}
+	p.bars.frame()
+	p.bars.wait()
+}
+
+// Wait settles the last phase and waits for the final frame. A session
+// stopped before its plan ran out leaves the overall bar where it got to.
+func (p *Progress) Wait() {
+	if p.detached.Load() {
+		return
+	}
+	p.endTransition()
+	p.settlePhase()
+	p.sessionRemaining.Store(0)
+	switch {
+	case p.overallBar == nil:
+	case !p.showOverall:
+		p.overallBar.complete()
+	case p.overallDone < p.overallTotal:
+		p.overallBar.abort()
+	}
+	if p.header != nil {
+		p.header.complete()
+	}
+	p.bars.frame()
+	p.bars.wait()
+}
+
+// settlePhase leaves the phase bar, or the compact line, as the last event
+// had it: full if the phase ran out, and otherwise marked where it stopped,
+// so the final frame shows the time actually spent.
+func (p *Progress) settlePhase() {
+	for _, b := range []bar{p.phaseBar, p.compactBar} {
+		switch {
+		case b == nil:
+		case p.ranOut:
+			b.complete()
+		default:
+			b.abort()
+		}
+	}
+}
+
+func (p *Progress) barStyle(phase engine.Phase) mpb.BarFillerBuilder {
+	if p.gradient == nil {
+		return barStyleForPhase(phase)
+	}
+
+	return gradientStyle{
+		style:    mpb.BarStyle().Lbound("[").Filler("=").Tip(">").Padding("-").Rbound("]"),
+		gradient: *p.gradient,
+		profile:  p.profile,
+		reverse:  phase != engine.PhaseWork,
 	}
-	p.container.Wait()
 }
 
 func barStyleForPhase(phase engine.Phase) mpb.BarFillerBuilder {
-	style := mpb.BarStyle().Lbound("[").Tip(">").Padding("-").Rbound("]")
+	// FillerMeta colors the filler after the bar is measured; coloring the
+	// "=" itself makes each escape sequence count toward the width.
+	style := mpb.BarStyle().Lbound("[").Filler("=").Tip(">").Padding("-").Rbound("]")
 
 	switch phase {
 	case engine.PhaseWork:
-		return style.Filler(workColor.Sprint("="))
+		return style.FillerMeta(paint(workColor))
 	case engine.PhaseShortBreak:
-		return style.Filler(shortColor.Sprint("="))
+		return style.FillerMeta(paint(shortColor))
 	case engine.PhaseLongBreak:
-		return style.Filler(longColor.Sprint("="))
+		return style.FillerMeta(paint(longColor))
+	case engine.PhaseCooldown:
+		return style.FillerMeta(paint(cooldownColor))
+	case engine.PhaseWarmup:
+		// Warmup is neither work nor a break, and left neutral.
+		return style
 	default:
-		return style.Filler("=")
+		return style
 	}
 }
 
-func formatPhaseName(e engine.TimerEvent) string {
-	var c *color.Color
-	name := e.Phase.String()
-
-	switch e.Phase {
+// PhaseColor is the current theme's color for phase.
+func PhaseColor(phase engine.Phase) *color.Color {
+	switch phase {
 	case engine.PhaseWork:
-		c = workColor
+		return workColor
 	case engine.PhaseShortBreak:
-		c = shortColor
+		return shortColor
 	case engine.PhaseLongBreak:
-		c = longColor
+		return longColor
+	case engine.PhaseCooldown:
+		return cooldownColor
+	case engine.PhaseWarmup:
+		return overallColor
 	default:
-		c = overallColor
+		return overallColor
 	}
+}
 
-	if e.TotalCycles > 0 {
-		cycleNum := e.CycleNum
-		if e.Phase != engine.PhaseWork {
-			cycleNum = e.CycleNum - 1
+func paint(c *color.Color) func(string) string {
+	return func(s string) string { return c.Sprint(s) }
+}
+
+func formatPhaseName(e engine.TimerEvent) string {
+	c := PhaseColor(e.Phase)
+	name := e.Phase.String()
+	if e.Lunch {
+		name = "Lunch"
+	}
+
+	if e.Extra {
+		return c.Sprintf("%s (extra)", name)
+	}
+	if e.Final > 0 {
+		return c.Sprintf("%s (final, %s)", name, format.DurationHuman(e.Final))
+	}
+	if e.TotalCycles > 0 && e.Phase != engine.PhaseCooldown && e.Phase != engine.PhaseWarmup {
+		cycleNum := phaseCycle(e)
+		if e.Retry {
+			return c.Sprintf("%s (%d/%d, retry)", name, cycleNum, e.TotalCycles)
 		}
 		return c.Sprintf("%s (%d/%d)", name, cycleNum, e.TotalCycles)
 	}
+	if e.Retry {
+		return c.Sprintf("%s (retry)", name)
+	}
 
 	return c.Sprint(name)
 }
 
-func formatDuration(d time.Duration) string {
-	d = d.Round(time.Second)
-	m := d / time.Minute
-	s := (d % time.Minute) / time.Second
-	return fmt.Sprintf("%02d:%02d", m, s)
+// phaseCycle is the cycle e's phase belongs to: a break the one before it.
+func phaseCycle(e engine.TimerEvent) int {
+	if e.Phase != engine.PhaseWork {
+		return e.CycleNum - 1
+	}
+	return e.CycleNum
+}
+
+// nextLine says what runs after e's phase, and, after a break, which work
+// phase follows it.
+func (p *Progress) nextLine(e engine.TimerEvent) string {
+	// The work after this phase, if it is a work phase of the schedule,
+	// is the next cycle's.
+	cycle := e.CycleNum
+	if e.Phase == engine.PhaseWork && !e.Extra {
+		cycle++
+	}
+	work := fmt.Sprintf("%s %d", engine.PhaseWork, cycle)
+	if e.TotalCycles > 0 {
+		work += fmt.Sprintf("/%d", e.TotalCycles)
+	}
+
+	d := format.DurationHuman(e.NextDuration)
+	then, done := "→", "next: session complete 🎉"
+	if p.layout.ASCII {
+		then, done = "->", "next: session complete"
+	}
+	switch e.NextPhase {
+	case engine.PhaseDone:
+		return done
+	case engine.PhaseWork:
+		return fmt.Sprintf("next: %s (%s)", work, d)
+	case engine.PhaseShortBreak, engine.PhaseLongBreak:
+		return fmt.Sprintf("next: %s (%s) %s %s", e.NextPhase, d, then, work)
+	default:
+		return fmt.Sprintf("next: %s (%s)", e.NextPhase, d)
+	}
+}
+
+// phaseNote counts down to the next long break on the work bar, and shows
+// the bank, and any extension past it, on the break bar while banking.
+func phaseNote(e engine.TimerEvent, banking bool) string {
+	if e.Extra {
+		return ""
+	}
+	if e.Phase == engine.PhaseShortBreak || e.Phase == engine.PhaseLongBreak {
+		if !banking {
+			return ""
+		}
+		var notes []string
+		if e.Bank > 0 {
+			notes = append(notes, "bank "+format.DurationHuman(e.Bank))
+		}
+		if past := e.Extended - e.FromBank; past > 0 {
+			notes = append(notes, format.DurationHuman(past)+" past the bank")
+		}
+		return strings.Join(notes, ", ")
+	}
+	if e.Phase != engine.PhaseWork {
+		return ""
+	}
+	switch {
+	case e.UntilLongBreak == 1:
+		return "long break next"
+	case e.UntilLongBreak > 1:
+		return fmt.Sprintf("long break after %d more", e.UntilLongBreak)
+	case e.WorkUntilLongBreak < 0:
+		return ""
+	case e.WorkUntilLongBreak <= e.Remaining:
+		return "long break next"
+	default:
+		return fmt.Sprintf("long break after %s", format.DurationHuman(e.WorkUntilLongBreak))
+	}
 }
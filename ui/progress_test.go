@@ -0,0 +1,610 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// fakeBars records what Progress asks of its bars, one line per operation
+// but for frames.
+type fakeBars struct {
+	ops []string
+}
+
+func (f *fakeBars) record(format string, args ...any) {
+	f.ops = append(f.ops, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeBars) add(name string, total int64) bar {
+	f.record("add %s/%d", name, total)
+	return &fakeBar{bars: f, name: name, live: true}
+}
+
+func (f *fakeBars) Write(p []byte) (int, error) {
+	f.record("print %q", p)
+	return len(p), nil
+}
+
+func (f *fakeBars) addPhase(spec phaseSpec) bar { return f.add(spec.name, spec.total) }
+func (f *fakeBars) addOverall(total int64, unit string, _ *atomic.Int64) bar {
+	return f.add("overall"+unit, total)
+}
+func (f *fakeBars) addTally(_, _ *atomic.Int64) bar { return f.add("tally", 1) }
+func (f *fakeBars) addHeader(func() string) bar     { return f.add("header", 1) }
+func (f *fakeBars) addTransition(next string, _ *atomic.Int64) bar {
+	return f.add("transition to "+next, 1)
+}
+func (f *fakeBars) addSnooze(next string, _ *atomic.Int64) bar {
+	return f.add("snooze before "+next, 1)
+}
+func (f *fakeBars) addAway(next string, _ *atomic.Int64) bar { return f.add("away before "+next, 1) }
+func (f *fakeBars) addCompact(*atomic.Pointer[compactView], Layout) bar {
+	return f.add("compact", 1)
+}
+func (f *fakeBars) width() int { return 0 }
+func (f *fakeBars) frame()     {}
+func (f *fakeBars) err() error { return nil }
+func (f *fakeBars) shutdown()  { f.record("shutdown") }
+func (f *fakeBars) wait()      { f.record("wait") }
+
+type fakeBar struct {
+	bars *fakeBars
+	name string
+	live bool
+}
+
+func (b *fakeBar) setCurrent(n int64) { b.bars.record("%s = %d", b.name, n) }
+func (b *fakeBar) increment()         { b.bars.record("%s +1", b.name) }
+func (b *fakeBar) complete()          { b.end("complete") }
+func (b *fakeBar) abort()             { b.end("abort") }
+func (b *fakeBar) drop()              { b.end("drop") }
+func (b *fakeBar) running() bool      { return b.live }
+
+func (b *fakeBar) end(how string) {
+	b.live = false
+	b.bars.record("%s %s", b.name, how)
+}
+
+// recordProgress is a Progress drawing with fakeBars, and without color.
+func recordProgress(t *testing.T, total int, options ...Option) (*Progress, *fakeBars) {
+	t.Helper()
+	noColor(t)
+	fake := &fakeBars{}
+	saved := newBars
+	newBars = func(io.Writer, bool, int, format.Style) barSet { return fake }
+	t.Cleanup(func() { newBars = saved })
+	return NewProgress(total, io.Discard, options...), fake
+}
+
+func noColor(t *testing.T) {
+	saved := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = saved })
+}
+
+// twoCycles is a session of two 2s work phases with a 1s break between,
+// a tick a second, each phase ended as ended has it, and the session over
+// after the phases given.
+func twoCycles(phases int, ended ...engine.EndReason) []engine.TimerEvent {
+	cfg := engine.Config{TotalCycles: 2}
+	events := []engine.TimerEvent{{Type: engine.EventSessionStarted, Config: &cfg, TotalCycles: 2, TotalPhases: 3}}
+	schedule := []struct {
+		phase engine.Phase
+		total time.Duration
+		cycle int
+		next  engine.Phase
+	}{
+		{engine.PhaseWork, 2 * time.Second, 1, engine.PhaseShortBreak},
+		{engine.PhaseShortBreak, time.Second, 2, engine.PhaseWork},
+		{engine.PhaseWork, 2 * time.Second, 2, engine.PhaseDone},
+	}
+	for i, s := range schedule[:phases] {
+		end := engine.EndCompleted
+		if i < len(ended) {
+			end = ended[i]
+		}
+		for elapsed := time.Duration(0); elapsed <= s.total; elapsed += time.Second {
+			e := engine.TimerEvent{
+				Type:               engine.EventTick,
+				Phase:              s.phase,
+				Elapsed:            elapsed,
+				Remaining:          s.total - elapsed,
+				Total:              s.total,
+				Counted:            true,
+				CycleNum:           s.cycle,
+				TotalCycles:        2,
+				PhaseNum:           i + 1,
+				TotalPhases:        3,
+				UntilLongBreak:     -1,
+				WorkUntilLongBreak: -1,
+				NextPhase:          s.next,
+			}
+			if end != engine.EndCompleted && elapsed == time.Second {
+				e.Ended, e.PhaseComplete = end, end != engine.EndInterrupted
+				events = append(events, e)
+				break
+			}
+			if elapsed == s.total {
+				e.Ended, e.PhaseComplete = end, true
+			}
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+func TestProgressCompletedSession(t *testing.T) {
+	p, fake := recordProgress(t, 3)
+	for _, e := range twoCycles(3) {
+		p.Update(e)
+	}
+	p.Wait()
+
+	want := []string{
+		"add overall/3",
+		"overall = 0",
+		"add Work (1/2)/2000",
+		"Work (1/2) = 0",
+		"Work (1/2) = 1000",
+		"Work (1/2) = 2000",
+		"overall +1",
+		"Work (1/2) complete",
+		"add Short Break (1/2)/1000",
+		"Short Break (1/2) = 0",
+		"Short Break (1/2) = 1000",
+		"overall +1",
+		"Short Break (1/2) complete",
+		"add Work (2/2)/2000",
+		"Work (2/2) = 0",
+		"Work (2/2) = 1000",
+		"Work (2/2) = 2000",
+		"overall +1",
+		"Work (2/2) complete",
+		"wait",
+	}
+	checkOps(t, fake, want)
+}
+
+func TestProgressStoppedEarly(t *testing.T) {
+	p, fake := recordProgress(t, 3)
+	for _, e := range twoCycles(1) {
+		p.Update(e)
+	}
+	p.Wait()
+
+	// The overall bar stays where the session got to.
+	checkOps(t, fake, []string{
+		"add overall/3",
+		"overall = 0",
+		"add Work (1/2)/2000",
+		"Work (1/2) = 0",
+		"Work (1/2) = 1000",
+		"Work (1/2) = 2000",
+		"overall +1",
+		"Work (1/2) complete",
+		"overall abort",
+		"wait",
+	})
+}
+
+func TestProgressInterrupted(t *testing.T) {
+	p, fake := recordProgress(t, 3)
+	for _, e := range twoCycles(1, engine.EndInterrupted) {
+		p.Update(e)
+	}
+	p.Abort()
+
+	checkOps(t, fake, []string{
+		"add overall/3",
+		"overall = 0",
+		"add Work (1/2)/2000",
+		"Work (1/2) = 0",
+		"Work (1/2) = 1000",
+		"Work (1/2) abort",
+		"overall abort",
+		"wait",
+	})
+}
+
+func TestProgressSkippedPhase(t *testing.T) {
+	p, fake := recordProgress(t, 3)
+	for _, e := range twoCycles(2, engine.EndSkipped) {
+		p.Update(e)
+	}
+	p.Wait()
+
+	// The skipped work stays where it was skipped as the break starts.
+	checkOps(t, fake, []string{
+		"add overall/3",
+		"overall = 0",
+		"add Work (1/2)/2000",
+		"Work (1/2) = 0",
+		"Work (1/2) = 1000",
+		"overall +1",
+		"Work (1/2) abort",
+		"add Short Break (1/2)/1000",
+		"Short Break (1/2) = 0",
+		"Short Break (1/2) = 1000",
+		"overall +1",
+		"Short Break (1/2) complete",
+		"overall abort",
+		"wait",
+	})
+}
+
+func TestProgressOverallCounts(t *testing.T) {
+	done, skipped := engine.EndCompleted, engine.EndSkipped
+	tests := []struct {
+		name   string
+		counts OverallCounts
+		ended  []engine.EndReason
+		want   []string
+	}{
+		{"phases, none skipped", CountPhases, nil, []string{"+1", "+1", "+1"}},
+		// Every phase counts however it ended.
+		{"phases, first work skipped", CountPhases, []engine.EndReason{skipped}, []string{"+1", "+1", "+1"}},
+		{"phases, break skipped", CountPhases, []engine.EndReason{done, skipped}, []string{"+1", "+1", "+1"}},
+		{"phases, last work skipped", CountPhases, []engine.EndReason{done, done, skipped}, []string{"+1", "+1", "+1"}},
+		{"work, none skipped", CountWork, nil, []string{"+1", "+1"}},
+		// Neither skipped work nor a break counts, so the bar stops one
+		// pomodoro short.
+		{"work, first work skipped", CountWork, []engine.EndReason{skipped}, []string{"+1", "abort"}},
+		{"work, break skipped", CountWork, []engine.EndReason{done, skipped}, []string{"+1", "+1"}},
+		{"work, last work skipped", CountWork, []engine.EndReason{done, done, skipped}, []string{"+1", "abort"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, fake := recordProgress(t, tt.counts.Total(3, 2), WithOverallCounts(tt.counts))
+			for _, e := range twoCycles(3, tt.ended...) {
+				p.Update(e)
+			}
+			p.Wait()
+
+			name := "overall"
+			if tt.counts == CountWork {
+				name = "overall pomodoros"
+			}
+			want := []string{fmt.Sprintf("add %s/%d", name, tt.counts.Total(3, 2)), name + " = 0"}
+			for _, op := range tt.want {
+				want = append(want, name+" "+op)
+			}
+			var overall []string
+			for _, op := range fake.ops {
+				if strings.HasPrefix(op, name+" ") || strings.HasPrefix(op, "add "+name+"/") {
+					overall = append(overall, op)
+				}
+			}
+			checkOps(t, &fakeBars{overall}, want)
+		})
+	}
+}
+
+func TestProgressDetachesOnRenderFailure(t *testing.T) {
+	p, fake := recordProgress(t, 3)
+	events := twoCycles(1)
+	p.Update(events[0])
+	p.Update(events[1])
+	p.phaseBar.(*fakeBar).live = false
+	p.Update(events[2])
+
+	select {
+	case <-p.Failed():
+	default:
+		t.Fatal("a phase bar that stopped running did not fail the progress")
+	}
+	p.Wait()
+	if got := fake.ops[len(fake.ops)-1]; got != "shutdown" {
+		t.Errorf("last op %q, want the bars shut down and left alone", got)
+	}
+}
+
+func checkOps(t *testing.T, fake *fakeBars, want []string) {
+	t.Helper()
+	if !slices.Equal(fake.ops, want) {
+		t.Errorf("ops:\n  %s\nwant:\n  %s", strings.Join(fake.ops, "\n  "), strings.Join(want, "\n  "))
+	}
+}
+
+// TestProgressGolden draws a session with mpb, frame by frame, as the
+// default path does, which must not change unless meant to: go test
+// ./ui -run Golden -update rewrites the golden file.
+func TestProgressGolden(t *testing.T) {
+	noColor(t)
+	var out bytes.Buffer
+	p := NewProgress(3, &out, WithStepping())
+	for _, e := range twoCycles(3) {
+		p.Update(e)
+	}
+	p.Wait()
+
+	golden := filepath.Join("testdata", "progress.golden")
+	if *update {
+		if err := os.WriteFile(golden, out.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("output differs from %s:\n%q\nwant:\n%q", golden, out.Bytes(), want)
+	}
+}
+
+func TestEveryPhaseRenders(t *testing.T) {
+	noColor(t)
+	colors := map[*color.Color]engine.Phase{}
+	abbrevs := map[string]engine.Phase{}
+	for phase := engine.PhaseWork; phase < engine.PhaseDone; phase++ {
+		c := PhaseColor(phase)
+		if other, ok := colors[c]; ok {
+			t.Errorf("%s has %s's color", phase, other)
+		}
+		colors[c] = phase
+
+		abbrev := phaseAbbrev(phase)
+		if abbrev == phase.String() {
+			t.Errorf("%s has no abbreviation", phase)
+		}
+		if other, ok := abbrevs[abbrev]; ok {
+			t.Errorf("%s abbreviates as %s does, %q", phase, other, abbrev)
+		}
+		abbrevs[abbrev] = phase
+
+		e := engine.TimerEvent{Type: engine.EventTick, Phase: phase, Total: time.Minute, CycleNum: 2, TotalCycles: 4}
+		if name := formatPhaseName(e); !strings.HasPrefix(name, phase.String()) {
+			t.Errorf("%s named %q", phase, name)
+		}
+		if line := CompactLine(e, 0, false, format.StyleClock); !strings.Contains(line, abbrev) {
+			t.Errorf("%s drawn compact as %q", phase, line)
+		}
+		if barStyleForPhase(phase) == nil {
+			t.Errorf("%s has no bar style", phase)
+		}
+	}
+}
+
+func TestEveryEventTypeHandled(t *testing.T) {
+	// What each event type does to a work phase under way: the engine's
+	// tests make sure every type has a name.
+	want := map[string][]string{
+		"tick":            {"Work (1/2) = 1000"},
+		"session started": nil,
+		"session ended":   nil,
+		"transition":      {"add transition to Short Break (1/2)/1", `print "\a"`},
+		"snooze":          {"add snooze before Short Break (1/2)/1"},
+		"away":            {"add away before Short Break (1/2)/1"},
+	}
+	cfg := engine.Config{TotalCycles: 2}
+	summary := engine.SessionSummary{Ended: engine.EndCompleted}
+	n := 0
+	for typ := engine.EventTick; typ.String() != "Unknown"; typ++ {
+		n++
+		ops, ok := want[typ.String()]
+		if !ok {
+			t.Errorf("no renderer test for %s events", typ)
+			continue
+		}
+		p, fake := recordProgress(t, 3)
+		events := twoCycles(1)
+		p.Update(events[0])
+		p.Update(events[1])
+		before := len(fake.ops)
+
+		e := events[2]
+		e.Type = typ
+		e.Config, e.Summary = &cfg, &summary
+		if typ != engine.EventTick {
+			e.Phase, e.CycleNum, e.Remaining, e.Total = engine.PhaseShortBreak, 2, time.Second, time.Second
+			e.Ended, e.PhaseComplete = "", false
+		}
+		p.Update(e)
+		checkOps(t, &fakeBars{fake.ops[before:]}, ops)
+	}
+	if n != len(want) {
+		t.Errorf("%d event types, %d tested", n, len(want))
+	}
+}
+
+// TestProgressCanceled draws a session with mpb, frame by frame, whose
+// context is canceled 3s into a minute of work, and checks the final
+// frame shows those 3s, not the phase run out or a bar left hanging.
+func TestProgressCanceled(t *testing.T) {
+	noColor(t)
+	clock := engine.NewMockClock(time.Date(2025, time.January, 6, 9, 0, 0, 0, time.UTC))
+	timer := engine.NewTimerWithClock(engine.Config{
+		WorkDuration:       time.Minute,
+		ShortBreakDuration: time.Minute,
+		LongBreakDuration:  time.Minute,
+		LongBreakEvery:     4,
+		TotalCycles:        1,
+	}, clock, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan engine.TimerEvent)
+	done := make(chan error, 1)
+	go func() { done <- timer.Run(ctx, events) }()
+
+	var out bytes.Buffer
+	p := NewProgress(1, &out, WithStepping())
+	for e := range events {
+		p.Update(e)
+		if e.Type != engine.EventTick || ctx.Err() != nil {
+			continue
+		}
+		if e.Elapsed == 3*time.Second {
+			cancel()
+			continue
+		}
+		clock.Advance(time.Second)
+	}
+	p.Abort()
+	if err := <-done; err == nil {
+		t.Error("Run returned no error when canceled")
+	}
+
+	// The phase bar stays as the cancel left it, 3s of 60 filled, and the
+	// overall bar no longer counts time left.
+	frames := strings.Split(out.String(), "\x1b[J")
+	final := frames[len(frames)-1]
+	want := "  Total     [------------------------------------------------]           0/1\n" +
+		"Work (1/1)  [=>----------------------------------------------]   00:03/01:00 interrupted\n"
+	if final != want {
+		t.Errorf("final frame:\n%q\nwant:\n%q", final, want)
+	}
+	if strings.Contains(out.String(), "01:00/01:00") {
+		t.Errorf("a frame shows the phase run out:\n%s", out.String())
+	}
+}
+
+// TestBankNotes extends a break from the bank, partly past it, and past
+// it, checking what the break bar's note and the log say each time.
+func TestBankNotes(t *testing.T) {
+	for _, banking := range []bool{true, false} {
+		p, fake := recordProgress(t, 3)
+		cfg := engine.Config{TotalCycles: 2, BankBreaks: banking}
+		p.Update(engine.TimerEvent{Type: engine.EventSessionStarted, Config: &cfg, TotalCycles: 2, TotalPhases: 3})
+
+		steps := []struct {
+			bank, extended, fromBank time.Duration
+			note, log                string
+		}{
+			{7 * time.Minute, 0, 0, "bank 7m", ""},
+			{5 * time.Minute, 2 * time.Minute, 2 * time.Minute, "bank 5m", "Break extended 2m from the bank"},
+			{0, 8 * time.Minute, 7 * time.Minute, "1m past the bank", "Break extended 6m, 5m from the bank and 1m past it"},
+			{0, 10 * time.Minute, 7 * time.Minute, "3m past the bank", "Break extended 2m past the bank"},
+		}
+		for i, s := range steps {
+			fake.ops = nil
+			e := engine.TimerEvent{
+				Type: engine.EventTick, Phase: engine.PhaseShortBreak,
+				Elapsed: time.Duration(i) * time.Second, Total: 5*time.Minute + s.extended,
+				CycleNum: 1, TotalCycles: 2, PhaseNum: 2, TotalPhases: 3,
+				Bank: s.bank, Extended: s.extended, FromBank: s.fromBank,
+			}
+			p.Update(e)
+
+			note, log := s.note, s.log
+			if !banking {
+				note = ""
+				if s.extended > 0 {
+					log = "Break extended " + format.DurationHuman(s.extended-steps[i-1].extended)
+				}
+			}
+			if got := *p.phaseNote.Load(); got != note {
+				t.Errorf("banking %v, step %d: note %q, want %q", banking, i, got, note)
+			}
+			var logged []string
+			for _, op := range fake.ops {
+				if line, ok := strings.CutPrefix(op, "print "); ok {
+					logged = append(logged, line)
+				}
+			}
+			var want []string
+			if log != "" {
+				want = []string{fmt.Sprintf("%q", log+"\n")}
+			}
+			if !slices.Equal(logged, want) {
+				t.Errorf("banking %v, step %d: logged %v, want %v", banking, i, logged, want)
+			}
+		}
+	}
+}
+
+func TestNextLine(t *testing.T) {
+	work := engine.TimerEvent{Phase: engine.PhaseWork, CycleNum: 2, TotalCycles: 4}
+	brk := engine.TimerEvent{Phase: engine.PhaseShortBreak, CycleNum: 3, TotalCycles: 4}
+	infinite := engine.TimerEvent{Phase: engine.PhaseWork, CycleNum: 7}
+	extra := engine.TimerEvent{Phase: engine.PhaseShortBreak, CycleNum: 2, TotalCycles: 4, Extra: true}
+	warmup := engine.TimerEvent{Phase: engine.PhaseWarmup, CycleNum: 1, TotalCycles: 4}
+	next := func(e engine.TimerEvent, phase engine.Phase, d time.Duration) engine.TimerEvent {
+		e.NextPhase, e.NextDuration = phase, d
+		return e
+	}
+	tests := []struct {
+		e           engine.TimerEvent
+		want, ascii string
+	}{
+		{next(work, engine.PhaseShortBreak, 10*time.Minute), "next: Short Break (10m) → Work 3/4", "next: Short Break (10m) -> Work 3/4"},
+		{next(work, engine.PhaseLongBreak, 15*time.Minute), "next: Long Break (15m) → Work 3/4", ""},
+		{next(work, engine.PhaseWork, 25*time.Minute), "next: Work 3/4 (25m)", ""},
+		{next(work, engine.PhaseCooldown, 5*time.Minute), "next: Cooldown (5m)", ""},
+		{next(work, engine.PhaseDone, 0), "next: session complete 🎉", "next: session complete"},
+		{next(brk, engine.PhaseWork, 20*time.Minute), "next: Work 3/4 (20m)", ""},
+		{next(infinite, engine.PhaseShortBreak, 5*time.Minute), "next: Short Break (5m) → Work 8", ""},
+		// An extra break comes between phases of the schedule, so the work
+		// after it is the cycle it was put into.
+		{next(extra, engine.PhaseWork, 25*time.Minute), "next: Work 2/4 (25m)", ""},
+		{next(warmup, engine.PhaseWork, 25*time.Minute), "next: Work 1/4 (25m)", ""},
+	}
+	for _, tt := range tests {
+		p := NewProgress(4, io.Discard)
+		if got := p.nextLine(tt.e); got != tt.want {
+			t.Errorf("%s %d/%d then %s: %q, want %q", tt.e.Phase, tt.e.CycleNum, tt.e.TotalCycles, tt.e.NextPhase, got, tt.want)
+		}
+		if tt.ascii == "" {
+			continue
+		}
+		p = NewProgress(4, io.Discard, WithLayout(Layout{ASCII: true}))
+		if got := p.nextLine(tt.e); got != tt.ascii {
+			t.Errorf("%s then %s in ASCII: %q, want %q", tt.e.Phase, tt.e.NextPhase, got, tt.ascii)
+		}
+	}
+}
+
+// TestLunchBreak starts a long break stretched to lunch, and one offered
+// lunch, checking the bar's name and what is said of each.
+func TestLunchBreak(t *testing.T) {
+	for _, tt := range []struct {
+		lunch      bool
+		offer      time.Duration
+		total      time.Duration
+		name, said string
+	}{
+		{true, 0, time.Hour, "Lunch (1/2)", "Lunch time: this long break runs 1h"},
+		{false, 44*time.Minute + 30*time.Second, 15 * time.Minute, "Long Break (1/2)", "Lunch time: pomo extend 45m stretches this break to lunch"},
+		{false, 45 * time.Minute, 15 * time.Minute, "Long Break (1/2)", "Lunch time: pomo extend 45m stretches this break to lunch"},
+		{false, 90 * time.Minute, 15 * time.Minute, "Long Break (1/2)", "Lunch time: pomo extend 1h30m stretches this break to lunch"},
+		{false, 0, 15 * time.Minute, "Long Break (1/2)", ""},
+	} {
+		p, fake := recordProgress(t, 3)
+		p.Update(engine.TimerEvent{
+			Type: engine.EventTick, Phase: engine.PhaseLongBreak, Total: tt.total, Remaining: tt.total,
+			CycleNum: 2, TotalCycles: 2, PhaseNum: 2, TotalPhases: 3,
+			Lunch: tt.lunch, LunchOffer: tt.offer,
+		})
+		if p.spec.name != tt.name || p.spec.total != tt.total.Milliseconds() {
+			t.Errorf("lunch %v: bar %q of %dms, want %q of %s", tt.lunch, p.spec.name, p.spec.total, tt.name, tt.total)
+		}
+		var want []string
+		if tt.said != "" {
+			want = append(want, fmt.Sprintf("print %q", tt.said+"\n"))
+		}
+		var got []string
+		for _, op := range fake.ops {
+			if strings.HasPrefix(op, "print ") {
+				got = append(got, op)
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("lunch %v, offered %s: said %q, want %q", tt.lunch, tt.offer, got, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Example runs a pomodoro session in a bubbletea program drawn by the
+// teapomo model, quitting once the session ends. p or space pauses and
+// resumes, s skips, and q or Ctrl-C ends the session early.
+//
+//	cd ui/teapomo && go run ./example -work 25m -break 5m -cycles 4
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/ui/teapomo"
+)
+
+// app wraps the model, as a dashboard would, to quit with the session.
+type app struct {
+	pomo   tea.Model
+	cancel context.CancelFunc
+}
+
+func (a app) Init() tea.Cmd { return a.pomo.Init() }
+
+func (a app) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case teapomo.DoneMsg:
+		return a, tea.Quit
+	case tea.KeyMsg:
+		// Cancelling ends the session, interrupted, and the model sees
+		// its end before the channel closes.
+		if s := msg.String(); s == "q" || s == "ctrl+c" {
+			a.cancel()
+			return a, nil
+		}
+	}
+	var cmd tea.Cmd
+	a.pomo, cmd = a.pomo.Update(msg)
+	return a, cmd
+}
+
+func (a app) View() string { return a.pomo.View() }
+
+func main() {
+	work := flag.Duration("work", 25*time.Minute, "work phase length")
+	short := flag.Duration("break", 5*time.Minute, "short break length")
+	cycles := flag.Int("cycles", 4, "work phases to run (0 = until interrupted)")
+	flag.Parse()
+
+	cfg := engine.Config{
+		WorkDuration:       *work,
+		ShortBreakDuration: *short,
+		LongBreakDuration:  3 * *short,
+		LongBreakEvery:     4,
+		TotalCycles:        *cycles,
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	timer := engine.NewTimer(cfg)
+	events := make(chan engine.TimerEvent)
+	done := make(chan error, 1)
+	go func() { done <- timer.Run(ctx, events) }()
+
+	if _, err := tea.NewProgram(app{pomo: teapomo.New(events, timer), cancel: cancel}).Run(); err != nil {
+		log.Fatal(err)
+	}
+	if err := <-done; err != nil && ctx.Err() == nil {
+		log.Fatal(err)
+	}
+}
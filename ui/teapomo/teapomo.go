@@ -0,0 +1,191 @@
+// Package teapomo is a bubbletea model that draws a pomodoro session as an
+// engine.Timer reports it: the phase, its bar and time left, and the
+// session's bar, or the compact line in a narrow pane. Keys pause, resume,
+// and skip. It is a module of its own, so that pomo does not pull in
+// bubbletea for those who never embed it.
+//
+//	events := make(chan engine.TimerEvent)
+//	go timer.Run(ctx, events)
+//	tea.NewProgram(teapomo.New(events, timer)).Run()
+package teapomo
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/ui"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+// Controls is what the keys act on, as *engine.Timer does.
+type Controls interface {
+	Pause()
+	Resume()
+	Skip()
+}
+
+// EventMsg carries an event from the timer into Update.
+type EventMsg engine.TimerEvent
+
+// DoneMsg is sent once the timer has closed the event channel, after the
+// session's end.
+type DoneMsg struct{}
+
+// KeyMap is which keys, as tea.KeyMsg.String gives them, pause or resume
+// and skip.
+type KeyMap struct {
+	Pause []string
+	Skip  []string
+}
+
+// DefaultKeyMap pauses with p or space and skips with s, as pomo start
+// does.
+func DefaultKeyMap() KeyMap {
+	return KeyMap{Pause: []string{"p", " "}, Skip: []string{"s"}}
+}
+
+// Model is the session's view. It takes every event from the channel
+// given to New, as the timer waits on each, until it closes.
+type Model struct {
+	// Keys may be changed before the program starts.
+	Keys KeyMap
+	// ASCII draws the compact line's icons as plain characters.
+	ASCII bool
+	// Style is how time left is shown.
+	Style format.Style
+
+	events   <-chan engine.TimerEvent
+	controls Controls
+	width    int
+	last     engine.TimerEvent
+	started  bool
+}
+
+// New is a model of the session whose events come on events, with keys
+// acting on controls, which may be nil to leave keys alone.
+func New(events <-chan engine.TimerEvent, controls Controls) Model {
+	return Model{Keys: DefaultKeyMap(), events: events, controls: controls}
+}
+
+// Init starts taking events.
+func (m Model) Init() tea.Cmd {
+	return m.next()
+}
+
+// next waits for the next event, or the channel closing.
+func (m Model) next() tea.Cmd {
+	events := m.events
+	return func() tea.Msg {
+		e, ok := <-events
+		if !ok {
+			return DoneMsg{}
+		}
+		return EventMsg(e)
+	}
+}
+
+// Update takes the timer's events, key presses, and the window's size. It
+// never quits the program; a program of its own can on DoneMsg.
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case EventMsg:
+		e := engine.TimerEvent(msg)
+		// The session's start carries its plan, not a phase to show.
+		if e.Type != engine.EventSessionStarted {
+			m.last, m.started = e, true
+		}
+		return m, m.next()
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case tea.KeyMsg:
+		if m.controls == nil || !m.started || m.last.Type == engine.EventSessionEnded {
+			return m, nil
+		}
+		key := msg.String()
+		switch {
+		case contains(m.Keys.Pause, key) && m.last.Paused:
+			m.controls.Resume()
+		case contains(m.Keys.Pause, key):
+			m.controls.Pause()
+		case contains(m.Keys.Skip, key):
+			m.controls.Skip()
+		}
+	}
+	return m, nil
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// View draws the phase under way, the countdown to the next, or the
+// session's summary once it ends.
+func (m Model) View() string {
+	e := m.last
+	switch {
+	case !m.started:
+		return "Starting…\n"
+	case e.Type == engine.EventSessionEnded:
+		s := e.Summary
+		return fmt.Sprintf("Session %s: %d cycles, %s of work\n", s.Ended, s.CyclesComplete, format.DurationHuman(s.Work))
+	case m.width > 0 && m.width < ui.CompactBelow:
+		return ui.CompactLine(e, m.width, m.ASCII, m.Style) + "\n"
+	case e.Type == engine.EventTransition || e.Type == engine.EventSnooze:
+		return fmt.Sprintf("%s in %s\n", ui.PhaseName(e), format.DurationClock(e.Remaining))
+	case e.Type == engine.EventAway:
+		return fmt.Sprintf("Away %s, %s waits for a check-in\n", format.DurationClock(e.Elapsed), ui.PhaseName(e))
+	}
+
+	var b strings.Builder
+	b.WriteString(ui.PhaseName(e))
+	if e.Paused {
+		b.WriteString("  paused")
+	}
+	b.WriteString("\n")
+
+	// Both bars are as wide as the one with more after it allows.
+	left := m.Style.Duration(e.Remaining)
+	// A break belongs to the cycle before it.
+	cycle := e.CycleNum
+	if e.Phase != engine.PhaseWork {
+		cycle--
+	}
+	total := fmt.Sprintf("Cycle %d, %s of work", cycle, format.DurationHuman(e.SessionWork))
+	if e.TotalPhases > 0 {
+		total = fmt.Sprintf("%d/%d ~%s left", min(e.PhaseNum, e.TotalPhases), e.TotalPhases, format.DurationHuman(e.SessionRemaining))
+	}
+	width := m.barWidth(max(utf8.RuneCountInString(left), utf8.RuneCountInString(total)))
+	b.WriteString(ui.Bar(width, e.Fraction, e.Phase) + " " + left + "\n")
+	if e.TotalPhases > 0 {
+		done := float64(e.PhaseNum-1) + e.Fraction
+		if e.PhaseComplete {
+			done = float64(e.PhaseNum)
+		}
+		b.WriteString(ui.Bar(width, done/float64(e.TotalPhases), engine.PhaseDone) + " " + total + "\n")
+	} else {
+		// An infinite session has no end to fill a bar toward.
+		b.WriteString(total + "\n")
+	}
+	return b.String()
+}
+
+// defaultBar is the bars' width, brackets included, until the window's
+// size is known.
+const defaultBar = 40
+
+// barWidth leaves after columns, and a space, on a line of the window's
+// width.
+func (m Model) barWidth(after int) int {
+	if m.width == 0 {
+		return defaultBar
+	}
+	return max(m.width-after-1, ui.MinCompactBar)
+}
@@ -0,0 +1,206 @@
+package teapomo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fatih/color"
+	"github.com/steenfuentes/pomo/engine"
+)
+
+// fakeControls records which controls the keys reached.
+type fakeControls struct {
+	calls []string
+}
+
+func (f *fakeControls) Pause()  { f.calls = append(f.calls, "pause") }
+func (f *fakeControls) Resume() { f.calls = append(f.calls, "resume") }
+func (f *fakeControls) Skip()   { f.calls = append(f.calls, "skip") }
+
+// tick is an event of the session's second phase, a second work phase or
+// the break after the first.
+func tick(phase engine.Phase, elapsed, total time.Duration) EventMsg {
+	return EventMsg{
+		Type:             engine.EventTick,
+		Phase:            phase,
+		Elapsed:          elapsed,
+		Remaining:        total - elapsed,
+		Total:            total,
+		Fraction:         float64(elapsed) / float64(total),
+		CycleNum:         2,
+		TotalCycles:      4,
+		PhaseNum:         2,
+		TotalPhases:      7,
+		SessionRemaining: 90 * time.Minute,
+		UntilLongBreak:   -1,
+	}
+}
+
+func paused(e EventMsg) EventMsg {
+	e.Paused = true
+	return e
+}
+
+func noColor(t *testing.T) {
+	saved := color.NoColor
+	color.NoColor = true
+	t.Cleanup(func() { color.NoColor = saved })
+}
+
+func key(s string) tea.KeyMsg {
+	switch s {
+	case " ":
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	case "ctrl+c":
+		return tea.KeyMsg{Type: tea.KeyCtrlC}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestUpdate(t *testing.T) {
+	noColor(t)
+	ended := engine.SessionSummary{Ended: engine.EndCompleted, CyclesComplete: 4, Work: 100 * time.Minute}
+	work := tick(engine.PhaseWork, 10*time.Minute, 25*time.Minute)
+
+	tests := []struct {
+		name  string
+		msgs  []tea.Msg
+		calls []string
+		// view is the whole view, or with lines, the lines it starts with.
+		view  string
+		lines bool
+	}{
+		{
+			name: "before any event",
+			msgs: []tea.Msg{key("p")},
+			view: "Starting…\n",
+		},
+		{
+			name: "session started",
+			msgs: []tea.Msg{EventMsg{Type: engine.EventSessionStarted, TotalPhases: 7}},
+			view: "Starting…\n",
+		},
+		{
+			name: "tick",
+			msgs: []tea.Msg{work},
+			view: "Work (2/4)\n" +
+				"[==============>-----------------------] 15:00\n" +
+				"[======>-------------------------------] 2/7 ~1h 30m left\n",
+		},
+		{
+			name:  "pause",
+			msgs:  []tea.Msg{work, key("p"), paused(work)},
+			calls: []string{"pause"},
+			view:  "Work (2/4)  paused\n",
+			lines: true,
+		},
+		{
+			name:  "resume with space",
+			msgs:  []tea.Msg{paused(work), key(" ")},
+			calls: []string{"resume"},
+		},
+		{
+			name:  "skip",
+			msgs:  []tea.Msg{work, key("s")},
+			calls: []string{"skip"},
+		},
+		{
+			name: "quit is left to the program",
+			msgs: []tea.Msg{work, key("q"), key("ctrl+c")},
+		},
+		{
+			name:  "break",
+			msgs:  []tea.Msg{tick(engine.PhaseShortBreak, time.Minute, 5*time.Minute)},
+			view:  "Short Break (1/4)\n",
+			lines: true,
+		},
+		{
+			name:  "long break",
+			msgs:  []tea.Msg{tick(engine.PhaseLongBreak, time.Minute, 15*time.Minute)},
+			view:  "Long Break (1/4)\n",
+			lines: true,
+		},
+		{
+			name:  "cooldown",
+			msgs:  []tea.Msg{tick(engine.PhaseCooldown, time.Minute, 5*time.Minute)},
+			view:  "Cooldown\n",
+			lines: true,
+		},
+		{
+			name:  "warmup",
+			msgs:  []tea.Msg{tick(engine.PhaseWarmup, time.Minute, 3*time.Minute)},
+			view:  "Warmup\n",
+			lines: true,
+		},
+		{
+			name: "transition",
+			msgs: []tea.Msg{EventMsg{Type: engine.EventTransition, Phase: engine.PhaseWork, CycleNum: 2, TotalCycles: 4, Remaining: 5 * time.Second}},
+			view: "Work (2/4) in 00:05\n",
+		},
+		{
+			name:  "narrow window",
+			msgs:  []tea.Msg{tea.WindowSizeMsg{Width: 30, Height: 10}, work},
+			view:  "▶ W",
+			lines: true,
+		},
+		{
+			name: "ended",
+			msgs: []tea.Msg{work, EventMsg{Type: engine.EventSessionEnded, Summary: &ended}, key("s")},
+			view: "Session completed: 4 cycles, 1h 40m of work\n",
+		},
+		{
+			name: "done",
+			msgs: []tea.Msg{work, DoneMsg{}, key("p")},
+			// The model keeps the last phase once the channel closes; a
+			// program quits on DoneMsg.
+			calls: []string{"pause"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controls := &fakeControls{}
+			var m tea.Model = New(nil, controls)
+			for _, msg := range tt.msgs {
+				var cmd tea.Cmd
+				m, cmd = m.Update(msg)
+				// Only an event asks for the next.
+				if _, isEvent := msg.(EventMsg); isEvent != (cmd != nil) {
+					t.Errorf("Update(%T) command %v", msg, cmd != nil)
+				}
+			}
+			if got, want := strings.Join(controls.calls, ","), strings.Join(tt.calls, ","); got != want {
+				t.Errorf("controls %q, want %q", got, want)
+			}
+			view := m.View()
+			switch {
+			case tt.view == "":
+			case tt.lines && !strings.HasPrefix(view, tt.view):
+				t.Errorf("view\n%s\nwant it to start\n%s", view, tt.view)
+			case !tt.lines && view != tt.view:
+				t.Errorf("view %q, want %q", view, tt.view)
+			}
+		})
+	}
+}
+
+func TestUpdateResize(t *testing.T) {
+	noColor(t)
+	work := tick(engine.PhaseWork, 10*time.Minute, 25*time.Minute)
+	var m tea.Model = New(nil, nil)
+	m, _ = m.Update(work)
+
+	for _, width := range []int{60, 80, 120} {
+		m, _ = m.Update(tea.WindowSizeMsg{Width: width, Height: 24})
+		for _, line := range strings.Split(strings.TrimSuffix(m.View(), "\n"), "\n") {
+			if n := len([]rune(line)); n > width {
+				t.Errorf("at width %d, %q is %d wide", width, line, n)
+			}
+		}
+		bars := strings.Split(m.View(), "\n")[1:3]
+		if a, b := strings.Index(bars[0], "]"), strings.Index(bars[1], "]"); a != b || a < width/2 {
+			t.Errorf("at width %d, bars end at %d and %d:\n%s", width, a, b, m.View())
+		}
+	}
+}
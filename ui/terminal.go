@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	"github.com/mattn/go-isatty"
+	"github.com/vbauerster/mpb/v8/cwriter"
+)
+
+// resetTerminal clears colors and attributes and shows the cursor.
+const resetTerminal = "\x1b[0m\x1b[?25h"
+
+// terminal is what has to be put back on the terminal pomo draws to,
+// however the process ends.
+var terminal struct {
+	mu       sync.Mutex
+	out      io.Writer
+	cleanups []*func()
+}
+
+// GuardTerminal makes RestoreTerminal reset output if it is a terminal. A nil
+// output means standard output.
+func GuardTerminal(output io.Writer) {
+	if output == nil {
+		output = os.Stdout
+	}
+	if f, ok := output.(*os.File); !ok || !isatty.IsTerminal(f.Fd()) {
+		return
+	}
+	terminal.mu.Lock()
+	defer terminal.mu.Unlock()
+	terminal.out = output
+}
+
+// terminalWidth is output's width in columns, or 0 if it is not a
+// terminal. A nil output means standard output.
+func terminalWidth(output io.Writer) int {
+	if output == nil {
+		output = os.Stdout
+	}
+	f, ok := output.(*os.File)
+	if !ok || !isatty.IsTerminal(f.Fd()) {
+		return 0
+	}
+	width, _, err := cwriter.GetSize(int(f.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// OnRestore has RestoreTerminal call f, e.g. to leave cbreak mode, until the
+// returned function is called. Cleanups run latest first.
+func OnRestore(f func()) (remove func()) {
+	terminal.mu.Lock()
+	defer terminal.mu.Unlock()
+	entry := &f
+	terminal.cleanups = append(terminal.cleanups, entry)
+	return func() {
+		terminal.mu.Lock()
+		defer terminal.mu.Unlock()
+		for i, c := range terminal.cleanups {
+			if c == entry {
+				terminal.cleanups = append(terminal.cleanups[:i], terminal.cleanups[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// RestoreTerminal runs the registered cleanups, then resets a guarded
+// terminal. Only the first call after each GuardTerminal does anything, so
+// it is safe on every exit path at once.
+func RestoreTerminal() {
+	terminal.mu.Lock()
+	defer terminal.mu.Unlock()
+	for i := len(terminal.cleanups) - 1; i >= 0; i-- {
+		(*terminal.cleanups[i])()
+	}
+	terminal.cleanups = nil
+	if terminal.out != nil {
+		io.WriteString(terminal.out, resetTerminal)
+		terminal.out = nil
+	}
+}
+
+// RestoreOnPanic restores the terminal before letting a panic continue. It
+// must be deferred directly, at the top of any goroutine that may panic
+// while pomo owns the terminal.
+func RestoreOnPanic() {
+	if r := recover(); r != nil {
+		slog.Error("panic", "value", r, "stack", string(debug.Stack()))
+		RestoreTerminal()
+		panic(r)
+	}
+}
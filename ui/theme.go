@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/steenfuentes/pomo/keys"
+)
+
+const backgroundQueryTimeout = 200 * time.Millisecond
+
+type Theme struct {
+	Work     *color.Color
+	Short    *color.Color
+	Long     *color.Color
+	Cooldown *color.Color
+	Warning  *color.Color
+	Overall  *color.Color
+	Dim      *color.Color
+}
+
+var (
+	DarkTheme = Theme{
+		Work:     color.New(color.FgRed),
+		Short:    color.New(color.FgCyan),
+		Long:     color.New(color.FgGreen),
+		Cooldown: color.New(color.FgMagenta),
+		Warning:  color.New(color.FgYellow),
+		Overall:  color.New(color.FgWhite),
+		Dim:      color.New(color.Faint),
+	}
+	LightTheme = Theme{
+		Work:     color.New(color.FgRed),
+		Short:    color.New(color.FgBlue),
+		Long:     color.New(color.FgGreen),
+		Cooldown: color.New(color.FgMagenta),
+		Warning:  color.New(color.FgYellow, color.Bold),
+		Overall:  color.New(color.FgBlack),
+		Dim:      color.New(color.Faint),
+	}
+
+	// The high-contrast themes are bold and, on dark backgrounds, bright,
+	// and draw what the others dim as ordinary text: they never emit
+	// faint.
+	DarkHighContrastTheme = Theme{
+		Work:     color.New(color.FgHiRed, color.Bold),
+		Short:    color.New(color.FgHiCyan, color.Bold),
+		Long:     color.New(color.FgHiGreen, color.Bold),
+		Cooldown: color.New(color.FgHiMagenta, color.Bold),
+		Warning:  color.New(color.FgHiYellow, color.Bold),
+		Overall:  color.New(color.FgHiWhite, color.Bold),
+		Dim:      color.New(color.Reset),
+	}
+	LightHighContrastTheme = Theme{
+		Work:     color.New(color.FgRed, color.Bold),
+		Short:    color.New(color.FgBlue, color.Bold),
+		Long:     color.New(color.FgGreen, color.Bold),
+		Cooldown: color.New(color.FgMagenta, color.Bold),
+		Warning:  color.New(color.FgYellow, color.Bold),
+		Overall:  color.New(color.FgBlack, color.Bold),
+		Dim:      color.New(color.Reset),
+	}
+)
+
+// HighContrast is t's high-contrast counterpart.
+func HighContrast(t Theme) Theme {
+	if t == LightTheme || t == LightHighContrastTheme {
+		return LightHighContrastTheme
+	}
+	return DarkHighContrastTheme
+}
+
+// LimitedAttributes reports whether $TERM names a terminal known to have
+// no faint text, such as a VT100, where dimmed text comes out unreadable
+// or as stray characters.
+func LimitedAttributes() bool {
+	term := os.Getenv("TERM")
+	switch {
+	case strings.HasPrefix(term, "vt"), term == "ansi", term == "cons25", term == "sun":
+		return true
+	default:
+		return false
+	}
+}
+
+// UseTheme must be called before any Progress is created.
+func UseTheme(t Theme) {
+	workColor = t.Work
+	shortColor = t.Short
+	longColor = t.Long
+	cooldownColor = t.Cooldown
+	warningColor = t.Warning
+	overallColor = t.Overall
+	dimColor = t.Dim
+}
+
+// DetectTheme picks LightTheme when the terminal reports a light background,
+// and DarkTheme when it is dark or the terminal cannot be asked. It reads
+// from the terminal, so it must run before any Progress takes over output.
+func DetectTheme() Theme {
+	if color.NoColor {
+		return DarkTheme
+	}
+
+	spec, err := keys.QueryBackground(os.Stdin, os.Stdout, backgroundQueryTimeout)
+	if err != nil {
+		return DarkTheme
+	}
+
+	bg, ok := parseColorSpec(spec)
+	if !ok || luminance(bg) < 0.5 {
+		return DarkTheme
+	}
+	return LightTheme
+}
+
+// parseColorSpec reads the XParseColor "rgb:r/g/b" form terminals reply
+// with, where each component has one to four hex digits.
+func parseColorSpec(spec string) (RGB, bool) {
+	rest, ok := strings.CutPrefix(spec, "rgb:")
+	if !ok {
+		return RGB{}, false
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 {
+		return RGB{}, false
+	}
+
+	var c [3]uint8
+	for i, p := range parts {
+		if len(p) < 1 || len(p) > 4 {
+			return RGB{}, false
+		}
+		v, err := strconv.ParseUint(p, 16, 16)
+		if err != nil {
+			return RGB{}, false
+		}
+		full := uint64(1)<<(4*len(p)) - 1
+		c[i] = uint8(v * 255 / full)
+	}
+
+	return RGB{c[0], c[1], c[2]}, true
+}
+
+func luminance(c RGB) float64 {
+	return (0.2126*float64(c.R) + 0.7152*float64(c.G) + 0.0722*float64(c.B)) / 255
+}
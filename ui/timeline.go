@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/history"
+	"github.com/steenfuentes/pomo/ui/format"
+)
+
+// PrintTimeline writes one row per record in the order given, with a dim
+// row for every pause between records longer than gap. With ids, each row
+// starts with the record's ID, linked to the link template filled in with
+// it unless link is empty.
+func PrintTimeline(w io.Writer, records []history.Record, gap time.Duration, ids bool, link string) {
+	for i, r := range records {
+		if i > 0 {
+			if idle := r.Start.Sub(records[i-1].End); gap > 0 && idle > gap {
+				fmt.Fprintln(w, dimColor.Sprintf("        — %s gap —", format.DurationHuman(idle)))
+			}
+		}
+
+		name := fmt.Sprintf("%-11s", r.Phase)
+		var row []string
+		if ids {
+			id := dimColor.Sprint(r.ID())
+			if link != "" {
+				id = Hyperlink(ExpandLink(link, r.ID()), id)
+			}
+			row = append(row, id)
+		}
+		row = append(row,
+			r.Start.Local().Format("15:04"),
+			PhaseColor(r.Phase).Sprint(name),
+			fmt.Sprintf("%s / %s", format.DurationClock(r.Actual()), format.DurationClock(r.Planned())),
+		)
+
+		if r.Label != "" {
+			row = append(row, r.Label)
+		}
+		for _, tag := range r.Tags {
+			row = append(row, "#"+tag)
+		}
+
+		var notes []string
+		if r.Extra {
+			notes = append(notes, "extra")
+		}
+		if r.Enforced {
+			notes = append(notes, "enforced")
+		}
+		if r.Lunch {
+			notes = append(notes, "lunch")
+		}
+		if r.Ended != engine.EndCompleted {
+			notes = append(notes, string(r.Ended))
+		}
+		if r.Voided {
+			notes = append(notes, "voided")
+		}
+		if r.Distractions > 0 {
+			notes = append(notes, fmt.Sprintf("%d %s", r.Distractions, plural(r.Distractions, "distraction")))
+		}
+		if a, ok := r.Activity(); ok {
+			notes = append(notes, format.Percent(a)+" active")
+		}
+		if r.Pauses > 0 {
+			notes = append(notes, fmt.Sprintf("%d %s (%s)", r.Pauses, plural(r.Pauses, "pause"), format.DurationPrecise(r.Paused())))
+		}
+		if r.Rating > 0 {
+			notes = append(notes, fmt.Sprintf("rated %d/%d", r.Rating, history.MaxRating))
+		}
+		if len(notes) > 0 {
+			row = append(row, dimColor.Sprint(strings.Join(notes, ", ")))
+		}
+		if r.Note != "" {
+			row = append(row, fmt.Sprintf("%q", r.Note))
+		}
+		if r.Retro != "" {
+			row = append(row, fmt.Sprintf("%q", r.Retro))
+		}
+
+		fmt.Fprintln(w, strings.Join(row, "  "))
+	}
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"io"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/sound"
+	"github.com/vbauerster/mpb/v8"
+	"github.com/vbauerster/mpb/v8/decor"
+)
+
+// WithWarnings turns a phase's bar to the warning color, and rings the bell
+// once, when no more than before[phase] of it remains.
+func WithWarnings(before map[engine.Phase]time.Duration) Option {
+	return func(p *Progress) {
+		p.warnings = before
+	}
+}
+
+// WithQuiet leaves the bell out of warnings while quiet reports true. The
+// warning line and bar color still show.
+func WithQuiet(quiet func() bool) Option {
+	return func(p *Progress) {
+		p.quiet = quiet
+	}
+}
+
+// WithSounds has cues play what s sets for them in place of the bell:
+// phase starts, warnings, and the session's end. Quiet hours silence them
+// too.
+func WithSounds(s *sound.Set) Option {
+	return func(p *Progress) {
+		p.sounds = s
+	}
+}
+
+// warningStyle wraps barStyle so the filler switches to the warning color
+// for the final stretch of phases with a warning. Phases no longer than
+// the warning keep their usual color throughout.
+func (p *Progress) warningStyle(phase engine.Phase, total time.Duration) mpb.BarFillerBuilder {
+	style := p.barStyle(phase)
+	before := p.warnings[phase]
+	if before <= 0 || total <= before {
+		return style
+	}
+	return warningStyle{
+		normal:  style,
+		warning: mpb.BarStyle().Lbound("[").Tip(">").Padding("-").Rbound("]").Filler("=").FillerMeta(paint(warningColor)),
+		before:  before.Milliseconds(),
+	}
+}
+
+type warningStyle struct {
+	normal  mpb.BarFillerBuilder
+	warning mpb.BarFillerBuilder
+	before  int64
+}
+
+func (w warningStyle) Build() mpb.BarFiller {
+	normal, warning := w.normal.Build(), w.warning.Build()
+
+	return mpb.BarFillerFunc(func(out io.Writer, st decor.Statistics) error {
+		if st.Total-st.Current <= w.before {
+			return warning.Fill(out, st)
+		}
+		return normal.Fill(out, st)
+	})
+}
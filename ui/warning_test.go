@@ -0,0 +1,125 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steenfuentes/pomo/engine"
+	"github.com/steenfuentes/pomo/sound"
+)
+
+// cuePlayer records the cues played, from files named after them.
+type cuePlayer struct {
+	mu     sync.Mutex
+	played []string
+}
+
+func (c *cuePlayer) Play(path string, volume float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.played = append(c.played, strings.TrimSuffix(filepath.Base(path), ".wav"))
+	return nil
+}
+
+// cueSounds gives every cue a file of its own, played by the returned
+// player.
+func cueSounds(t *testing.T) (*sound.Set, *cuePlayer) {
+	t.Helper()
+	dir := t.TempDir()
+	cues := make(map[string]string)
+	for _, c := range sound.Cues {
+		path := filepath.Join(dir, string(c)+".wav")
+		if err := os.WriteFile(path, []byte("RIFF"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cues[string(c)] = path
+	}
+	player := &cuePlayer{}
+	s, err := sound.New(cues, 1, player)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, player
+}
+
+// withTransitions puts a 2s countdown before each phase of events after
+// the first, as a session with --transition does.
+func withTransitions(events []engine.TimerEvent) []engine.TimerEvent {
+	var out []engine.TimerEvent
+	for i, e := range events {
+		if e.Type == engine.EventTick && e.Elapsed == 0 && i > 1 {
+			for left := 2 * time.Second; left > 0; left -= time.Second {
+				out = append(out, engine.TimerEvent{Type: engine.EventTransition, Phase: e.Phase, Total: 2 * time.Second, Elapsed: 2*time.Second - left, Remaining: left, CycleNum: e.CycleNum, TotalCycles: e.TotalCycles})
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func ended(how engine.EndReason) engine.TimerEvent {
+	return engine.TimerEvent{Type: engine.EventSessionEnded, Summary: &engine.SessionSummary{Ended: how}}
+}
+
+// TestSoundCues plays two cycles, each event at a time, and checks which
+// cue sounded for which, by its index. Without transitions, events 1 to 3
+// are the first work phase, 4 and 5 the break, and 6 to 8 the second work
+// phase; with them, each of the later phases has two countdown events
+// before it.
+func TestSoundCues(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []engine.TimerEvent
+		quiet  bool
+		want   []string
+	}{
+		{
+			name:   "completed",
+			events: append(twoCycles(3), ended(engine.EndCompleted)),
+			want:   []string{"1 work_start", "2 warning", "4 break_start", "6 work_start", "7 warning", "9 session_done"},
+		},
+		{
+			name:   "with transitions",
+			events: append(withTransitions(twoCycles(3)), ended(engine.EndCompleted)),
+			want:   []string{"1 work_start", "2 warning", "4 break_start", "8 work_start", "11 warning", "13 session_done"},
+		},
+		{
+			name:   "stopped",
+			events: append(twoCycles(1, engine.EndInterrupted), ended(engine.EndInterrupted)),
+			want:   []string{"1 work_start"},
+		},
+		{
+			name:   "quiet hours",
+			events: append(withTransitions(twoCycles(3)), ended(engine.EndCompleted)),
+			quiet:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sounds, player := cueSounds(t)
+			p, _ := recordProgress(t, 3,
+				WithSounds(sounds),
+				WithWarnings(map[engine.Phase]time.Duration{engine.PhaseWork: time.Second}),
+				WithQuiet(func() bool { return tt.quiet }))
+			var got []string
+			for i, e := range tt.events {
+				p.Update(e)
+				sounds.Wait()
+				for _, cue := range player.played {
+					got = append(got, fmt.Sprintf("%d %s", i, cue))
+				}
+				player.played = nil
+			}
+			p.Wait()
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("played\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
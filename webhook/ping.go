@@ -0,0 +1,71 @@
+// Package webhook sends healthchecks.io-style heartbeat pings, as a notify
+// channel.
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/steenfuentes/pomo/notify"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+	DefaultRetries = 2
+)
+
+// Pinger follows healthchecks.io conventions: a GET to the check URL after
+// each completed work phase, and to URL/fail when a session is interrupted
+// or stopped by its hard cap.
+type Pinger struct {
+	http       *http.Client
+	successURL string
+	failURL    string
+}
+
+// NewPinger derives the success and failure URLs from base. Either can be
+// overridden with a non-empty successURL or failURL.
+func NewPinger(base, successURL, failURL string) *Pinger {
+	if successURL == "" {
+		successURL = base
+	}
+	if failURL == "" && base != "" {
+		failURL = strings.TrimRight(base, "/") + "/fail"
+	}
+	return &Pinger{http: &http.Client{}, successURL: successURL, failURL: failURL}
+}
+
+func (p *Pinger) Name() string { return "heartbeat" }
+
+func (p *Pinger) Notify(ctx context.Context, m notify.Message) error {
+	var url string
+	switch m.Kind {
+	case notify.KindWorkDone:
+		url = p.successURL
+	case notify.KindInterrupted, notify.KindCapped:
+		url = p.failURL
+	}
+	if url == "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/steenfuentes/pomo/notify"
+)
+
+// checkServer records the path of every request, answering each with
+// status.
+func checkServer(t *testing.T, status int) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method != http.MethodGet {
+			t.Errorf("%s %s, want GET", r.Method, r.URL.Path)
+		}
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(paths)
+	}
+}
+
+func TestPingerPaths(t *testing.T) {
+	srv, paths := checkServer(t, http.StatusOK)
+	p := NewPinger(srv.URL+"/ping/abc/", "", "")
+	for _, kind := range []notify.Kind{notify.KindWorkDone, notify.KindInterrupted, notify.KindCapped, notify.KindMilestone} {
+		if err := p.Notify(context.Background(), notify.Message{Kind: kind}); err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+	}
+	want := []string{"/ping/abc/", "/ping/abc/fail", "/ping/abc/fail"}
+	if got := paths(); !slices.Equal(got, want) {
+		t.Errorf("requests %q, want %q", got, want)
+	}
+}
+
+func TestPingerOverrides(t *testing.T) {
+	srv, paths := checkServer(t, http.StatusOK)
+	p := NewPinger(srv.URL+"/base", srv.URL+"/ok", "")
+	p.Notify(context.Background(), notify.Message{Kind: notify.KindWorkDone})
+	p.Notify(context.Background(), notify.Message{Kind: notify.KindInterrupted})
+	if got, want := paths(), []string{"/ok", "/base/fail"}; !slices.Equal(got, want) {
+		t.Errorf("requests %q, want %q", got, want)
+	}
+
+	// Without a base, only what is given is pinged.
+	p = NewPinger("", "", srv.URL+"/down")
+	p.Notify(context.Background(), notify.Message{Kind: notify.KindWorkDone})
+	p.Notify(context.Background(), notify.Message{Kind: notify.KindCapped})
+	if got, want := paths(), []string{"/ok", "/base/fail", "/down"}; !slices.Equal(got, want) {
+		t.Errorf("requests %q, want %q", got, want)
+	}
+}
+
+func TestPingerReportsErrorStatus(t *testing.T) {
+	srv, _ := checkServer(t, http.StatusNotFound)
+	p := NewPinger(srv.URL, "", "")
+	if err := p.Notify(context.Background(), notify.Message{Kind: notify.KindInterrupted}); err == nil {
+		t.Error("404 from the /fail URL: no error")
+	}
+}